@@ -0,0 +1,87 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.aledante.io/ae"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxAttemptErrors bounds the number of attempt errors kept for the error returned when giving up.
+const maxAttemptErrors = 10
+
+// attemptKey is an unexported type used as the key for storing the attempt number in a context.
+type attemptKey struct{}
+
+// previousErrorKey is an unexported type used as the key for storing the error of the previous attempt in a context.
+type previousErrorKey struct{}
+
+// withAttempt returns a new context based on ctx that carries the 1-based number of the current attempt within the
+// grace window and the error of the previous attempt, if any.
+func withAttempt(ctx context.Context, attempt int, previous error) context.Context {
+	ctx = context.WithValue(ctx, attemptKey{}, attempt)
+	if previous != nil {
+		ctx = context.WithValue(ctx, previousErrorKey{}, previous)
+	}
+
+	return ctx
+}
+
+// RestartAttempt returns the 1-based number of the current run of the service within the grace window: 1 for the
+// first run, 2 for the first restart, and so on. It returns 0 if the context does not belong to a supervised
+// service.
+func RestartAttempt(ctx context.Context) int {
+	v, _ := ctx.Value(attemptKey{}).(int)
+	return v
+}
+
+// PreviousError returns the error the previous run of the service failed with, or nil on the first run and after
+// restarts following a successful run (see RestartOnSuccess).
+func PreviousError(ctx context.Context) error {
+	v, _ := ctx.Value(previousErrorKey{}).(error)
+	return v
+}
+
+// attemptErrors records the errors of the most recent failed attempts of a service.
+type attemptErrors struct {
+	start    time.Time
+	attempts int
+	// previous are the errors of the failed attempts before the current one.
+	previous []error
+	current  error
+}
+
+// record records a new attempt with the given error, which is nil if the attempt succeeded.
+func (a *attemptErrors) record(err error) {
+	a.attempts++
+	if a.current != nil {
+		a.previous = append(a.previous, a.current)
+		if len(a.previous) > maxAttemptErrors {
+			a.previous = a.previous[len(a.previous)-maxAttemptErrors:]
+		}
+	}
+	a.current = err
+}
+
+// wrap returns err, the final error of the current attempt, annotated with the number of attempts, the total
+// elapsed time, and the errors of the previous failed attempts as causes, so they are matched by errors.Is and
+// errors.As.
+func (a *attemptErrors) wrap(ctx context.Context, err error) error {
+	if a.attempts <= 1 {
+		return err
+	}
+
+	elapsed := time.Since(a.start)
+	ctx = ae.WithOtelAttribute(ctx,
+		attribute.Int("attempt_count", a.attempts),
+		attribute.String("total_elapsed", elapsed.String()),
+	)
+
+	causes := append(append(make([]error, 0, len(a.previous)+1), a.previous...), err)
+
+	return ae.NewC(ctx).
+		Cause(ae.WrapMany(fmt.Sprintf("%d failed attempts", len(causes)), causes...)).
+		Msg(fmt.Sprintf("service failed after %d attempts in %s", a.attempts, elapsed.Round(time.Millisecond)))
+}
@@ -0,0 +1,128 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttemptErrors(t *testing.T) {
+	errs := []error{errors.New("first"), errors.New("second"), errors.New("third")}
+
+	var attempts []int
+	var previous []error
+	svc := &testService{run: func(ctx context.Context) error {
+		attempt := RestartAttempt(ctx)
+		attempts = append(attempts, attempt)
+		previous = append(previous, PreviousError(ctx))
+		return errs[attempt-1]
+	}}
+
+	err := RunC(svc, context.Background(), testOptions(WithGraceCount(2))...)
+	if !errors.Is(err, ErrRestartBudgetExhausted) {
+		t.Fatalf("RunC() = %v, want ErrRestartBudgetExhausted", err)
+	}
+
+	// The final error exposes the causes of all attempts
+	for _, want := range errs {
+		if !errors.Is(err, want) {
+			t.Errorf("errors.Is(%v, %v) = false", err, want)
+		}
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("error %q lacks the attempt count", err)
+	}
+
+	if want := []int{1, 2, 3}; !slices.Equal(attempts, want) {
+		t.Errorf("RestartAttempt() = %v, want %v", attempts, want)
+	}
+	if previous[0] != nil || !errors.Is(previous[1], errs[0]) || !errors.Is(previous[2], errs[1]) {
+		t.Errorf("PreviousError() = %v", previous)
+	}
+}
+
+func TestAttemptErrorsBounded(t *testing.T) {
+	a := &attemptErrors{start: time.Now()}
+	var errs []error
+	for i := range maxAttemptErrors + 5 {
+		err := fmt.Errorf("attempt %d", i+1)
+		errs = append(errs, err)
+		a.record(err)
+	}
+
+	if len(a.previous) != maxAttemptErrors {
+		t.Fatalf("kept %d previous errors, want %d", len(a.previous), maxAttemptErrors)
+	}
+
+	err := a.wrap(context.Background(), a.current)
+	if errors.Is(err, errs[0]) {
+		t.Error("the oldest error was kept")
+	}
+	for _, want := range errs[len(errs)-maxAttemptErrors-1:] {
+		if !errors.Is(err, want) {
+			t.Errorf("errors.Is(%v) = false", want)
+		}
+	}
+}
+
+func TestAttemptErrorsSingleAttempt(t *testing.T) {
+	errFailed := errors.New("failed")
+	a := &attemptErrors{start: time.Now()}
+	a.record(errFailed)
+
+	if err := a.wrap(context.Background(), errFailed); err != errFailed {
+		t.Errorf("wrap() = %v, want the error unchanged", err)
+	}
+}
+
+func TestRestartAttemptWithoutSupervisor(t *testing.T) {
+	if got := RestartAttempt(context.Background()); got != 0 {
+		t.Errorf("RestartAttempt() = %d, want 0", got)
+	}
+}
+
+func TestRestartAttemptInInit(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	type observed struct {
+		attempt  int
+		previous error
+	}
+	var inits []observed
+	third := make(chan struct{})
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			inits = append(inits, observed{RestartAttempt(ctx), PreviousError(ctx)})
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			switch RestartAttempt(ctx) {
+			case 1:
+				return errFailed
+			case 2:
+				return nil
+			default:
+				close(third)
+				<-ctx.Done()
+				return nil
+			}
+		},
+	}
+
+	cancel, done := runTest(t, svc, WithRestartOnSuccess(true))
+	<-third
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// Init of the second attempt observes the failure of the first; the successful second attempt leaves none
+	if len(inits) != 3 || inits[0] != (observed{1, nil}) || inits[1].attempt != 2 ||
+		!errors.Is(inits[1].previous, errFailed) || inits[2] != (observed{3, nil}) {
+		t.Errorf("observed in Init = %+v", inits)
+	}
+}
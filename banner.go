@@ -0,0 +1,113 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// logBanner logs a single Info record describing the starting service: its identity, build, runtime, environment,
+// OTEL exporters, key options, and the prefixed environment variables, with likely secrets redacted.
+func logBanner(ctx context.Context, opts Options) {
+	if !opts.ShowBanner {
+		return
+	}
+
+	build := []any{
+		"vcs_revision", VCSVersion(),
+		"vcs_modified", vcsModified(),
+		"go_version", runtime.Version(),
+	}
+
+	hostname, _ := os.Hostname()
+	runtimeAttrs := []any{
+		"pid", os.Getpid(),
+		"hostname", hostname,
+		"gomaxprocs", runtime.GOMAXPROCS(0),
+	}
+
+	otel := []any{
+		"traces_exporter", envOrDefault("OTEL_TRACES_EXPORTER", "default"),
+		"metrics_exporter", envOrDefault("OTEL_METRICS_EXPORTER", "default"),
+		"fallback", string(opts.OTELFallback),
+	}
+
+	var env []any
+	for _, entry := range PrefixedEnviron(ctx) {
+		env = append(env, entry.Key, entry.Value)
+	}
+
+	Logger(ctx).Info("starting service",
+		"environment", deploymentEnvironment(),
+		slog.Group("build", build...),
+		slog.Group("runtime", runtimeAttrs...),
+		slog.Group("otel", otel...),
+		slog.Group("options", keyOptions(opts)...),
+		slog.Group("env", env...),
+	)
+}
+
+// keyOptions returns the options describing the behavior of the service as key-value pairs, for the startup
+// banner and crash reports. Options which may carry credentials, like HeartbeatURL, are never included.
+func keyOptions(opts Options) []any {
+	options := []any{
+		"restart_on_error", opts.RestartOnError,
+		"restart_on_panic", opts.RestartOnPanic,
+		"grace_period", opts.GracePeriod.String(),
+		"grace_count", opts.GraceCount,
+		"shutdown_timeout", opts.ShutdownTimeout.String(),
+		"drain_delay", opts.DrainDelay.String(),
+		"log_level", logLevel(opts).String(),
+		"log_output", opts.LogOutput,
+	}
+	if opts.ReadyFile != "" {
+		options = append(options, "ready_file", opts.ReadyFile)
+	}
+	if opts.CrashDir != "" {
+		options = append(options, "crash_dir", opts.CrashDir)
+	}
+
+	return options
+}
+
+// vcsModified reports whether the binary was built from a source tree with local modifications.
+func vcsModified() bool {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return false
+	}
+
+	for _, setting := range bi.Settings {
+		if setting.Key == "vcs.modified" {
+			return setting.Value == "true"
+		}
+	}
+
+	return false
+}
+
+// deploymentEnvironment returns the deployment environment (e.g. prod) set in the OTEL_RESOURCE_ATTRIBUTES
+// environment variable, or the empty string.
+func deploymentEnvironment() string {
+	for _, attr := range strings.Split(os.Getenv("OTEL_RESOURCE_ATTRIBUTES"), ",") {
+		key, value, _ := strings.Cut(attr, "=")
+		switch strings.TrimSpace(key) {
+		case "deployment.environment.name", "deployment.environment":
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
+
+// envOrDefault returns the value of the environment variable key, or def if it is empty.
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return def
+}
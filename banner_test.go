@@ -0,0 +1,107 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// bannerGolden is the startup banner of TestBanner, with the values depending on the build and host replaced by
+// placeholders.
+const bannerGolden = `{
+	"level": "INFO",
+	"msg": "starting service",
+	"service": "test",
+	"namespace": "astest",
+	"version": "v1.0.0",
+	"environment": "staging",
+	"build": {"go_version": "<go>", "vcs_modified": "<vcs>", "vcs_revision": "<vcs>"},
+	"runtime": {"gomaxprocs": "<host>", "hostname": "<host>", "pid": "<host>"},
+	"otel": {"fallback": "noop", "metrics_exporter": "default", "traces_exporter": "otlp"},
+	"options": {
+		"restart_on_error": true,
+		"restart_on_panic": true,
+		"grace_period": "1m0s",
+		"grace_count": 3,
+		"shutdown_timeout": "30s",
+		"drain_delay": "0s",
+		"log_level": "INFO",
+		"log_output": "stdout",
+		"crash_dir": "/var/crash"
+	},
+	"env": {"DB_PASSWORD": "[REDACTED]", "REGION": "eu-west-1"}
+}`
+
+func TestBanner(t *testing.T) {
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.instance.id=1,deployment.environment.name=staging")
+	t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+	t.Setenv("OTEL_METRICS_EXPORTER", "")
+	t.Setenv("ASTEST_TEST_REGION", "eu-west-1")
+	t.Setenv("ASTEST_TEST_DB_PASSWORD", "hunter2")
+
+	svc := &testService{run: func(ctx context.Context) error { return nil }}
+	logs := &logCapture{}
+	opts := testOptions(captureLogs(svc, logs), WithShowBanner(true), WithCrashDir("/var/crash"))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	record := logs.find("starting service")
+	if record == nil {
+		t.Fatal("banner not logged")
+	}
+
+	delete(record, "time")
+	placeholders := map[string]map[string]string{
+		"build":   {"go_version": "<go>", "vcs_modified": "<vcs>", "vcs_revision": "<vcs>"},
+		"runtime": {"gomaxprocs": "<host>", "hostname": "<host>", "pid": "<host>"},
+	}
+	for group, keys := range placeholders {
+		attrs, _ := record[group].(map[string]any)
+		for key, placeholder := range keys {
+			if _, ok := attrs[key]; ok {
+				attrs[key] = placeholder
+			}
+		}
+	}
+
+	var want map[string]any
+	if err := json.Unmarshal([]byte(bannerGolden), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(record, want) {
+		got, _ := json.MarshalIndent(record, "", "\t")
+		t.Errorf("banner = %s, want %s", got, bannerGolden)
+	}
+}
+
+func TestBannerDisabled(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error { return nil }}
+	logs := &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs), WithShowBanner(false))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if record := logs.find("starting service"); record != nil {
+		t.Errorf("banner logged although disabled: %v", record)
+	}
+}
+
+func TestDeploymentEnvironment(t *testing.T) {
+	tests := []struct {
+		attrs string
+		want  string
+	}{
+		{attrs: "", want: ""},
+		{attrs: "deployment.environment=prod", want: "prod"},
+		{attrs: "service.version=1, deployment.environment.name = dev ", want: "dev"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("OTEL_RESOURCE_ATTRIBUTES", tt.attrs)
+		if got := deploymentEnvironment(); got != tt.want {
+			t.Errorf("deploymentEnvironment() with %q = %q, want %q", tt.attrs, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package as
+
+import (
+	"time"
+)
+
+// OpenPolicy defines what the supervisor does when the restart circuit breaker opens.
+type OpenPolicy string
+
+const (
+	// OpenPolicyGiveUp stops restarting the service and returns its last error.
+	OpenPolicyGiveUp OpenPolicy = "giveup"
+	// OpenPolicyCooldown pauses restarts for BreakerCooldown, then restarts the service once as a probe.
+	// If the probe runs for at least BreakerWindow, the breaker closes again; otherwise it reopens immediately.
+	OpenPolicyCooldown OpenPolicy = "cooldown"
+)
+
+// restartBreaker is a circuit breaker counting service failures within a sliding window.
+// Its accounting is independent of GracePeriod and GraceCount.
+type restartBreaker struct {
+	failures   []time.Time
+	halfOpen   bool
+	probeStart time.Time
+}
+
+// recordFailure records a failure at now and reports whether the breaker opened.
+// Always returns false if BreakerFailures is not positive.
+func (b *restartBreaker) recordFailure(now time.Time, opts Options) bool {
+	if opts.BreakerFailures <= 0 {
+		return false
+	}
+
+	if b.halfOpen {
+		b.halfOpen = false
+
+		// A probe failing within the window reopens the breaker right away
+		if now.Sub(b.probeStart) < opts.BreakerWindow {
+			return true
+		}
+		b.failures = nil
+	}
+
+	if opts.BreakerWindow > 0 {
+		cutoff := now.Add(-opts.BreakerWindow)
+
+		i := 0
+		for i < len(b.failures) && !b.failures[i].After(cutoff) {
+			i++
+		}
+		b.failures = b.failures[i:]
+	}
+
+	b.failures = append(b.failures, now)
+	if len(b.failures) >= opts.BreakerFailures {
+		b.failures = nil
+		return true
+	}
+
+	return false
+}
+
+// startProbe moves the breaker into the half-open state after a cool-down, starting a probe run at now.
+func (b *restartBreaker) startProbe(now time.Time) {
+	b.halfOpen = true
+	b.probeStart = now
+}
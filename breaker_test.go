@@ -0,0 +1,136 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRestartBreaker(t *testing.T) {
+	opts := Options{BreakerFailures: 3, BreakerWindow: 10 * time.Minute}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// failures are the offsets of the failures from start
+		failures []time.Duration
+		// probe starts a half-open probe at the offset before the last failure, if non-zero
+		probe    time.Duration
+		wantOpen bool
+	}{
+		{name: "below threshold", failures: []time.Duration{0, 2 * time.Minute}},
+		{
+			name:     "failures just outside a grace period",
+			failures: []time.Duration{0, 2 * time.Minute, 4 * time.Minute},
+			wantOpen: true,
+		},
+		{
+			name:     "failures spread beyond the window",
+			failures: []time.Duration{0, 6 * time.Minute, 12 * time.Minute, 18 * time.Minute},
+		},
+		{
+			name:     "probe failing within the window",
+			failures: []time.Duration{20 * time.Minute},
+			probe:    15 * time.Minute,
+			wantOpen: true,
+		},
+		{
+			name:     "probe failing after the window",
+			failures: []time.Duration{30 * time.Minute},
+			probe:    15 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &restartBreaker{}
+			if tt.probe != 0 {
+				b.startProbe(start.Add(tt.probe))
+			}
+
+			var opened bool
+			for _, offset := range tt.failures {
+				opened = b.recordFailure(start.Add(offset), opts)
+			}
+			if opened != tt.wantOpen {
+				t.Errorf("breaker opened = %t, want %t", opened, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestRestartBreakerDisabled(t *testing.T) {
+	b := &restartBreaker{}
+	now := time.Now()
+	for range 100 {
+		if b.recordFailure(now, Options{}) {
+			t.Fatal("breaker opened without BreakerFailures")
+		}
+	}
+}
+
+func TestRestartBreakerGiveUp(t *testing.T) {
+	attempts := 0
+	svc := &testService{run: func(ctx context.Context) error {
+		attempts++
+		return errors.New("failed")
+	}}
+
+	err := RunC(svc, context.Background(), testOptions(
+		WithGraceCount(100),
+		WithBreakerFailures(3),
+		WithBreakerWindow(time.Minute),
+		WithBreakerPolicy(OpenPolicyGiveUp),
+	)...)
+	if !errors.Is(err, ErrRestartBudgetExhausted) {
+		t.Fatalf("RunC() = %v, want ErrRestartBudgetExhausted", err)
+	}
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want 3", attempts)
+	}
+}
+
+func TestRestartBreakerCooldown(t *testing.T) {
+	var degraded atomic.Bool
+	attempts := 0
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			if attempts == 0 {
+				supervisorFrom(ctx).onStateChange(func(state State) {
+					if state == StateDegraded {
+						degraded.Store(true)
+					}
+				})
+			}
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			if attempts++; attempts <= 2 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+
+	start := time.Now()
+	err := RunC(svc, context.Background(), testOptions(
+		WithBreakerFailures(2),
+		WithBreakerWindow(time.Minute),
+		WithBreakerPolicy(OpenPolicyCooldown),
+		WithBreakerCooldown(50*time.Millisecond),
+	)...)
+	if err != nil {
+		t.Fatalf("RunC() = %v, want the probe run to succeed", err)
+	}
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want 3", attempts)
+	}
+	if !degraded.Load() {
+		t.Error("service was not degraded while the breaker was open")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("RunC returned after %s, want a cool-down of 50ms", elapsed)
+	}
+}
@@ -0,0 +1,138 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aledante.io/ae"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RestartBudget is a restart limit shared by several services run in the same process, e.g. when the process is
+// deployed as one unit. Every failure restart of a service using the budget (see WithRestartBudget) is counted
+// against it in addition to the own grace limits of the service. Once the services together restarted more than
+// the allowed number of times within the period, all of them are stopped and exit with an error naming the
+// services which contributed to the restarts, so the orchestrator can reschedule the process.
+//
+// A RestartBudget is safe for concurrent use and must be created with NewRestartBudget.
+type RestartBudget struct {
+	count  int
+	period time.Duration
+
+	mu        sync.Mutex
+	restarts  []budgetRestart
+	total     int
+	members   map[*supervisor]context.CancelFunc
+	exhausted error
+}
+
+// budgetRestart is a restart counted against a RestartBudget.
+type budgetRestart struct {
+	time    time.Time
+	service string
+	err     error
+}
+
+// NewRestartBudget returns a RestartBudget allowing count restarts within the sliding window period, across all
+// services using it. A zero period counts all restarts since the process started.
+func NewRestartBudget(count int, period time.Duration) *RestartBudget {
+	return &RestartBudget{
+		count:   count,
+		period:  period,
+		members: make(map[*supervisor]context.CancelFunc),
+	}
+}
+
+// join adds the service of ctx to the budget; cancel stops it once the budget is exhausted. It returns a function
+// removing the service again.
+func (b *RestartBudget) join(ctx context.Context, cancel context.CancelFunc) func() {
+	sup := supervisorFrom(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.members[sup] = cancel
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.members, sup)
+	}
+}
+
+// take counts a restart of the service of ctx after err against the budget. If the budget is exhausted, all
+// services using it are stopped and the aggregated error is returned.
+func (b *RestartBudget) take(ctx context.Context, err error) error {
+	service := Namespace(ctx) + "/" + Name(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.exhausted != nil {
+		return b.exhausted
+	}
+
+	now := time.Now()
+	if b.period > 0 {
+		b.restarts = slices.DeleteFunc(b.restarts, func(r budgetRestart) bool {
+			return now.Sub(r.time) > b.period
+		})
+	}
+	b.restarts = append(b.restarts, budgetRestart{time: now, service: service, err: err})
+	b.total++
+
+	if counter, cErr := Meter(ctx).Int64Counter(
+		"as.group.restarts",
+		metric.WithDescription("Number of restarts counted against the shared restart budget"),
+	); cErr == nil {
+		counter.Add(ctx, 1, metric.WithAttributes(attribute.String("service", service)))
+	}
+
+	if b.count <= 0 || len(b.restarts) <= b.count {
+		return nil
+	}
+
+	// Name each contributor once, in the order of its first restart within the window
+	var contributors []string
+	var errs []error
+	for _, r := range b.restarts {
+		if !slices.Contains(contributors, r.service) {
+			contributors = append(contributors, r.service)
+		}
+		errs = append(errs, ae.Wrap(r.service, r.err))
+	}
+
+	msg := fmt.Sprintf("exceeded the shared budget of %d restarts", b.count)
+	if b.period > 0 {
+		msg += fmt.Sprintf(" within %s", b.period)
+	}
+	b.exhausted = ae.WrapMany(fmt.Sprintf("%s, restarted: %s", msg, strings.Join(contributors, ", ")), errs...)
+
+	for _, cancel := range b.members {
+		cancel()
+	}
+
+	return b.exhausted
+}
+
+// err returns the error the budget was exhausted with, or nil.
+func (b *RestartBudget) err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.exhausted
+}
+
+// restartCount returns the number of restarts counted against the budget since it was created.
+func (b *RestartBudget) restartCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.total
+}
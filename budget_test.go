@@ -0,0 +1,109 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestRestartBudget(t *testing.T) {
+	budget := NewRestartBudget(3, time.Minute)
+
+	// Each service fails whenever the test sends to its channel, and is well within its own grace limits
+	fail := map[string]chan struct{}{"api": make(chan struct{}), "worker": make(chan struct{})}
+	logs := map[string]*logCapture{"api": {}, "worker": {}}
+	errs := map[string]chan error{"api": make(chan error, 1), "worker": make(chan error, 1)}
+	for name := range fail {
+		svc := &testService{name: name, run: func(ctx context.Context) error {
+			select {
+			case <-fail[name]:
+				return errors.New(name + " failed")
+			case <-ctx.Done():
+				return nil
+			}
+		}}
+
+		opts := testOptions(captureLogs(svc, logs[name]), WithRestartBudget(budget), WithGraceCount(0), WithGracePeriod(0))
+		go func() { errs[name] <- RunC(svc, context.Background(), opts...) }()
+	}
+
+	// The fourth restart within the minute exhausts the budget, stopping both services
+	for _, name := range []string{"api", "worker", "api", "worker"} {
+		select {
+		case fail[name] <- struct{}{}:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s did not run", name)
+		}
+	}
+
+	for name, done := range errs {
+		var err error
+		select {
+		case err = <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s was not stopped", name)
+		}
+
+		// The error names all contributors
+		if err == nil || !strings.Contains(err.Error(), "astest/api") || !strings.Contains(err.Error(), "astest/worker") {
+			t.Errorf("RunC(%s) = %v, want the budget error naming both services", name, err)
+		}
+
+		summary := logs[name].find("service exited")
+		if summary == nil || summary["reason"] != "shared restart budget exceeded" || summary["shared_restarts"] != float64(4) {
+			t.Errorf("exit summary of %s = %v", name, summary)
+		}
+	}
+}
+
+func TestRestartBudgetWindow(t *testing.T) {
+	tests := []struct {
+		name         string
+		interval     time.Duration
+		wantRestarts int
+		wantErr      bool
+	}{
+		// Restarts older than the period are no longer counted, so one restart every 40 seconds is tolerated
+		{name: "tolerated", interval: 40 * time.Second, wantRestarts: 15},
+		{name: "exhausted", interval: 20 * time.Second, wantRestarts: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute+time.Second)
+				defer cancel()
+				ctx = WithLogger(ctx, slog.New(slog.DiscardHandler))
+				ctx = withSupervisor(ctx, newSupervisor())
+
+				budget := NewRestartBudget(2, time.Minute)
+				defer budget.join(ctx, cancel)()
+
+				svc := &testService{run: func(ctx context.Context) error {
+					if err := Sleep(ctx, tt.interval); err != nil {
+						return nil
+					}
+					return errors.New("failed")
+				}}
+
+				opts := DefaultOptions()
+				opts.GraceCount = 0
+				opts.GracePeriod = 0
+				opts.RestartOnErrorDelay = 0
+				opts.RestartBudget = budget
+
+				err := runLoop(svc, ctx, opts, nil)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("runLoop() = %v, want error %t", err, tt.wantErr)
+				}
+				if got := budget.restartCount(); got != tt.wantRestarts {
+					t.Errorf("restartCount() = %d, want %d", got, tt.wantRestarts)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package as
+
+import (
+	"context"
+	"runtime"
+
+	"go.aledante.io/ae"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// initBuildInfoMetric registers the constant build info gauge configured by opts.BuildInfoMetric on meter. The
+// gauge reports 1 on every collection, labeled with the version, VCS revision, and Go version of the binary and the
+// name and namespace of the service, so the versions running where can be found with a single query.
+func initBuildInfoMetric(ctx context.Context, opts Options, meter metric.Meter) error {
+	if opts.BuildInfoMetric == "" {
+		return nil
+	}
+
+	// The labels do not change while the process is running
+	labels := metric.WithAttributeSet(attribute.NewSet(
+		attribute.String("version", Version(ctx)),
+		attribute.String("revision", VCSVersion()),
+		attribute.String("go_version", runtime.Version()),
+		attribute.String("name", Name(ctx)),
+		attribute.String("namespace", Namespace(ctx)),
+	))
+
+	_, err := meter.Int64ObservableGauge(
+		opts.BuildInfoMetric,
+		metric.WithDescription("Constant 1, labeled with the build info of the service"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(1, labels)
+			return nil
+		}),
+	)
+	if err != nil {
+		return ae.Wrap("failed to register build info metric", err)
+	}
+
+	return nil
+}
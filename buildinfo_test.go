@@ -0,0 +1,59 @@
+package as
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestBuildInfoMetric(t *testing.T) {
+	for _, name := range []string{"service_build_info", "build_info"} {
+		t.Run(name, func(t *testing.T) {
+			ctx := withNamespace(withName(withVersion(context.Background(), "v1.2.3"), "api"), "shop")
+			ctx, reader := testMeterContext(t, ctx)
+
+			if err := initBuildInfoMetric(ctx, Options{BuildInfoMetric: name}, Meter(ctx)); err != nil {
+				t.Fatalf("initBuildInfoMetric() = %v", err)
+			}
+
+			// Every collection reports the same data point
+			for range 2 {
+				gauge, _ := collectMetric(t, reader, name).(metricdata.Gauge[int64])
+				if len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != 1 {
+					t.Fatalf("%s = %+v, want a single data point of 1", name, gauge)
+				}
+
+				attrs := gauge.DataPoints[0].Attributes
+				want := map[attribute.Key]string{
+					"version":    "v1.2.3",
+					"revision":   VCSVersion(),
+					"go_version": runtime.Version(),
+					"name":       "api",
+					"namespace":  "shop",
+				}
+				if attrs.Len() != len(want) {
+					t.Errorf("labels = %v, want %v", attrs.ToSlice(), want)
+				}
+				for key, value := range want {
+					if got, _ := attrs.Value(key); got.AsString() != value {
+						t.Errorf("label %s = %q, want %q", key, got.AsString(), value)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBuildInfoMetricDisabled(t *testing.T) {
+	ctx, reader := testMeterContext(t, context.Background())
+
+	if err := initBuildInfoMetric(ctx, Options{}, Meter(ctx)); err != nil {
+		t.Fatalf("initBuildInfoMetric() = %v", err)
+	}
+	if data := collectMetric(t, reader, DefaultOptions().BuildInfoMetric); data != nil {
+		t.Errorf("build info metric registered while disabled: %+v", data)
+	}
+}
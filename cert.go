@@ -0,0 +1,202 @@
+package as
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.aledante.io/ae"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// certPollInterval is the interval in which a ReloadingCertificate checks the modification times of its files.
+const certPollInterval = 5 * time.Second
+
+// certNamespace is the namespace of the service returned by CertReloader.
+const certNamespace = "tls"
+
+// ReloadingCertificate is a TLS certificate which is reloaded when its files change, e.g. after a rotation.
+// Use GetCertificate or TLSConfig to serve it. It is a Service (in the namespace tls), so it can be run by the
+// supervisor; alternatively, Run can be started with Go from another service.
+type ReloadingCertificate struct {
+	name     string
+	certFile string
+	keyFile  string
+
+	cert    atomic.Pointer[tls.Certificate]
+	loadErr atomic.Pointer[error]
+
+	mu       sync.Mutex
+	modTimes [2]time.Time
+}
+
+// CertReloader returns a certificate loaded from the PEM encoded certFile and keyFile, which is reloaded whenever
+// the modification time of either file changes while Run is running. The files are checked every 5 seconds.
+//
+// New key pairs are only applied if they are valid and not expired; otherwise the error is logged and the current
+// certificate is kept. Reloads are logged, and the expiry of the leaf certificate is recorded by the
+// as.tls.certificate.not_after gauge (in seconds since the epoch) with the given name as "certificate" attribute.
+func CertReloader(name, certFile, keyFile string) *ReloadingCertificate {
+	r := &ReloadingCertificate{
+		name:     name,
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	// The files may not exist yet, in which case Init fails if they are still missing
+	if err := r.reload(); err != nil {
+		r.loadErr.Store(&err)
+	}
+
+	return r
+}
+
+// GetCertificate returns the current certificate. It can be used as tls.Config.GetCertificate.
+func (r *ReloadingCertificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cert := r.cert.Load(); cert != nil {
+		return cert, nil
+	}
+
+	if err := r.loadErr.Load(); err != nil {
+		return nil, *err
+	}
+
+	return nil, ae.New().Msg(fmt.Sprintf("certificate %s not loaded", r.name))
+}
+
+// TLSConfig returns a TLS configuration serving the current certificate, e.g. for an http.Server or gRPC
+// credentials.
+func (r *ReloadingCertificate) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// Name returns the name of the certificate.
+func (r *ReloadingCertificate) Name() string { return r.name }
+
+// Namespace returns the namespace of the service, tls.
+func (r *ReloadingCertificate) Namespace() string { return certNamespace }
+
+// Version returns the empty string, since the service is part of this package.
+func (r *ReloadingCertificate) Version() string { return "" }
+
+// Init loads the certificate, unless it was loaded already, and records its expiry.
+func (r *ReloadingCertificate) Init(ctx context.Context) error {
+	if r.cert.Load() == nil {
+		if err := r.reload(); err != nil {
+			return err
+		}
+	}
+
+	r.recordNotAfter(ctx)
+
+	return nil
+}
+
+// Run reloads the certificate whenever its files change, until ctx is cancelled.
+func (r *ReloadingCertificate) Run(ctx context.Context) error {
+	return Tick(ctx, certPollInterval, func(ctx context.Context) error {
+		if !r.changed() {
+			return nil
+		}
+
+		if err := r.reload(); err != nil {
+			Logger(ctx).Error("failed to reload certificate, keeping the current one",
+				"certificate", r.name,
+				"error", err,
+			)
+			return nil
+		}
+
+		Logger(ctx).Info("certificate reloaded",
+			"certificate", r.name,
+			"not_after", r.cert.Load().Leaf.NotAfter,
+		)
+		r.recordNotAfter(ctx)
+
+		return nil
+	})
+}
+
+// Close does nothing; the certificate keeps being served.
+func (r *ReloadingCertificate) Close(context.Context) error { return nil }
+
+// changed reports whether the modification time of either file changed since the last check.
+func (r *ReloadingCertificate) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes := r.statFiles()
+	if modTimes == r.modTimes {
+		return false
+	}
+	r.modTimes = modTimes
+
+	return true
+}
+
+// statFiles returns the modification times of the certificate and key file; missing files have a zero time.
+func (r *ReloadingCertificate) statFiles() [2]time.Time {
+	var modTimes [2]time.Time
+	for i, path := range []string{r.certFile, r.keyFile} {
+		if fi, err := os.Stat(path); err == nil {
+			modTimes[i] = fi.ModTime()
+		}
+	}
+
+	return modTimes
+}
+
+// reload loads and validates the key pair and, if it is valid, replaces the current certificate.
+func (r *ReloadingCertificate) reload() error {
+	r.mu.Lock()
+	r.modTimes = r.statFiles()
+	r.mu.Unlock()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return ae.Wrap(fmt.Sprintf("failed to load certificate %s", r.name), err)
+	}
+
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return ae.Wrap(fmt.Sprintf("failed to parse certificate %s", r.name), err)
+		}
+	}
+
+	if now := time.Now(); now.After(cert.Leaf.NotAfter) {
+		return ae.New().Msg(fmt.Sprintf("certificate %s expired at %s", r.name, cert.Leaf.NotAfter))
+	}
+
+	r.cert.Store(&cert)
+	r.loadErr.Store(nil)
+
+	return nil
+}
+
+// recordNotAfter records the expiry of the current leaf certificate on the as.tls.certificate.not_after gauge.
+func (r *ReloadingCertificate) recordNotAfter(ctx context.Context) {
+	cert := r.cert.Load()
+	if cert == nil {
+		return
+	}
+
+	gauge, err := Meter(ctx).Int64Gauge(
+		"as.tls.certificate.not_after",
+		metric.WithDescription("Expiry of the leaf certificate served by as.CertReloader, in seconds since the epoch"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return
+	}
+
+	gauge.Record(ctx, cert.Leaf.NotAfter.Unix(), metric.WithAttributes(attribute.String("certificate", r.name)))
+}
@@ -0,0 +1,172 @@
+package as
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// writeCertPair writes a self-signed key pair with the given serial number and expiry to certFile and keyFile,
+// setting their modification time to modTime.
+func writeCertPair(t *testing.T, certFile, keyFile string, serial int64, notAfter, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    notAfter.Add(-48 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	}
+	for path, block := range files {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// servedSerial returns the serial number of the certificate currently returned by r.
+func servedSerial(t *testing.T, r *ReloadingCertificate) int64 {
+	t.Helper()
+
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() = %v", err)
+	}
+
+	return cert.Leaf.SerialNumber.Int64()
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	modTime := time.Now().Add(-time.Hour)
+	writeCertPair(t, certFile, keyFile, 1, time.Now().Add(24*time.Hour), modTime)
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx, reader := testMeterContext(t, context.Background())
+		ctx = WithLogger(ctx, slog.New(slog.DiscardHandler))
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		r := CertReloader("server", certFile, keyFile)
+		if err := r.Init(ctx); err != nil {
+			t.Fatalf("Init() = %v", err)
+		}
+		if got := servedSerial(t, r); got != 1 {
+			t.Fatalf("serial = %d, want 1", got)
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- r.Run(ctx) }()
+
+		// A rotated pair is served after the next poll
+		notAfter := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+		writeCertPair(t, certFile, keyFile, 2, notAfter, modTime.Add(time.Minute))
+		time.Sleep(certPollInterval)
+		synctest.Wait()
+		if got := servedSerial(t, r); got != 2 {
+			t.Errorf("serial after rotation = %d, want 2", got)
+		}
+
+		gauge, _ := collectMetric(t, reader, "as.tls.certificate.not_after").(metricdata.Gauge[int64])
+		if len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != notAfter.Unix() {
+			t.Errorf("as.tls.certificate.not_after = %+v, want %d", gauge, notAfter.Unix())
+		}
+
+		// An invalid pair is rejected, keeping the current one
+		if err := os.WriteFile(certFile, []byte("invalid"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(certFile, modTime.Add(2*time.Minute), modTime.Add(2*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(certPollInterval)
+		synctest.Wait()
+		if got := servedSerial(t, r); got != 2 {
+			t.Errorf("serial after invalid rotation = %d, want 2", got)
+		}
+
+		// So is an expired one
+		writeCertPair(t, certFile, keyFile, 3, time.Now().Add(-time.Hour), modTime.Add(3*time.Minute))
+		time.Sleep(certPollInterval)
+		synctest.Wait()
+		if got := servedSerial(t, r); got != 2 {
+			t.Errorf("serial after expired rotation = %d, want 2", got)
+		}
+
+		cancel()
+		<-done
+	})
+}
+
+func TestCertReloaderTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCertPair(t, certFile, keyFile, 7, time.Now().Add(24*time.Hour), time.Now())
+
+	r := CertReloader("server", certFile, keyFile)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.TLS = r.TLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	// httptest adds its own certificate, which is only served to clients without SNI
+	conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{ServerName: "localhost", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if got := conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(); got != 7 {
+		t.Errorf("served serial = %d, want 7", got)
+	}
+}
+
+func TestCertReloaderMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	r := CertReloader("server", filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+
+	if _, err := r.GetCertificate(nil); err == nil {
+		t.Error("GetCertificate() = nil error for missing files")
+	}
+	if err := r.Init(context.Background()); err == nil {
+		t.Error("Init() = nil for missing files")
+	}
+}
@@ -0,0 +1,289 @@
+package as
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// cgroupDir is the directory of a cgroup of the process in a mounted cgroup hierarchy.
+type cgroupDir struct {
+	// path is the directory of the cgroup.
+	path string
+	// mount is the mount point of the hierarchy. The limits of the cgroups from path up to mount all apply.
+	mount string
+	// v2 reports whether the hierarchy is the unified cgroup v2 hierarchy.
+	v2 bool
+}
+
+// cgroupMount is a cgroup hierarchy mounted into the filesystem, see parseCgroupMount.
+type cgroupMount struct {
+	// root is the cgroup mounted, relative to the root of the hierarchy.
+	root string
+	// point is the mount point.
+	point string
+	// controllers are the controllers of a cgroup v1 hierarchy.
+	controllers []string
+	v2          bool
+}
+
+// findCgroup resolves the directory of the cgroup of the process for the controller (e.g. "cpu" or "memory") in the
+// filesystem mounted at root, from /proc/self/cgroup and the cgroup mounts in /proc/self/mountinfo. Resolving the
+// cgroup of the process, rather than using the root of the mount, is required unless the process runs in a cgroup
+// namespace of its own. A cgroup v1 hierarchy of the controller is preferred, as the controller is unavailable in
+// the unified hierarchy then. The boolean result is false if the cgroup of the process is not mounted.
+func findCgroup(root, controller string) (cgroupDir, bool, error) {
+	data, err := os.ReadFile(filepath.Join(root, "proc/self/cgroup"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return cgroupDir{}, false, nil
+	}
+	if err != nil {
+		return cgroupDir{}, false, err
+	}
+
+	// Lines are "<hierarchy id>:<controllers>:<path>"; the unified hierarchy has the id 0 and no controllers
+	var v1Path, v2Path string
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		switch {
+		case len(parts) != 3:
+		case parts[0] == "0" && parts[1] == "":
+			v2Path = parts[2]
+		case slices.Contains(strings.Split(parts[1], ","), controller):
+			v1Path = parts[2]
+		}
+	}
+	if v1Path == "" && v2Path == "" {
+		return cgroupDir{}, false, nil
+	}
+
+	data, err = os.ReadFile(filepath.Join(root, "proc/self/mountinfo"))
+	if err != nil {
+		return cgroupDir{}, false, err
+	}
+
+	var mounts []cgroupMount
+	for _, line := range strings.Split(string(data), "\n") {
+		if mount, ok := parseCgroupMount(line); ok {
+			mounts = append(mounts, mount)
+		}
+	}
+
+	for _, v2 := range []bool{false, true} {
+		path := v1Path
+		if v2 {
+			path = v2Path
+		}
+		if path == "" {
+			continue
+		}
+
+		for _, mount := range mounts {
+			if mount.v2 != v2 || (!v2 && !slices.Contains(mount.controllers, controller)) {
+				continue
+			}
+
+			// The cgroup of the process is only visible if it is below the cgroup mounted
+			rel, err := filepath.Rel(mount.root, path)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+				continue
+			}
+
+			point := filepath.Join(root, mount.point)
+			return cgroupDir{path: filepath.Join(point, rel), mount: point, v2: v2}, true, nil
+		}
+	}
+
+	return cgroupDir{}, false, nil
+}
+
+// mountinfoUnescaper reverts the octal escapes of paths in /proc/self/mountinfo.
+var mountinfoUnescaper = strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+
+// parseCgroupMount parses a line of /proc/self/mountinfo, returning false if it is not a cgroup mount. Lines are
+// "<id> <parent id> <major:minor> <root> <mount point> <options> [<optional fields>...] - <type> <source>
+// <super options>"; the controllers of cgroup v1 hierarchies are among the super options.
+func parseCgroupMount(line string) (cgroupMount, bool) {
+	fields := strings.Fields(line)
+	sep := slices.Index(fields, "-")
+	if sep < 5 || len(fields) < sep+4 {
+		return cgroupMount{}, false
+	}
+
+	mount := cgroupMount{
+		root:  mountinfoUnescaper.Replace(fields[3]),
+		point: mountinfoUnescaper.Replace(fields[4]),
+	}
+	switch fields[sep+1] {
+	case "cgroup2":
+		mount.v2 = true
+	case "cgroup":
+		mount.controllers = strings.Split(fields[sep+3], ",")
+	default:
+		return cgroupMount{}, false
+	}
+
+	return mount, true
+}
+
+// cgroupLimit returns the lowest limit read by read for the cgroup dir and its parents up to the mount point of the
+// hierarchy, as the limits of all of them apply. The boolean result is false if none has a limit.
+func cgroupLimit[T int64 | float64](dir cgroupDir, read func(dir string) (T, bool, error)) (T, bool, error) {
+	var lowest T
+	found := false
+	for path := dir.path; ; path = filepath.Dir(path) {
+		limit, ok, err := read(path)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok && (!found || limit < lowest) {
+			lowest, found = limit, true
+		}
+
+		if path == dir.mount || !strings.HasPrefix(path, dir.mount) || path == filepath.Dir(path) {
+			return lowest, found, nil
+		}
+	}
+}
+
+// cgroupCPUQuota returns the CPU quota of the cgroup of the process in the filesystem mounted at root, expressed as
+// a (possibly fractional) number of CPUs. Both cgroup v2 (cpu.max) and cgroup v1 (cpu.cfs_quota_us /
+// cpu.cfs_period_us) are supported. The boolean result is false if no quota is configured or if no cgroup files
+// could be found.
+func cgroupCPUQuota(root string) (float64, bool, error) {
+	dir, ok, err := findCgroup(root, "cpu")
+	if err != nil || !ok {
+		return 0, false, err
+	}
+
+	if dir.v2 {
+		return cgroupLimit(dir, readCPUMax)
+	}
+
+	return cgroupLimit(dir, func(dir string) (float64, bool, error) {
+		quota, err := readCgroupInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+
+		period, err := readCgroupInt(filepath.Join(dir, "cpu.cfs_period_us"))
+		if err != nil {
+			return 0, false, err
+		}
+
+		return cpuQuota(float64(quota), float64(period))
+	})
+}
+
+// readCPUMax reads the CPU quota of a cgroup v2 from its cpu.max file.
+func readCPUMax(dir string) (float64, bool, error) {
+	// "<quota> <period>" or "max <period>"
+	data, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, false, errors.New("invalid cpu.max format")
+	}
+	if fields[0] == "max" {
+		return 0, false, nil
+	}
+
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false, err
+	}
+
+	period := 100000.0
+	if len(fields) == 2 {
+		period, err = strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	return cpuQuota(quota, period)
+}
+
+// cgroupMemoryLimit returns the memory limit in bytes of the cgroup of the process in the filesystem mounted at
+// root. Both cgroup v2 (memory.max) and cgroup v1 (memory.limit_in_bytes) are supported.
+// The boolean result is false if no limit is configured or if no cgroup files could be found.
+func cgroupMemoryLimit(root string) (int64, bool, error) {
+	dir, ok, err := findCgroup(root, "memory")
+	if err != nil || !ok {
+		return 0, false, err
+	}
+
+	file := "memory.limit_in_bytes"
+	if dir.v2 {
+		file = "memory.max"
+	}
+
+	return cgroupLimit(dir, func(dir string) (int64, bool, error) {
+		// cgroup v2 writes "max" for no limit
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+
+		v := strings.TrimSpace(string(data))
+		if v == "max" {
+			return 0, false, nil
+		}
+
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, false, err
+		}
+
+		return memoryLimit(limit)
+	})
+}
+
+// memoryLimit validates a cgroup memory limit. Non-positive values and the huge values cgroup v1 reports
+// for unlimited cgroups (page-aligned math.MaxInt64) mean that no limit is set.
+func memoryLimit(limit int64) (int64, bool, error) {
+	if limit <= 0 || limit >= 1<<62 {
+		return 0, false, nil
+	}
+
+	return limit, true, nil
+}
+
+// cpuQuota converts a CFS quota and period into a number of CPUs.
+// A non-positive quota means that no limit is set.
+func cpuQuota(quota, period float64) (float64, bool, error) {
+	if quota <= 0 {
+		return 0, false, nil
+	}
+	if period <= 0 {
+		return 0, false, errors.New("invalid CPU period")
+	}
+
+	return quota / period, true, nil
+}
+
+// readCgroupInt reads a cgroup file containing a single integer value.
+func readCgroupInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
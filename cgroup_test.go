@@ -0,0 +1,254 @@
+package as
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCgroupFiles writes the files (relative path to content) into a temporary filesystem root.
+func writeCgroupFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+const (
+	// cgroupV2Mountinfo mounts the unified hierarchy at /sys/fs/cgroup, as in a cgroup namespace of its own.
+	cgroupV2Mountinfo = "30 24 0:26 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:4 - cgroup2 cgroup2 rw,nsdelegate\n"
+	// cgroupV1Mountinfo mounts the cpu and memory hierarchies below /sys/fs/cgroup.
+	cgroupV1Mountinfo = "25 24 0:22 / /proc rw - proc proc rw\n" +
+		"33 30 0:29 / /sys/fs/cgroup/cpu,cpuacct rw,nosuid shared:8 - cgroup cgroup rw,cpu,cpuacct\n" +
+		"35 30 0:31 / /sys/fs/cgroup/memory rw,nosuid shared:10 - cgroup cgroup rw,memory\n"
+)
+
+// cgroupV2Files returns the files of a process in the root of a cgroup v2 namespace, with the given files of the
+// cgroup.
+func cgroupV2Files(files map[string]string) map[string]string {
+	root := map[string]string{"proc/self/cgroup": "0::/\n", "proc/self/mountinfo": cgroupV2Mountinfo}
+	for name, content := range files {
+		root[filepath.Join("sys/fs/cgroup", name)] = content
+	}
+
+	return root
+}
+
+// cgroupV1Files returns the files of a process in the root of the cgroup v1 cpu and memory hierarchies, with the
+// given files of the cgroups, prefixed by "cpu/" or "memory/".
+func cgroupV1Files(files map[string]string) map[string]string {
+	root := map[string]string{
+		"proc/self/cgroup":    "12:memory:/\n4:cpu,cpuacct:/\n1:name=systemd:/\n",
+		"proc/self/mountinfo": cgroupV1Mountinfo,
+	}
+	dirs := map[string]string{"cpu": "sys/fs/cgroup/cpu,cpuacct", "memory": "sys/fs/cgroup/memory"}
+	for name, content := range files {
+		controller, file, _ := strings.Cut(name, "/")
+		root[filepath.Join(dirs[controller], file)] = content
+	}
+
+	return root
+}
+
+func TestCgroupCPUQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    float64
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "no cgroup files"},
+		{name: "v2 no limit files", files: cgroupV2Files(nil)},
+		{name: "v2 quota", files: cgroupV2Files(map[string]string{"cpu.max": "150000 100000\n"}), want: 1.5, wantOK: true},
+		{name: "v2 quota without period", files: cgroupV2Files(map[string]string{"cpu.max": "200000"}), want: 2, wantOK: true},
+		{name: "v2 unlimited", files: cgroupV2Files(map[string]string{"cpu.max": "max 100000\n"})},
+		{name: "v2 invalid quota", files: cgroupV2Files(map[string]string{"cpu.max": "abc 100000"}), wantErr: true},
+		{name: "v2 invalid format", files: cgroupV2Files(map[string]string{"cpu.max": "1 2 3"}), wantErr: true},
+		{name: "v2 zero period", files: cgroupV2Files(map[string]string{"cpu.max": "100000 0"}), wantErr: true},
+		{
+			name:   "v1 quota",
+			files:  cgroupV1Files(map[string]string{"cpu/cpu.cfs_quota_us": "50000\n", "cpu/cpu.cfs_period_us": "100000\n"}),
+			want:   0.5,
+			wantOK: true,
+		},
+		{
+			name:  "v1 unlimited",
+			files: cgroupV1Files(map[string]string{"cpu/cpu.cfs_quota_us": "-1\n", "cpu/cpu.cfs_period_us": "100000\n"}),
+		},
+		{
+			name:    "v1 missing period",
+			files:   cgroupV1Files(map[string]string{"cpu/cpu.cfs_quota_us": "50000\n"}),
+			wantErr: true,
+		},
+		{
+			// The limit of a parent cgroup applies if it is lower
+			name: "v2 nested",
+			files: map[string]string{
+				"proc/self/cgroup":                          "0::/kubepods/pod1/app\n",
+				"proc/self/mountinfo":                       cgroupV2Mountinfo,
+				"sys/fs/cgroup/kubepods/pod1/app/cpu.max":   "400000 100000\n",
+				"sys/fs/cgroup/kubepods/pod1/cpu.max":       "250000 100000\n",
+				"sys/fs/cgroup/kubepods/cpu.max":            "max 100000\n",
+				"sys/fs/cgroup/cpu.max":                     "800000 100000\n",
+				"sys/fs/cgroup/kubepods/pod1/other/cpu.max": "50000 100000\n",
+			},
+			want:   2.5,
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := cgroupCPUQuota(writeCgroupFiles(t, tt.files))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("cgroupCPUQuota() = %v, %t, want %v, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCgroupMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    int64
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "no cgroup files"},
+		{name: "v2 limit", files: cgroupV2Files(map[string]string{"memory.max": "536870912\n"}), want: 536870912, wantOK: true},
+		{name: "v2 unlimited", files: cgroupV2Files(map[string]string{"memory.max": "max\n"})},
+		{name: "v2 invalid", files: cgroupV2Files(map[string]string{"memory.max": "lots\n"}), wantErr: true},
+		{
+			name:   "v1 limit",
+			files:  cgroupV1Files(map[string]string{"memory/memory.limit_in_bytes": "1073741824\n"}),
+			want:   1073741824,
+			wantOK: true,
+		},
+		{
+			name:  "v1 unlimited",
+			files: cgroupV1Files(map[string]string{"memory/memory.limit_in_bytes": "9223372036854771712\n"}),
+		},
+		{name: "v1 invalid", files: cgroupV1Files(map[string]string{"memory/memory.limit_in_bytes": "x"}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := cgroupMemoryLimit(writeCgroupFiles(t, tt.files))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("cgroupMemoryLimit() = %v, %t, want %v, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFindCgroup(t *testing.T) {
+	tests := []struct {
+		name       string
+		controller string
+		cgroup     string
+		mountinfo  string
+		wantPath   string
+		wantMount  string
+		wantV2     bool
+		wantOK     bool
+	}{
+		{
+			name:       "v2 without namespace",
+			controller: "cpu",
+			cgroup:     "0::/system.slice/app.service\n",
+			mountinfo:  cgroupV2Mountinfo,
+			wantPath:   "sys/fs/cgroup/system.slice/app.service",
+			wantMount:  "sys/fs/cgroup",
+			wantV2:     true,
+			wantOK:     true,
+		},
+		{
+			// Docker without a cgroup namespace mounts the cgroup of the container
+			name:       "v1 mounted cgroup",
+			controller: "memory",
+			cgroup:     "12:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n",
+			mountinfo:  "35 30 0:31 /docker/abc /sys/fs/cgroup/memory ro - cgroup cgroup rw,memory\n",
+			wantPath:   "sys/fs/cgroup/memory",
+			wantMount:  "sys/fs/cgroup/memory",
+			wantOK:     true,
+		},
+		{
+			name:       "hybrid prefers v1",
+			controller: "cpu",
+			cgroup:     "4:cpu,cpuacct:/app\n0::/app\n",
+			mountinfo:  cgroupV1Mountinfo + "30 24 0:26 / /sys/fs/cgroup/unified rw - cgroup2 cgroup2 rw\n",
+			wantPath:   "sys/fs/cgroup/cpu,cpuacct/app",
+			wantMount:  "sys/fs/cgroup/cpu,cpuacct",
+			wantOK:     true,
+		},
+		{
+			name:       "hybrid without v1 controller",
+			controller: "io",
+			cgroup:     "4:cpu,cpuacct:/app\n0::/app\n",
+			mountinfo:  cgroupV1Mountinfo + "30 24 0:26 / /sys/fs/cgroup/unified rw - cgroup2 cgroup2 rw\n",
+			wantPath:   "sys/fs/cgroup/unified/app",
+			wantMount:  "sys/fs/cgroup/unified",
+			wantV2:     true,
+			wantOK:     true,
+		},
+		{
+			name:       "escaped mount point",
+			controller: "cpu",
+			cgroup:     "0::/\n",
+			mountinfo:  `30 24 0:26 / /cgroup\040fs rw - cgroup2 cgroup2 rw` + "\n",
+			wantPath:   "cgroup fs",
+			wantMount:  "cgroup fs",
+			wantV2:     true,
+			wantOK:     true,
+		},
+		{
+			name:       "cgroup not below the mount",
+			controller: "cpu",
+			cgroup:     "0::/other\n",
+			mountinfo:  "30 24 0:26 /app /sys/fs/cgroup rw - cgroup2 cgroup2 rw\n",
+		},
+		{
+			name:       "not mounted",
+			controller: "cpu",
+			cgroup:     "0::/\n",
+			mountinfo:  "25 24 0:22 / /proc rw - proc proc rw\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeCgroupFiles(t, map[string]string{"proc/self/cgroup": tt.cgroup, "proc/self/mountinfo": tt.mountinfo})
+
+			dir, ok, err := findCgroup(root, tt.controller)
+			if err != nil {
+				t.Fatalf("findCgroup() = %v", err)
+			}
+			want := cgroupDir{}
+			if tt.wantOK {
+				want = cgroupDir{path: filepath.Join(root, tt.wantPath), mount: filepath.Join(root, tt.wantMount), v2: tt.wantV2}
+			}
+			if ok != tt.wantOK || dir != want {
+				t.Errorf("findCgroup() = %+v, %t, want %+v, %t", dir, ok, want, tt.wantOK)
+			}
+		})
+	}
+}
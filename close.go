@@ -0,0 +1,190 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aledante.io/ae"
+)
+
+// Closer is a named component closed by CloseAll, e.g. a server, a consumer, or a connection pool.
+type Closer struct {
+	// Name is the name of the component, used for logging and errors. Names must be unique.
+	Name string
+	// Close closes the component.
+	Close func(ctx context.Context) error
+	// DependsOn names the closers this component depends on. They are closed only after this component was closed,
+	// so dependents close before their dependencies.
+	DependsOn []string
+}
+
+// CloseAll closes the components concurrently, e.g. from Close. It is CloseAllLimit without a limit.
+func CloseAll(ctx context.Context, closers ...Closer) error {
+	return CloseAllLimit(ctx, 0, closers...)
+}
+
+// CloseAllLimit closes the components with at most limit components closing concurrently; a limit of zero or less
+// does not limit the concurrency. Dependents close before their dependencies, independent components in parallel.
+// A component is closed even if a component depending on it failed to close.
+//
+// If ctx has a deadline, the remaining time is shared among the levels of the dependency graph: a component gets
+// an equal share of the time remaining when it starts closing, divided by the number of levels still to close, so
+// a slow component cannot use up the time of its dependencies. If any component fails, an error naming all failed
+// components is returned, wrapping their errors.
+func CloseAllLimit(ctx context.Context, limit int, closers ...Closer) error {
+	levels, err := closeLevels(closers)
+	if err != nil {
+		return err
+	}
+
+	if limit <= 0 || limit > len(closers) {
+		limit = len(closers)
+	}
+
+	depth := 0
+	for _, l := range levels {
+		depth = max(depth, l+1)
+	}
+
+	// A component waits for all components depending on it
+	index := make(map[string]int, len(closers))
+	for i, c := range closers {
+		index[c.Name] = i
+	}
+	dependents := make([][]int, len(closers))
+	for i, c := range closers {
+		for _, dep := range c.DependsOn {
+			j := index[dep]
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	done := make([]chan struct{}, len(closers))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	errs := make([]error, len(closers))
+	slots := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+	for i, c := range closers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, j := range dependents[i] {
+				<-done[j]
+			}
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			errs[i] = runCloser(ctx, c, depth-levels[i])
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	var closeErrs []error
+	for i, c := range closers {
+		if errs[i] != nil {
+			failed = append(failed, c.Name)
+			closeErrs = append(closeErrs, errs[i])
+		}
+	}
+
+	if len(closeErrs) > 0 {
+		return ae.WrapMany(fmt.Sprintf("failed to close: %s", strings.Join(failed, ", ")), closeErrs...)
+	}
+
+	return nil
+}
+
+// closeLevels returns the level of each closer in the dependency graph: closers nobody depends on have level zero,
+// every other closer a level above all of its dependents. It fails on duplicate names, unknown dependencies, and
+// dependency cycles.
+func closeLevels(closers []Closer) ([]int, error) {
+	index := make(map[string]int, len(closers))
+	for i, c := range closers {
+		if _, ok := index[c.Name]; ok {
+			return nil, ae.New().Msg(fmt.Sprintf("closer %s registered more than once", c.Name))
+		}
+		index[c.Name] = i
+	}
+
+	for _, c := range closers {
+		for _, dep := range c.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, ae.New().Msg(fmt.Sprintf("closer %s depends on unknown closer %s", c.Name, dep))
+			}
+		}
+	}
+
+	// The level of a dependency is one above the level of its dependent; visiting marks detect cycles
+	levels := make([]int, len(closers))
+	visiting := make([]bool, len(closers))
+	var visit func(i int) error
+	visit = func(i int) error {
+		if visiting[i] {
+			return ae.New().Msg(fmt.Sprintf("closer %s has a dependency cycle", closers[i].Name))
+		}
+
+		visiting[i] = true
+		defer func() { visiting[i] = false }()
+
+		for _, dep := range closers[i].DependsOn {
+			j := index[dep]
+			if levels[j] > levels[i] {
+				continue
+			}
+
+			levels[j] = levels[i] + 1
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for i := range closers {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return levels, nil
+}
+
+// runCloser closes a single component with its share of the remaining time, converting panics to errors.
+// remainingLevels is the number of dependency levels still to close, including the one of the component.
+func runCloser(ctx context.Context, c Closer, remainingLevels int) (err error) {
+	if deadline, ok := ctx.Deadline(); ok && remainingLevels > 1 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Until(deadline)/time.Duration(remainingLevels))
+		defer cancel()
+	}
+
+	start := time.Now()
+	defer func() {
+		if cause := recover(); cause != nil {
+			err = panicError(ctx, cause, nil)
+		}
+
+		if err != nil {
+			err = ae.Wrap(fmt.Sprintf("failed to close %s", c.Name), err)
+		}
+
+		Logger(ctx).Debug("component closed",
+			"component", c.Name,
+			"duration", time.Since(start).String(),
+			"error", err,
+		)
+	}()
+
+	return c.Close(ctx)
+}
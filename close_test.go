@@ -0,0 +1,205 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// closeTimes records when closers started and ended closing, relative to the start of the test.
+type closeTimes struct {
+	mu           sync.Mutex
+	start        time.Time
+	started, end map[string]time.Duration
+}
+
+func newCloseTimes() *closeTimes {
+	return &closeTimes{start: time.Now(), started: make(map[string]time.Duration), end: make(map[string]time.Duration)}
+}
+
+// closer returns a closer taking d to close.
+func (c *closeTimes) closer(name string, d time.Duration, dependsOn ...string) Closer {
+	return Closer{Name: name, DependsOn: dependsOn, Close: func(ctx context.Context) error {
+		c.mu.Lock()
+		c.started[name] = time.Since(c.start)
+		c.mu.Unlock()
+
+		err := Sleep(ctx, d)
+
+		c.mu.Lock()
+		c.end[name] = time.Since(c.start)
+		c.mu.Unlock()
+		return err
+	}}
+}
+
+func closeContext() context.Context {
+	return WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+}
+
+func TestCloseAllConcurrency(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		want  time.Duration
+	}{
+		{name: "unlimited", limit: 0, want: 5 * time.Second},
+		{name: "limited", limit: 4, want: 15 * time.Second},
+		{name: "sequential", limit: 1, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				times := newCloseTimes()
+				var closers []Closer
+				for _, name := range strings.Fields("a b c d e f g h i j k l") {
+					closers = append(closers, times.closer(name, 5*time.Second))
+				}
+
+				if err := CloseAllLimit(closeContext(), tt.limit, closers...); err != nil {
+					t.Fatalf("CloseAllLimit() = %v", err)
+				}
+				if elapsed := time.Since(times.start); elapsed != tt.want {
+					t.Errorf("closed after %s, want %s", elapsed, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestCloseAllOrdering(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		times := newCloseTimes()
+		err := CloseAll(closeContext(),
+			times.closer("db", 2*time.Second),
+			times.closer("api", 2*time.Second, "db", "cache"),
+			times.closer("cache", time.Second),
+			times.closer("worker", 3*time.Second, "db"),
+		)
+		if err != nil {
+			t.Fatalf("CloseAll() = %v", err)
+		}
+
+		// Dependents close in parallel, each dependency once all of its dependents were closed
+		if times.started["api"] != 0 || times.started["worker"] != 0 {
+			t.Errorf("dependents started at %s and %s, want both at once", times.started["api"], times.started["worker"])
+		}
+		if times.started["cache"] != times.end["api"] {
+			t.Errorf("cache started at %s, want after api at %s", times.started["cache"], times.end["api"])
+		}
+		if times.started["db"] != times.end["worker"] {
+			t.Errorf("db started at %s, want after worker at %s", times.started["db"], times.end["worker"])
+		}
+		if elapsed := time.Since(times.start); elapsed != 5*time.Second {
+			t.Errorf("closed after %s, want 5s", elapsed)
+		}
+	})
+}
+
+func TestCloseAllDeadlineShares(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(closeContext(), 9*time.Second)
+		defer cancel()
+
+		// Each level hangs until its share of the remaining time expired
+		times := newCloseTimes()
+		err := CloseAll(ctx,
+			times.closer("server", time.Hour),
+			times.closer("consumer", time.Hour, "queue"),
+			times.closer("queue", time.Hour, "pool"),
+			times.closer("pool", time.Hour),
+		)
+		if err == nil {
+			t.Fatal("CloseAll() = nil, want the timeouts")
+		}
+
+		// Components of the first level share the time with the two levels below them, the last gets all that is left
+		want := map[string]time.Duration{"server": 3 * time.Second, "consumer": 3 * time.Second, "queue": 6 * time.Second, "pool": 9 * time.Second}
+		for name, end := range want {
+			if times.end[name] != end {
+				t.Errorf("%s ended at %s, want %s", name, times.end[name], end)
+			}
+		}
+	})
+}
+
+func TestCloseAllErrors(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	closed := false
+
+	err := CloseAll(closeContext(),
+		Closer{Name: "writer", Close: func(ctx context.Context) error { return errFlush }},
+		Closer{Name: "panics", Close: func(ctx context.Context) error { panic("boom") }},
+		Closer{Name: "ok", Close: func(ctx context.Context) error { return nil }},
+		Closer{Name: "store", Close: func(ctx context.Context) error {
+			closed = true
+			return nil
+		}},
+	)
+	if err == nil {
+		t.Fatal("CloseAll() = nil, want an error")
+	}
+
+	// Every failed component is named and its error wrapped
+	for _, name := range []string{"writer", "panics"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not name %s", err, name)
+		}
+	}
+	if strings.Contains(err.Error(), "close ok") {
+		t.Errorf("error %q names the closed component", err)
+	}
+	if !errors.Is(err, errFlush) {
+		t.Errorf("error %v does not wrap the close error", err)
+	}
+	if !closed {
+		t.Error("independent component not closed")
+	}
+}
+
+func TestCloseAllDependencyOfFailed(t *testing.T) {
+	closed := false
+	err := CloseAll(closeContext(),
+		Closer{Name: "api", DependsOn: []string{"db"}, Close: func(ctx context.Context) error { return errors.New("failed") }},
+		Closer{Name: "db", Close: func(ctx context.Context) error {
+			closed = true
+			return nil
+		}},
+	)
+
+	// A dependency is closed even if its dependent failed
+	if err == nil || !closed {
+		t.Errorf("CloseAll() = %v, dependency closed = %t", err, closed)
+	}
+}
+
+func TestCloseLevelsInvalid(t *testing.T) {
+	noop := func(ctx context.Context) error { return nil }
+
+	tests := []struct {
+		name    string
+		closers []Closer
+	}{
+		{name: "duplicate", closers: []Closer{{Name: "a", Close: noop}, {Name: "a", Close: noop}}},
+		{name: "unknown", closers: []Closer{{Name: "a", Close: noop, DependsOn: []string{"b"}}}},
+		{name: "cycle", closers: []Closer{
+			{Name: "a", Close: noop, DependsOn: []string{"b"}},
+			{Name: "b", Close: noop, DependsOn: []string{"c"}},
+			{Name: "c", Close: noop, DependsOn: []string{"a"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CloseAll(closeContext(), tt.closers...); err == nil {
+				t.Error("CloseAll() = nil, want an error")
+			}
+		})
+	}
+}
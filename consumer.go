@@ -0,0 +1,234 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// consumerConfig holds the configuration of a ConsumerService.
+type consumerConfig struct {
+	concurrency  int
+	timeout      time.Duration
+	drainTimeout time.Duration
+	init         func(ctx context.Context) error
+	close        func(ctx context.Context) error
+}
+
+// ConsumerOption is a function which applies a configuration change to a ConsumerService.
+type ConsumerOption func(*consumerConfig)
+
+// WithConsumerConcurrency sets the maximum number of messages processed concurrently. Defaults to 1.
+func WithConsumerConcurrency(v int) ConsumerOption {
+	return func(c *consumerConfig) { c.concurrency = v }
+}
+
+// WithConsumerTimeout sets the maximum duration of processing a single message.
+// If set to zero (the default), there is no limit.
+func WithConsumerTimeout(v time.Duration) ConsumerOption {
+	return func(c *consumerConfig) { c.timeout = v }
+}
+
+// WithConsumerDrainTimeout sets the maximum duration in-flight messages are processed for after shutdown began,
+// after which their contexts are cancelled. If set to zero (the default), there is no limit.
+func WithConsumerDrainTimeout(v time.Duration) ConsumerOption {
+	return func(c *consumerConfig) { c.drainTimeout = v }
+}
+
+// WithConsumerInit sets a function called from Init of the service, e.g. to connect to the message broker.
+func WithConsumerInit(fn func(ctx context.Context) error) ConsumerOption {
+	return func(c *consumerConfig) { c.init = fn }
+}
+
+// WithConsumerClose sets a function called from Close of the service, after all messages were drained.
+func WithConsumerClose(fn func(ctx context.Context) error) ConsumerOption {
+	return func(c *consumerConfig) { c.close = fn }
+}
+
+// consumerService is the Service returned by ConsumerService.
+type consumerService[T any] struct {
+	name      string
+	namespace string
+	version   string
+	source    func(ctx context.Context) (T, error)
+	handle    func(ctx context.Context, msg T) error
+	cfg       consumerConfig
+}
+
+// ConsumerService returns a Service running a message consumer loop: it pulls messages from source and processes
+// each with handle, e.g. acknowledging it on success and rejecting it on error.
+//
+// A failing or panicking handle only fails the message: the error is logged, recorded on the span of the message,
+// and counted by the as.consumer.messages counter; the durations are recorded by the as.consumer.message.duration
+// histogram. An error returned by source fails the service, subject to the restart policy.
+//
+// Once shutdown begins (see Stopping), no further messages are pulled; the context passed to source is cancelled
+// and messages in flight are processed to completion before Run returns, bounded by WithConsumerDrainTimeout.
+func ConsumerService[T any](
+	name, namespace, version string,
+	source func(ctx context.Context) (T, error),
+	handle func(ctx context.Context, msg T) error,
+	opts ...ConsumerOption,
+) Service {
+	cfg := consumerConfig{
+		concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.concurrency < 1 {
+		cfg.concurrency = 1
+	}
+
+	return &consumerService[T]{
+		name:      name,
+		namespace: namespace,
+		version:   version,
+		source:    source,
+		handle:    handle,
+		cfg:       cfg,
+	}
+}
+
+// Name returns the service name.
+func (s *consumerService[T]) Name() string { return s.name }
+
+// Namespace returns the service namespace.
+func (s *consumerService[T]) Namespace() string { return s.namespace }
+
+// Version returns the service version.
+func (s *consumerService[T]) Version() string { return s.version }
+
+// Init calls the function set with WithConsumerInit, if any.
+func (s *consumerService[T]) Init(ctx context.Context) error {
+	if s.cfg.init == nil {
+		return nil
+	}
+
+	return s.cfg.init(ctx)
+}
+
+// Close calls the function set with WithConsumerClose, if any.
+func (s *consumerService[T]) Close(ctx context.Context) error {
+	if s.cfg.close == nil {
+		return nil
+	}
+
+	return s.cfg.close(ctx)
+}
+
+// Run pulls and processes messages until shutdown begins or source fails, then drains the messages in flight.
+func (s *consumerService[T]) Run(ctx context.Context) error {
+	// Stop pulling as soon as shutdown begins, before the context is cancelled
+	pullCtx, stopPulling := context.WithCancel(ctx)
+	defer stopPulling()
+	go func() {
+		select {
+		case <-Stopping(ctx):
+			stopPulling()
+		case <-pullCtx.Done():
+		}
+	}()
+
+	// Messages in flight keep being processed after the cancellation of ctx, for at most the drain timeout
+	handleCtx, cancelHandle := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandle()
+	if s.cfg.drainTimeout > 0 {
+		stopDrain := context.AfterFunc(pullCtx, func() {
+			time.AfterFunc(s.cfg.drainTimeout, cancelHandle)
+		})
+		defer stopDrain()
+	}
+
+	messages, _ := Meter(ctx).Int64Counter(
+		"as.consumer.messages",
+		metric.WithDescription("Number of messages processed by as.ConsumerService"),
+	)
+	durations, _ := Meter(ctx).Float64Histogram(
+		"as.consumer.message.duration",
+		metric.WithDescription("Duration of processing a message by as.ConsumerService"),
+		metric.WithUnit("s"),
+	)
+
+	slots := make(chan struct{}, s.cfg.concurrency)
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-pullCtx.Done():
+			return nil
+		}
+
+		msg, err := s.source(pullCtx)
+		if err != nil {
+			<-slots
+			if pullCtx.Err() != nil {
+				return nil
+			}
+
+			// Messages in flight are drained like on shutdown
+			stopPulling()
+			return err
+		}
+
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			defer func() { <-slots }()
+
+			start := time.Now()
+			outcome := s.process(handleCtx, msg)
+
+			attrs := metric.WithAttributes(attribute.String("outcome", outcome))
+			if messages != nil {
+				messages.Add(ctx, 1, attrs)
+			}
+			if durations != nil {
+				durations.Record(ctx, time.Since(start).Seconds(), attrs)
+			}
+		}()
+	}
+}
+
+// process handles a single message in its own span, recovering panics. It returns the outcome of the message:
+// ok, error, or panic.
+func (s *consumerService[T]) process(ctx context.Context, msg T) (outcome string) {
+	if s.cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.timeout)
+		defer cancel()
+	}
+
+	ctx, span := Tracer(ctx).Start(ctx, s.name+" process", trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+
+	defer func() {
+		if cause := recover(); cause != nil {
+			err := panicError(ctx, cause, nil)
+			Logger(ctx).Error("message handler panicked", "error", err)
+			span.SetStatus(codes.Error, err.Error())
+			outcome = "panic"
+		}
+	}()
+
+	if err := s.handle(ctx, msg); err != nil {
+		level := errorLogLevel(err)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+			Logger(ctx).Log(ctx, level, "message handler timed out", "error", err)
+		} else {
+			Logger(ctx).Log(ctx, level, "message handler failed", "error", err)
+		}
+		span.SetStatus(codes.Error, err.Error())
+		return "error"
+	}
+
+	return "ok"
+}
@@ -0,0 +1,193 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// memorySource returns a message source pulling from ch, and blocking until ctx is done once it is empty.
+func memorySource(ch <-chan int) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		select {
+		case msg := <-ch:
+			return msg, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// consumerContext returns the context of a supervised service whose metrics are read by the returned reader.
+func consumerContext(t *testing.T) (context.Context, *supervisor, func() map[string]int64) {
+	t.Helper()
+
+	ctx, reader := testMeterContext(t, context.Background())
+	ctx = WithLogger(ctx, slog.New(slog.DiscardHandler))
+	sup := newSupervisor()
+	ctx = withSupervisor(ctx, sup)
+
+	outcomes := func() map[string]int64 {
+		sum, _ := collectMetric(t, reader, "as.consumer.messages").(metricdata.Sum[int64])
+		counts := make(map[string]int64)
+		for _, point := range sum.DataPoints {
+			outcome, _ := point.Attributes.Value("outcome")
+			counts[outcome.AsString()] += point.Value
+		}
+		return counts
+	}
+
+	return ctx, sup, outcomes
+}
+
+func TestConsumerPanicContainment(t *testing.T) {
+	ctx, sup, outcomes := consumerContext(t)
+
+	ch := make(chan int, 4)
+	for msg := range 4 {
+		ch <- msg + 1
+	}
+
+	var mu sync.Mutex
+	var handled []int
+	svc := ConsumerService("consumer", "astest", "v1.0.0", memorySource(ch), func(ctx context.Context, msg int) error {
+		mu.Lock()
+		handled = append(handled, msg)
+		mu.Unlock()
+
+		switch msg {
+		case 2:
+			panic("boom")
+		case 3:
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(ctx) }()
+
+	// A panicking message does not stop the consumer
+	waitFor(t, "all messages", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(handled) == 4
+	})
+	sup.beginStopping()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	if want := []int{1, 2, 3, 4}; !slices.Equal(handled, want) {
+		t.Errorf("handled %v, want %v", handled, want)
+	}
+
+	if got, want := outcomes(), map[string]int64{"ok": 2, "panic": 1, "error": 1}; !maps.Equal(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+}
+
+func TestConsumerDrain(t *testing.T) {
+	ctx, sup, outcomes := consumerContext(t)
+
+	ch := make(chan int, 2)
+	ch <- 1
+	ch <- 2
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var drained atomic.Int32
+	pulls := 0
+	source := memorySource(ch)
+	svc := ConsumerService("consumer", "astest", "v1.0.0", func(ctx context.Context) (int, error) {
+		pulls++
+		return source(ctx)
+	}, func(msgCtx context.Context, msg int) error {
+		started <- struct{}{}
+		<-release
+		// Messages in flight keep their context after the service context is cancelled
+		if msgCtx.Err() == nil {
+			drained.Add(1)
+		}
+		return nil
+	}, WithConsumerConcurrency(2))
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(runCtx) }()
+
+	<-started
+	<-started
+	sup.beginStopping()
+	cancel()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Run() = %v before the messages in flight were drained", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+
+	if got := drained.Load(); got != 2 {
+		t.Errorf("%d messages drained with a live context, want 2", got)
+	}
+	// Both slots are taken, so no further message is pulled before or after shutdown began
+	if pulls != 2 {
+		t.Errorf("pulled %d times, want 2", pulls)
+	}
+	if got := outcomes()["ok"]; got != 2 {
+		t.Errorf("%d messages ok, want 2", got)
+	}
+}
+
+func TestConsumerTimeout(t *testing.T) {
+	ctx, sup, outcomes := consumerContext(t)
+
+	ch := make(chan int, 1)
+	ch <- 1
+
+	handled := make(chan struct{})
+	svc := ConsumerService("consumer", "astest", "v1.0.0", memorySource(ch), func(ctx context.Context, msg int) error {
+		defer close(handled)
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithConsumerTimeout(time.Millisecond))
+
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(ctx) }()
+
+	<-handled
+	sup.beginStopping()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	if got := outcomes()["error"]; got != 1 {
+		t.Errorf("%d messages failed, want 1", got)
+	}
+}
+
+func TestConsumerSourceError(t *testing.T) {
+	ctx, _, _ := consumerContext(t)
+
+	errSource := errors.New("broker unavailable")
+	svc := ConsumerService("consumer", "astest", "v1.0.0", func(ctx context.Context) (int, error) {
+		return 0, errSource
+	}, func(ctx context.Context, msg int) error { return nil })
+
+	if err := svc.Run(ctx); !errors.Is(err, errSource) {
+		t.Errorf("Run() = %v, want %v", err, errSource)
+	}
+}
@@ -0,0 +1,42 @@
+package as
+
+import "context"
+
+// serviceContextKeys are the keys of all values the supervisor attaches to a service context.
+var serviceContextKeys = []any{
+	nameKey{},
+	namespaceKey{},
+	versionKey{},
+	descriptionKey{},
+	labelsKey{},
+	envPrefixKey{},
+	envFallbackPrefixesKey{},
+	runtimeEnvKey{},
+	loggerKey{},
+	tracerProviderKey{},
+	tracerKey{},
+	meterProviderKey{},
+	meterKey{},
+	textMapPropagatorKey{},
+	otelInfoKey{},
+	supervisorKey{},
+	sharedValuesKey{},
+	attemptKey{},
+	previousErrorKey{},
+}
+
+// WithServiceContext returns a new context based on ctx that carries all values the supervisor attached to
+// serviceCtx, such as the logger, the OTEL providers, the service identity, and the env prefix.
+// Deadlines and cancellation of serviceCtx are not propagated.
+//
+// This is useful for contexts which do not descend from the service context, like the request contexts of an
+// http.Server, so that e.g. Logger(ctx) returns the service logger.
+func WithServiceContext(ctx, serviceCtx context.Context) context.Context {
+	for _, key := range serviceContextKeys {
+		if v := serviceCtx.Value(key); v != nil {
+			ctx = context.WithValue(ctx, key, v)
+		}
+	}
+
+	return ctx
+}
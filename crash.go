@@ -0,0 +1,191 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// crashReportPrefix is the file name prefix of crash reports.
+const crashReportPrefix = "crash-"
+
+// writeCrashReport writes a crash report to the directory configured by opts.CrashDir, then removes the oldest
+// reports exceeding opts.CrashRetain. The report contains the given kind and value (e.g. the panic value), the stack
+// of the failing goroutine, a dump of all goroutines, the service identity, build information, the key options and
+// prefixed environment variables (with likely secrets redacted, see PrefixedEnviron), and the most recent log
+// records.
+//
+// Failures are logged and otherwise ignored, so they never mask the original error. Does nothing if no crash
+// directory is configured.
+func writeCrashReport(ctx context.Context, opts Options, kind string, value any, stack []byte) {
+	if opts.CrashDir == "" {
+		return
+	}
+
+	now := time.Now().UTC()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s: %v\n\n", kind, value)
+	fmt.Fprintf(&b, "time: %s\n", now.Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "service: %s\n", Name(ctx))
+	fmt.Fprintf(&b, "namespace: %s\n", Namespace(ctx))
+	fmt.Fprintf(&b, "version: %s\n", Version(ctx))
+	fmt.Fprintf(&b, "vcs_revision: %s\n", VCSVersion())
+	fmt.Fprintf(&b, "go_version: %s\n", runtime.Version())
+	fmt.Fprintf(&b, "pid: %d\n", os.Getpid())
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(&b, "\n--- build info ---\n%s\n", bi.String())
+	}
+
+	b.WriteString("\n--- options ---\n")
+	options := keyOptions(opts)
+	for i := 0; i+1 < len(options); i += 2 {
+		fmt.Fprintf(&b, "%s: %v\n", options[i], options[i+1])
+	}
+
+	b.WriteString("\n--- env ---\n")
+	for _, entry := range PrefixedEnviron(ctx) {
+		fmt.Fprintf(&b, "%s=%s\n", entry.Name, entry.Value)
+	}
+
+	if len(stack) > 0 {
+		fmt.Fprintf(&b, "\n--- stack ---\n%s\n", stack)
+	}
+
+	fmt.Fprintf(&b, "\n--- goroutines ---\n%s\n", allGoroutines())
+
+	if sup := supervisorFrom(ctx); sup != nil && sup.recentLogs != nil {
+		fmt.Fprintf(&b, "\n--- recent logs ---\n%s", strings.Join(sup.recentLogs.lines(), ""))
+	}
+
+	if err := os.MkdirAll(opts.CrashDir, 0o755); err != nil {
+		Logger(ctx).Error("failed to create crash directory", "path", opts.CrashDir, "error", err)
+		return
+	}
+
+	path := filepath.Join(opts.CrashDir, crashReportPrefix+now.Format("20060102T150405.000000000Z")+".txt")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		Logger(ctx).Error("failed to write crash report", "path", path, "error", err)
+		return
+	}
+
+	Logger(ctx).Info("wrote crash report", "path", path)
+
+	pruneCrashReports(ctx, opts)
+}
+
+// pruneCrashReports removes the oldest crash reports exceeding opts.CrashRetain.
+func pruneCrashReports(ctx context.Context, opts Options) {
+	pruneFiles(ctx, opts.CrashDir, crashReportPrefix, opts.CrashRetain)
+}
+
+// pruneFiles removes the oldest files in dir with the given prefix exceeding retain. The names of the files must
+// contain a timestamp after the prefix. Does nothing if retain is not positive.
+func pruneFiles(ctx context.Context, dir, prefix string, retain int) {
+	if retain <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		Logger(ctx).Error("failed to list files", "path", dir, "error", err)
+		return
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			files = append(files, entry.Name())
+		}
+	}
+
+	// Names contain the timestamp, so lexical order is chronological order
+	slices.Sort(files)
+
+	for len(files) > retain {
+		path := filepath.Join(dir, files[0])
+		if err := os.Remove(path); err != nil {
+			Logger(ctx).Error("failed to remove file", "path", path, "error", err)
+		}
+		files = files[1:]
+	}
+}
+
+// allGoroutines returns the stacks of all goroutines.
+func allGoroutines() []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// initCrashLog returns a logger additionally recording the most recent log records in sup, for inclusion in crash
+// reports. All records are recorded in text form, regardless of the configured log level.
+// Returns logger unchanged if no crash directory is configured.
+func initCrashLog(sup *supervisor, opts Options, logger *slog.Logger) *slog.Logger {
+	if opts.CrashDir == "" || opts.CrashLogLines <= 0 {
+		return logger
+	}
+
+	sup.recentLogs = newLogRing(opts.CrashLogLines)
+
+	return slog.New(newTeeHandler(
+		logger.Handler(),
+		slog.NewTextHandler(sup.recentLogs, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+}
+
+// logRing is an io.Writer retaining the most recent writes. slog handlers write each record with a single write,
+// so each retained write is one log line.
+type logRing struct {
+	mu    sync.Mutex
+	lns   []string
+	next  int
+	count int
+}
+
+// newLogRing returns a logRing retaining the given number of lines.
+func newLogRing(size int) *logRing {
+	return &logRing{lns: make([]string, size)}
+}
+
+// Write records p as a single line.
+func (r *logRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lns[r.next] = string(p)
+	r.next = (r.next + 1) % len(r.lns)
+	r.count = min(r.count+1, len(r.lns))
+
+	return len(p), nil
+}
+
+// lines returns the retained lines, oldest first.
+func (r *logRing) lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]string, 0, r.count)
+	start := (r.next - r.count + len(r.lns)) % len(r.lns)
+	for i := 0; i < r.count; i++ {
+		out = append(out, r.lns[(start+i)%len(r.lns)])
+	}
+
+	return out
+}
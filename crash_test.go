@@ -0,0 +1,55 @@
+package as
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrashReport(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ASTEST_TEST_API_TOKEN", "token-from-env")
+	t.Setenv("ASTEST_TEST_REGION", "eu-west-1")
+
+	heartbeat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer heartbeat.Close()
+
+	svc := &testService{run: func(ctx context.Context) error {
+		panic("scripted panic")
+	}}
+	err := RunC(svc, context.Background(), testOptions(
+		WithCrashDir(dir),
+		WithRecoverPanic(true),
+		WithRestartOnPanic(false),
+		WithHeartbeatURL(heartbeat.URL+"/ping/token-in-url", time.Hour),
+	)...)
+	if err == nil {
+		t.Fatal("RunC() = nil, want the panic")
+	}
+
+	reports, _ := filepath.Glob(filepath.Join(dir, crashReportPrefix+"*.txt"))
+	if len(reports) != 1 {
+		t.Fatalf("got crash reports %v, want one", reports)
+	}
+	data, err := os.ReadFile(reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+
+	for _, want := range []string{"scripted panic", "service: test", "namespace: astest", "--- stack ---", "restart_on_panic: false", "ASTEST_TEST_REGION=eu-west-1", "ASTEST_TEST_API_TOKEN=" + redactedValue} {
+		if !strings.Contains(report, want) {
+			t.Errorf("crash report lacks %q", want)
+		}
+	}
+	for _, secret := range []string{"token-in-url", "token-from-env"} {
+		if strings.Contains(report, secret) {
+			t.Errorf("crash report contains the secret %q", secret)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package as
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.aledante.io/ae"
+)
+
+// dataDir manages the data directory and the temp directory of the current attempt of a service.
+type dataDir struct {
+	mu      sync.Mutex
+	path    string
+	mode    os.FileMode
+	created bool
+
+	tempPrefix string
+	tempDir    string
+}
+
+// DataDir returns the data directory of the service the context belongs to, creating it on first access.
+// It is DataDir of the options (env DATA_DIR) or, by default, <namespace>/<name> in os.UserCacheDir().
+func DataDir(ctx context.Context) (string, error) {
+	sup := supervisorFrom(ctx)
+	if sup == nil || sup.dataDir == nil {
+		return "", ae.New().Msg("no data directory outside of a supervised service")
+	}
+
+	d := sup.dataDir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.created {
+		if err := os.MkdirAll(d.path, d.mode); err != nil {
+			return "", ae.Wrap("failed to create data directory", err)
+		}
+		d.created = true
+	}
+
+	return d.path, nil
+}
+
+// TempDir returns a temporary directory for the current run of the service the context belongs to, creating it on
+// first access. The directory is removed with its contents when the run ends, before a restart.
+func TempDir(ctx context.Context) (string, error) {
+	sup := supervisorFrom(ctx)
+	if sup == nil || sup.dataDir == nil {
+		return "", ae.New().Msg("no temp directory outside of a supervised service")
+	}
+
+	d := sup.dataDir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.tempDir == "" {
+		dir, err := os.MkdirTemp("", d.tempPrefix)
+		if err != nil {
+			return "", ae.Wrap("failed to create temp directory", err)
+		}
+		d.tempDir = dir
+	}
+
+	return d.tempDir, nil
+}
+
+// initDataDir resolves the data directory configured in opts and, if WipeDataDir is set, removes its contents.
+func initDataDir(ctx context.Context, opts Options) {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		return
+	}
+
+	path := opts.DataDir
+	if path == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			cacheDir = os.TempDir()
+		}
+		path = filepath.Join(cacheDir, Namespace(ctx), Name(ctx))
+	}
+
+	sup.dataDir = &dataDir{
+		path:       path,
+		mode:       opts.DataDirMode,
+		tempPrefix: Namespace(ctx) + "-" + Name(ctx) + "-*",
+	}
+
+	if opts.WipeDataDir {
+		if err := os.RemoveAll(path); err != nil {
+			Logger(ctx).Error("failed to wipe data directory", "path", path, "error", err)
+		}
+	}
+}
+
+// removeTempDir removes the temp directory of the current run, if it was created.
+func (d *dataDir) removeTempDir(ctx context.Context) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.tempDir == "" {
+		return
+	}
+
+	if err := os.RemoveAll(d.tempDir); err != nil {
+		Logger(ctx).Error("failed to remove temp directory", "path", d.tempDir, "error", err)
+	}
+	d.tempDir = ""
+}
@@ -0,0 +1,139 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// dataDirOf runs svc once and returns the data directory it observed.
+func dataDirOf(t *testing.T, opts ...Option) string {
+	t.Helper()
+
+	var dir string
+	svc := &testService{run: func(ctx context.Context) error {
+		var err error
+		dir, err = DataDir(ctx)
+		return err
+	}}
+	if err := RunC(svc, context.Background(), testOptions(opts...)...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	return dir
+}
+
+func TestDataDir(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("the user cache directory is only overridable on linux")
+		}
+		cache := t.TempDir()
+		t.Setenv("XDG_CACHE_HOME", cache)
+
+		if got, want := dataDirOf(t), filepath.Join(cache, "astest", "test"); got != want {
+			t.Errorf("DataDir() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("env override", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state")
+		t.Setenv("ASTEST_TEST_DATA_DIR", path)
+
+		if got := dataDirOf(t, WithDataDir("ignored")); got != path {
+			t.Errorf("DataDir() = %q, want %q", got, path)
+		}
+	})
+
+	t.Run("creation", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "state")
+
+		dataDirOf(t, WithDataDir(path), WithDataDirMode(0o750))
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("data directory not created: %v", err)
+		}
+		// The umask may remove further permissions, but none may be added
+		if runtime.GOOS != "windows" && info.Mode().Perm()&^0o750 != 0 {
+			t.Errorf("data directory mode = %v, want at most 0750", info.Mode().Perm())
+		}
+	})
+
+	t.Run("wipe", func(t *testing.T) {
+		path := t.TempDir()
+		stale := filepath.Join(path, "stale")
+		if err := os.WriteFile(stale, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		dataDirOf(t, WithDataDir(path), WithWipeDataDir(true))
+		if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("stale file not wiped: %v", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("data directory not recreated: %v", err)
+		}
+	})
+
+	t.Run("kept", func(t *testing.T) {
+		path := t.TempDir()
+		kept := filepath.Join(path, "kept")
+		if err := os.WriteFile(kept, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		dataDirOf(t, WithDataDir(path))
+		if _, err := os.Stat(kept); err != nil {
+			t.Errorf("file removed without WipeDataDir: %v", err)
+		}
+	})
+}
+
+func TestTempDir(t *testing.T) {
+	attempt := 0
+	var dirs []string
+	svc := &testService{run: func(ctx context.Context) error {
+		dir, err := TempDir(ctx)
+		if err != nil {
+			return err
+		}
+		if again, _ := TempDir(ctx); again != dir {
+			t.Errorf("TempDir() = %q, then %q", dir, again)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "scratch"), nil, 0o600); err != nil {
+			return err
+		}
+		dirs = append(dirs, dir)
+
+		if attempt++; attempt == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	}}
+
+	if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// Each run gets its own directory, removed when the run ends
+	if len(dirs) != 2 || dirs[0] == dirs[1] {
+		t.Fatalf("temp directories = %v, want one per run", dirs)
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("temp directory %s not removed: %v", dir, err)
+		}
+	}
+}
+
+func TestDataDirWithoutSupervisor(t *testing.T) {
+	if _, err := DataDir(context.Background()); err == nil {
+		t.Error("DataDir() = nil error outside of a supervised service")
+	}
+	if _, err := TempDir(context.Background()); err == nil {
+		t.Error("TempDir() = nil error outside of a supervised service")
+	}
+}
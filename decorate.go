@@ -0,0 +1,34 @@
+package as
+
+import "context"
+
+// WithContextValue adds a value to the context of the service, visible in Init, Run, and Close of every run.
+// It can be used multiple times.
+func WithContextValue(key, value any) Option {
+	return WithContextDecorator(func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, key, value)
+	})
+}
+
+// WithContextDecorator adds a function deriving the context of the service, applied before Init of every run.
+// It can be used multiple times; decorators are applied in order. Values set by the supervisor, like the logger
+// and the OTEL providers, cannot be replaced by decorators.
+func WithContextDecorator(fn func(ctx context.Context) context.Context) Option {
+	return func(o *Options) {
+		o.ContextDecorators = append(o.ContextDecorators, fn)
+	}
+}
+
+// decorateContext applies the context decorators of opts to ctx, then restores the values of the supervisor.
+func decorateContext(ctx context.Context, opts Options) context.Context {
+	if len(opts.ContextDecorators) == 0 {
+		return ctx
+	}
+
+	decorated := ctx
+	for _, fn := range opts.ContextDecorators {
+		decorated = fn(decorated)
+	}
+
+	return WithServiceContext(decorated, ctx)
+}
@@ -0,0 +1,71 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+type flagsKey struct{}
+
+type tenantKey struct{}
+
+func TestContextDecorators(t *testing.T) {
+	log := &eventLog{}
+	observe := func(ctx context.Context, phase string) {
+		flags, _ := ctx.Value(flagsKey{}).(string)
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		log.add(phase + ":" + flags + ":" + tenant)
+		Logger(ctx).Info("observed " + phase)
+	}
+
+	attempt := 0
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			observe(ctx, "init")
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			observe(ctx, "run")
+			if attempt++; attempt == 1 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+		close: func(ctx context.Context) error {
+			observe(ctx, "close")
+			return nil
+		},
+	}
+
+	logs := &logCapture{}
+	opts := testOptions(
+		captureLogs(svc, logs),
+		WithContextValue(flagsKey{}, "flags"),
+		WithContextDecorator(func(ctx context.Context) context.Context {
+			// Decorators see the values of the decorators before them, and cannot replace the logger
+			flags, _ := ctx.Value(flagsKey{}).(string)
+			ctx = WithLogger(ctx, slog.New(slog.DiscardHandler))
+			return context.WithValue(ctx, tenantKey{}, "tenant of "+flags)
+		}),
+	)
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// Close is not called after a failed run, so the first attempt only has init and run
+	want := []string{"init:flags:tenant of flags", "run:flags:tenant of flags"}
+	want = append(want, want...)
+	want = append(want, "close:flags:tenant of flags")
+	if got := log.all(); !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	for _, phase := range []string{"init", "run", "close"} {
+		if logs.find("observed "+phase) == nil {
+			t.Errorf("record of %s not logged with the service logger", phase)
+		}
+	}
+}
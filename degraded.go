@@ -0,0 +1,66 @@
+package as
+
+import (
+	"context"
+	"errors"
+)
+
+// degradedError is an error marking the service as degraded, see Degraded.
+type degradedError struct {
+	err error
+}
+
+// Error returns the error message of the wrapped error.
+func (e *degradedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *degradedError) Unwrap() error {
+	return e.err
+}
+
+// Degraded marks err as a non-critical failure, e.g. of a background sync. If Run returns a degraded error while
+// the service is not stopping, the supervisor logs it, sets the health status to HealthDegraded (see SetHealth),
+// and calls Run again, without Close, Init, or counting a restart. After MaxDegradations consecutive degraded
+// errors in the same run, the error is handled like any other error. The service is responsible for setting its
+// health status back to HealthHealthy once it recovered. Degraded returns nil if err is nil.
+func Degraded(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &degradedError{err: err}
+}
+
+// IsDegraded reports whether err or any error it wraps was marked with Degraded.
+func IsDegraded(err error) bool {
+	var dErr *degradedError
+	return errors.As(err, &dErr)
+}
+
+// runDegradable calls run until it returns an error not marked with Degraded, ctx is done, or opts.MaxDegradations
+// consecutive degraded errors occurred. Degraded errors set the health status to HealthDegraded.
+func runDegradable(ctx context.Context, opts Options, run func() (error, bool)) (err error, isPanic bool) {
+	for degradations := 0; ; degradations++ {
+		err, isPanic = run()
+		if isPanic || !IsDegraded(err) || ctx.Err() != nil {
+			return err, isPanic
+		}
+
+		if degradations >= opts.MaxDegradations {
+			Logger(ctx).Error("service degraded too often, handling as failure",
+				"error", err,
+				"max_degradations", opts.MaxDegradations,
+			)
+			return err, false
+		}
+
+		Logger(ctx).Warn("service degraded, running again",
+			"error", err,
+			"degradations", degradations+1,
+			"max_degradations", opts.MaxDegradations,
+		)
+		SetHealth(ctx, HealthDegraded, err.Error())
+	}
+}
@@ -0,0 +1,127 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDegraded(t *testing.T) {
+	errSync := errors.New("sync failed")
+
+	tests := []struct {
+		name string
+		// degradations is the number of degraded errors Run returns before it succeeds
+		degradations int
+		wantRuns     int
+		wantErr      bool
+	}{
+		{name: "recovers", degradations: 2, wantRuns: 3},
+		{name: "escalates", degradations: 10, wantRuns: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inits, runs := 0, 0
+			var health []HealthStatus
+			svc := &testService{
+				init: func(ctx context.Context) error {
+					inits++
+					return nil
+				},
+				run: func(ctx context.Context) error {
+					status, _ := Health(ctx)
+					health = append(health, status)
+					if runs++; runs <= tt.degradations {
+						return Degraded(fmt.Errorf("background: %w", errSync))
+					}
+					return nil
+				},
+			}
+
+			logs := &logCapture{}
+			opts := testOptions(captureLogs(svc, logs), WithMaxDegradations(2), WithRestartOnError(false))
+			err := RunC(svc, context.Background(), opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunC() = %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr && (!IsDegraded(err) || !errors.Is(err, errSync)) {
+				t.Errorf("RunC() = %v, want the degraded error", err)
+			}
+
+			// Run is called again without Init or a restart, with the health status degraded
+			if runs != tt.wantRuns || inits != 1 {
+				t.Errorf("runs = %d, inits = %d, want %d runs and one init", runs, inits, tt.wantRuns)
+			}
+			if health[0] != HealthHealthy || health[1] != HealthDegraded || health[2] != HealthDegraded {
+				t.Errorf("health observed by Run = %v", health)
+			}
+			if summary := logs.find("service exited"); summary == nil || summary["restarts"] != float64(0) {
+				t.Errorf("exit summary = %v, want no restarts", summary)
+			}
+			if tt.wantErr && logs.find("service degraded too often, handling as failure") == nil {
+				t.Error("escalation not logged")
+			}
+		})
+	}
+}
+
+func TestDegradedEscalationRestarts(t *testing.T) {
+	inits, runs := 0, 0
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			inits++
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			if runs++; runs <= 2 {
+				return Degraded(errors.New("sync failed"))
+			}
+			return nil
+		},
+	}
+
+	// Once escalated, the error is handled like any other, so the service is restarted
+	if err := RunC(svc, context.Background(), testOptions(WithMaxDegradations(1))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if runs != 3 || inits != 2 {
+		t.Errorf("runs = %d, inits = %d, want 3 runs and 2 inits", runs, inits)
+	}
+}
+
+func TestIsDegraded(t *testing.T) {
+	if Degraded(nil) != nil {
+		t.Error("Degraded(nil) != nil")
+	}
+	if IsDegraded(errors.New("failed")) {
+		t.Error("IsDegraded() of a regular error = true")
+	}
+
+	err := fmt.Errorf("run: %w", Degraded(errors.New("sync failed")))
+	if !IsDegraded(err) {
+		t.Error("IsDegraded() of a wrapped degraded error = false")
+	}
+	if err.Error() != "run: sync failed" {
+		t.Errorf("Error() = %q, want the message unchanged", err.Error())
+	}
+}
+
+func TestDegradedMinimumRunDuration(t *testing.T) {
+	runs := 0
+	svc := &testService{run: func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		if runs++; runs <= 2 {
+			return Degraded(errors.New("sync failed"))
+		}
+		return nil
+	}}
+
+	// The minimum run duration covers all calls of Run, since degraded errors do not end the run
+	opts := testOptions(WithMinimumRunDuration(50*time.Millisecond), WithRestartOnError(false))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,77 @@
+package as
+
+import (
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// DevOptions returns DefaultOptions with the local development preset applied, see WithDevMode.
+func DevOptions() Options {
+	o := DefaultOptions()
+	WithDevMode()(&o)
+	return o
+}
+
+// WithDevMode applies the local development preset: colored console logs at debug level, no restarts so failures
+// are loud, spans and metrics printed to the console if no OTEL exporter is configured, and a short shutdown
+// timeout. Options applied after it take precedence.
+func WithDevMode() Option {
+	return func(o *Options) {
+		setOption(o, &o.DevMode, true)
+		setOption(o, &o.LogJson, false)
+		setOption(o, &o.LogAutoFormat, false)
+		setOption(o, &o.LogFormat, LogFormatDefault)
+		setOption(o, &o.LogColors, true)
+		setOption(o, &o.LogLevel, "debug")
+		setOption(o, &o.RestartOnError, false)
+		setOption(o, &o.RestartOnPanic, false)
+		setOption(o, &o.OTELFallback, OTELFallbackConsole)
+		setOption(o, &o.ShutdownTimeout, 5*time.Second)
+	}
+}
+
+// WithDevModeAuto sets the DevModeAuto field, enabling or disabling the activation of the development preset for
+// modified builds running on a terminal.
+func WithDevModeAuto(v bool) Option {
+	return func(o *Options) { setOption(o, &o.DevModeAuto, v) }
+}
+
+// devModeEnabled reports whether the development preset applies: as set by the DEV env var or in code if either
+// is set, otherwise if DevModeAuto is set, the build has uncommitted changes, and stdout is a terminal.
+func devModeEnabled(o Options, explicit map[int]bool, namespace string) bool {
+	field, _ := reflect.TypeFor[Options]().FieldByName("DevMode")
+	if _, ok := optionEnvVar(o, namespace, "DEV"); ok || explicit[field.Index[0]] {
+		return o.DevMode
+	}
+
+	return o.DevModeAuto && vcsModified() && isatty.IsTerminal(os.Stdout.Fd())
+}
+
+// applyDevMode applies the development preset to o if it is enabled. Fields set in code or by an env var keep
+// their value.
+func applyDevMode(o *Options, explicit map[int]bool, namespace string) {
+	if !devModeEnabled(*o, explicit, namespace) {
+		return
+	}
+
+	dev := *o
+	WithDevMode()(&dev)
+
+	t := reflect.TypeFor[Options]()
+	current := reflect.ValueOf(o).Elem()
+	preset := reflect.ValueOf(dev)
+	for i := range t.NumField() {
+		key, ok := t.Field(i).Tag.Lookup("env")
+		if !ok || explicit[i] || current.Field(i).Equal(preset.Field(i)) {
+			continue
+		}
+		if _, ok := optionEnvVar(*o, namespace, key); ok {
+			continue
+		}
+
+		current.Field(i).Set(preset.Field(i))
+	}
+}
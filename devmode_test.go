@@ -0,0 +1,105 @@
+package as
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDevOptions(t *testing.T) {
+	o := DevOptions()
+	if o.LogJson || o.LogAutoFormat || o.LogFormat != LogFormatDefault || !o.LogColors || o.LogLevel != "debug" ||
+		o.RestartOnError || o.RestartOnPanic || o.OTELFallback != OTELFallbackConsole ||
+		o.ShutdownTimeout != 5*time.Second || !o.DevMode {
+		t.Errorf("DevOptions() = %+v", o)
+	}
+
+	// Everything else keeps its default
+	if want := DefaultOptions(); o.GraceCount != want.GraceCount || o.GracePeriod != want.GracePeriod {
+		t.Errorf("GraceCount = %d, GracePeriod = %s, want the defaults", o.GraceCount, o.GracePeriod)
+	}
+}
+
+func TestDevMode(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		opts []Option
+		// wantDev reports whether the preset is expected to apply to the fields set neither in code nor by env vars
+		wantDev         bool
+		wantLevel       string
+		wantRestart     bool
+		wantShutdown    time.Duration
+		wantLogJson     bool
+		wantOTELConsole bool
+	}{
+		{
+			name:         "disabled",
+			wantLevel:    "info",
+			wantRestart:  true,
+			wantShutdown: DefaultOptions().ShutdownTimeout,
+			wantLogJson:  true,
+		},
+		{
+			name:            "option",
+			opts:            []Option{WithDevMode()},
+			wantDev:         true,
+			wantLevel:       "debug",
+			wantShutdown:    5 * time.Second,
+			wantOTELConsole: true,
+		},
+		{
+			name:            "options after it win",
+			opts:            []Option{WithDevMode(), WithLogLevel("warn"), WithRestartOnError(true), WithLogJson(true)},
+			wantDev:         true,
+			wantLevel:       "warn",
+			wantRestart:     true,
+			wantShutdown:    5 * time.Second,
+			wantLogJson:     true,
+			wantOTELConsole: true,
+		},
+		{
+			name:            "env var",
+			env:             map[string]string{"ASTEST_TEST_DEV": "true", "ASTEST_TEST_SHUTDOWN_TIMEOUT": "30s"},
+			opts:            []Option{WithLogLevel("warn")},
+			wantDev:         true,
+			wantLevel:       "warn",
+			wantShutdown:    30 * time.Second,
+			wantOTELConsole: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			o, _, _, err := loadOptions("test", "astest", tt.opts)
+			if err != nil {
+				t.Fatalf("loadOptions() = %v", err)
+			}
+
+			if o.LogColors != tt.wantDev || o.LogLevel != tt.wantLevel || o.RestartOnError != tt.wantRestart ||
+				o.ShutdownTimeout != tt.wantShutdown || o.LogJson != tt.wantLogJson ||
+				(o.OTELFallback == OTELFallbackConsole) != tt.wantOTELConsole {
+				t.Errorf("LogColors = %t, LogLevel = %q, RestartOnError = %t, ShutdownTimeout = %s, LogJson = %t, OTELFallback = %v",
+					o.LogColors, o.LogLevel, o.RestartOnError, o.ShutdownTimeout, o.LogJson, o.OTELFallback)
+			}
+		})
+	}
+}
+
+func TestDevModeAuto(t *testing.T) {
+	// Tests do not run on a terminal, so the preset is not activated automatically
+	o, _, _, err := loadOptions("test", "astest", nil)
+	if err != nil {
+		t.Fatalf("loadOptions() = %v", err)
+	}
+	if o.LogColors || o.LogLevel != "info" {
+		t.Errorf("LogColors = %t, LogLevel = %q, want the defaults", o.LogColors, o.LogLevel)
+	}
+
+	if devModeEnabled(Options{DevModeAuto: false}, nil, "astest") {
+		t.Error("devModeEnabled() = true with DevModeAuto disabled")
+	}
+}
@@ -0,0 +1,73 @@
+package as
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"time"
+)
+
+// initDiagnostics starts logging runtime diagnostics at debug level every opts.DiagnosticsInterval.
+// It returns a function stopping the ticker and waiting for the logging goroutine to exit.
+func initDiagnostics(ctx context.Context, opts Options) func() {
+	if opts.DiagnosticsInterval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(opts.DiagnosticsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				logDiagnostics(ctx)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// logDiagnostics emits a single debug record with the goroutine count, heap usage, GC pauses and, where
+// available, the number of open file descriptors.
+func logDiagnostics(ctx context.Context) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	args := []any{
+		"goroutines", runtime.NumGoroutine(),
+		"heap_inuse", ms.HeapInuse,
+		"heap_objects", ms.HeapObjects,
+		"num_gc", ms.NumGC,
+		"gc_pause_last", time.Duration(ms.PauseNs[(ms.NumGC+255)%256]),
+		"gc_pause_total", time.Duration(ms.PauseTotalNs),
+	}
+
+	if fds, ok := openFDs(); ok {
+		args = append(args, "open_fds", fds)
+	}
+
+	Logger(ctx).Debug("runtime diagnostics", args...)
+}
+
+// openFDs returns the number of open file descriptors of the process.
+// The count is only available on systems exposing /proc/self/fd.
+func openFDs() (int, bool) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, false
+	}
+
+	// The directory handle used to read the entries is counted as well.
+	return len(entries) - 1, true
+}
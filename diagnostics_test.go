@@ -0,0 +1,61 @@
+package as
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiagnostics(t *testing.T) {
+	svc := &testService{}
+	logs := &logCapture{}
+	_, done := runTest(t, svc,
+		captureLogs(svc, logs),
+		WithLogDebug(true),
+		WithDiagnosticsInterval(10*time.Millisecond),
+	)
+
+	var record map[string]any
+	waitFor(t, "runtime diagnostics record", func() bool {
+		record = logs.find("runtime diagnostics")
+		return record != nil
+	})
+
+	if record["level"] != "DEBUG" {
+		t.Errorf("level = %v, want DEBUG", record["level"])
+	}
+	for _, key := range []string{"goroutines", "heap_inuse", "heap_objects", "num_gc", "gc_pause_last", "gc_pause_total"} {
+		if _, ok := record[key]; !ok {
+			t.Errorf("record is missing %q: %v", key, record)
+		}
+	}
+	if n, ok := record["goroutines"].(float64); !ok || n < 1 {
+		t.Errorf("goroutines = %v, want a positive count", record["goroutines"])
+	}
+	if _, ok := openFDs(); ok {
+		if n, ok := record["open_fds"].(float64); !ok || n < 1 {
+			t.Errorf("open_fds = %v, want a positive count", record["open_fds"])
+		}
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("service stopped early: %v", err)
+	default:
+	}
+}
+
+func TestDiagnosticsStop(t *testing.T) {
+	stop := initDiagnostics(t.Context(), Options{DiagnosticsInterval: time.Millisecond})
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("diagnostics did not stop")
+	}
+}
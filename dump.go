@@ -0,0 +1,92 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"syscall"
+	"time"
+)
+
+// signalDumpMinInterval is the minimum interval between two dumps triggered by SIGQUIT.
+const signalDumpMinInterval = 10 * time.Second
+
+// initSignalDump intercepts SIGQUIT if opts.SignalDump is enabled: instead of terminating the process, a dump of
+// all goroutines and heap statistics is written to the crash directory (if configured) or to stderr.
+// Signals arriving within signalDumpMinInterval of the previous dump are ignored.
+// It returns a function restoring the default SIGQUIT behavior.
+func initSignalDump(ctx context.Context, opts Options) func() {
+	if !opts.SignalDump {
+		return func() {}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGQUIT)
+
+	done := make(chan struct{})
+	go func() {
+		var last time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case <-sigs:
+				if time.Since(last) < signalDumpMinInterval {
+					Logger(ctx).Warn("ignoring SIGQUIT, dumped goroutines recently", "last_dump", last)
+					continue
+				}
+
+				last = time.Now()
+				writeSignalDump(ctx, opts)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
+
+// writeSignalDump writes a dump of all goroutines and heap statistics to the crash directory or stderr.
+func writeSignalDump(ctx context.Context, opts Options) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "SIGQUIT dump at %s\n\n", time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "goroutines: %d\n", runtime.NumGoroutine())
+	fmt.Fprintf(&b, "heap_alloc: %d\n", ms.HeapAlloc)
+	fmt.Fprintf(&b, "heap_inuse: %d\n", ms.HeapInuse)
+	fmt.Fprintf(&b, "heap_sys: %d\n", ms.HeapSys)
+	fmt.Fprintf(&b, "heap_objects: %d\n", ms.HeapObjects)
+	fmt.Fprintf(&b, "num_gc: %d\n", ms.NumGC)
+	fmt.Fprintf(&b, "pause_total: %s\n\n", time.Duration(ms.PauseTotalNs))
+
+	if err := pprof.Lookup("goroutine").WriteTo(&b, 2); err != nil {
+		Logger(ctx).Error("failed to dump goroutines", "error", err)
+	}
+
+	if opts.CrashDir != "" {
+		path := filepath.Join(opts.CrashDir, "dump-"+time.Now().UTC().Format("20060102T150405.000000000Z")+".txt")
+
+		err := os.MkdirAll(opts.CrashDir, 0o755)
+		if err == nil {
+			err = os.WriteFile(path, b.Bytes(), 0o644)
+		}
+		if err == nil {
+			Logger(ctx).Info("wrote goroutine dump", "path", path)
+			return
+		}
+
+		Logger(ctx).Error("failed to write goroutine dump, writing to stderr", "path", path, "error", err)
+	}
+
+	_, _ = os.Stderr.Write(b.Bytes())
+	Logger(ctx).Info("wrote goroutine dump to stderr")
+}
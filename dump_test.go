@@ -0,0 +1,53 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package as
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalDump(t *testing.T) {
+	dir := t.TempDir()
+	logs := &logCapture{}
+	ctx := WithLogger(context.Background(), slog.New(slog.NewJSONHandler(logs, nil)))
+
+	restore := initSignalDump(ctx, Options{SignalDump: true, CrashDir: dir})
+	defer restore()
+
+	dumps := func() []string {
+		files, _ := filepath.Glob(filepath.Join(dir, "dump-*.txt"))
+		return files
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGQUIT); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "goroutine dump", func() bool { return len(dumps()) == 1 })
+
+	data, err := os.ReadFile(dumps()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"SIGQUIT dump", "heap_inuse:", "goroutine ", "TestSignalDump"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("dump lacks %q", want)
+		}
+	}
+
+	// The process survived; a second signal within the minimum interval is ignored
+	if err := syscall.Kill(os.Getpid(), syscall.SIGQUIT); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "ignored signal", func() bool { return logs.find("ignoring SIGQUIT, dumped goroutines recently") != nil })
+	time.Sleep(20 * time.Millisecond)
+	if got := len(dumps()); got != 1 {
+		t.Errorf("got %d dumps, want the second signal rate-limited", got)
+	}
+}
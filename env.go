@@ -2,7 +2,9 @@ package as
 
 import (
 	"context"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 	"unicode"
 
@@ -28,31 +30,249 @@ func EnvPrefix(ctx context.Context) string {
 	return v
 }
 
-// EnvKey returns the normalized environment variable key for the given key, with any prefix set in the context applied.
+// FullEnvKey returns the name of the environment variable read for key by GetEnv, LookupEnv, and LoadEnv: the key
+// normalized with NormalizeEnvKey, with the prefix set in the context applied. Since variable names cannot start
+// with a digit, an underscore is prepended to such names, e.g. "2fa-secret" without a prefix becomes "_2FA_SECRET".
+// This is useful for logging exactly which variable a service reads.
+func FullEnvKey(ctx context.Context, key string) string {
+	return resolveEnvKey(EnvPrefix(ctx), key)
+}
+
+// EnvKey returns the name of the environment variable read for key, see FullEnvKey.
+//
+// Deprecated: Use FullEnvKey.
 func EnvKey(ctx context.Context, key string) string {
-	return NormalizeEnvKey(EnvPrefix(ctx) + key)
+	return FullEnvKey(ctx, key)
+}
+
+// resolveEnvKey returns the name of the environment variable for key under prefix. It is the single resolution
+// rule of all env helpers: the prefix and the key are normalized separately and joined with an underscore, and an
+// underscore is prepended to names starting with a digit. An empty key resolves to the prefix itself.
+func resolveEnvKey(prefix, key string) string {
+	name := NormalizeEnvKey(prefix)
+	if k := NormalizeEnvKey(key); k != "" {
+		if name != "" {
+			name += "_"
+		}
+		name += k
+	}
+
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// legacyEnvKey returns the name the env helpers used for key under prefix before resolveEnvKey, which differs for
+// names starting with a digit. It is still read if the variable is not set under its current name.
+func legacyEnvKey(prefix, key string) string {
+	return NormalizeEnvKey(prefix + key)
+}
+
+// lookupEnvKey looks up the variable for key under prefix, falling back to its legacy name.
+func lookupEnvKey(prefix, key string) (string, bool) {
+	if v, ok := os.LookupEnv(resolveEnvKey(prefix, key)); ok {
+		return v, true
+	}
+
+	return os.LookupEnv(legacyEnvKey(prefix, key))
 }
 
 // GetEnv retrieves the value of the environment variable named by the key, with any prefix set in the context applied.
-// The key is normalized using NormalizeEnvKey before being used to retrieve the value from the environment.
+// The variable name is resolved by FullEnvKey.
+// With EnvPrefixFallback, the less specific variables are tried as by LookupEnv.
 func GetEnv(ctx context.Context, key string) string {
-	return os.Getenv(EnvKey(ctx, key))
+	v, _ := LookupEnv(ctx, key)
+	return v
 }
 
 // LookupEnv retrieves the value of the environment variable named by the key, with any prefix in the context applied.
-// The key is normalized using NormalizeEnvKey before lookup. It returns the value and a boolean indicating whether the variable was present.
+// The variable name is resolved by FullEnvKey. It returns the value and a boolean indicating whether the variable was present.
+// With EnvPrefixFallback, the key is looked up with the namespace-only prefix, then without a prefix, if it is not set
+// under the prefix of the service.
 func LookupEnv(ctx context.Context, key string) (string, bool) {
-	v, ok := os.LookupEnv(EnvKey(ctx, key))
-	return v, ok
+	if v, ok := lookupEnvKey(EnvPrefix(ctx), key); ok {
+		return v, true
+	}
+
+	for _, prefix := range envFallbackPrefixesFrom(ctx) {
+		if v, ok := lookupEnvKey(prefix, key); ok {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// Environ returns the environment variables read by GetEnv for the keys set under the prefix set in the context,
+// in the "key=value" form of os.Environ, sorted by name. With EnvPrefixFallback, variables under the namespace-only
+// prefix are included for keys not set under the prefix of the service; variables without any prefix are not.
+// Variables whose names are not resolved by FullEnvKey, e.g. with lowercase letters, are omitted, since GetEnv
+// does not read them. Without a prefix, Environ returns nil.
+func Environ(ctx context.Context) []string {
+	var environ []string
+	for _, v := range prefixedEnvVars(ctx) {
+		environ = append(environ, v.name+"="+v.value)
+	}
+
+	return environ
+}
+
+// EnvironWithoutPrefix returns the environment variables like Environ, with the keys used with GetEnv instead of
+// the full names. This is useful for passing the configuration of the service to processes not using the prefix.
+func EnvironWithoutPrefix(ctx context.Context) []string {
+	var environ []string
+	for _, v := range prefixedEnvVars(ctx) {
+		environ = append(environ, v.key+"="+v.value)
+	}
+
+	return environ
+}
+
+// prefixedEnvVar is an environment variable read by GetEnv, see prefixedEnvVars.
+type prefixedEnvVar struct {
+	// name is the full name of the variable and key the key it is read for.
+	name, key string
+	// value is the value of the variable.
+	value string
+}
+
+// prefixedEnvVars returns the environment variables read by GetEnv for the keys set under the prefix set in the
+// context, then under the non-empty fallback prefixes, sorted by name. Each key and each variable is returned once,
+// for the first prefix it is found under, as GetEnv resolves it. It returns nil if no prefix is set.
+func prefixedEnvVars(ctx context.Context) []prefixedEnvVar {
+	prefix := EnvPrefix(ctx)
+	if NormalizeEnvKey(prefix) == "" {
+		return nil
+	}
+
+	prefixes := []string{prefix}
+	for _, fallback := range envFallbackPrefixesFrom(ctx) {
+		if NormalizeEnvKey(fallback) != "" {
+			prefixes = append(prefixes, fallback)
+		}
+	}
+
+	environ := env.ToMap(os.Environ())
+	names := slices.Sorted(maps.Keys(environ))
+	keys, read := make(map[string]bool), make(map[string]bool)
+
+	var vars []prefixedEnvVar
+	for _, prefix := range prefixes {
+		for _, name := range names {
+			key, ok := strings.CutPrefix(name, resolveEnvKey(prefix, "")+"_")
+			if !ok || keys[key] || read[name] || resolveEnvKey(prefix, key) != name {
+				continue
+			}
+
+			keys[key], read[name] = true, true
+			vars = append(vars, prefixedEnvVar{name: name, key: key, value: environ[name]})
+		}
+	}
+
+	slices.SortFunc(vars, func(a, b prefixedEnvVar) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	return vars
+}
+
+// EnvEntry is an environment variable under the prefix of a service, see PrefixedEnviron.
+type EnvEntry struct {
+	// Name is the full name of the variable, including the prefix.
+	Name string
+	// Key is the name of the variable without the prefix, as used with GetEnv.
+	Key string
+	// Value is the value of the variable, or "[REDACTED]" if the variable looks like a secret.
+	Value string
+	// Redacted reports whether the value was redacted.
+	Redacted bool
+}
+
+// redactedValue replaces the values of secret environment variables.
+const redactedValue = "[REDACTED]"
+
+// secretEnvKeyParts are the parts of environment variable keys marking their values as secrets.
+var secretEnvKeyParts = []string{"SECRET", "PASSWORD", "PASSWD", "TOKEN", "CREDENTIAL", "PRIVATE", "API_KEY", "AUTH"}
+
+// PrefixedEnviron returns the environment variables listed by Environ, sorted by name. Values of variables whose
+// keys look like secrets (e.g. containing PASSWORD, TOKEN or SECRET) are redacted, so the result can be included in
+// support bundles or logs.
+func PrefixedEnviron(ctx context.Context) []EnvEntry {
+	var entries []EnvEntry
+	for _, v := range prefixedEnvVars(ctx) {
+		entry := EnvEntry{
+			Name:  v.name,
+			Key:   v.key,
+			Value: v.value,
+		}
+		if isSecretEnvKey(entry.Key) {
+			entry.Value = redactedValue
+			entry.Redacted = true
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// isSecretEnvKey reports whether the value of the environment variable key is likely a secret.
+func isSecretEnvKey(key string) bool {
+	key = NormalizeEnvKey(key)
+	for _, part := range secretEnvKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AppendEnv appends the variables in kv to base in the "key=value" form of os.Environ, with the prefix set in the
+// context applied and the keys resolved by FullEnvKey. Variables are appended in key order; base is not modified.
+// This is useful for passing configuration to child processes using the same conventions, e.g.
+//
+//	cmd.Env = as.AppendEnv(ctx, os.Environ(), map[string]string{"ROLE": "worker"})
+//
+// sets MYAPP_ROLE=worker for a service with the prefix MYAPP_.
+func AppendEnv(ctx context.Context, base []string, kv map[string]string) []string {
+	environ := slices.Clip(base)
+	for _, key := range slices.Sorted(maps.Keys(kv)) {
+		environ = append(environ, FullEnvKey(ctx, key)+"="+kv[key])
+	}
+
+	return environ
 }
 
 // LoadEnv parses environment variables into a struct of type T, applying any prefix set in the context.
-// It relies on the github.com/caarlos0/env/v11 library for parsing.
-// Note: This does **NOT** normalize the environment variable keys using NormalizeEnvKey. The env keys from the tags are used as is.
+// It relies on the github.com/caarlos0/env/v11 library for parsing. The variable names are resolved by FullEnvKey
+// from the env tags (including the envPrefix tags of nested structs), so e.g. `env:"db-url"` reads MYAPP_DB_URL.
+//
+// Variables set under the raw tag, as read before the names were resolved, are still read if the resolved
+// variable is not set.
 func LoadEnv[T any](ctx context.Context) (T, error) {
-	return env.ParseAsWithOptions[T](env.Options{
-		Prefix: EnvPrefix(ctx),
-	})
+	prefix := EnvPrefix(ctx)
+	opts := env.Options{Prefix: prefix}
+
+	var zero T
+	params, err := env.GetFieldParamsWithOptions(&zero, opts)
+	if err != nil {
+		return zero, err
+	}
+
+	// The library looks up the raw keys, so the values of the resolved names are provided under them
+	environ := env.ToMap(os.Environ())
+	for _, param := range params {
+		key := strings.TrimPrefix(param.Key, prefix)
+		if v, ok := lookupEnvKey(prefix, key); ok {
+			environ[param.Key] = v
+		}
+	}
+	opts.Environment = environ
+
+	return env.ParseAsWithOptions[T](opts)
 }
 
 // NormalizeEnvKey normalizes a string for use as an environment variable key.
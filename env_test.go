@@ -0,0 +1,213 @@
+package as
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testEnvContext returns a context with the env prefix ASTEST_TEST_ and sets the variables for the test.
+func testEnvContext(t *testing.T, env map[string]string) context.Context {
+	t.Helper()
+
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	return withEnvPrefix(context.Background(), "ASTEST_TEST_")
+}
+
+func TestEnviron(t *testing.T) {
+	ctx := testEnvContext(t, map[string]string{
+		"ASTEST_TEST_ROLE":  "worker",
+		"ASTEST_TEST_PORT":  "8080",
+		"ASTEST_OTHER_PORT": "9090",
+		"ROLE":              "unprefixed",
+	})
+
+	got := Environ(ctx)
+	slices.Sort(got)
+	if want := []string{"ASTEST_TEST_PORT=8080", "ASTEST_TEST_ROLE=worker"}; !slices.Equal(got, want) {
+		t.Errorf("Environ() = %v, want %v", got, want)
+	}
+
+	got = EnvironWithoutPrefix(ctx)
+	slices.Sort(got)
+	if want := []string{"PORT=8080", "ROLE=worker"}; !slices.Equal(got, want) {
+		t.Errorf("EnvironWithoutPrefix() = %v, want %v", got, want)
+	}
+}
+
+func TestEnvironResolution(t *testing.T) {
+	ctx := testEnvContext(t, map[string]string{
+		"ASTEST_TEST_ROLE":   "worker",
+		"ASTEST_TEST_db_url": "not read",
+		"ASTEST_ROLE":        "namespace",
+		"ASTEST_REGION":      "eu-west-1",
+		"REGION":             "unprefixed",
+	})
+
+	// Only the variables read by GetEnv are listed
+	if got, want := EnvironWithoutPrefix(ctx), []string{"ROLE=worker"}; !slices.Equal(got, want) {
+		t.Errorf("EnvironWithoutPrefix() = %v, want %v", got, want)
+	}
+
+	// The namespace-only fallback prefix applies to keys not set under the prefix of the service
+	ctx = withEnvFallbackPrefixes(ctx, []string{"ASTEST_", ""})
+	if got, want := Environ(ctx), []string{"ASTEST_REGION=eu-west-1", "ASTEST_TEST_ROLE=worker"}; !slices.Equal(got, want) {
+		t.Errorf("Environ() with fallbacks = %v, want %v", got, want)
+	}
+	for _, kv := range EnvironWithoutPrefix(ctx) {
+		key, value, _ := strings.Cut(kv, "=")
+		if got := GetEnv(ctx, key); got != value {
+			t.Errorf("GetEnv(%s) = %q, want %q", key, got, value)
+		}
+	}
+
+	// Without a prefix, no variables are listed instead of the whole environment
+	ctx = withEnvPrefix(context.Background(), "")
+	if got := Environ(ctx); got != nil {
+		t.Errorf("Environ() without prefix = %v, want none", got)
+	}
+	if got := PrefixedEnviron(ctx); got != nil {
+		t.Errorf("PrefixedEnviron() without prefix = %v, want none", got)
+	}
+}
+
+func TestAppendEnv(t *testing.T) {
+	ctx := testEnvContext(t, nil)
+
+	base := []string{"PATH=/usr/bin"}
+	environ := AppendEnv(ctx, base, map[string]string{"role": "worker", "db-url": "postgres://db"})
+
+	want := []string{"PATH=/usr/bin", "ASTEST_TEST_DB_URL=postgres://db", "ASTEST_TEST_ROLE=worker"}
+	if !slices.Equal(environ, want) {
+		t.Errorf("AppendEnv() = %v, want %v", environ, want)
+	}
+	if len(base) != 1 {
+		t.Errorf("base modified: %v", base)
+	}
+
+	// A child process with the same prefix reads the values with GetEnv
+	for _, kv := range environ[len(base):] {
+		key, value, _ := strings.Cut(kv, "=")
+		t.Setenv(key, value)
+	}
+	if got := GetEnv(ctx, "ROLE"); got != "worker" {
+		t.Errorf("GetEnv(ROLE) = %q, want worker", got)
+	}
+	if got := GetEnv(ctx, "db-url"); got != "postgres://db" {
+		t.Errorf("GetEnv(db-url) = %q, want postgres://db", got)
+	}
+}
+
+func TestPrefixedEnviron(t *testing.T) {
+	ctx := testEnvContext(t, map[string]string{
+		"ASTEST_TEST_REGION":      "eu-west-1",
+		"ASTEST_TEST_DB_PASSWORD": "hunter2",
+		"ASTEST_TEST_API_TOKEN":   "abc",
+		"ASTEST_OTHER_REGION":     "us-east-1",
+		"REGION":                  "unprefixed",
+	})
+
+	want := []EnvEntry{
+		{Name: "ASTEST_TEST_API_TOKEN", Key: "API_TOKEN", Value: redactedValue, Redacted: true},
+		{Name: "ASTEST_TEST_DB_PASSWORD", Key: "DB_PASSWORD", Value: redactedValue, Redacted: true},
+		{Name: "ASTEST_TEST_REGION", Key: "REGION", Value: "eu-west-1"},
+	}
+	if got := PrefixedEnviron(ctx); !slices.Equal(got, want) {
+		t.Errorf("PrefixedEnviron() = %+v, want %+v", got, want)
+	}
+
+	// The keys read the same variables as GetEnv
+	for _, entry := range PrefixedEnviron(ctx) {
+		if name := FullEnvKey(ctx, entry.Key); name != entry.Name {
+			t.Errorf("FullEnvKey(%s) = %s, want %s", entry.Key, name, entry.Name)
+		}
+	}
+}
+
+func TestFullEnvKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		key    string
+		want   string
+	}{
+		{name: "plain", prefix: "ASTEST_TEST_", key: "PORT", want: "ASTEST_TEST_PORT"},
+		{name: "dashes", prefix: "ASTEST_TEST_", key: "db-url", want: "ASTEST_TEST_DB_URL"},
+		{name: "repeated separators", prefix: "ASTEST_TEST_", key: "--db..url--", want: "ASTEST_TEST_DB_URL"},
+		{name: "unicode", prefix: "ASTEST_TEST_", key: "café-größe", want: "ASTEST_TEST_CAFE_GRO_E"},
+		{name: "unnormalized prefix", prefix: "my-app.", key: "port", want: "MY_APP_PORT"},
+		{name: "leading digit", prefix: "ASTEST_TEST_", key: "2fa-secret", want: "ASTEST_TEST_2FA_SECRET"},
+		{name: "leading digit without prefix", key: "2fa-secret", want: "_2FA_SECRET"},
+		{name: "leading digit prefix", prefix: "1app_", key: "port", want: "_1APP_PORT"},
+		{name: "empty prefix", key: "db-url", want: "DB_URL"},
+		{name: "empty key", prefix: "ASTEST_TEST_", want: "ASTEST_TEST"},
+		{name: "empty", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := withEnvPrefix(context.Background(), tt.prefix)
+			if got := FullEnvKey(ctx, tt.key); got != tt.want {
+				t.Errorf("FullEnvKey(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvKeyResolution(t *testing.T) {
+	ctx := testEnvContext(t, map[string]string{"ASTEST_TEST_DB_URL": "postgres://db"})
+
+	// All helpers read the variable named by FullEnvKey
+	if got := GetEnv(ctx, "db-url"); got != "postgres://db" {
+		t.Errorf("GetEnv() = %q", got)
+	}
+	if got, ok := LookupEnv(ctx, "db-url"); !ok || got != "postgres://db" {
+		t.Errorf("LookupEnv() = %q, %t", got, ok)
+	}
+	cfg, err := LoadEnv[struct {
+		URL string `env:"db-url"`
+	}](ctx)
+	if err != nil || cfg.URL != "postgres://db" {
+		t.Errorf("LoadEnv() = %+v, %v", cfg, err)
+	}
+
+	// So does the supervisor, for its options and the context of the service
+	t.Setenv(FullEnvKey(ctx, "grace-count"), "7")
+	if got := applyOptions("test", "astest", nil).GraceCount; got != 7 {
+		t.Errorf("GraceCount = %d, want 7", got)
+	}
+
+	var key string
+	svc := &testService{run: func(ctx context.Context) error {
+		key = FullEnvKey(ctx, "db-url")
+		return nil
+	}}
+	if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if key != "ASTEST_TEST_DB_URL" {
+		t.Errorf("FullEnvKey() in the service = %q", key)
+	}
+}
+
+func TestLegacyEnvKey(t *testing.T) {
+	// Variables set under the names read before keys starting with a digit got an underscore are still read
+	t.Setenv("2FA_SECRET", "legacy")
+	ctx := context.Background()
+
+	if got := GetEnv(ctx, "2fa-secret"); got != "legacy" {
+		t.Errorf("GetEnv() = %q, want the legacy variable", got)
+	}
+
+	t.Setenv("_2FA_SECRET", "current")
+	if got := GetEnv(ctx, "2fa-secret"); got != "current" {
+		t.Errorf("GetEnv() = %q, want the current variable to take precedence", got)
+	}
+	if got := EnvKey(ctx, "2fa-secret"); got != FullEnvKey(ctx, "2fa-secret") {
+		t.Errorf("EnvKey() = %q, want the FullEnvKey", got)
+	}
+}
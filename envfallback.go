@@ -0,0 +1,95 @@
+package as
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/caarlos0/env/v11"
+)
+
+// envFallbackPrefixesKey is an unexported type used as a key for storing the fallback env prefixes in a context.
+type envFallbackPrefixesKey struct{}
+
+// withEnvFallbackPrefixes returns a new context.Context derived from ctx that contains the env prefixes tried by
+// GetEnv and LookupEnv after the prefix of the service, see EnvPrefixFallback.
+func withEnvFallbackPrefixes(ctx context.Context, prefixes []string) context.Context {
+	return context.WithValue(ctx, envFallbackPrefixesKey{}, prefixes)
+}
+
+// envFallbackPrefixesFrom returns the fallback env prefixes stored in ctx, or nil if there are none.
+func envFallbackPrefixesFrom(ctx context.Context) []string {
+	prefixes, _ := ctx.Value(envFallbackPrefixesKey{}).([]string)
+	return prefixes
+}
+
+// envFallbackPrefixes returns the prefixes tried after the prefix of the service if EnvPrefixFallback is set:
+// the namespace-only prefix, then no prefix at all. It returns nil if fallbacks are disabled.
+func envFallbackPrefixes(opts Options, namespace string) []string {
+	if !opts.EnvPrefixFallback || opts.DisableEnvPrefix || opts.EnvPrefix == "" {
+		return nil
+	}
+
+	var prefixes []string
+	if namespace != "" {
+		if nsPrefix := NormalizeEnvKey(namespace) + "_"; nsPrefix != opts.EnvPrefix {
+			prefixes = append(prefixes, nsPrefix)
+		}
+	}
+
+	return append(prefixes, "")
+}
+
+// envFallback records an option field whose value was taken from a less specific env var.
+type envFallback struct {
+	// field is the env key of the field, without any prefix.
+	field string
+	// name is the env var the value was taken from.
+	name string
+	// order is the resolution order of the env vars tried.
+	order []string
+}
+
+// fallbackEnvironment returns the environment of the process, extended so that each env field of v missing under
+// prefix is set to the value of the first env var found under one of the fallback prefixes. The applied fallbacks
+// are returned as well.
+func fallbackEnvironment(v any, prefix string, fallbacks []string) (map[string]string, []envFallback) {
+	environ := env.ToMap(os.Environ())
+
+	params, err := env.GetFieldParams(v)
+	if err != nil {
+		return environ, nil
+	}
+
+	var applied []envFallback
+	for _, param := range params {
+		if _, ok := environ[prefix+param.OwnKey]; ok {
+			continue
+		}
+
+		order := []string{prefix + param.OwnKey}
+		for _, fallback := range fallbacks {
+			name := fallback + param.OwnKey
+			order = append(order, name)
+
+			if value, ok := environ[name]; ok {
+				environ[prefix+param.OwnKey] = value
+				applied = append(applied, envFallback{field: param.OwnKey, name: name, order: order})
+				break
+			}
+		}
+	}
+
+	return environ, applied
+}
+
+// logEnvFallbacks logs the option fields set from a fallback env var at debug level.
+func logEnvFallbacks(ctx context.Context, fallbacks []envFallback) {
+	for _, fallback := range fallbacks {
+		Logger(ctx).DebugContext(ctx, "option set from fallback env var",
+			"field", fallback.field,
+			"env", fallback.name,
+			"order", strings.Join(fallback.order, ", "),
+		)
+	}
+}
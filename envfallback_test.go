@@ -0,0 +1,134 @@
+package as
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEnvPrefixFallbackOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		fallback bool
+		want     int
+		wantEnv  string
+	}{
+		{
+			name:     "unprefixed",
+			env:      map[string]string{"GRACE_COUNT": "1"},
+			fallback: true,
+			want:     1,
+			wantEnv:  "GRACE_COUNT",
+		},
+		{
+			name:     "namespace",
+			env:      map[string]string{"GRACE_COUNT": "1", "ASTEST_GRACE_COUNT": "2"},
+			fallback: true,
+			want:     2,
+			wantEnv:  "ASTEST_GRACE_COUNT",
+		},
+		{
+			name:     "service",
+			env:      map[string]string{"GRACE_COUNT": "1", "ASTEST_GRACE_COUNT": "2", "ASTEST_TEST_GRACE_COUNT": "3"},
+			fallback: true,
+			want:     3,
+		},
+		{
+			name: "disabled",
+			env:  map[string]string{"GRACE_COUNT": "1", "ASTEST_GRACE_COUNT": "2"},
+			want: DefaultOptions().GraceCount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			o, fallbacks, _, err := loadOptions("test", "astest", []Option{WithEnvPrefixFallback(tt.fallback)})
+			if err != nil {
+				t.Fatalf("loadOptions() = %v", err)
+			}
+			if o.GraceCount != tt.want {
+				t.Errorf("GraceCount = %d, want %d", o.GraceCount, tt.want)
+			}
+
+			if tt.wantEnv == "" {
+				if len(fallbacks) != 0 {
+					t.Errorf("fallbacks = %+v, want none", fallbacks)
+				}
+				return
+			}
+			if len(fallbacks) != 1 || fallbacks[0].field != "GRACE_COUNT" || fallbacks[0].name != tt.wantEnv {
+				t.Fatalf("fallbacks = %+v, want GRACE_COUNT from %s", fallbacks, tt.wantEnv)
+			}
+		})
+	}
+}
+
+func TestEnvPrefixFallbackMerge(t *testing.T) {
+	t.Setenv("ASTEST_TEST_GRACE_COUNT", "3")
+	t.Setenv("ASTEST_RESTART_ON_ERROR_DELAY", "2s")
+	t.Setenv("GRACE_COUNT", "1")
+
+	// Each field is resolved on its own
+	o, fallbacks, _, err := loadOptions("test", "astest", []Option{WithEnvPrefixFallback(true)})
+	if err != nil {
+		t.Fatalf("loadOptions() = %v", err)
+	}
+	if o.GraceCount != 3 || o.RestartOnErrorDelay != 2*time.Second {
+		t.Errorf("GraceCount = %d, RestartOnErrorDelay = %s", o.GraceCount, o.RestartOnErrorDelay)
+	}
+
+	if len(fallbacks) != 1 {
+		t.Fatalf("fallbacks = %+v, want one", fallbacks)
+	}
+	got := fallbacks[0]
+	if got.field != "RESTART_ON_ERROR_DELAY" || len(got.order) != 2 ||
+		got.order[0] != "ASTEST_TEST_RESTART_ON_ERROR_DELAY" || got.order[1] != "ASTEST_RESTART_ON_ERROR_DELAY" {
+		t.Errorf("fallback = %+v", got)
+	}
+}
+
+func TestEnvPrefixFallbackLookupEnv(t *testing.T) {
+	ctx := testEnvContext(t, map[string]string{
+		"ASTEST_TEST_REGION": "eu-west",
+		"REGION":             "global",
+		"ASTEST_TIER":        "gold",
+		"TIER":               "silver",
+		"COLOR":              "blue",
+	})
+
+	if _, ok := LookupEnv(ctx, "tier"); ok {
+		t.Error("LookupEnv() fell back without EnvPrefixFallback")
+	}
+
+	ctx = withEnvFallbackPrefixes(ctx, envFallbackPrefixes(Options{EnvPrefix: "ASTEST_TEST_", EnvPrefixFallback: true}, "astest"))
+	for key, want := range map[string]string{"region": "eu-west", "tier": "gold", "color": "blue"} {
+		if got := GetEnv(ctx, key); got != want {
+			t.Errorf("GetEnv(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if _, ok := LookupEnv(ctx, "missing"); ok {
+		t.Error("LookupEnv() of a missing key = true")
+	}
+}
+
+func TestEnvPrefixFallbackLogged(t *testing.T) {
+	t.Setenv("ASTEST_GRACE_COUNT", "2")
+
+	svc := &testService{run: func(ctx context.Context) error { return nil }}
+	logs := &logCapture{}
+	opts := testOptions(captureLogs(svc, logs), WithEnvPrefixFallback(true), WithLogDebug(true))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	record := logs.find("option set from fallback env var")
+	if record == nil || record["level"] != "DEBUG" || record["field"] != "GRACE_COUNT" ||
+		record["order"] != "ASTEST_TEST_GRACE_COUNT, ASTEST_GRACE_COUNT" {
+		t.Errorf("fallback logged as %v", record)
+	}
+}
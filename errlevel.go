@@ -0,0 +1,24 @@
+package as
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// LogLeveler is implemented by errors indicating the level they are logged with by the supervisor, e.g. to log an
+// expected reconnect at Warn instead of Error level.
+type LogLeveler interface {
+	// LogLevel returns the level to log the error with.
+	LogLevel() slog.Level
+}
+
+// errorLogLevel returns the level to log err with: the level of the first error in its tree implementing
+// LogLeveler, or slog.LevelError.
+func errorLogLevel(err error) slog.Level {
+	var leveler LogLeveler
+	if errors.As(err, &leveler) {
+		return leveler.LogLevel()
+	}
+
+	return slog.LevelError
+}
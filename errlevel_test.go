@@ -0,0 +1,92 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+// leveledError is an error logged at the given level.
+type leveledError struct {
+	level slog.Level
+}
+
+func (e *leveledError) Error() string { return "reconnecting" }
+
+func (e *leveledError) LogLevel() slog.Level { return e.level }
+
+func TestErrorLogLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want slog.Level
+	}{
+		{name: "plain error", err: errors.New("failed"), want: slog.LevelError},
+		{name: "leveled error", err: &leveledError{level: slog.LevelWarn}, want: slog.LevelWarn},
+		{name: "wrapped leveled error", err: fmt.Errorf("run: %w", &leveledError{level: slog.LevelInfo}), want: slog.LevelInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorLogLevel(tt.err); got != tt.want {
+				t.Errorf("errorLogLevel() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorLogLevelRecords(t *testing.T) {
+	tests := []struct {
+		name      string
+		runErr    error
+		closeErr  error
+		msg       string
+		wantLevel string
+	}{
+		{
+			name:      "restart",
+			runErr:    &leveledError{level: slog.LevelWarn},
+			msg:       "service failed, restarting immediately",
+			wantLevel: "WARN",
+		},
+		{
+			name:      "restart default",
+			runErr:    errors.New("failed"),
+			msg:       "service failed, restarting immediately",
+			wantLevel: "ERROR",
+		},
+		{
+			name:      "shutdown failure",
+			closeErr:  &leveledError{level: slog.LevelInfo},
+			msg:       "service shutdown failed",
+			wantLevel: "INFO",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempt := 0
+			svc := &testService{
+				run: func(ctx context.Context) error {
+					if attempt++; attempt == 1 {
+						return tt.runErr
+					}
+					return nil
+				},
+				close: func(ctx context.Context) error { return tt.closeErr },
+			}
+
+			logs := &logCapture{}
+			if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs))...); err != nil {
+				t.Fatalf("RunC() = %v", err)
+			}
+
+			record := logs.find(tt.msg)
+			if record == nil || record["level"] != tt.wantLevel {
+				t.Errorf("record %q = %v, want level %s", tt.msg, record, tt.wantLevel)
+			}
+		})
+	}
+}
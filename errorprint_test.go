@@ -0,0 +1,147 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.aledante.io/ae"
+)
+
+func TestErrorFrameFilter(t *testing.T) {
+	opts := Options{ErrorPrintFrameFilters: []func(*ae.StackFrame) bool{
+		func(frame *ae.StackFrame) bool { return !strings.HasPrefix(frame.Func, "golang.org/x/sync/errgroup.") },
+		func(frame *ae.StackFrame) bool { return !strings.HasPrefix(frame.Func, "runtime.") },
+	}}
+	filter := errorFrameFilter(opts)
+
+	tests := []struct {
+		fn   string
+		want bool
+	}{
+		{fn: "main.main", want: true},
+		{fn: "example.com/svc.(*Service).Run", want: true},
+		{fn: "go.aledante.io/as.runOnce"},
+		{fn: "golang.org/x/sync/errgroup.(*Group).Go.func1"},
+		{fn: "runtime.goexit"},
+	}
+
+	for _, tt := range tests {
+		if got := filter(&ae.StackFrame{Func: tt.fn}); got != tt.want {
+			t.Errorf("filter(%s) = %t, want %t", tt.fn, got, tt.want)
+		}
+	}
+
+	if !errorFrameFilter(Options{})(&ae.StackFrame{Func: "runtime.goexit"}) {
+		t.Error("default filter hides frames outside of this package")
+	}
+}
+
+func TestErrorPrintFullStacksEnv(t *testing.T) {
+	t.Setenv("ASTEST_TEST_ERROR_PRINT_FULL_STACKS", "true")
+
+	if o := applyOptions("test", "astest", nil); !o.ErrorPrintFullStacks {
+		t.Error("ErrorPrintFullStacks not set from the environment")
+	}
+}
+
+// captureStreams redirects os.Stdout and os.Stderr to files for the test, and returns a function returning what
+// was written to them so far.
+func captureStreams(t *testing.T) func() (stdout, stderr string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := make([]*os.File, 2)
+	for i, name := range []string{"stdout", "stderr"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = f.Close() })
+		files[i] = f
+	}
+
+	prevStdout, prevStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = files[0], files[1]
+	t.Cleanup(func() { os.Stdout, os.Stderr = prevStdout, prevStderr })
+
+	return func() (string, string) {
+		stdout, err := os.ReadFile(files[0].Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderr, err := os.ReadFile(files[1].Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(stdout), string(stderr)
+	}
+}
+
+func TestTerminalErrorStreams(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantRecord  bool
+		wantColored bool
+	}{
+		{name: "json", opts: []Option{WithLogJson(true)}, wantRecord: true},
+		{name: "logfmt", opts: []Option{WithLogFormat(LogFormatLogfmt)}, wantRecord: true},
+		{name: "colors", opts: []Option{WithLogJson(false), WithLogColors(true)}, wantColored: true},
+		{name: "text", opts: []Option{WithLogJson(false), WithLogColors(false)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			streams := captureStreams(t)
+
+			svc := &testService{run: func(ctx context.Context) error { return errors.New("database unavailable") }}
+			if code := runAndExitTest(t, svc, tt.opts...); code != ExitFailure {
+				t.Fatalf("exit code = %d, want %d", code, ExitFailure)
+			}
+			stdout, stderr := streams()
+
+			// The terminal error is logged as the last record on stdout only if the logs are structured
+			lines := strings.Split(strings.TrimSpace(stdout), "\n")
+			last := lines[len(lines)-1]
+			if logged := strings.Contains(last, "service terminated"); logged != tt.wantRecord {
+				t.Errorf("last stdout line = %q, want terminal error record %t", last, tt.wantRecord)
+			}
+			if tt.wantRecord && (!strings.Contains(last, "database unavailable") || !strings.Contains(last, "exit_code")) {
+				t.Errorf("terminal error record = %q, want the error and exit code", last)
+			}
+
+			// The printed error never corrupts stdout, and is only colored if colors are enabled
+			if strings.Contains(stdout, "service failed:") {
+				t.Error("error printed to stdout")
+			}
+			if colored := strings.Contains(stderr, ansiBoldRed+"✗ service failed:"+ansiReset+" "); colored != tt.wantColored {
+				t.Errorf("colored header = %t, want %t; stderr:\n%s", colored, tt.wantColored, stderr)
+			}
+		})
+	}
+}
+
+func TestColorsEnabled(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if !colorsEnabled(Options{LogColors: true}, f) {
+		t.Error("colorsEnabled() = false with LogColors")
+	}
+	if colorsEnabled(Options{LogAutoColors: true}, f) {
+		t.Error("colorsEnabled() = true for a file which is not a terminal")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if colorsEnabled(Options{LogDebug: true}, f) {
+		t.Error("colorsEnabled() = true with NO_COLOR")
+	}
+}
@@ -0,0 +1,114 @@
+package as
+
+import (
+	"errors"
+	"os"
+)
+
+// Exit codes used by RunAndExit and RunAndExitC, depending on the class of the error the service exited with:
+//
+//	 0  clean exit, including shutdown signals and quiet errors
+//	 1  any other error
+//	10  invalid configuration, e.g. an invalid service name (ErrInvalidConfig)
+//	11  internal initialization failure, e.g. of OTEL (ErrInitInternal)
+//	12  restart limits exhausted (ErrRestartBudgetExhausted)
+//	13  shutdown timed out (ErrShutdownTimeout)
+//
+// Errors implementing ExitCoder override the class, and a function set with WithExitCodeFunc overrides both.
+const (
+	ExitOK              = 0
+	ExitFailure         = 1
+	ExitInvalidConfig   = 10
+	ExitInitInternal    = 11
+	ExitRestartBudget   = 12
+	ExitShutdownTimeout = 13
+)
+
+var (
+	// ErrInvalidConfig classifies errors caused by the configuration of the service, e.g. its options, environment,
+	// or identity.
+	ErrInvalidConfig = errors.New("invalid configuration")
+	// ErrInitInternal classifies failures of the initialization performed by the supervisor, e.g. of OTEL, the
+	// instance lock, or shared values.
+	ErrInitInternal = errors.New("internal initialization failure")
+	// ErrRestartBudgetExhausted classifies errors of services the supervisor gave up restarting, e.g. because the
+	// grace limits, the lifetime restart limit, or a shared RestartBudget were exceeded.
+	ErrRestartBudgetExhausted = errors.New("restart budget exhausted")
+	// ErrShutdownTimeout classifies errors of shutdowns exceeding ShutdownTimeout.
+	ErrShutdownTimeout = errors.New("shutdown timed out")
+	// ErrForcedShutdown classifies the error returned by RunC if the shutdown was forced by repeated shutdown
+	// signals, see ForceExitSignals. RunAndExit exits the process with ForceExitCode instead.
+	ErrForcedShutdown = errors.New("shutdown forced")
+)
+
+// ExitCoder is an optional interface errors can implement to select the exit code of RunAndExit.
+type ExitCoder interface {
+	// ExitCode returns the exit code of the process.
+	ExitCode() int
+}
+
+// classifiedError attaches one of the class sentinels (e.g. ErrInitInternal) to an error, without changing its
+// message. Both the error and the class are matched by errors.Is and errors.As.
+type classifiedError struct {
+	class error
+	err   error
+}
+
+// classify attaches class to err. It returns nil if err is nil.
+func classify(class, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &classifiedError{class: class, err: err}
+}
+
+// Error returns the message of the classified error.
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the classified error and the class.
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.err, e.class}
+}
+
+// exitCode returns the exit code for err, see ExitOK.
+func exitCode(err error, opts Options) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	if opts.ExitCodeFunc != nil {
+		if code, ok := opts.ExitCodeFunc(err); ok {
+			return code
+		}
+	}
+
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+
+	switch {
+	case errors.Is(err, ErrInvalidConfig):
+		return ExitInvalidConfig
+	case errors.Is(err, ErrInitInternal):
+		return ExitInitInternal
+	case errors.Is(err, ErrRestartBudgetExhausted):
+		return ExitRestartBudget
+	case errors.Is(err, ErrShutdownTimeout):
+		return ExitShutdownTimeout
+	default:
+		return ExitFailure
+	}
+}
+
+// exitFunc returns the function exiting the process configured by opts, os.Exit by default.
+func exitFunc(opts Options) func(code int) {
+	if opts.ExitFunc != nil {
+		return opts.ExitFunc
+	}
+
+	return os.Exit
+}
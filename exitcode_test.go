@@ -0,0 +1,132 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// exitCodeError is an error selecting its own exit code.
+type exitCodeError struct{ code int }
+
+func (e exitCodeError) Error() string { return "exit code error" }
+
+func (e exitCodeError) ExitCode() int { return e.code }
+
+func TestExitCodes(t *testing.T) {
+	errFailed := errors.New("failed")
+	failing := func(ctx context.Context) error { return errFailed }
+
+	// A file cannot be used as the directory of the PID file
+	notDir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notDir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	tests := []struct {
+		name string
+		svc  *testService
+		env  map[string]string
+		opts []Option
+		want int
+	}{
+		{
+			name: "clean",
+			svc:  &testService{run: func(ctx context.Context) error { return nil }},
+			want: -1,
+		},
+		{
+			name: "other",
+			svc:  &testService{run: failing},
+			opts: []Option{WithRestartOnError(false)},
+			want: ExitFailure,
+		},
+		{
+			name: "invalid option",
+			svc:  &testService{run: failing},
+			opts: []Option{WithGraceCount(-1)},
+			want: ExitInvalidConfig,
+		},
+		{
+			name: "invalid env var",
+			svc:  &testService{run: failing},
+			env:  map[string]string{"ASTEST_TEST_GRACE_COUNT": "many"},
+			want: ExitInvalidConfig,
+		},
+		{
+			name: "invalid service name",
+			svc:  &testService{name: "Test Service", run: failing},
+			opts: []Option{WithIdentityMode(IdentityStrict)},
+			want: ExitInvalidConfig,
+		},
+		{
+			name: "init internal",
+			svc:  &testService{run: failing},
+			opts: []Option{WithPIDFile(filepath.Join(notDir, "test.pid"))},
+			want: ExitInitInternal,
+		},
+		{
+			name: "restart budget",
+			svc:  &testService{run: failing},
+			opts: []Option{WithGraceCount(1)},
+			want: ExitRestartBudget,
+		},
+		{
+			name: "shutdown timeout",
+			svc: &testService{
+				run: func(ctx context.Context) error { return nil },
+				close: func(ctx context.Context) error {
+					<-release
+					return nil
+				},
+			},
+			opts: []Option{WithShutdownTimeout(10 * time.Millisecond)},
+			want: ExitShutdownTimeout,
+		},
+		{
+			name: "exit coder",
+			svc:  &testService{run: func(ctx context.Context) error { return exitCodeError{code: 42} }},
+			opts: []Option{WithRestartOnError(false)},
+			want: 42,
+		},
+		{
+			name: "exit code func",
+			svc:  &testService{run: func(ctx context.Context) error { return exitCodeError{code: 42} }},
+			opts: []Option{
+				WithRestartOnError(false),
+				WithExitCodeFunc(func(err error) (int, bool) { return 43, true }),
+			},
+			want: 43,
+		},
+		{
+			name: "exit code func declined",
+			svc:  &testService{run: failing},
+			opts: []Option{
+				WithGraceCount(1),
+				WithExitCodeFunc(func(err error) (int, bool) { return 0, false }),
+			},
+			want: ExitRestartBudget,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			code := -1
+			opts := append(testOptions(tt.opts...), WithExitFunc(func(c int) { code = c }))
+			RunAndExitC(tt.svc, context.Background(), opts...)
+			if code != tt.want {
+				t.Errorf("exit code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
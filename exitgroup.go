@@ -0,0 +1,72 @@
+package as
+
+import (
+	"context"
+	"sync"
+)
+
+// ExitGroup stops several services run in the same process together once one of them exits successfully, e.g. to
+// shut down the consumers of a batch producer once it finished. A service exits successfully if Run returns nil
+// without having been asked to stop and it is not restarted. The other services of the group are then drained and
+// shut down gracefully, and return nil.
+//
+// An ExitGroup is safe for concurrent use and must be created with NewExitGroup.
+type ExitGroup struct {
+	mu       sync.Mutex
+	members  map[*supervisor]context.CancelFunc
+	exitedBy string
+}
+
+// NewExitGroup returns an empty ExitGroup. Services join it with WithStopOnFirstExit.
+func NewExitGroup() *ExitGroup {
+	return &ExitGroup{members: make(map[*supervisor]context.CancelFunc)}
+}
+
+// join adds the service of ctx to the group; cancel stops it once another member exited. It returns a function
+// removing the service again.
+func (g *ExitGroup) join(ctx context.Context, cancel context.CancelFunc) func() {
+	sup := supervisorFrom(ctx)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.members[sup] = cancel
+
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		delete(g.members, sup)
+	}
+}
+
+// exit records the successful exit of the service of ctx and stops all other members, unless another member
+// exited first.
+func (g *ExitGroup) exit(ctx context.Context) {
+	sup := supervisorFrom(ctx)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.exitedBy != "" {
+		return
+	}
+	g.exitedBy = Namespace(ctx) + "/" + Name(ctx)
+
+	Logger(ctx).Info("service exited, stopping the exit group")
+	for member, cancel := range g.members {
+		if member == sup {
+			continue
+		}
+
+		cancel()
+	}
+}
+
+// exited returns the service whose exit stopped the group, or the empty string.
+func (g *ExitGroup) exited() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.exitedBy
+}
@@ -0,0 +1,96 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStopOnFirstExit(t *testing.T) {
+	group := NewExitGroup()
+
+	// The consumer drains its work when asked to stop
+	consuming := make(chan struct{})
+	var drained atomic.Bool
+	consumer := &testService{
+		name: "consumer",
+		run: func(ctx context.Context) error {
+			close(consuming)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		close: func(ctx context.Context) error {
+			drained.Store(true)
+			return nil
+		},
+	}
+	producer := &testService{
+		name: "producer",
+		run: func(ctx context.Context) error {
+			<-consuming
+			return nil
+		},
+	}
+
+	logs := &logCapture{}
+	_, consumerDone := runTest(t, consumer, captureLogs(consumer, logs), WithStopOnFirstExit(group))
+	_, producerDone := runTest(t, producer, captureLogs(producer, logs), WithStopOnFirstExit(group))
+
+	for name, done := range map[string]<-chan error{"producer": producerDone, "consumer": consumerDone} {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%s: RunC() = %v, want nil", name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s did not stop", name)
+		}
+	}
+
+	if !drained.Load() {
+		t.Error("consumer not closed")
+	}
+	if group.exited() != "astest/producer" {
+		t.Errorf("group stopped by %q, want astest/producer", group.exited())
+	}
+	if logs.find("service exited, stopping the exit group") == nil {
+		t.Error("group stop not logged")
+	}
+}
+
+func TestStopOnFirstExitFailure(t *testing.T) {
+	group := NewExitGroup()
+	errFailed := errors.New("failed")
+
+	// A failed member does not stop the group
+	failing := &testService{name: "failing", run: func(ctx context.Context) error { return errFailed }}
+	cancel, done := runTest(t, &testService{name: "api"}, WithStopOnFirstExit(group))
+
+	err := RunC(failing, context.Background(), testOptions(WithStopOnFirstExit(group), WithRestartOnError(false))...)
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("RunC() = %v, want the failure", err)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("api stopped with %v after a failed member", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Neither does a member stopped from outside
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("RunC() = %v", err)
+	}
+	if group.exited() != "" {
+		t.Errorf("group stopped by %q", group.exited())
+	}
+}
+
+func TestStopOnFirstExitRestartOnSuccess(t *testing.T) {
+	opts := testOptions(WithStopOnFirstExit(NewExitGroup()), WithRestartOnSuccess(true))
+	if err := RunC(&testService{}, context.Background(), opts...); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("RunC() = %v, want ErrInvalidConfig", err)
+	}
+}
@@ -0,0 +1,84 @@
+package as
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// expvarName is the name of the expvar map the state of all supervised services is published under.
+const expvarName = "as"
+
+var (
+	expvarOnce sync.Once
+	expvarMap  *expvar.Map
+)
+
+// expvarStatus is the state of a supervised service as published via expvar.
+type expvarStatus struct {
+	// Description and Labels are set if the service implements Describer and Labeler.
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	State       string            `json:"state"`
+	Health      string            `json:"health"`
+	Started     string            `json:"started"`
+	Restarts    int               `json:"restarts"`
+	Panics      int               `json:"panics"`
+	LastError   string            `json:"last_error,omitempty"`
+	StopReason  string            `json:"stop_reason,omitempty"`
+	Flaps       int               `json:"flaps"`
+	// MemoryLimit is the GOMEMLIMIT derived from the cgroup memory limit, if it was adjusted.
+	MemoryLimit int64              `json:"memory_limit,omitempty"`
+	History     []HealthTransition `json:"history,omitempty"`
+	OTEL        OTELConfig         `json:"otel"`
+	Options     Options            `json:"options"`
+}
+
+// publishExpvar publishes the state of the service under the "as" expvar map (served at /debug/vars by
+// http.DefaultServeMux), keyed by "<namespace>/<name>". The state is read from the supervisor whenever the
+// variables are requested. Publishing a service again replaces the previous entry.
+func publishExpvar(ctx context.Context, sup *supervisor, opts Options) {
+	expvarOnce.Do(func() {
+		// Another package may have published a variable with the same name
+		if v, ok := expvar.Get(expvarName).(*expvar.Map); ok {
+			expvarMap = v
+		} else if expvar.Get(expvarName) == nil {
+			expvarMap = expvar.NewMap(expvarName)
+		}
+	})
+	if expvarMap == nil {
+		Logger(ctx).Warn("cannot publish expvar state, name already in use", "expvar", expvarName)
+		return
+	}
+
+	otelInfo := OTELInfo(ctx)
+	description, labels := Description(ctx), Labels(ctx)
+	expvarMap.Set(Namespace(ctx)+"/"+Name(ctx), expvar.Func(func() any {
+		sup.mu.Lock()
+		defer sup.mu.Unlock()
+
+		status := expvarStatus{
+			Description: description,
+			Labels:      labels,
+			State:       sup.state.String(),
+			Health:      sup.health.String(),
+			Started:     sup.started.Format(time.RFC3339),
+			Restarts:    sup.restarts,
+			Panics:      sup.panics,
+			StopReason:  sup.stopReason,
+			MemoryLimit: sup.memLimit,
+			OTEL:        otelInfo,
+			Options:     opts,
+		}
+		if sup.lastError != nil {
+			status.LastError = sup.lastError.Error()
+		}
+		if sup.history != nil {
+			status.History = sup.history.all()
+			status.Flaps = sup.history.flaps(time.Now().Add(-sup.flapWindow))
+		}
+
+		return status
+	}))
+}
@@ -0,0 +1,85 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// debugVars requests /debug/vars and returns the status of the service published under the "as" map, or false if
+// it is not published.
+func debugVars(t *testing.T, key string) (expvarStatus, bool) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	expvar.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/vars", nil))
+
+	var vars struct {
+		AS map[string]expvarStatus `json:"as"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("failed to decode /debug/vars: %v", err)
+	}
+
+	status, ok := vars.AS[key]
+	return status, ok
+}
+
+func TestExpvar(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	attempt := 0
+	var running, restarted expvarStatus
+	svc := &testService{
+		name: "expvar",
+		run: func(ctx context.Context) error {
+			attempt++
+			if attempt == 1 {
+				running, _ = debugVars(t, "astest/expvar")
+				return errFailed
+			}
+
+			restarted, _ = debugVars(t, "astest/expvar")
+			return nil
+		},
+	}
+
+	// Publishing is idempotent, so running the service again does not panic on the duplicate name
+	for range 2 {
+		attempt = 0
+		if err := RunC(svc, context.Background(), testOptions(WithGraceCount(2))...); err != nil {
+			t.Fatalf("RunC() = %v", err)
+		}
+	}
+
+	if running.State != StateRunning.String() {
+		t.Errorf("state = %q, want %q", running.State, StateRunning.String())
+	}
+	if _, err := time.Parse(time.RFC3339, running.Started); err != nil {
+		t.Errorf("started %q is not RFC 3339: %v", running.Started, err)
+	}
+	if running.Options.GraceCount != 2 {
+		t.Errorf("options grace count = %d, want 2", running.Options.GraceCount)
+	}
+
+	if restarted.Restarts != 1 {
+		t.Errorf("restarts = %d, want 1", restarted.Restarts)
+	}
+	if !strings.Contains(restarted.LastError, errFailed.Error()) {
+		t.Errorf("last error = %q, want it to contain %q", restarted.LastError, errFailed.Error())
+	}
+
+	// The state is read when requested, not copied when published
+	status, ok := debugVars(t, "astest/expvar")
+	if !ok {
+		t.Fatal("service not published")
+	}
+	if status.State != StateStopped.String() {
+		t.Errorf("state after exit = %q, want %q", status.State, StateStopped.String())
+	}
+}
@@ -0,0 +1,169 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// goroutineError is the error escalated to the service when a goroutine started by Go fails.
+type goroutineError struct {
+	name string
+	err  error
+}
+
+// Error returns the error message, including the name of the goroutine.
+func (e *goroutineError) Error() string {
+	return "goroutine " + e.name + " failed: " + e.err.Error()
+}
+
+// Unwrap returns the error returned by the goroutine.
+func (e *goroutineError) Unwrap() error {
+	return e.err
+}
+
+// Go runs fn in a new goroutine supervised by the service the context belongs to.
+//
+// Panics in fn are recovered and handled like errors, regardless of the RecoverPanic option. Errors are logged
+// and counted by the as.goroutine.failures metric; errors caused by the cancellation of ctx are ignored.
+// If EscalateGoroutineErrors is enabled, a failing goroutine cancels the context passed to Run and its error
+// becomes the error of the service, subject to the restart policy.
+//
+// The supervisor waits for all goroutines started by Go to return before calling Close, for at most
+// ShutdownTimeout. Goroutines are expected to return once ctx is cancelled, which happens when Run returns.
+// If ctx was not created by the supervisor, fn is still run with panic recovery but not waited for.
+func Go(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	sup := supervisorFrom(ctx)
+	done := func() {}
+	if sup != nil {
+		done = sup.trackGoroutine()
+	}
+
+	go func() {
+		defer done()
+
+		err := runGoroutine(ctx, fn)
+		if err == nil || (ctx.Err() != nil && errors.Is(err, context.Canceled)) {
+			return
+		}
+
+		Logger(ctx).Error("goroutine failed", "goroutine", name, "error", err)
+
+		failures, cErr := Meter(ctx).Int64Counter(
+			"as.goroutine.failures",
+			metric.WithDescription("Number of goroutines started by as.Go which failed or panicked"),
+		)
+		if cErr == nil {
+			failures.Add(ctx, 1, metric.WithAttributes(attribute.String("goroutine", name)))
+		}
+
+		if sup != nil && sup.escalatesGoroutineErrors() {
+			sup.failAttempt(&goroutineError{name: name, err: err})
+		}
+	}()
+}
+
+// runGoroutine calls fn, converting any panic into an error. Panics are recorded like those handled by Recover,
+// with a crash report and the reporters.
+func runGoroutine(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if cause := recover(); cause != nil {
+			err = panicError(ctx, cause, nil)
+			if sup := supervisorFrom(ctx); sup != nil {
+				sup.recordPanic(ctx, cause, debug.Stack())
+			}
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// failAttempt cancels the context of the current attempt with err as cause, making err the error of the service.
+// Does nothing if no attempt is running.
+func (s *supervisor) failAttempt(err error) {
+	s.mu.Lock()
+	cancel := s.cancelAttempt
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel(err)
+	}
+}
+
+// setAttempt sets the function used to cancel the current attempt, and whether failed goroutines started by Go
+// are escalated to the service. A nil function marks that no attempt is running. Each attempt counts its
+// goroutines separately, so goroutines stuck in one attempt do not delay the shutdown of the next.
+func (s *supervisor) setAttempt(cancel context.CancelCauseFunc, escalateGoroutineErrors bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cancelAttempt = cancel
+	s.escalateGoroutineErrors = escalateGoroutineErrors
+	s.goroutines = nil
+	if cancel != nil {
+		s.goroutines = &sync.WaitGroup{}
+	}
+}
+
+// trackGoroutine counts a goroutine of the current attempt, returning the function to call once it returned.
+// Goroutines started while no attempt is running, or after waitGoroutines was called, are not waited for.
+func (s *supervisor) trackGoroutine() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.goroutines == nil {
+		return func() {}
+	}
+
+	s.goroutines.Add(1)
+	return s.goroutines.Done
+}
+
+// escalatesGoroutineErrors reports whether failed goroutines started by Go are escalated to the service.
+func (s *supervisor) escalatesGoroutineErrors() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.escalateGoroutineErrors
+}
+
+// waitGoroutines waits for all goroutines started by Go during the current attempt to return, for at most
+// timeout. If timeout is zero, there is no limit. Goroutines started afterward are not counted, so the wait
+// group is never added to while it is waited for.
+func (s *supervisor) waitGoroutines(ctx context.Context, timeout time.Duration) {
+	s.mu.Lock()
+	goroutines := s.goroutines
+	s.goroutines = nil
+	s.mu.Unlock()
+
+	if goroutines == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		goroutines.Wait()
+		close(done)
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-done:
+	case <-expired:
+		Logger(ctx).Warn(
+			"timed out waiting for goroutines to return",
+			"shutdown_timeout", timeout.String(),
+		)
+	}
+}
@@ -0,0 +1,135 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGoPanicContained(t *testing.T) {
+	dir := t.TempDir()
+	reporter := &recordingReporter{}
+
+	svc := &testService{}
+	svc.run = func(ctx context.Context) error {
+		failed := make(chan struct{})
+		Go(ctx, "panicky", func(ctx context.Context) error {
+			defer close(failed)
+			panic("boom")
+		})
+		<-failed
+		return nil
+	}
+
+	logs := &logCapture{}
+	opts := testOptions(captureLogs(svc, logs), WithCrashDir(dir), WithReporter(reporter))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v, want the panic contained", err)
+	}
+
+	record := logs.find("goroutine failed")
+	if record == nil || record["goroutine"] != "panicky" {
+		t.Errorf("failure record = %v, want one for the panicking goroutine", record)
+	}
+
+	// The panic is reported like one handled by Recover
+	reporter.mu.Lock()
+	panics := reporter.panics
+	reporter.mu.Unlock()
+	if len(panics) != 1 || panics[0] != "boom" {
+		t.Errorf("reported panics = %v", panics)
+	}
+	if reports, _ := filepath.Glob(filepath.Join(dir, crashReportPrefix+"*.txt")); len(reports) != 1 {
+		t.Errorf("crash reports = %v, want one", reports)
+	}
+	if summary := logs.find("service exited"); summary == nil || summary["panics"] != float64(1) {
+		t.Errorf("exit summary = %v, want one panic", summary)
+	}
+}
+
+func TestGoEscalatedError(t *testing.T) {
+	errFailed := errors.New("failed")
+	svc := &testService{run: func(ctx context.Context) error {
+		Go(ctx, "worker", func(ctx context.Context) error { return errFailed })
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	err := RunC(svc, context.Background(), testOptions(WithEscalateGoroutineErrors(true), WithRestartOnError(false))...)
+	var goErr *goroutineError
+	if !errors.As(err, &goErr) || goErr.name != "worker" || !errors.Is(err, errFailed) {
+		t.Fatalf("RunC() = %v, want the error of the goroutine", err)
+	}
+}
+
+func TestGoWaitedForBeforeClose(t *testing.T) {
+	var returned atomic.Bool
+	var returnedBeforeClose bool
+	svc := &testService{
+		run: func(ctx context.Context) error {
+			Go(ctx, "slow", func(ctx context.Context) error {
+				<-ctx.Done()
+				time.Sleep(50 * time.Millisecond)
+				returned.Store(true)
+				return ctx.Err()
+			})
+			return nil
+		},
+		close: func(ctx context.Context) error {
+			returnedBeforeClose = returned.Load()
+			return nil
+		},
+	}
+
+	if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if !returnedBeforeClose {
+		t.Error("Close was called before the goroutine returned")
+	}
+}
+
+func TestGoStuckGoroutineDoesNotDelayLaterAttempts(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	const shutdownTimeout = 300 * time.Millisecond
+	var attempts int
+	var runReturned time.Time
+	var secondWait time.Duration
+	svc := &testService{
+		run: func(ctx context.Context) error {
+			attempts++
+			if attempts == 1 {
+				Go(ctx, "stuck", func(ctx context.Context) error {
+					<-release
+					return nil
+				})
+				return errors.New("first attempt failed")
+			}
+
+			Go(ctx, "quick", func(ctx context.Context) error { return nil })
+			runReturned = time.Now()
+			return nil
+		},
+		close: func(ctx context.Context) error {
+			if attempts == 2 {
+				secondWait = time.Since(runReturned)
+			}
+			return nil
+		},
+	}
+
+	if err := RunC(svc, context.Background(), testOptions(WithShutdownTimeout(shutdownTimeout))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("got %d attempts, want 2", attempts)
+	}
+	if secondWait >= shutdownTimeout {
+		t.Errorf("second attempt waited %s for goroutines, want the stuck goroutine of the first ignored", secondWait)
+	}
+}
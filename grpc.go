@@ -0,0 +1,293 @@
+package as
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcHealth binds a gRPC health server to the lifecycle state of a service.
+type grpcHealth struct {
+	mu        sync.Mutex
+	server    *health.Server
+	statuses  map[string]healthpb.HealthCheckResponse_ServingStatus
+	state     State
+	unhealthy bool
+	// pausedReady keeps serving while the service is paused, see PausedReadiness.
+	pausedReady bool
+	stop        func()
+}
+
+// RegisterGRPCHealth registers the standard gRPC health service (grpc.health.v1.Health) on srv and binds it to the
+// lifecycle of the service the context belongs to. The overall status (the empty service name) is NOT_SERVING
+// until the service is running, SERVING while it is running (or paused, see PausedReadiness) and not unhealthy
+// (see SetHealth), and all statuses switch to NOT_SERVING as soon as the service starts shutting down. Statuses of
+// individual gRPC services are set using SetGRPCHealth.
+//
+// It is intended to be called from Init with the service context, once per gRPC server. A later call (e.g. from
+// Init after a restart) replaces the previous registration. If ctx was not created by the supervisor, the health
+// server is registered but not bound to any lifecycle.
+func RegisterGRPCHealth(ctx context.Context, srv grpc.ServiceRegistrar) *health.Server {
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		return hs
+	}
+
+	gh := &grpcHealth{
+		server:   hs,
+		statuses: make(map[string]healthpb.HealthCheckResponse_ServingStatus),
+	}
+
+	sup.mu.Lock()
+	prev := sup.grpcHealth
+	sup.grpcHealth = gh
+	gh.unhealthy = sup.health == HealthUnhealthy
+	gh.pausedReady = sup.pausedReadiness
+	sup.mu.Unlock()
+
+	if prev != nil {
+		prev.stop()
+	}
+
+	gh.stop = sup.onStateChange(gh.update)
+
+	return hs
+}
+
+// SetGRPCHealth sets the serving status of the named gRPC service on the health server registered by
+// RegisterGRPCHealth for the service the context belongs to. The status is retained across lifecycle
+// transitions: while the service is not running, all services are reported as NOT_SERVING, and the
+// status set here is restored once it is running again.
+func SetGRPCHealth(ctx context.Context, service string, status healthpb.HealthCheckResponse_ServingStatus) {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		Logger(ctx).Warn("cannot set gRPC health status outside of a supervised service", "grpc_service", service)
+		return
+	}
+
+	sup.mu.Lock()
+	gh := sup.grpcHealth
+	sup.mu.Unlock()
+
+	if gh == nil {
+		Logger(ctx).Warn("cannot set gRPC health status, no health server registered", "grpc_service", service)
+		return
+	}
+
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+
+	gh.statuses[service] = status
+	gh.server.SetServingStatus(service, status)
+}
+
+// update applies the given lifecycle state to the health server.
+func (g *grpcHealth) update(state State) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.state = state
+	g.apply()
+}
+
+// setUnhealthy applies the health status set with SetHealth to the health server.
+func (g *grpcHealth) setUnhealthy(unhealthy bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.unhealthy == unhealthy {
+		return
+	}
+
+	g.unhealthy = unhealthy
+	g.apply()
+}
+
+// apply sets the statuses of the health server according to the lifecycle state and health status.
+// g.mu must be held.
+func (g *grpcHealth) apply() {
+	switch {
+	case isReady(g.state, g.pausedReady):
+		g.server.Resume()
+		for service, status := range g.statuses {
+			g.server.SetServingStatus(service, status)
+		}
+		if g.unhealthy {
+			g.server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		}
+	case g.state == StateStopping || g.state == StateStopped:
+		// Shutdown sets all services to NOT_SERVING and ignores later updates until Resume is called.
+		g.server.Shutdown()
+	default:
+		g.server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor injecting the values of the service context into the context
+// of each unary call (see WithServiceContext) and adding the gRPC method to the logger.
+//
+// The trace context of incoming calls is extracted from the call metadata using the TextMapPropagator of the
+// service context and a server span is started for each call. If the call context already carries a valid span,
+// e.g. because the server uses the otelgrpc stats handler, no additional span is started.
+func UnaryServerInterceptor(serviceCtx context.Context) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := startGRPCServerCall(ctx, serviceCtx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		endGRPCCall(span, err)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor injecting the values of the service context into the context
+// of each streaming call. It behaves like UnaryServerInterceptor.
+func StreamServerInterceptor(serviceCtx context.Context) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startGRPCServerCall(ss.Context(), serviceCtx, info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		endGRPCCall(span, err)
+
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a gRPC interceptor injecting the trace context of outgoing unary calls into the
+// call metadata using the TextMapPropagator of the call context, and starting a client span for each call.
+// If the call context does not descend from a service context, only the (no-op) defaults are used.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := startGRPCClientCall(ctx, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		endGRPCCall(span, err)
+
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a gRPC interceptor injecting the trace context of outgoing streaming calls into
+// the call metadata. It behaves like UnaryClientInterceptor, with the span ending once the stream is established.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := startGRPCClientCall(ctx, method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		endGRPCCall(span, err)
+
+		return cs, err
+	}
+}
+
+// startGRPCServerCall prepares the context of an incoming call. The returned span is nil if the call context
+// already carried a span.
+func startGRPCServerCall(ctx, serviceCtx context.Context, fullMethod string) (context.Context, trace.Span) {
+	ctx = WithServiceContext(ctx, serviceCtx)
+	ctx = WithLogger(ctx, Logger(ctx).With("grpc_method", fullMethod))
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if header := logLevelHeader(ctx); header != "" {
+		if values := md.Get(header); len(values) > 0 {
+			ctx = withRequestLogLevelHeader(ctx, values[0])
+		}
+	}
+
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+
+	ctx = TextMapPropagator(ctx).Extract(ctx, metadataCarrier(md))
+
+	return Tracer(ctx).Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemNameGRPC,
+			semconv.RPCMethod(strings.TrimPrefix(fullMethod, "/")),
+		),
+	)
+}
+
+// startGRPCClientCall starts a client span for an outgoing call and injects the trace context into the
+// outgoing metadata.
+func startGRPCClientCall(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	ctx, span := Tracer(ctx).Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.RPCSystemNameGRPC,
+			semconv.RPCMethod(strings.TrimPrefix(fullMethod, "/")),
+		),
+	)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	TextMapPropagator(ctx).Inject(ctx, metadataCarrier(md))
+
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// endGRPCCall records the outcome of a call on span and ends it. Does nothing if span is nil.
+func endGRPCCall(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCResponseStatusCode(code.String()))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// serverStream is a grpc.ServerStream with a replaced context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier adapts metadata.MD to propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+// Get returns the first value associated with key.
+func (m metadataCarrier) Get(key string) string {
+	values := metadata.MD(m).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// Set sets the value associated with key.
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+// Keys returns all keys of the metadata.
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,185 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialBufconn returns a client connection to lis.
+func dialBufconn(t *testing.T, lis *bufconn.Listener, opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn", append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestGRPCHealth(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	conn := dialBufconn(t, lis)
+	client := healthpb.NewHealthClient(conn)
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("health check of %q failed: %v", service, err)
+		}
+		return resp.Status
+	}
+
+	var srv *grpc.Server
+	checked := make(chan struct{})
+	statuses := make(map[string]healthpb.HealthCheckResponse_ServingStatus)
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			srv = grpc.NewServer()
+			RegisterGRPCHealth(ctx, srv)
+			go func() { _ = srv.Serve(lis) }()
+
+			statuses["init"] = check("")
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			statuses["running"] = check("")
+
+			SetGRPCHealth(ctx, "api", healthpb.HealthCheckResponse_SERVING)
+			statuses["api"] = check("api")
+
+			SetHealth(ctx, HealthUnhealthy, "test")
+			statuses["unhealthy"] = check("")
+			SetHealth(ctx, HealthHealthy, "test")
+			statuses["healthy again"] = check("")
+			close(checked)
+
+			<-ctx.Done()
+			return nil
+		},
+		close: func(ctx context.Context) error {
+			statuses["stopping"] = check("")
+			statuses["api stopping"] = check("api")
+			srv.Stop()
+			return nil
+		},
+	}
+
+	cancel, done := runTest(t, svc)
+	<-checked
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	want := map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"init":          healthpb.HealthCheckResponse_NOT_SERVING,
+		"running":       healthpb.HealthCheckResponse_SERVING,
+		"api":           healthpb.HealthCheckResponse_SERVING,
+		"unhealthy":     healthpb.HealthCheckResponse_NOT_SERVING,
+		"healthy again": healthpb.HealthCheckResponse_SERVING,
+		"stopping":      healthpb.HealthCheckResponse_NOT_SERVING,
+		"api stopping":  healthpb.HealthCheckResponse_NOT_SERVING,
+	}
+	for step, status := range want {
+		if statuses[step] != status {
+			t.Errorf("status at %s = %v, want %v", step, statuses[step], status)
+		}
+	}
+}
+
+func TestGRPCInterceptors(t *testing.T) {
+	serverCtx, serverSpans := testServiceContext(t)
+	logs := &logCapture{}
+	serverCtx = WithLogger(serverCtx, slog.New(slog.NewJSONHandler(logs, nil)).With("service", "test"))
+	clientCtx, clientSpans := testServiceContext(t)
+
+	// handlerCtx captures the contexts the handlers are called with
+	handlerCtx := make(chan context.Context, 2)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			UnaryServerInterceptor(serverCtx),
+			func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				Logger(ctx).Info("handled")
+				handlerCtx <- ctx
+				return handler(ctx, req)
+			},
+		),
+		grpc.ChainStreamInterceptor(
+			StreamServerInterceptor(serverCtx),
+			func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				Logger(ss.Context()).Info("handled")
+				handlerCtx <- ss.Context()
+				return handler(srv, ss)
+			},
+		),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn := dialBufconn(t, lis,
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(StreamClientInterceptor()),
+	)
+	client := healthpb.NewHealthClient(conn)
+
+	ctx, parent := Tracer(clientCtx).Start(clientCtx, "caller")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	stream, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancelWatch()
+	parent.End()
+
+	for _, method := range []string{"unary", "stream"} {
+		ctx := <-handlerCtx
+		spanCtx := trace.SpanContextFromContext(ctx)
+		if !spanCtx.IsValid() || spanCtx.TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("%s handler span = %v, want a span of trace %v", method, spanCtx, parent.SpanContext().TraceID())
+		}
+	}
+
+	records := logs.records()
+	if len(records) != 2 {
+		t.Fatalf("got %d records of the service logger, want one per call", len(records))
+	}
+	for _, record := range records {
+		if record["service"] != "test" || record["grpc_method"] == nil {
+			t.Errorf("record = %v, want the service logger with the gRPC method", record)
+		}
+	}
+
+	waitFor(t, "server spans", func() bool { return len(serverSpans.Ended()) == 2 })
+	for _, span := range serverSpans.Ended() {
+		if span.SpanKind() != trace.SpanKindServer || !span.Parent().IsRemote() {
+			t.Errorf("server span %s has kind %v and parent %v, want a server span below the client span", span.Name(), span.SpanKind(), span.Parent().SpanID())
+		}
+	}
+	if got := len(clientSpans.Ended()); got != 3 {
+		t.Errorf("got %d client spans, want 2 calls and the caller", got)
+	}
+}
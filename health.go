@@ -0,0 +1,111 @@
+package as
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// HealthStatus is the health of a service as reported by the service itself using SetHealth.
+type HealthStatus int
+
+const (
+	// HealthHealthy is the status of a service working as expected. It is the initial status of every service.
+	HealthHealthy HealthStatus = iota
+	// HealthDegraded is the status of a service which works with reduced functionality, e.g. because an optional
+	// upstream is unavailable. Degraded services still serve requests.
+	HealthDegraded
+	// HealthUnhealthy is the status of a service which cannot serve requests.
+	HealthUnhealthy
+)
+
+// String returns the lower-case name of the status.
+func (h HealthStatus) String() string {
+	switch h {
+	case HealthHealthy:
+		return "healthy"
+	case HealthDegraded:
+		return "degraded"
+	case HealthUnhealthy:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
+
+// SetHealth sets the health status of the service the context belongs to, with a reason describing it.
+// Transitions are logged at info level and recorded on the as.health.status gauge (0 healthy, 1 degraded,
+// 2 unhealthy). While the service is unhealthy, the overall status of the gRPC health server registered with
+// RegisterGRPCHealth is NOT_SERVING and the ready file (see WithReadyFile) is removed; degraded services keep
+// serving.
+//
+// Transitions are recorded in the health history (see HealthHistory). While the health changed at least
+// FlapThreshold times within the FlapWindow, a service reporting healthy is set to degraded instead.
+func SetHealth(ctx context.Context, status HealthStatus, reason string) {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		Logger(ctx).Warn("cannot set health status outside of a supervised service", "health", status.String())
+		return
+	}
+
+	sup.mu.Lock()
+	status, reason = sup.flappingHealth(status, reason)
+	prev := sup.health
+	sup.health = status
+	sup.healthReason = reason
+	gh := sup.grpcHealth
+	rf := sup.readyFile
+	hb := sup.heartbeat
+	history := sup.history
+	sup.mu.Unlock()
+
+	if prev != status && history != nil {
+		history.record(HealthTransition{
+			Time:   time.Now(),
+			Kind:   "health",
+			From:   prev.String(),
+			To:     status.String(),
+			Reason: reason,
+		})
+	}
+
+	if gauge, err := Meter(ctx).Int64Gauge(
+		"as.health.status",
+		metric.WithDescription("Health status of the service: 0 healthy, 1 degraded, 2 unhealthy"),
+	); err == nil {
+		gauge.Record(ctx, int64(status))
+	}
+
+	if prev != status {
+		Logger(ctx).Info("health status changed",
+			"health", status.String(),
+			"previous_health", prev.String(),
+			"reason", reason,
+		)
+	}
+
+	if gh != nil {
+		gh.setUnhealthy(status == HealthUnhealthy)
+	}
+	if rf != nil {
+		rf.setUnhealthy(status == HealthUnhealthy)
+	}
+	if hb != nil {
+		hb.notify()
+	}
+}
+
+// Health returns the health status of the service the context belongs to and the reason given with it.
+// If the context was not created by the supervisor, HealthHealthy is returned.
+func Health(ctx context.Context) (HealthStatus, string) {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		return HealthHealthy, ""
+	}
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	return sup.health, sup.healthReason
+}
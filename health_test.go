@@ -0,0 +1,116 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestSetHealth(t *testing.T) {
+	ctx, reader := testMeterContext(t, context.Background())
+	logs := &logCapture{}
+	ctx = WithLogger(ctx, slog.New(slog.NewJSONHandler(logs, nil)))
+	ctx = withSupervisor(ctx, newSupervisor())
+
+	if status, _ := Health(ctx); status != HealthHealthy {
+		t.Fatalf("initial health = %s, want healthy", status)
+	}
+
+	SetHealth(ctx, HealthDegraded, "cache unavailable")
+	if status, reason := Health(ctx); status != HealthDegraded || reason != "cache unavailable" {
+		t.Errorf("Health() = %s, %q, want degraded, %q", status, reason, "cache unavailable")
+	}
+
+	record := logs.find("health status changed")
+	if record == nil || record["level"] != "INFO" || record["health"] != "degraded" ||
+		record["previous_health"] != "healthy" || record["reason"] != "cache unavailable" {
+		t.Errorf("transition record = %v", record)
+	}
+
+	SetHealth(ctx, HealthUnhealthy, "database unavailable")
+	gauge, ok := collectMetric(t, reader, "as.health.status").(metricdata.Gauge[int64])
+	if !ok || len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != int64(HealthUnhealthy) {
+		t.Errorf("as.health.status = %+v, want %d", gauge, HealthUnhealthy)
+	}
+
+	// Setting the same status again is not a transition
+	before := len(logs.records())
+	SetHealth(ctx, HealthUnhealthy, "database unavailable")
+	if after := len(logs.records()); after != before {
+		t.Errorf("%d records logged for an unchanged status, want none", after-before)
+	}
+}
+
+func TestHealthWithoutSupervisor(t *testing.T) {
+	ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+
+	SetHealth(ctx, HealthUnhealthy, "ignored")
+	if status, _ := Health(ctx); status != HealthHealthy {
+		t.Errorf("Health() = %s, want healthy", status)
+	}
+}
+
+func TestHealthReadiness(t *testing.T) {
+	readyFile := filepath.Join(t.TempDir(), "ready")
+	lis := bufconn.Listen(1 << 20)
+	client := healthpb.NewHealthClient(dialBufconn(t, lis))
+
+	type readiness struct {
+		file   bool
+		status healthpb.HealthCheckResponse_ServingStatus
+	}
+	observe := func(want readiness) {
+		t.Helper()
+
+		waitFor(t, "readiness", func() bool {
+			_, err := os.Stat(readyFile)
+			resp, checkErr := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+			if checkErr != nil {
+				t.Fatal(checkErr)
+			}
+			return readiness{file: err == nil, status: resp.Status} == want
+		})
+	}
+
+	done := make(chan struct{})
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			srv := grpc.NewServer()
+			RegisterGRPCHealth(ctx, srv)
+			go func() { _ = srv.Serve(lis) }()
+			t.Cleanup(srv.Stop)
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			defer close(done)
+
+			ready := readiness{file: true, status: healthpb.HealthCheckResponse_SERVING}
+			notReady := readiness{status: healthpb.HealthCheckResponse_NOT_SERVING}
+
+			observe(ready)
+
+			// Degraded services stay ready
+			SetHealth(ctx, HealthDegraded, "cache unavailable")
+			observe(ready)
+
+			SetHealth(ctx, HealthUnhealthy, "database unavailable")
+			observe(notReady)
+
+			SetHealth(ctx, HealthHealthy, "recovered")
+			observe(ready)
+			return nil
+		},
+	}
+
+	if err := RunC(svc, context.Background(), testOptions(WithReadyFile(readyFile))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	<-done
+}
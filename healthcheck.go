@@ -0,0 +1,52 @@
+package as
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"go.aledante.io/ae"
+)
+
+// HealthCheck probes the health of a running instance of svc from a separate process, e.g. for a Docker
+// HEALTHCHECK running the same binary. The options are resolved like in Run, including the prefixed environment,
+// but neither OTEL nor the supervisor are initialized.
+//
+// The instance is healthy if its ready file (see WithReadyFile) exists, i.e. it is running and not unhealthy.
+// An error is returned if the instance is not healthy or no ready file is configured.
+func HealthCheck(svc Service, opts ...Option) error {
+	id, err := validateService(svc, identityModeOf(opts))
+	if err != nil {
+		return ae.Wrap("invalid service", err)
+	}
+
+	options := applyOptions(id.name, id.namespace, opts)
+	if options.ReadyFile == "" {
+		return ae.New().Msg(fmt.Sprintf("health check not enabled: set %sREADY_FILE", options.EnvPrefix))
+	}
+
+	if _, err := os.Stat(options.ReadyFile); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ae.New().Msg(fmt.Sprintf("service not ready: %s does not exist", options.ReadyFile))
+		}
+		return ae.Wrap("failed to check ready file", err)
+	}
+
+	return nil
+}
+
+// HealthCheckAndExit runs HealthCheck and exits the process with status 0 if the instance is healthy, or prints
+// the reason to stderr and exits with status 1. Intended for a healthcheck subcommand of main, e.g.
+//
+//	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+//		as.HealthCheckAndExit(svc)
+//	}
+func HealthCheckAndExit(svc Service, opts ...Option) {
+	if err := HealthCheck(svc, opts...); err != nil {
+		fmt.Fprintln(os.Stderr, "unhealthy:", err)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
@@ -0,0 +1,56 @@
+package as
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ready")
+	// The probe resolves the ready file from the same prefixed environment as the server
+	t.Setenv("ASTEST_TEST_READY_FILE", path)
+
+	var running, unhealthy error
+	svc := &testService{run: func(ctx context.Context) error {
+		waitFor(t, "ready file", func() bool { return readyFileExists(t, path) })
+		running = HealthCheck(&testService{})
+
+		SetHealth(ctx, HealthUnhealthy, "database unavailable")
+		waitFor(t, "ready file removal", func() bool { return !readyFileExists(t, path) })
+		unhealthy = HealthCheck(&testService{})
+		return nil
+	}}
+
+	if err := HealthCheck(&testService{}); err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Errorf("HealthCheck() before start = %v, want not ready", err)
+	}
+
+	if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if running != nil {
+		t.Errorf("HealthCheck() while running = %v, want nil", running)
+	}
+	if unhealthy == nil {
+		t.Error("HealthCheck() while unhealthy = nil, want an error")
+	}
+	if err := HealthCheck(&testService{}); err == nil {
+		t.Error("HealthCheck() after exit = nil, want an error")
+	}
+}
+
+func TestHealthCheckNotEnabled(t *testing.T) {
+	err := HealthCheck(&testService{})
+	if err == nil || !strings.Contains(err.Error(), "ASTEST_TEST_READY_FILE") {
+		t.Errorf("HealthCheck() = %v, want an error naming ASTEST_TEST_READY_FILE", err)
+	}
+}
+
+func TestHealthCheckInvalidService(t *testing.T) {
+	if err := HealthCheck(&testService{name: "-"}); err == nil {
+		t.Error("HealthCheck() of an invalid service = nil, want an error")
+	}
+}
@@ -0,0 +1,157 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aledante.io/ae"
+)
+
+// healthCommandWaitDelay bounds the wait for the output of a killed health command, e.g. if a child process of a
+// script keeps stderr open.
+const healthCommandWaitDelay = time.Second
+
+// healthCommandMaxReason is the maximum length of the stderr output of a health command used as health reason.
+const healthCommandMaxReason = 256
+
+// HealthCommand is an external command checking the health of the service, see WithHealthCommand.
+type HealthCommand struct {
+	// Name is the name of the check, used in logs and health reasons.
+	Name string
+	// Command is the executable and its arguments.
+	Command []string
+	// Interval is the interval between runs of the command.
+	Interval time.Duration
+	// Timeout is the time after which the command is killed and the check failed. Zero does not limit it.
+	Timeout time.Duration
+}
+
+// healthCommands runs the health commands of the service and derives its health status from their results.
+type healthCommands struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	failing map[string]string
+	// reason is the reason of the unhealthy status set by the commands, or empty if they did not set it
+	reason string
+}
+
+// initHealthCommands starts running the HealthCommands while the service is running. It returns a function
+// stopping them and waiting for running commands to be killed.
+func initHealthCommands(ctx context.Context, opts Options) func() {
+	if len(opts.HealthCommands) == 0 || supervisorFrom(ctx) == nil {
+		return func() {}
+	}
+
+	h := &healthCommands{ctx: ctx, failing: make(map[string]string)}
+	runCtx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	for _, cmd := range opts.HealthCommands {
+		if len(cmd.Command) == 0 || cmd.Interval <= 0 {
+			Logger(ctx).Warn("ignoring health command without command or interval", "check", cmd.Name)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Runs never overlap, since the next one is only scheduled once the previous one returned
+			_ = Tick(runCtx, cmd.Interval, func(ctx context.Context) error {
+				if CurrentState(ctx) != StateRunning {
+					return nil
+				}
+
+				// Commands killed because the service stopped are not failures
+				if err := runHealthCommand(ctx, cmd); ctx.Err() == nil {
+					h.record(cmd.Name, err)
+				}
+				return nil
+			})
+		}()
+	}
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// runHealthCommand runs cmd once, returning an error with its stderr output as message if it did not exit with
+// status 0 within its timeout.
+func runHealthCommand(ctx context.Context, cmd HealthCommand) error {
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
+
+	var stderr bytes.Buffer
+	c := exec.CommandContext(ctx, cmd.Command[0], cmd.Command[1:]...)
+	c.Stderr = &stderr
+	c.WaitDelay = healthCommandWaitDelay
+
+	err := c.Run()
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return ae.New().Msg(fmt.Sprintf("timed out after %s", cmd.Timeout))
+	}
+
+	reason := strings.TrimSpace(stderr.String())
+	if reason == "" {
+		return err
+	}
+	if len(reason) > healthCommandMaxReason {
+		reason = reason[:healthCommandMaxReason] + "..."
+	}
+
+	return ae.New().Msg(reason)
+}
+
+// record records the result of the named command. The service is set unhealthy while any command fails, with the
+// failures as reason, and healthy again once all of them succeed.
+func (h *healthCommands) record(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, wasFailing := h.failing[name]
+	if err != nil {
+		if !wasFailing {
+			Logger(h.ctx).Warn("health command failed", "check", name, "error", err)
+		}
+		h.failing[name] = err.Error()
+	} else {
+		if wasFailing {
+			Logger(h.ctx).Info("health command succeeded again", "check", name)
+		}
+		delete(h.failing, name)
+	}
+
+	switch {
+	case len(h.failing) > 0:
+		reasons := make([]string, 0, len(h.failing))
+		for _, n := range slices.Sorted(maps.Keys(h.failing)) {
+			reasons = append(reasons, fmt.Sprintf("health command %s failed: %s", n, h.failing[n]))
+		}
+
+		if reason := strings.Join(reasons, "; "); reason != h.reason {
+			h.reason = reason
+			SetHealth(h.ctx, HealthUnhealthy, reason)
+		}
+	case h.reason != "":
+		// Only the health status set by the commands is reset, not one set by the service itself
+		h.reason = ""
+		SetHealth(h.ctx, HealthHealthy, "health commands succeeded")
+	}
+}
@@ -0,0 +1,167 @@
+package as
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// healthScript returns the command of a shell script exiting with the code in the returned file, writing reason to
+// stderr if it fails.
+func healthScript(t *testing.T, reason string) ([]string, string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	codeFile := filepath.Join(t.TempDir(), "code")
+	setExitCode(t, codeFile, "0")
+	script := `code=$(cat "$1"); [ "$code" = 0 ] || echo "$2" >&2; exit "$code"`
+
+	return []string{"sh", "-c", script, "sh", codeFile, reason}, codeFile
+}
+
+// setExitCode sets the exit code of a health script.
+func setExitCode(t *testing.T, codeFile, code string) {
+	t.Helper()
+
+	if err := os.WriteFile(codeFile, []byte(code), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHealthCommand(t *testing.T) {
+	cmd, codeFile := healthScript(t, "array degraded")
+
+	serviceCtx := make(chan context.Context, 1)
+	svc := &testService{run: func(ctx context.Context) error {
+		serviceCtx <- ctx
+		<-ctx.Done()
+		return nil
+	}}
+	logs := &logCapture{}
+	cancel, done := runTest(t, svc, captureLogs(svc, logs), WithHealthCommand("disk", cmd, 10*time.Millisecond, time.Second))
+	ctx := <-serviceCtx
+
+	// The reason starts with the stderr output of the command
+	healthIs := func(want HealthStatus, wantReason string) func() bool {
+		return func() bool {
+			status, reason := Health(ctx)
+			return status == want && strings.HasPrefix(reason, wantReason)
+		}
+	}
+
+	// A failing command sets the service unhealthy with its stderr output as reason, until it succeeds again
+	setExitCode(t, codeFile, "3")
+	waitFor(t, "unhealthy", healthIs(HealthUnhealthy, "health command disk failed: array degraded"))
+	setExitCode(t, codeFile, "0")
+	waitFor(t, "healthy", healthIs(HealthHealthy, "health commands succeeded"))
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if failed := logs.find("health command failed"); failed == nil || failed["check"] != "disk" {
+		t.Errorf("failure logged as %v", failed)
+	}
+	if logs.find("health command succeeded again") == nil {
+		t.Error("recovery not logged")
+	}
+}
+
+func TestHealthCommandServiceHealth(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Succeeding commands do not reset the health status set by the service itself
+	serviceCtx := make(chan context.Context, 1)
+	svc := &testService{run: func(ctx context.Context) error {
+		SetHealth(ctx, HealthDegraded, "cache cold")
+		serviceCtx <- ctx
+		<-ctx.Done()
+		return nil
+	}}
+	logFile := filepath.Join(t.TempDir(), "runs")
+	cmd := []string{"sh", "-c", `echo run >> "$1"`, "sh", logFile}
+	runTest(t, svc, WithHealthCommand("ok", cmd, 5*time.Millisecond, time.Second))
+	ctx := <-serviceCtx
+
+	waitFor(t, "health command runs", func() bool {
+		data, _ := os.ReadFile(logFile)
+		return strings.Count(string(data), "run") >= 3
+	})
+	if status, reason := Health(ctx); status != HealthDegraded || reason != "cache cold" {
+		t.Errorf("Health() = %v, %q, want the status set by the service", status, reason)
+	}
+}
+
+func TestHealthCommandNoOverlap(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	logFile := filepath.Join(t.TempDir(), "runs")
+	script := `echo start >> "$1"; sleep 0.05; echo end >> "$1"`
+
+	// The interval is shorter than a run of the command
+	runTest(t, &testService{}, WithHealthCommand("slow", []string{"sh", "-c", script, "sh", logFile}, time.Millisecond, time.Second))
+	waitFor(t, "health command runs", func() bool {
+		data, _ := os.ReadFile(logFile)
+		return strings.Count(string(data), "end") >= 3
+	})
+
+	data, _ := os.ReadFile(logFile)
+	lines := strings.Fields(string(data))
+	for i, line := range lines {
+		if want := []string{"start", "end"}[i%2]; line != want {
+			t.Fatalf("runs overlapped: %v", lines)
+		}
+	}
+}
+
+func TestRunHealthCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	long := strings.Repeat("x", healthCommandMaxReason+10)
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		want    string
+	}{
+		{name: "success", script: "exit 0"},
+		{name: "stderr", script: "echo '  dongle missing  ' >&2; exit 1", want: "dongle missing"},
+		{name: "truncated", script: "echo " + long + " >&2; exit 1", want: long[:healthCommandMaxReason] + "..."},
+		{name: "no stderr", script: "exit 2", want: "exit status 2"},
+		{name: "timeout", script: "sleep 10", timeout: 50 * time.Millisecond, want: "timed out after 50ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := runHealthCommand(context.Background(), HealthCommand{Command: []string{"sh", "-c", tt.script}, Timeout: tt.timeout})
+			if time.Since(start) > 5*time.Second {
+				t.Error("command not killed at the timeout")
+			}
+
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("runHealthCommand() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("runHealthCommand() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HealthTransition is a change of the lifecycle state or health status of a service, see HealthHistory.
+type HealthTransition struct {
+	// Time is when the transition happened.
+	Time time.Time `json:"time"`
+	// Kind is "state" for lifecycle state transitions and "health" for health status transitions.
+	Kind string `json:"kind"`
+	// From is the previous state or health status.
+	From string `json:"from"`
+	// To is the new state or health status.
+	To string `json:"to"`
+	// Reason is the reason given with SetHealth, if any.
+	Reason string `json:"reason,omitempty"`
+}
+
+// healthHistory retains the most recent transitions of a service in a ring buffer of fixed size, so memory stays
+// bounded regardless of uptime.
+type healthHistory struct {
+	mu          sync.Mutex
+	transitions []HealthTransition
+	next        int
+	count       int
+}
+
+// newHealthHistory returns a healthHistory retaining the given number of transitions.
+func newHealthHistory(size int) *healthHistory {
+	return &healthHistory{transitions: make([]HealthTransition, size)}
+}
+
+// record adds a transition, evicting the oldest one if the buffer is full.
+func (h *healthHistory) record(t HealthTransition) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.transitions[h.next] = t
+	h.next = (h.next + 1) % len(h.transitions)
+	h.count = min(h.count+1, len(h.transitions))
+}
+
+// all returns the retained transitions, oldest first.
+func (h *healthHistory) all() []HealthTransition {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]HealthTransition, 0, h.count)
+	start := (h.next - h.count + len(h.transitions)) % len(h.transitions)
+	for i := 0; i < h.count; i++ {
+		out = append(out, h.transitions[(start+i)%len(h.transitions)])
+	}
+
+	return out
+}
+
+// flaps returns the number of retained health status transitions since the given time.
+func (h *healthHistory) flaps(since time.Time) int {
+	var n int
+	for _, t := range h.all() {
+		if t.Kind == "health" && !t.Time.Before(since) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// initHealthHistory records the lifecycle state transitions of the service in a health history of the size
+// configured by opts; health status transitions are recorded by SetHealth. It returns a function unbinding the
+// history from the lifecycle.
+func initHealthHistory(ctx context.Context, opts Options) func() {
+	sup := supervisorFrom(ctx)
+	if opts.HealthHistorySize <= 0 || sup == nil {
+		return func() {}
+	}
+
+	history := newHealthHistory(opts.HealthHistorySize)
+
+	sup.mu.Lock()
+	sup.history = history
+	sup.mu.Unlock()
+
+	var prev State
+	return sup.onStateChange(func(state State) {
+		if state != prev {
+			history.record(HealthTransition{
+				Time: time.Now(),
+				Kind: "state",
+				From: prev.String(),
+				To:   state.String(),
+			})
+		}
+		prev = state
+	})
+}
+
+// HealthHistory returns the most recent lifecycle state and health status transitions of the service the context
+// belongs to, oldest first. At most HealthHistorySize transitions are retained. If the context was not created by
+// the supervisor, nil is returned.
+func HealthHistory(ctx context.Context) []HealthTransition {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		return nil
+	}
+
+	sup.mu.Lock()
+	history := sup.history
+	sup.mu.Unlock()
+
+	if history == nil {
+		return nil
+	}
+
+	return history.all()
+}
+
+// HealthFlaps returns the number of health status transitions of the service the context belongs to within the
+// FlapWindow. Only transitions retained in the health history are counted.
+func HealthFlaps(ctx context.Context) int {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		return 0
+	}
+
+	sup.mu.Lock()
+	history, window := sup.history, sup.flapWindow
+	sup.mu.Unlock()
+
+	if history == nil {
+		return 0
+	}
+
+	return history.flaps(time.Now().Add(-window))
+}
+
+// flappingHealth returns the health status to apply instead of status while the service is flapping, i.e. its
+// health changed at least threshold times within the flap window: a service reporting healthy is degraded.
+// The reason describes the flapping.
+func (s *supervisor) flappingHealth(status HealthStatus, reason string) (HealthStatus, string) {
+	if s.flapThreshold <= 0 || s.history == nil || status != HealthHealthy {
+		return status, reason
+	}
+
+	flaps := s.history.flaps(time.Now().Add(-s.flapWindow))
+	if flaps < s.flapThreshold {
+		return status, reason
+	}
+
+	return HealthDegraded, fmt.Sprintf("flapping: %d health transitions within %s", flaps, s.flapWindow)
+}
@@ -0,0 +1,99 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHealthHistoryEviction(t *testing.T) {
+	h := newHealthHistory(3)
+	for i := range 5 {
+		h.record(HealthTransition{Kind: "health", Reason: fmt.Sprint(i)})
+	}
+
+	// Only the newest transitions are retained, oldest first
+	got := h.all()
+	if len(got) != 3 {
+		t.Fatalf("retained %d transitions, want 3", len(got))
+	}
+	for i, transition := range got {
+		if want := fmt.Sprint(i + 2); transition.Reason != want {
+			t.Errorf("transition %d = %q, want %q", i, transition.Reason, want)
+		}
+	}
+	if len(h.transitions) != 3 {
+		t.Errorf("buffer grew to %d", len(h.transitions))
+	}
+}
+
+func TestHealthHistoryFlaps(t *testing.T) {
+	now := time.Now()
+	h := newHealthHistory(8)
+	h.record(HealthTransition{Time: now.Add(-2 * time.Hour), Kind: "health"})
+	h.record(HealthTransition{Time: now.Add(-time.Minute), Kind: "state"})
+	h.record(HealthTransition{Time: now.Add(-time.Minute), Kind: "health"})
+	h.record(HealthTransition{Time: now, Kind: "health"})
+
+	// Only health transitions within the window are flaps
+	if got := h.flaps(now.Add(-time.Hour)); got != 2 {
+		t.Errorf("flaps() = %d, want 2", got)
+	}
+}
+
+func TestHealthHistory(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error {
+		SetHealth(ctx, HealthUnhealthy, "database down")
+		SetHealth(ctx, HealthHealthy, "")
+		SetHealth(ctx, HealthUnhealthy, "database down")
+
+		// The third flap degrades a service reporting healthy
+		SetHealth(ctx, HealthHealthy, "")
+		if status, reason := Health(ctx); status != HealthDegraded || reason == "" {
+			t.Errorf("Health() while flapping = %s, %q, want degraded", status, reason)
+		}
+		if got := HealthFlaps(ctx); got != 4 {
+			t.Errorf("HealthFlaps() = %d, want 4", got)
+		}
+
+		var health []HealthTransition
+		for _, transition := range HealthHistory(ctx) {
+			if transition.Kind == "health" {
+				health = append(health, transition)
+			}
+		}
+		if len(health) != 4 || health[0].From != "healthy" || health[0].To != "unhealthy" ||
+			health[0].Reason != "database down" || health[3].To != "degraded" {
+			t.Errorf("health transitions = %+v", health)
+		}
+
+		history := HealthHistory(ctx)
+		if first := history[0]; first.Kind != "state" || first.To != StateStarting.String() {
+			t.Errorf("first transition = %+v, want the start", first)
+		}
+		return nil
+	}}
+
+	opts := testOptions(WithHealthHistorySize(16), WithFlapDetection(3, time.Hour))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+}
+
+func TestHealthHistoryDisabled(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error {
+		SetHealth(ctx, HealthUnhealthy, "")
+		if history := HealthHistory(ctx); history != nil {
+			t.Errorf("HealthHistory() = %+v, want nil", history)
+		}
+		if got := HealthFlaps(ctx); got != 0 {
+			t.Errorf("HealthFlaps() = %d, want 0", got)
+		}
+		return nil
+	}}
+
+	if err := RunC(svc, context.Background(), testOptions(WithHealthHistorySize(0))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+}
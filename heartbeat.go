@@ -0,0 +1,202 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.aledante.io/ae"
+)
+
+// heartbeatTimeout is the timeout of a single heartbeat ping.
+const heartbeatTimeout = 5 * time.Second
+
+// heartbeatStatus is the status reported by a heartbeat ping.
+type heartbeatStatus int
+
+const (
+	// heartbeatNone means no ping is sent, e.g. while the service is starting or restarting.
+	heartbeatNone heartbeatStatus = iota
+	// heartbeatOK is reported with a GET request to the heartbeat URL.
+	heartbeatOK
+	// heartbeatFail is reported with a POST request to <url>/fail carrying a summary of the failure.
+	heartbeatFail
+)
+
+// heartbeat pings the HeartbeatURL while the service is ready, see WithHeartbeatURL.
+type heartbeat struct {
+	// ctx is not cancelled with the service, so the final ping is sent on shutdown
+	ctx         context.Context
+	url         string
+	interval    time.Duration
+	client      *http.Client
+	sup         *supervisor
+	pausedReady bool
+	unsubscribe func()
+
+	changed chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// initHeartbeat starts pinging the HeartbeatURL every HeartbeatInterval, if configured. The returned heartbeat
+// must be stopped with the final error of the service; it is nil if the heartbeat is disabled.
+func initHeartbeat(ctx context.Context, opts Options) *heartbeat {
+	sup := supervisorFrom(ctx)
+	if opts.HeartbeatURL == "" || opts.HeartbeatInterval <= 0 || sup == nil {
+		return nil
+	}
+
+	h := &heartbeat{
+		ctx:         context.WithoutCancel(ctx),
+		url:         strings.TrimSuffix(opts.HeartbeatURL, "/"),
+		interval:    opts.HeartbeatInterval,
+		client:      &http.Client{Timeout: min(heartbeatTimeout, opts.HeartbeatInterval)},
+		sup:         sup,
+		pausedReady: opts.PausedReadiness,
+		changed:     make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+
+	sup.mu.Lock()
+	sup.heartbeat = h
+	sup.mu.Unlock()
+
+	h.unsubscribe = sup.onStateChange(func(State) { h.notify() })
+	go h.run()
+
+	return h
+}
+
+// notify makes the heartbeat re-evaluate the status of the service, pinging immediately if it changed.
+func (h *heartbeat) notify() {
+	select {
+	case h.changed <- struct{}{}:
+	default:
+	}
+}
+
+// status returns the status to report for the current state and health of the service, with a summary of the
+// failure if any.
+func (h *heartbeat) status() (heartbeatStatus, string) {
+	state := h.sup.State()
+
+	h.sup.mu.Lock()
+	health, reason := h.sup.health, h.sup.healthReason
+	h.sup.mu.Unlock()
+
+	switch {
+	case !isReady(state, h.pausedReady):
+		return heartbeatNone, ""
+	case health == HealthUnhealthy:
+		return heartbeatFail, "unhealthy: " + reason
+	default:
+		return heartbeatOK, ""
+	}
+}
+
+// run pings on the interval and on status changes until the heartbeat is stopped. Failed pings are retried with
+// an exponential backoff, bounded by the interval.
+func (h *heartbeat) run() {
+	defer close(h.stopped)
+
+	backoff := retryConfig{initialDelay: time.Second, maxDelay: h.interval, multiplier: 2, jitter: 0.1}
+	timer := time.NewTimer(h.interval)
+	defer timer.Stop()
+
+	last := heartbeatNone
+	failures := 0
+	for {
+		changed := false
+		select {
+		case <-h.done:
+			return
+		case <-h.changed:
+			changed = true
+		case <-timer.C:
+		}
+
+		status, summary := h.status()
+		if status == heartbeatNone || (changed && status == last) {
+			last = status
+			if !changed {
+				timer.Reset(h.interval)
+			}
+			continue
+		}
+		last = status
+
+		delay := h.interval
+		if err := h.ping(status, summary); err != nil {
+			failures++
+			delay = min(backoff.delay(failures), h.interval)
+			Logger(h.ctx).Warn("heartbeat ping failed",
+				"failures", failures,
+				"retry_in", delay.String(),
+				"error", err,
+			)
+		} else if failures > 0 {
+			Logger(h.ctx).Info("heartbeat ping succeeded again", "failures", failures)
+			failures = 0
+		}
+		timer.Reset(delay)
+	}
+}
+
+// stop stops the heartbeat and sends the final ping: a success ping after a clean shutdown, or a failure ping
+// with the error summary if the service exited with err.
+func (h *heartbeat) stop(err error) {
+	if h == nil {
+		return
+	}
+
+	h.unsubscribe()
+	close(h.done)
+	<-h.stopped
+
+	status, summary := heartbeatOK, ""
+	if err != nil && !errors.Is(err, context.Canceled) {
+		status, summary = heartbeatFail, firstLine(err.Error())
+	}
+
+	if pingErr := h.ping(status, summary); pingErr != nil {
+		Logger(h.ctx).Warn("final heartbeat ping failed", "error", pingErr)
+	}
+}
+
+// ping sends a single ping reporting status.
+func (h *heartbeat) ping(status heartbeatStatus, summary string) error {
+	ctx, cancel := context.WithTimeout(h.ctx, h.client.Timeout)
+	defer cancel()
+
+	method, url, body := http.MethodGet, h.url, io.Reader(nil)
+	if status == heartbeatFail {
+		method, url, body = http.MethodPost, h.url+"/fail", strings.NewReader(summary)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return ae.New().Msg(fmt.Sprintf("unexpected status %s", resp.Status))
+	}
+
+	return nil
+}
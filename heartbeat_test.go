@@ -0,0 +1,143 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// heartbeatServer is an httptest server recording the pings it receives as "<method> <path> <body>".
+type heartbeatServer struct {
+	*httptest.Server
+	status int
+
+	mu    sync.Mutex
+	pings []string
+}
+
+// newHeartbeatServer returns a heartbeatServer responding with status.
+func newHeartbeatServer(t *testing.T, status int) *heartbeatServer {
+	t.Helper()
+
+	s := &heartbeatServer{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.pings = append(s.pings, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		s.mu.Unlock()
+
+		w.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// received returns the pings received so far.
+func (s *heartbeatServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.pings)
+}
+
+func TestHeartbeat(t *testing.T) {
+	srv := newHeartbeatServer(t, http.StatusOK)
+
+	health := make(chan HealthStatus)
+	svc := &testService{run: func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case status := <-health:
+				SetHealth(ctx, status, "db down")
+			}
+		}
+	}}
+
+	// With a long interval, pings are only sent on status changes
+	cancel, done := runTest(t, svc, WithHeartbeatURL(srv.URL+"/ping/", time.Hour))
+	pinged := func(n int) func() bool {
+		return func() bool { return len(srv.received()) >= n }
+	}
+
+	waitFor(t, "ready ping", pinged(1))
+	health <- HealthUnhealthy
+	waitFor(t, "failure ping", pinged(2))
+	health <- HealthHealthy
+	waitFor(t, "recovery ping", pinged(3))
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	want := []string{"GET /ping", "POST /ping/fail unhealthy: db down", "GET /ping", "GET /ping"}
+	if got := srv.received(); !slices.Equal(got, want) {
+		t.Errorf("pings = %q, want %q", got, want)
+	}
+}
+
+func TestHeartbeatInterval(t *testing.T) {
+	srv := newHeartbeatServer(t, http.StatusOK)
+
+	cancel, done := runTest(t, &testService{}, WithHeartbeatURL(srv.URL, 10*time.Millisecond))
+	waitFor(t, "repeated pings", func() bool { return len(srv.received()) >= 3 })
+	cancel()
+	<-done
+
+	for _, ping := range srv.received() {
+		if ping != "GET /" {
+			t.Errorf("ping = %q, want GET /", ping)
+		}
+	}
+}
+
+func TestHeartbeatGiveUp(t *testing.T) {
+	srv := newHeartbeatServer(t, http.StatusOK)
+
+	svc := &testService{run: func(ctx context.Context) error {
+		return errors.New("connection lost")
+	}}
+	opts := testOptions(WithHeartbeatURL(srv.URL, time.Hour), WithRestartOnError(false))
+	if err := RunC(svc, context.Background(), opts...); err == nil {
+		t.Fatal("RunC() = nil, want the error")
+	}
+
+	// The final ping reports the error
+	pings := srv.received()
+	if len(pings) == 0 || !strings.HasPrefix(pings[len(pings)-1], "POST /fail ") || !strings.Contains(pings[len(pings)-1], "connection lost") {
+		t.Errorf("pings = %q, want a final failure ping", pings)
+	}
+}
+
+func TestHeartbeatPingFailure(t *testing.T) {
+	srv := newHeartbeatServer(t, http.StatusServiceUnavailable)
+
+	svc := &testService{}
+	logs := &logCapture{}
+	cancel, done := runTest(t, svc, captureLogs(svc, logs), WithHeartbeatURL(srv.URL, time.Hour))
+	waitFor(t, "failed ping", func() bool { return logs.find("heartbeat ping failed") != nil })
+
+	// Failed pings do not affect the service
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	failed := logs.find("heartbeat ping failed")
+	if failed["level"] != "WARN" || failed["failures"] != float64(1) {
+		t.Errorf("failed ping logged as %v", failed)
+	}
+	if logs.find("final heartbeat ping failed") == nil {
+		t.Error("failed final ping not logged")
+	}
+}
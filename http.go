@@ -0,0 +1,89 @@
+package as
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTPMiddleware returns a middleware injecting the values of the service context into each request context
+// (see WithServiceContext), so that handlers can use e.g. Logger(r.Context()) and Tracer(r.Context()).
+//
+// The trace context of incoming requests is extracted using the TextMapPropagator of the service context and
+// a server span is started for each request. If the request context already carries a valid span, e.g. because
+// the handler is wrapped by otelhttp, no additional span is started.
+//
+// If a log level header is configured (see WithLogLevelHeader), its value overrides the log level for the request.
+func HTTPMiddleware(serviceCtx context.Context) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := WithServiceContext(r.Context(), serviceCtx)
+			if header := logLevelHeader(ctx); header != "" {
+				ctx = withRequestLogLevelHeader(ctx, r.Header.Get(header))
+			}
+
+			if trace.SpanContextFromContext(ctx).IsValid() {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			ctx = TextMapPropagator(ctx).Extract(ctx, propagation.HeaderCarrier(r.Header))
+			ctx, span := Tracer(ctx).Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					semconv.ServerAddress(r.Host),
+					semconv.UserAgentOriginal(r.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			req := r.WithContext(ctx)
+			next.ServeHTTP(rec, req)
+
+			// http.ServeMux sets the matched pattern on the request it routes, which may start with the method
+			if req.Pattern != "" {
+				_, route, ok := strings.Cut(req.Pattern, " ")
+				if !ok {
+					route = req.Pattern
+				}
+				span.SetName(r.Method + " " + route)
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+			if rec.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rec.status))
+			}
+		})
+	}
+}
+
+// statusRecorder is a http.ResponseWriter recording the status code of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the status code and forwards it to the wrapped http.ResponseWriter.
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for use by http.ResponseController.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
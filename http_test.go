@@ -0,0 +1,78 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	traceSdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testServiceContext returns a service context with a logger and a tracer recording its spans, as created by the
+// supervisor.
+func testServiceContext(t *testing.T) (context.Context, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := traceSdk.NewTracerProvider(traceSdk.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	ctx := withName(context.Background(), "test")
+	ctx = WithLogger(ctx, slog.New(slog.DiscardHandler).With("service", "test"))
+	ctx = withTracerProvider(ctx, provider)
+	ctx = withTracer(ctx, nil)
+	ctx = withTextMapPropagator(ctx, propagation.TraceContext{})
+
+	return ctx, recorder
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	serviceCtx, recorder := testServiceContext(t)
+
+	var logger *slog.Logger
+	var spanCtx trace.SpanContext
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger = Logger(r.Context())
+		spanCtx = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	// The trace of the caller is continued
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(context.Background(), parent), propagation.HeaderCarrier(req.Header))
+
+	rec := httptest.NewRecorder()
+	HTTPMiddleware(serviceCtx)(mux).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTeapot)
+	}
+	if logger != Logger(serviceCtx) {
+		t.Error("handler did not see the service logger")
+	}
+	if !spanCtx.IsValid() || spanCtx.TraceID() != parent.TraceID() {
+		t.Errorf("handler span = %v, want a valid span of trace %v", spanCtx, parent.TraceID())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if got := spans[0].Name(); got != "GET /items/{id}" {
+		t.Errorf("span name = %q, want the route", got)
+	}
+	if spans[0].SpanKind() != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", spans[0].SpanKind())
+	}
+}
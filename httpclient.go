@@ -0,0 +1,128 @@
+package as
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// httpClientConfig is the configuration of a client created by HTTPClient.
+type httpClientConfig struct {
+	base          http.RoundTripper
+	timeout       time.Duration
+	slowThreshold time.Duration
+}
+
+// HTTPClientOption is a function which applies a configuration change to a client created by HTTPClient.
+type HTTPClientOption func(*httpClientConfig)
+
+// WithHTTPClientTransport sets the transport performing the requests, e.g. a transport with custom TLS settings.
+// Defaults to http.DefaultTransport.
+func WithHTTPClientTransport(base http.RoundTripper) HTTPClientOption {
+	return func(c *httpClientConfig) { c.base = base }
+}
+
+// WithHTTPClientTimeout sets the timeout of the client, overriding HTTPClientTimeout. Zero disables the timeout.
+func WithHTTPClientTimeout(d time.Duration) HTTPClientOption {
+	return func(c *httpClientConfig) { c.timeout = d }
+}
+
+// WithHTTPClientSlowThreshold sets the duration above which requests are logged as slow, overriding
+// HTTPClientSlowThreshold. Zero disables the logging.
+func WithHTTPClientSlowThreshold(d time.Duration) HTTPClientOption {
+	return func(c *httpClientConfig) { c.slowThreshold = d }
+}
+
+// HTTPClient returns an http.Client for outbound requests of the service the context belongs to. Each request
+// gets a client span started with Tracer(ctx), as child of the span of the request context if any, and the trace
+// context is injected into the request headers using TextMapPropagator(ctx). Requests slower than
+// HTTPClientSlowThreshold are logged as warnings with the logger of the request context or else of ctx.
+//
+// The timeout of the client defaults to HTTPClientTimeout.
+func HTTPClient(ctx context.Context, opts ...HTTPClientOption) *http.Client {
+	cfg := httpClientConfig{base: http.DefaultTransport}
+	if sup := supervisorFrom(ctx); sup != nil {
+		cfg.timeout = sup.httpClientTimeout
+		cfg.slowThreshold = sup.httpClientSlowThreshold
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.base == nil {
+		cfg.base = http.DefaultTransport
+	}
+
+	return &http.Client{
+		Transport: &tracingTransport{
+			serviceCtx:    ctx,
+			base:          cfg.base,
+			slowThreshold: cfg.slowThreshold,
+		},
+		Timeout: cfg.timeout,
+	}
+}
+
+// tracingTransport is an http.RoundTripper starting client spans, propagating the trace context, and logging slow
+// requests.
+type tracingTransport struct {
+	serviceCtx    context.Context
+	base          http.RoundTripper
+	slowThreshold time.Duration
+}
+
+// RoundTrip performs the request with the base transport in a client span.
+func (t *tracingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// The request context may not descend from the service context, e.g. in an HTTP handler
+	ctx := r.Context()
+	if supervisorFrom(ctx) == nil {
+		ctx = WithServiceContext(ctx, t.serviceCtx)
+	}
+
+	ctx, span := Tracer(ctx).Start(ctx, r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLFull(r.URL.Redacted()),
+			semconv.ServerAddress(r.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	// Transports must not modify the request, so the headers are set on a clone
+	r = r.Clone(ctx)
+	TextMapPropagator(ctx).Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(r)
+	duration := time.Since(start)
+
+	status := 0
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		status = resp.StatusCode
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+
+	if t.slowThreshold > 0 && duration > t.slowThreshold {
+		Logger(ctx).WarnContext(ctx, "slow HTTP request",
+			"method", r.Method,
+			"url", r.URL.Redacted(),
+			"status", status,
+			"duration", duration.String(),
+			"threshold", t.slowThreshold.String(),
+			"error", err,
+		)
+	}
+
+	return resp, err
+}
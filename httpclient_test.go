@@ -0,0 +1,169 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// roundTripperFunc adapts a function to an http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestHTTPClient(t *testing.T) {
+	serviceCtx, recorder := testServiceContext(t)
+
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	// The request context does not descend from the service context, but carries the span of the caller
+	parentCtx, parent := Tracer(serviceCtx).Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(parentCtx, http.MethodGet, srv.URL+"/items", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := HTTPClient(serviceCtx).Do(req)
+	if err != nil {
+		t.Fatalf("Do() = %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want the client and the parent span", len(spans))
+	}
+	client := spans[0]
+	if client.SpanKind() != trace.SpanKindClient || client.Name() != http.MethodGet {
+		t.Errorf("span = %s of kind %v, want a client span", client.Name(), client.SpanKind())
+	}
+	if client.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("client span is not a child of the span of the request context")
+	}
+	if client.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want an error for status 500", client.Status())
+	}
+	found := false
+	for _, attr := range client.Attributes() {
+		if attr == semconv.HTTPResponseStatusCode(http.StatusInternalServerError) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("span attributes %v lack the status code", client.Attributes())
+	}
+
+	// The server continues the trace of the client span
+	sc := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(header)))
+	if sc.TraceID() != parent.SpanContext().TraceID() || sc.SpanID() != client.SpanContext().SpanID() {
+		t.Errorf("traceparent = %q, want the client span", header.Get("traceparent"))
+	}
+}
+
+func TestHTTPClientTransport(t *testing.T) {
+	serviceCtx, recorder := testServiceContext(t)
+
+	errUnavailable := errors.New("unavailable")
+	var traceparent string
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		traceparent = r.Header.Get("traceparent")
+		return nil, errUnavailable
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "http://example.invalid/items", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := HTTPClient(serviceCtx, WithHTTPClientTransport(base)).Do(req); !errors.Is(err, errUnavailable) {
+		t.Fatalf("Do() = %v, want the error of the base transport", err)
+	}
+
+	// The caller's request is not modified
+	if traceparent == "" || req.Header.Get("traceparent") != "" {
+		t.Errorf("traceparent = %q on the transport and %q on the request", traceparent, req.Header.Get("traceparent"))
+	}
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Errorf("spans = %v, want one failed span", spans)
+	}
+}
+
+func TestHTTPClientSlowRequests(t *testing.T) {
+	serviceCtx, _ := testServiceContext(t)
+	logs := &logCapture{}
+	serviceCtx = WithLogger(serviceCtx, slog.New(slog.NewJSONHandler(logs, nil)))
+
+	var delay atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(delay.Load()))
+	}))
+	defer srv.Close()
+
+	client := HTTPClient(serviceCtx, WithHTTPClientSlowThreshold(20*time.Millisecond))
+	for _, d := range []time.Duration{0, 50 * time.Millisecond} {
+		delay.Store(int64(d))
+		resp, err := client.Get(srv.URL + "/slow")
+		if err != nil {
+			t.Fatalf("Get() = %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	var slow []map[string]any
+	for _, record := range logs.records() {
+		if record["msg"] == "slow HTTP request" {
+			slow = append(slow, record)
+		}
+	}
+	if len(slow) != 1 || slow[0]["level"] != "WARN" || slow[0]["status"] != float64(http.StatusOK) ||
+		slow[0]["url"] != srv.URL+"/slow" || slow[0]["threshold"] != "20ms" {
+		t.Errorf("slow requests logged as %v, want the second request", slow)
+	}
+}
+
+func TestHTTPClientTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		clientOpts []HTTPClientOption
+		want       time.Duration
+	}{
+		{name: "default", want: 30 * time.Second},
+		{name: "options", opts: []Option{WithHTTPClientDefaults(5*time.Second, 0)}, want: 5 * time.Second},
+		{name: "client option", clientOpts: []HTTPClientOption{WithHTTPClientTimeout(0)}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got time.Duration
+			svc := &testService{run: func(ctx context.Context) error {
+				got = HTTPClient(ctx, tt.clientOpts...).Timeout
+				return nil
+			}}
+			if err := RunC(svc, context.Background(), testOptions(tt.opts...)...); err != nil {
+				t.Fatalf("RunC() = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Timeout = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := HTTPClient(context.Background()).Timeout; got != 0 {
+		t.Errorf("Timeout without a service = %s, want none", got)
+	}
+}
@@ -0,0 +1,108 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ServeHTTP serves srv on ln until the service stops, draining it with the two-phase shutdown: once shutdown
+// was requested (see Stopping) or ctx is cancelled, keep-alives are disabled and the listener is closed, so no
+// new connections are accepted, while the requests in flight are served until they complete, for at most
+// ShutdownTimeout. Requests still in flight then are aborted by closing their connections. The number of requests
+// in flight is logged when draining starts and when the deadline is reached.
+//
+// If ln is nil, ServeHTTP listens on srv.Addr. It returns nil once the server is drained, or the error of the
+// server if it fails before.
+func ServeHTTP(ctx context.Context, srv *http.Server, ln net.Listener) error {
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen("tcp", srv.Addr); err != nil {
+			return err
+		}
+	}
+
+	handler := srv.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	var inFlight atomic.Int64
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		handler.ServeHTTP(w, r)
+	})
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	select {
+	case err := <-served:
+		return err
+	case <-Stopping(ctx):
+	case <-ctx.Done():
+	}
+
+	Logger(ctx).Info("draining HTTP server", "addr", ln.Addr().String(), "in_flight", inFlight.Load())
+	srv.SetKeepAlivesEnabled(false)
+
+	drainCtx, cancel := shutdownContext(ctx, shutdownTimeout(ctx))
+	defer cancel()
+
+	// Shutdown closes the listener and idle connections, and waits for the active ones to become idle
+	if err := srv.Shutdown(drainCtx); err != nil {
+		Logger(ctx).Warn("HTTP drain deadline reached, closing connections",
+			"addr", ln.Addr().String(),
+			"in_flight", inFlight.Load(),
+		)
+		_ = srv.Close()
+	}
+
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// DrainMiddleware returns a middleware rejecting requests arriving after shutdown of the service was requested
+// (see Stopping) with 503 Service Unavailable, a Retry-After header of retryAfter (rounded up to seconds, omitted
+// if zero), and "Connection: close", so clients retry on another instance. Requests in flight are not affected.
+// It is meant for handlers on listeners shared with other components, which keep accepting connections while the
+// service drains; servers run with ServeHTTP stop accepting connections instead.
+func DrainMiddleware(serviceCtx context.Context, retryAfter time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !IsStopping(serviceCtx) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if retryAfter > 0 {
+				seconds := (retryAfter + time.Second - 1) / time.Second
+				w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+			}
+			w.Header().Set("Connection", "close")
+			http.Error(w, "service is shutting down", http.StatusServiceUnavailable)
+		})
+	}
+}
+
+// shutdownTimeout returns the ShutdownTimeout of the service the context belongs to, or zero if the context was
+// not created by the supervisor.
+func shutdownTimeout(ctx context.Context) time.Duration {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		return 0
+	}
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	return sup.shutdownTimeout
+}
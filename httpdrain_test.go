@@ -0,0 +1,161 @@
+package as
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// drainContext returns a service context with the given shutdown timeout, logging to logs.
+func drainContext(timeout time.Duration, logs *logCapture) (context.Context, *supervisor) {
+	sup := newSupervisor()
+	sup.shutdownTimeout = timeout
+
+	ctx := WithLogger(context.Background(), slog.New(slog.NewJSONHandler(logs, nil)))
+	return withSupervisor(ctx, sup), sup
+}
+
+// startSlowRequest sends a GET request to url in a goroutine once the handler signals entered, and returns a
+// channel receiving the response status, or -1 if the request failed.
+func startSlowRequest(t *testing.T, url string, entered <-chan struct{}) <-chan int {
+	t.Helper()
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			status <- -1
+			return
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		status <- resp.StatusCode
+	}()
+
+	select {
+	case <-entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not started")
+	}
+
+	return status
+}
+
+func TestServeHTTPDrain(t *testing.T) {
+	logs := &logCapture{}
+	ctx, sup := drainContext(10*time.Second, logs)
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+
+	served := make(chan error, 1)
+	go func() { served <- ServeHTTP(ctx, srv, ln) }()
+
+	status := startSlowRequest(t, "http://"+addr, entered)
+	sup.beginStopping()
+
+	// New connections are refused while the request in flight is still served
+	waitFor(t, "listener to close", func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			_ = conn.Close()
+		}
+		return err != nil
+	})
+	close(release)
+
+	if got := <-status; got != http.StatusOK {
+		t.Errorf("in-flight request status = %d, want 200", got)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("ServeHTTP() = %v", err)
+	}
+	if drain := logs.find("draining HTTP server"); drain == nil || drain["in_flight"] != float64(1) {
+		t.Errorf("drain logged as %v", drain)
+	}
+	if logs.find("HTTP drain deadline reached, closing connections") != nil {
+		t.Error("deadline reached")
+	}
+}
+
+func TestServeHTTPDrainDeadline(t *testing.T) {
+	logs := &logCapture{}
+	ctx, sup := drainContext(50*time.Millisecond, logs)
+
+	entered := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-r.Context().Done()
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- ServeHTTP(ctx, srv, ln) }()
+
+	status := startSlowRequest(t, "http://"+ln.Addr().String(), entered)
+	sup.beginStopping()
+
+	// The request still in flight at the deadline is aborted
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("ServeHTTP() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeHTTP did not return after the deadline")
+	}
+	if got := <-status; got != -1 {
+		t.Errorf("aborted request status = %d, want a failed request", got)
+	}
+	if deadline := logs.find("HTTP drain deadline reached, closing connections"); deadline == nil || deadline["in_flight"] != float64(1) {
+		t.Errorf("deadline logged as %v", deadline)
+	}
+}
+
+func TestDrainMiddleware(t *testing.T) {
+	ctx, sup := drainContext(time.Second, &logCapture{})
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := DrainMiddleware(ctx, 1500*time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(entered)
+			<-release
+		}
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	status := startSlowRequest(t, srv.URL+"/slow", entered)
+	sup.beginStopping()
+
+	// Requests arriving after draining started are rejected, those in flight complete
+	resp, err := http.Get(srv.URL + "/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "2" || !resp.Close {
+		t.Errorf("new request got %d, Retry-After %q, close %t", resp.StatusCode, resp.Header.Get("Retry-After"), resp.Close)
+	}
+
+	close(release)
+	if got := <-status; got != http.StatusOK {
+		t.Errorf("in-flight request status = %d, want 200", got)
+	}
+}
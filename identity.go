@@ -0,0 +1,100 @@
+package as
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.aledante.io/ae"
+)
+
+// maxIdentityLength is the maximum length of service names and namespaces.
+const maxIdentityLength = 63
+
+// identityPattern is the pattern service names and namespaces must match: lowercase alphanumerics, dashes, and
+// dots, starting and ending with an alphanumeric.
+var identityPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+
+// identityInvalidChars matches runs of characters not allowed in service names and namespaces.
+var identityInvalidChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// IdentityMode selects how service names and namespaces not matching the identity pattern are handled.
+type IdentityMode string
+
+const (
+	// IdentityNormalize normalizes invalid names and namespaces, logging a warning.
+	IdentityNormalize IdentityMode = "normalize"
+	// IdentityStrict rejects invalid names and namespaces.
+	IdentityStrict IdentityMode = "strict"
+)
+
+// identity is the validated, possibly normalized identity of a service.
+type identity struct {
+	name      string
+	namespace string
+	// warnings describe the normalizations applied, to be logged once the logger exists.
+	warnings []string
+}
+
+// validateService validates the name and namespace of svc. They must match the identity pattern: lowercase
+// alphanumerics, dashes, and dots, starting and ending with an alphanumeric, at most 63 characters long.
+// Depending on mode, invalid values are normalized or rejected.
+func validateService(svc Service, mode IdentityMode) (identity, error) {
+	var id identity
+	var errs []error
+
+	validate := func(kind, value string) string {
+		if value == "" {
+			errs = append(errs, ae.New().Msg(fmt.Sprintf("service %s cannot be empty", kind)))
+			return ""
+		}
+		if identityPattern.MatchString(value) && len(value) <= maxIdentityLength {
+			return value
+		}
+
+		if mode == IdentityStrict {
+			errs = append(errs, ae.New().Msg(fmt.Sprintf(
+				"service %s %q must consist of at most %d lowercase alphanumerics, dashes, and dots",
+				kind, value, maxIdentityLength,
+			)))
+			return ""
+		}
+
+		normalized := normalizeIdentity(value)
+		if normalized == "" {
+			errs = append(errs, ae.New().Msg(fmt.Sprintf("service %s %q cannot be normalized", kind, value)))
+			return ""
+		}
+
+		id.warnings = append(id.warnings, fmt.Sprintf("service %s %q normalized to %q", kind, value, normalized))
+		return normalized
+	}
+
+	id.name = validate("name", svc.Name())
+	id.namespace = validate("namespace", svc.Namespace())
+
+	return id, ae.WrapMany("invalid service", errs...)
+}
+
+// normalizeIdentity lowercases value, replaces runs of invalid characters with a dash, trims dashes and dots from
+// both ends, and truncates the result to maxIdentityLength.
+func normalizeIdentity(value string) string {
+	value = identityInvalidChars.ReplaceAllString(strings.ToLower(value), "-")
+	value = strings.Trim(value, "-.")
+	if len(value) > maxIdentityLength {
+		value = strings.TrimRight(value[:maxIdentityLength], "-.")
+	}
+
+	return value
+}
+
+// identityModeOf returns the identity mode configured by opts, which cannot be set using the environment, since the
+// identity determines the env prefix.
+func identityModeOf(opts []Option) IdentityMode {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o.IdentityMode
+}
@@ -0,0 +1,110 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// identityService is a testService with the given name and namespace.
+type identityService struct {
+	testService
+	name      string
+	namespace string
+}
+
+func (s *identityService) Name() string { return s.name }
+
+func (s *identityService) Namespace() string { return s.namespace }
+
+func TestValidateService(t *testing.T) {
+	long := strings.Repeat("a", maxIdentityLength)
+
+	tests := []struct {
+		name          string
+		svcName       string
+		namespace     string
+		mode          IdentityMode
+		wantName      string
+		wantNamespace string
+		wantWarnings  int
+		wantErr       bool
+	}{
+		{name: "valid", svcName: "api", namespace: "shop", wantName: "api", wantNamespace: "shop"},
+		{name: "dots and dashes", svcName: "api-v2.internal", namespace: "shop-eu", wantName: "api-v2.internal", wantNamespace: "shop-eu"},
+		{name: "maximum length", svcName: long, namespace: "shop", wantName: long, wantNamespace: "shop"},
+		{name: "valid strict", svcName: "api", namespace: "shop", mode: IdentityStrict, wantName: "api", wantNamespace: "shop"},
+		{name: "normalized", svcName: "My Cool Service!!", namespace: "Shop", wantName: "my-cool-service", wantNamespace: "shop", wantWarnings: 2},
+		{name: "normalized edges", svcName: "-api.", namespace: "shop", wantName: "api", wantNamespace: "shop", wantWarnings: 1},
+		{name: "truncated", svcName: long + "-b", namespace: "shop", wantName: long, wantNamespace: "shop", wantWarnings: 1},
+		{name: "empty", svcName: "", namespace: "shop", wantErr: true},
+		{name: "not normalizable", svcName: "!!", namespace: "shop", wantErr: true},
+		{name: "strict", svcName: "My Service", namespace: "shop", mode: IdentityStrict, wantErr: true},
+		{name: "strict too long", svcName: long + "b", namespace: "shop", mode: IdentityStrict, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode := tt.mode
+			if mode == "" {
+				mode = IdentityNormalize
+			}
+			svc := &identityService{name: tt.svcName, namespace: tt.namespace}
+
+			id, err := validateService(svc, mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateService() = %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if id.name != tt.wantName || id.namespace != tt.wantNamespace {
+				t.Errorf("identity = %s/%s, want %s/%s", id.namespace, id.name, tt.wantNamespace, tt.wantName)
+			}
+			if len(id.warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %q, want %d", id.warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestNormalizedIdentity(t *testing.T) {
+	t.Setenv("ASTEST_MY_COOL_SERVICE_GREETING", "hello")
+
+	var name, prefix, greeting string
+	svc := &identityService{
+		testService: testService{run: func(ctx context.Context) error {
+			name, prefix = Name(ctx), EnvPrefix(ctx)
+			greeting, _ = LookupEnv(ctx, "greeting")
+			return nil
+		}},
+		name:      "My Cool Service!!",
+		namespace: "astest",
+	}
+
+	// Logs are routed by the normalized identity
+	logs := &logCapture{}
+	opts := testOptions(WithLogRouteWriter("astest", "my-cool-service", logs), WithLogJson(true))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// The name, env prefix, and logger attributes all use the normalized identity
+	if name != "my-cool-service" || prefix != "ASTEST_MY_COOL_SERVICE_" || greeting != "hello" {
+		t.Errorf("name = %q, env prefix = %q, greeting = %q", name, prefix, greeting)
+	}
+	record := logs.find(`service name "My Cool Service!!" normalized to "my-cool-service"`)
+	if record == nil || record["level"] != "WARN" || record["service"] != "my-cool-service" {
+		t.Errorf("normalization warning = %v", record)
+	}
+}
+
+func TestStrictIdentity(t *testing.T) {
+	svc := &identityService{name: "My Cool Service!!", namespace: "astest"}
+
+	err := RunC(svc, context.Background(), testOptions(WithIdentityMode(IdentityStrict))...)
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("RunC() = %v, want ErrInvalidConfig", err)
+	}
+}
@@ -0,0 +1,177 @@
+package as
+
+import (
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// journalSocket is the path of the socket of the systemd journal native protocol.
+const journalSocket = "/run/systemd/journal/socket"
+
+// logField is a single key-value field of a log entry, used by the journald and syslog handlers.
+type logField struct {
+	key   string
+	value string
+}
+
+// journalHandler is a slog.Handler writing records to the systemd journal using its native protocol.
+// Attributes are written as fields with upper-case keys, nested groups are joined with underscores.
+type journalHandler struct {
+	conn       *journalConn
+	level      slog.Leveler
+	identifier string
+	prefix     string
+	fields     []logField
+}
+
+// newJournalHandler connects to the journal socket and returns a handler logging records of at least the given level.
+// The identifier is written as SYSLOG_IDENTIFIER of each entry.
+func newJournalHandler(level slog.Leveler, identifier string) (*journalHandler, error) {
+	conn, err := dialJournal(journalSocket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &journalHandler{
+		conn:       conn,
+		level:      level,
+		identifier: identifier,
+	}, nil
+}
+
+// Enabled reports whether the handler handles records of the level.
+func (h *journalHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle writes the record to the journal.
+func (h *journalHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make([]logField, 0, len(h.fields)+r.NumAttrs()+3)
+	fields = append(fields,
+		logField{"MESSAGE", r.Message},
+		logField{"PRIORITY", strconv.Itoa(journalPriority(r.Level))},
+	)
+	if h.identifier != "" {
+		fields = append(fields, logField{"SYSLOG_IDENTIFIER", h.identifier})
+	}
+
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendJournalAttr(fields, h.prefix, a)
+		return true
+	})
+
+	return h.conn.send(encodeJournalEntry(fields))
+}
+
+// WithAttrs returns a handler writing the attributes with each record.
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = append([]logField(nil), h.fields...)
+	for _, a := range attrs {
+		h2.fields = appendJournalAttr(h2.fields, h.prefix, a)
+	}
+
+	return &h2
+}
+
+// WithGroup returns a handler prefixing the keys of subsequent attributes with the group name.
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.prefix = h.prefix + name + "_"
+
+	return &h2
+}
+
+// appendJournalAttr appends the attribute to fields, flattening groups.
+func appendJournalAttr(fields []logField, prefix string, a slog.Attr) []logField {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = prefix + a.Key + "_"
+		}
+
+		for _, ga := range a.Value.Group() {
+			fields = appendJournalAttr(fields, groupPrefix, ga)
+		}
+
+		return fields
+	}
+
+	key := logFieldKey(prefix + a.Key)
+	if key == "" {
+		return fields
+	}
+
+	value := a.Value.String()
+	if a.Value.Kind() == slog.KindTime {
+		value = a.Value.Time().Format(time.RFC3339Nano)
+	}
+
+	return append(fields, logField{key, value})
+}
+
+// logFieldKey normalizes a key to a valid journal field name: upper-case letters, digits and underscores,
+// not starting with an underscore or digit and at most 64 characters long.
+func logFieldKey(key string) string {
+	key = NormalizeEnvKey(key)
+	if key == "" {
+		return ""
+	}
+
+	if key[0] >= '0' && key[0] <= '9' {
+		key = "F_" + key
+	}
+
+	if len(key) > 64 {
+		key = strings.TrimRight(key[:64], "_")
+	}
+
+	return key
+}
+
+// journalPriority maps a slog level to a syslog priority.
+func journalPriority(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return 7 // debug
+	case level < slog.LevelWarn:
+		return 6 // info
+	case level < slog.LevelError:
+		return 4 // warning
+	default:
+		return 3 // err
+	}
+}
+
+// encodeJournalEntry serializes the fields using the journal native protocol. Values containing newlines are
+// written in the binary form, prefixed with their little-endian 64-bit length.
+func encodeJournalEntry(fields []logField) []byte {
+	var b []byte
+	for _, f := range fields {
+		b = append(b, f.key...)
+		if strings.ContainsRune(f.value, '\n') {
+			b = append(b, '\n')
+			b = binary.LittleEndian.AppendUint64(b, uint64(len(f.value)))
+		} else {
+			b = append(b, '=')
+		}
+		b = append(b, f.value...)
+		b = append(b, '\n')
+	}
+
+	return b
+}
@@ -0,0 +1,68 @@
+//go:build linux
+
+package as
+
+import (
+	"errors"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// journalConn is a socket sending entries to the journal socket.
+type journalConn struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+// dialJournal opens a socket sending entries to the journal socket at path.
+// The socket is not connected, since passing file descriptors requires sending to an explicit address.
+func dialJournal(path string) (*journalConn, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: "", Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &journalConn{
+		conn: conn,
+		addr: &net.UnixAddr{Name: path, Net: "unixgram"},
+	}, nil
+}
+
+// send writes an entry to the journal. Entries too large for a single datagram are written to a sealed memfd,
+// whose file descriptor is passed to the journal instead.
+func (c *journalConn) send(entry []byte) error {
+	_, _, err := c.conn.WriteMsgUnix(entry, nil, c.addr)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, unix.EMSGSIZE) && !errors.Is(err, unix.ENOBUFS) {
+		return err
+	}
+
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+
+	f := os.NewFile(uintptr(fd), "journal-entry")
+	defer f.Close()
+
+	if _, err := f.Write(entry); err != nil {
+		return err
+	}
+
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		return err
+	}
+
+	_, _, err = c.conn.WriteMsgUnix(nil, unix.UnixRights(int(f.Fd())), c.addr)
+
+	return err
+}
@@ -0,0 +1,101 @@
+package as
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenJournal listens on a unix datagram socket in a temporary directory standing in for the journal socket.
+func listenJournal(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn, path
+}
+
+// readJournalEntry reads the next entry from the socket, reading it from the passed memfd if the datagram is empty.
+func readJournalEntry(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1<<16)
+	oob := make([]byte, unix.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return string(buf[:n])
+	}
+
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("invalid control message: %v", err)
+	}
+	fds, err := unix.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("invalid unix rights: %v", err)
+	}
+
+	f := os.NewFile(uintptr(fds[0]), "journal-entry")
+	defer f.Close()
+
+	// The file offset is shared with the sender, which left it at the end of the entry
+	entry, err := io.ReadAll(io.NewSectionReader(f, 0, 1<<30))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(entry)
+}
+
+func TestJournalHandler(t *testing.T) {
+	listener, path := listenJournal(t)
+	conn, err := dialJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := &journalHandler{conn: conn, level: slog.LevelInfo, identifier: "test"}
+	logger := slog.New(handler).With("service", "test").WithGroup("http")
+	logger.Debug("not written")
+	logger.WarnContext(context.Background(), "slow request", "method", "GET", "status", 200)
+
+	want := "MESSAGE=slow request\nPRIORITY=4\nSYSLOG_IDENTIFIER=test\nSERVICE=test\nHTTP_METHOD=GET\nHTTP_STATUS=200\n"
+	if got := readJournalEntry(t, listener); got != want {
+		t.Errorf("entry = %q, want %q", got, want)
+	}
+}
+
+func TestJournalHandlerLargeEntry(t *testing.T) {
+	listener, path := listenJournal(t)
+	conn, err := dialJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The entry exceeds the maximum datagram size, so it is passed as a memfd
+	large := strings.Repeat("x", 4<<20)
+	handler := &journalHandler{conn: conn, level: slog.LevelInfo}
+	slog.New(handler).Info("large", "payload", large)
+
+	want := "MESSAGE=large\nPRIORITY=6\nPAYLOAD=" + large + "\n"
+	if got := readJournalEntry(t, listener); got != want {
+		t.Errorf("entry has %d bytes, want %d", len(got), len(want))
+	}
+}
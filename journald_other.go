@@ -0,0 +1,18 @@
+//go:build !linux
+
+package as
+
+import "errors"
+
+// journalConn is a connection to the journal socket.
+type journalConn struct{}
+
+// dialJournal is not supported on this platform.
+func dialJournal(string) (*journalConn, error) {
+	return nil, errors.New("journald is not supported on this platform")
+}
+
+// send is not supported on this platform.
+func (c *journalConn) send([]byte) error {
+	return errors.New("journald is not supported on this platform")
+}
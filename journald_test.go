@@ -0,0 +1,55 @@
+package as
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestEncodeJournalEntry(t *testing.T) {
+	got := string(encodeJournalEntry([]logField{
+		{"MESSAGE", "started"},
+		{"PRIORITY", "6"},
+		{"STACK", "a\nb"},
+	}))
+
+	want := "MESSAGE=started\nPRIORITY=6\nSTACK\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n"
+	if got != want {
+		t.Errorf("encodeJournalEntry() = %q, want %q", got, want)
+	}
+}
+
+func TestLogFieldKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"user_id", "USER_ID"},
+		{"http.method", "HTTP_METHOD"},
+		{"1st", "F_1ST"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := logFieldKey(tt.key); got != tt.want {
+			t.Errorf("logFieldKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestJournalPriority(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  int
+	}{
+		{slog.LevelDebug, 7},
+		{slog.LevelInfo, 6},
+		{slog.LevelWarn, 4},
+		{slog.LevelError, 3},
+	}
+
+	for _, tt := range tests {
+		if got := journalPriority(tt.level); got != tt.want {
+			t.Errorf("journalPriority(%s) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+}
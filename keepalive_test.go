@@ -0,0 +1,55 @@
+package as
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRestartOnSuccess(t *testing.T) {
+	var attempts atomic.Int32
+	fourth := make(chan struct{})
+	svc := &testService{run: func(ctx context.Context) error {
+		if attempts.Add(1) <= 3 {
+			return nil
+		}
+		close(fourth)
+		<-ctx.Done()
+		return nil
+	}}
+
+	cancel, done := runTest(t, svc, WithRestartOnSuccess(true))
+	<-fourth
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("RunC() = %v, want nil after cancellation", err)
+	}
+	if n := attempts.Load(); n != 4 {
+		t.Errorf("attempts = %d, want 4", n)
+	}
+}
+
+func TestRestartOnSuccessGraceCount(t *testing.T) {
+	attempts := 0
+	svc := &testService{run: func(ctx context.Context) error {
+		attempts++
+		return nil
+	}}
+
+	logs := &logCapture{}
+	err := RunC(svc, context.Background(), testOptions(
+		captureLogs(svc, logs),
+		WithRestartOnSuccess(true),
+		WithGraceCount(2),
+	)...)
+	if err != nil {
+		t.Fatalf("RunC() = %v, want nil", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+	if logs.find("service completed, exceeded grace count") == nil {
+		t.Error("grace count exhaustion not logged")
+	}
+}
@@ -0,0 +1,107 @@
+package as
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Describer is an optional interface a Service can implement to provide a human-readable description.
+type Describer interface {
+	// Description returns the description of the service.
+	Description() string
+}
+
+// Labeler is an optional interface a Service can implement to attach arbitrary labels, e.g. for service catalogs.
+// Labels are added to the logger as service.labels.<key> attributes and to the OTEL resource as
+// service.labels.<key> attributes with normalized keys.
+type Labeler interface {
+	// Labels returns the labels of the service.
+	Labels() map[string]string
+}
+
+// descriptionKey is an unexported type used as the key for storing the description in a context.
+type descriptionKey struct{}
+
+// withDescription returns a new context based on ctx that contains the description. If description is empty,
+// the original context is returned unchanged.
+func withDescription(ctx context.Context, description string) context.Context {
+	if description == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, descriptionKey{}, description)
+}
+
+// Description extracts the description of the service from the context, returning the empty string if the
+// service does not implement Describer.
+func Description(ctx context.Context) string {
+	v, ok := ctx.Value(descriptionKey{}).(string)
+	if !ok {
+		return ""
+	}
+
+	return v
+}
+
+// labelsKey is an unexported type used as the key for storing the labels in a context.
+type labelsKey struct{}
+
+// withLabels returns a new context based on ctx that contains a copy of the labels. If labels is empty,
+// the original context is returned unchanged.
+func withLabels(ctx context.Context, labels map[string]string) context.Context {
+	if len(labels) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, labelsKey{}, maps.Clone(labels))
+}
+
+// Labels extracts a copy of the labels of the service from the context, returning nil if the service does not
+// implement Labeler.
+func Labels(ctx context.Context) map[string]string {
+	v, ok := ctx.Value(labelsKey{}).(map[string]string)
+	if !ok {
+		return nil
+	}
+
+	return maps.Clone(v)
+}
+
+// labelLogAttrs returns the labels as logger attributes, sorted by key.
+func labelLogAttrs(labels map[string]string) []any {
+	var attrs []any
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		attrs = append(attrs, "service.labels."+key, labels[key])
+	}
+
+	return attrs
+}
+
+// labelResourceAttrs returns the labels as OTEL resource attributes with normalized keys.
+func labelResourceAttrs(labels map[string]string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		attrs = append(attrs, attribute.String("service.labels."+normalizeLabelKey(key), labels[key]))
+	}
+
+	return attrs
+}
+
+// normalizeLabelKey normalizes a label key for use in an attribute key: lower-case letters, digits, underscores
+// and dots, with any other characters replaced by underscores.
+func normalizeLabelKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '_'
+		}
+	}, key)
+}
@@ -0,0 +1,73 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// labeledService is a testService implementing Describer and Labeler.
+type labeledService struct {
+	testService
+}
+
+func (s *labeledService) Description() string { return "Processes orders" }
+
+func (s *labeledService) Labels() map[string]string {
+	return map[string]string{"team": "payments", "Cost-Center": "42"}
+}
+
+func TestServiceLabels(t *testing.T) {
+	svc := &labeledService{}
+
+	var status expvarStatus
+	svc.run = func(ctx context.Context) error {
+		v := expvar.Get(expvarName).(*expvar.Map).Get(Namespace(ctx) + "/" + Name(ctx))
+		if err := json.Unmarshal([]byte(v.String()), &status); err != nil {
+			return err
+		}
+
+		Logger(ctx).Info("running")
+		return nil
+	}
+
+	logs := &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if status.Description != "Processes orders" {
+		t.Errorf("status description = %q, want %q", status.Description, "Processes orders")
+	}
+	if status.Labels["team"] != "payments" || status.Labels["Cost-Center"] != "42" {
+		t.Errorf("status labels = %v", status.Labels)
+	}
+
+	record := logs.find("running")
+	if record == nil {
+		t.Fatal("record not logged")
+	}
+	if record["service.labels.team"] != "payments" || record["service.labels.Cost-Center"] != "42" {
+		t.Errorf("record lacks the labels: %v", record)
+	}
+}
+
+func TestLabelResourceAttrs(t *testing.T) {
+	got := labelResourceAttrs(map[string]string{"team": "payments", "Cost-Center": "42"})
+
+	want := []attribute.KeyValue{
+		attribute.String("service.labels.cost_center", "42"),
+		attribute.String("service.labels.team", "payments"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("labelResourceAttrs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("attribute %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
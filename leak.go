@@ -0,0 +1,149 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// maxLeakStacks is the number of stacks with the most new goroutines included in a leak warning.
+const maxLeakStacks = 5
+
+// leakSample is the goroutine count and profile recorded after an attempt of the service ended.
+type leakSample struct {
+	count int
+	// stacks maps each goroutine stack to the number of goroutines with that stack.
+	stacks map[string]int
+}
+
+// leakDetector detects goroutines leaked by the attempts of a restarting service: if the number of goroutines
+// grows after each of LeakCheckAttempts consecutive attempts, and by more than LeakCheckThreshold in total, a
+// warning is logged with the stacks of the new goroutines. Fluctuations break the monotonic growth and reset the
+// detection. It is nil if the check is disabled.
+type leakDetector struct {
+	attempts  int
+	threshold int
+	samples   []leakSample
+}
+
+// newLeakDetector returns the leak detector configured by opts, or nil if the check is disabled.
+func newLeakDetector(opts Options) *leakDetector {
+	if opts.LeakCheckAttempts <= 0 {
+		return nil
+	}
+
+	return &leakDetector{attempts: opts.LeakCheckAttempts, threshold: opts.LeakCheckThreshold}
+}
+
+// check records a sample after an attempt ended and logs a warning if the goroutines grew monotonically across the
+// configured number of attempts. It does nothing if d is nil.
+func (d *leakDetector) check(ctx context.Context) {
+	if d == nil {
+		return
+	}
+
+	sample := leakSample{count: runtime.NumGoroutine(), stacks: goroutineStacks()}
+
+	// Only monotonic growth is suspicious; any decrease starts over
+	if n := len(d.samples); n > 0 && sample.count <= d.samples[n-1].count {
+		d.samples = d.samples[:0]
+	}
+	d.samples = append(d.samples, sample)
+	if len(d.samples) <= d.attempts {
+		return
+	}
+
+	first := d.samples[0]
+	d.samples = slices.Delete(d.samples, 0, 1)
+
+	growth := sample.count - first.count
+	if growth <= d.threshold {
+		return
+	}
+
+	Logger(ctx).Warn("goroutines leaked across restart attempts",
+		"attempts", d.attempts,
+		"goroutines", sample.count,
+		"goroutines_growth", growth,
+		"new_stacks", newGoroutineStacks(first.stacks, sample.stacks),
+	)
+	if counter, err := Meter(ctx).Int64Counter(
+		"as.goroutine.leaks",
+		metric.WithDescription("Number of suspected goroutine leaks across restart attempts"),
+	); err == nil {
+		counter.Add(ctx, 1)
+	}
+
+	// Report each leak once, and again only after further attempts
+	d.samples = d.samples[:0]
+}
+
+// goroutineStacks returns the stacks of all goroutines, mapped to the number of goroutines with each stack.
+func goroutineStacks() map[string]int {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+
+	// With debug=1, the profile starts with a "goroutine profile: total <count>" line. Each stack is a block
+	// starting with "<count> @ <addresses>", followed by "#" frame lines
+	_, profile, _ := strings.Cut(buf.String(), "\n")
+
+	stacks := make(map[string]int)
+	for _, block := range strings.Split(profile, "\n\n") {
+		header, frames, ok := strings.Cut(block, "\n")
+		if !ok {
+			continue
+		}
+
+		countStr, _, ok := strings.Cut(header, " @ ")
+		if !ok {
+			continue
+		}
+
+		count, err := strconv.Atoi(countStr)
+		if err != nil {
+			continue
+		}
+
+		stacks[strings.TrimSpace(frames)] += count
+	}
+
+	return stacks
+}
+
+// newGoroutineStacks returns the stacks with the most goroutines added between before and after, prefixed with
+// the number of new goroutines.
+func newGoroutineStacks(before, after map[string]int) []string {
+	type stackGrowth struct {
+		stack  string
+		growth int
+	}
+
+	var grown []stackGrowth
+	for stack, count := range after {
+		if growth := count - before[stack]; growth > 0 {
+			grown = append(grown, stackGrowth{stack: stack, growth: growth})
+		}
+	}
+
+	slices.SortFunc(grown, func(a, b stackGrowth) int {
+		if a.growth != b.growth {
+			return b.growth - a.growth
+		}
+		return strings.Compare(a.stack, b.stack)
+	})
+
+	out := make([]string, 0, min(len(grown), maxLeakStacks))
+	for _, g := range grown[:min(len(grown), maxLeakStacks)] {
+		out = append(out, strconv.Itoa(g.growth)+" new: "+g.stack)
+	}
+
+	return out
+}
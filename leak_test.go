@@ -0,0 +1,141 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// leakyRun returns a Run function starting n goroutines per attempt blocking until release is closed, returning once
+// they are running. Attempts for which keep returns false release the goroutines of the previous attempt instead.
+// The service fails attempts times, then returns nil.
+func leakyRun(n, attempts int, release <-chan struct{}, keep func(attempt int) bool) func(ctx context.Context) error {
+	attempt := 0
+	var prev chan struct{}
+	return func(ctx context.Context) error {
+		attempt++
+		if !keep(attempt) && prev != nil {
+			close(prev)
+			prev = nil
+		} else {
+			stop := make(chan struct{})
+			var started sync.WaitGroup
+			for range n {
+				started.Add(1)
+				go func() {
+					started.Done()
+					select {
+					case <-stop:
+					case <-release:
+					}
+				}()
+			}
+			started.Wait()
+			prev = stop
+		}
+
+		if attempt <= attempts {
+			return errors.New("failed")
+		}
+		return nil
+	}
+}
+
+// runLeakCheck runs svc with the leak check until it completes and returns the logged leak warnings and the
+// value of as.goroutine.leaks.
+func runLeakCheck(t *testing.T, svc Service, attempts, threshold int) ([]map[string]any, int64) {
+	t.Helper()
+
+	ctx, reader := testMeterContext(t, context.Background())
+	logs := &logCapture{}
+	ctx = WithLogger(ctx, slog.New(slog.NewJSONHandler(logs, nil)))
+	ctx = withSupervisor(ctx, newSupervisor())
+
+	opts := DefaultOptions()
+	opts.GraceCount = 0
+	opts.GracePeriod = 0
+	opts.RestartOnErrorDelay = 0
+	opts.LeakCheckAttempts = attempts
+	opts.LeakCheckThreshold = threshold
+	if err := runLoop(svc, ctx, opts, nil); err != nil {
+		t.Fatalf("runLoop() = %v", err)
+	}
+
+	var warnings []map[string]any
+	for _, record := range logs.records() {
+		if record["msg"] == "goroutines leaked across restart attempts" {
+			warnings = append(warnings, record)
+		}
+	}
+
+	return warnings, counterTotal(t, reader, "as.goroutine.leaks")
+}
+
+func TestLeakCheck(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	svc := &testService{run: leakyRun(5, 5, release, func(int) bool { return true })}
+	warnings, leaks := runLeakCheck(t, svc, 3, 10)
+
+	// The growth over 3 attempts exceeds the threshold once the fourth attempt ended
+	if len(warnings) != 1 || leaks != 1 {
+		t.Fatalf("warnings = %v, as.goroutine.leaks = %d, want one leak", warnings, leaks)
+	}
+	if growth, _ := warnings[0]["goroutines_growth"].(float64); growth < 15 {
+		t.Errorf("goroutines_growth = %v, want at least 15", growth)
+	}
+
+	stacks, _ := warnings[0]["new_stacks"].([]any)
+	if len(stacks) == 0 {
+		t.Fatal("no new stacks reported")
+	}
+	// Goroutines just scheduled may be reported with another stack, so the counts of all leaking stacks are summed
+	leaked := 0
+	for _, stack := range stacks {
+		stack, _ := stack.(string)
+		if count, _, ok := strings.Cut(stack, " new: "); ok && strings.Contains(stack, "leakyRun") {
+			n, _ := strconv.Atoi(count)
+			leaked += n
+		}
+	}
+	if top, _ := stacks[0].(string); leaked != 15 || !strings.Contains(top, "leakyRun") {
+		t.Errorf("new stacks = %q, want 15 leaking goroutines on top", stacks)
+	}
+}
+
+func TestLeakCheckFluctuation(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	// Goroutines started by one attempt are stopped by the next, so the growth is not monotonic
+	svc := &testService{run: leakyRun(20, 8, release, func(attempt int) bool { return attempt%2 == 1 })}
+	if warnings, leaks := runLeakCheck(t, svc, 2, 10); len(warnings) != 0 || leaks != 0 {
+		t.Errorf("warnings = %v, as.goroutine.leaks = %d, want none", warnings, leaks)
+	}
+}
+
+func TestLeakCheckDisabled(t *testing.T) {
+	d := newLeakDetector(DefaultOptions())
+	if d != nil {
+		t.Fatalf("newLeakDetector() = %+v, want nil by default", d)
+	}
+
+	// A disabled detector does nothing
+	d.check(context.Background())
+}
+
+func TestNewGoroutineStacks(t *testing.T) {
+	before := map[string]int{"a": 1, "b": 5, "c": 2}
+	after := map[string]int{"a": 4, "b": 5, "c": 1, "d": 3, "e": 1}
+
+	got := newGoroutineStacks(before, after)
+	if want := []string{"3 new: a", "3 new: d", "1 new: e"}; !slices.Equal(got, want) {
+		t.Errorf("newGoroutineStacks() = %q, want %q", got, want)
+	}
+}
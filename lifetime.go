@@ -0,0 +1,66 @@
+package as
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxLifetime shuts the service down once MaxLifetime elapsed after it first started running.
+type maxLifetime struct {
+	once        sync.Once
+	timer       *time.Timer
+	expired     atomic.Bool
+	unsubscribe func()
+}
+
+// initMaxLifetime arms the MaxLifetime timer once the service is running for the first time. On expiry, the
+// service is shut down like after a shutdown signal, by cancelling the supervisor context with cancel. It returns
+// nil if MaxLifetime is not set.
+func initMaxLifetime(ctx context.Context, opts Options, cancel context.CancelFunc) *maxLifetime {
+	sup := supervisorFrom(ctx)
+	if opts.MaxLifetime <= 0 || sup == nil {
+		return nil
+	}
+
+	l := &maxLifetime{}
+	expire := func() {
+		l.expired.Store(true)
+		Logger(ctx).Info("max lifetime reached, shutting down", "max_lifetime", opts.MaxLifetime.String())
+
+		sup.beginStopping()
+		if opts.DrainDelay > 0 {
+			_ = Sleep(ctx, opts.DrainDelay)
+		}
+		cancel()
+	}
+
+	l.unsubscribe = sup.onStateChange(func(state State) {
+		if state != StateRunning {
+			return
+		}
+		l.once.Do(func() { l.timer = time.AfterFunc(opts.MaxLifetime, expire) })
+	})
+
+	return l
+}
+
+// stop disarms the timer.
+func (l *maxLifetime) stop() {
+	if l == nil {
+		return
+	}
+
+	l.unsubscribe()
+	// The listener is not called anymore, so the timer is not armed concurrently
+	l.once.Do(func() {})
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+}
+
+// reached reports whether the service was shut down because MaxLifetime elapsed.
+func (l *maxLifetime) reached() bool {
+	return l != nil && l.expired.Load()
+}
@@ -0,0 +1,115 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestMaxLifetime(t *testing.T) {
+	tests := []struct {
+		name string
+		// failAfter makes the first run fail after the duration, restarting the service
+		failAfter time.Duration
+		wantRuns  int
+	}{
+		{name: "single run", wantRuns: 1},
+		// The lifetime is not reset by restarts
+		{name: "restarted", failAfter: 30 * time.Minute, wantRuns: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				ctx = WithLogger(ctx, slog.New(slog.DiscardHandler))
+				ctx = withSupervisor(ctx, newSupervisor())
+
+				opts := DefaultOptions()
+				opts.RestartOnErrorDelay = 0
+				opts.GracePeriod = 0
+				opts.MaxLifetime = 2 * time.Hour
+
+				lifetime := initMaxLifetime(ctx, opts, cancel)
+				defer lifetime.stop()
+
+				start := time.Now()
+				var running time.Time
+				runs, closes := 0, 0
+				svc := &testService{
+					// The lifetime starts once the service is running, not with a slow Init
+					init: func(ctx context.Context) error {
+						if runs == 0 {
+							time.Sleep(5 * time.Minute)
+						}
+						return nil
+					},
+					run: func(ctx context.Context) error {
+						if runs++; runs == 1 {
+							running = time.Now()
+							if tt.failAfter > 0 {
+								time.Sleep(tt.failAfter)
+								return errors.New("failed")
+							}
+						}
+						<-ctx.Done()
+						return ctx.Err()
+					},
+					close: func(ctx context.Context) error {
+						closes++
+						return nil
+					},
+				}
+
+				err := runLoop(svc, ctx, opts, nil)
+				if err != nil && !isCancellation(ctx, err) {
+					t.Fatalf("runLoop() = %v, want a cancellation", err)
+				}
+				if !lifetime.reached() {
+					t.Error("reached() = false")
+				}
+				if got := time.Since(running); got != 2*time.Hour || running.Sub(start) != 5*time.Minute {
+					t.Errorf("stopped %s after running, %s after start", got, time.Since(start))
+				}
+				// Failed runs are not closed, only the one shut down gracefully
+				if runs != tt.wantRuns || closes != 1 {
+					t.Errorf("runs = %d, closes = %d, want %d runs and one close", runs, closes, tt.wantRuns)
+				}
+			})
+		})
+	}
+}
+
+func TestMaxLifetimeExit(t *testing.T) {
+	closed := false
+	svc := &testService{close: func(ctx context.Context) error {
+		closed = true
+		return nil
+	}}
+
+	logs := &logCapture{}
+	opts := []Option{captureLogs(svc, logs), WithMaxLifetime(20 * time.Millisecond)}
+	if code := runAndExitTest(t, svc, opts...); code != -1 {
+		t.Errorf("exit code = %d, want a clean exit", code)
+	}
+	if !closed {
+		t.Error("Close not called")
+	}
+	if summary := logs.find("service exited"); summary == nil || summary["reason"] != "max lifetime reached" {
+		t.Errorf("exit summary = %v", summary)
+	}
+	if logs.find("max lifetime reached, shutting down") == nil {
+		t.Error("expiry not logged")
+	}
+}
+
+func TestMaxLifetimeDisabled(t *testing.T) {
+	ctx := withSupervisor(context.Background(), newSupervisor())
+	if l := initMaxLifetime(ctx, DefaultOptions(), func() {}); l != nil || l.reached() {
+		t.Errorf("initMaxLifetime() = %v, want nil without MaxLifetime", l)
+	}
+}
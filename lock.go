@@ -0,0 +1,151 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.aledante.io/ae"
+)
+
+// errLocked is returned by lockFile if the file is locked by another process.
+var errLocked = errors.New("file is locked")
+
+// instanceLock is an exclusive lock on a file, ensuring only a single instance of a service runs on a machine.
+type instanceLock struct {
+	path string
+	file *os.File
+}
+
+// initInstanceLock acquires the instance lock configured by opts.InstanceLock, if any.
+// It returns a function releasing the lock, which logs any error encountered while doing so.
+func initInstanceLock(ctx context.Context, opts Options) (func(), error) {
+	if opts.InstanceLock == "" {
+		return func() {}, nil
+	}
+
+	lock, err := acquireInstanceLock(opts.InstanceLock)
+	if err != nil {
+		return func() {}, err
+	}
+
+	Logger(ctx).Debug("acquired instance lock", "path", opts.InstanceLock)
+
+	return func() {
+		if err := lock.release(); err != nil {
+			Logger(ctx).Error(
+				"failed to release instance lock",
+				"path", opts.InstanceLock,
+				"error", err,
+			)
+		}
+	}, nil
+}
+
+// instanceLockAttempts bounds the attempts to lock the instance lock file while other processes replace it.
+const instanceLockAttempts = 10
+
+// acquireInstanceLock takes an exclusive, non-blocking lock on the file at path, creating it if necessary, and
+// writes the pid of the current process into it. If the file is locked by another process, an error naming the
+// pid of the holder is returned. Files left behind by processes which were killed are taken over, since the lock
+// is released by the operating system when its holder exits.
+func acquireInstanceLock(path string) (*instanceLock, error) {
+	for range instanceLockAttempts {
+		f, err := lockInstanceFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			return &instanceLock{
+				path: path,
+				file: f,
+			}, nil
+		}
+	}
+
+	return nil, ae.Msgf("failed to lock instance lock file %s: it was replaced concurrently", path)
+}
+
+// lockInstanceFile opens and locks the file at path and writes the pid of the current process into it. It
+// returns a nil file if the locked file is no longer the one at path, because its previous holder removed it
+// while releasing the lock (see release) and another process may have created and locked a new one; the caller
+// must then try again.
+func lockInstanceFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, ae.Wrap("failed to open instance lock file", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		defer f.Close()
+
+		if errors.Is(err, errLocked) {
+			holder, _ := io.ReadAll(f)
+			return nil, ae.Msgf(
+				"another instance is already running: lock %s is held by pid %s",
+				path,
+				strings.TrimSpace(string(holder)),
+			)
+		}
+
+		return nil, ae.Wrap("failed to lock instance lock file", err)
+	}
+
+	locked, err := f.Stat()
+	if err != nil {
+		_ = unlockFile(f)
+		_ = f.Close()
+		return nil, ae.Wrap("failed to stat instance lock file", err)
+	}
+	current, err := os.Stat(path)
+	if err != nil || !os.SameFile(locked, current) {
+		_ = unlockFile(f)
+		_ = f.Close()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, ae.Wrap("failed to stat instance lock file", err)
+		}
+		return nil, nil
+	}
+
+	if err := writeLockPID(f); err != nil {
+		_ = unlockFile(f)
+		_ = f.Close()
+		return nil, ae.Wrap("failed to write pid to instance lock file", err)
+	}
+
+	return f, nil
+}
+
+// release removes the lock file and releases the lock. Another process may have opened the file before it was
+// removed and lock it once it is released; lockInstanceFile detects this by comparing the locked file with the
+// one at the path, so the lock on a removed file is never mistaken for the instance lock.
+func (l *instanceLock) release() error {
+	var errs []error
+
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, err)
+	}
+	if err := unlockFile(l.file); err != nil {
+		errs = append(errs, err)
+	}
+	if err := l.file.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return ae.WrapMany("failed to release instance lock", errs...)
+}
+
+// writeLockPID replaces the contents of f with the pid of the current process.
+func writeLockPID(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
@@ -0,0 +1,18 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package as
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile is not supported on this platform.
+func lockFile(f *os.File) error {
+	return errors.New("instance locks are not supported on this platform")
+}
+
+// unlockFile is not supported on this platform.
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,101 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestInstanceLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	running := make(chan struct{})
+	first := &testService{run: func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		return nil
+	}}
+	cancel, done := runTest(t, first, WithInstanceLock(path))
+	<-running
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != strconv.Itoa(os.Getpid()) {
+		t.Errorf("lock file contains %q, want the pid", got)
+	}
+
+	err = RunC(&testService{name: "second"}, context.Background(), testOptions(WithInstanceLock(path))...)
+	if !errors.Is(err, ErrInitInternal) {
+		t.Fatalf("second RunC() = %v, want ErrInitInternal", err)
+	}
+	if !strings.Contains(err.Error(), "pid "+strconv.Itoa(os.Getpid())) {
+		t.Errorf("second RunC() = %v, want the pid of the holder", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("first RunC() = %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("lock file not removed on shutdown: %v", err)
+	}
+}
+
+func TestInstanceLockStaleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	if err := os.WriteFile(path, []byte("999999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := acquireInstanceLock(path)
+	if err != nil {
+		t.Fatalf("acquireInstanceLock() = %v, want the unlocked file taken over", err)
+	}
+	if err := lock.release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstanceLockReplacedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	holder, err := acquireInstanceLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A process opening the file before its holder removes it locks a file which is no longer the lock
+	stale, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stale.Close()
+	if err := holder.release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lockFile(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := acquireInstanceLock(path)
+	if err != nil {
+		t.Fatalf("acquireInstanceLock() = %v, want the new file locked", err)
+	}
+	defer lock.release()
+
+	locked, _ := lock.file.Stat()
+	current, err := os.Stat(path)
+	if err != nil || !os.SameFile(locked, current) {
+		t.Errorf("locked file is not the file at the path: %v", err)
+	}
+	if f, err := lockInstanceFile(path); err == nil || f != nil {
+		t.Errorf("lockInstanceFile() = %v, want the lock to be held", err)
+	}
+}
@@ -0,0 +1,48 @@
+package as
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// LogFormat selects the format of logs written to stdout.
+type LogFormat string
+
+const (
+	// LogFormatDefault selects JSON or text depending on LogJson and LogColors.
+	LogFormatDefault LogFormat = ""
+	// LogFormatLogfmt writes logfmt lines (key=value pairs), e.g. for Loki.
+	LogFormatLogfmt LogFormat = "logfmt"
+)
+
+// newLogfmtHandler returns a handler writing logfmt lines to w: the time as ts in RFC 3339, the lower-case level,
+// the message, and all attributes with groups flattened to dotted keys. Values containing spaces, quotes, equal
+// signs, or control characters are quoted and escaped.
+func newLogfmtHandler(w io.Writer) slog.Handler {
+	return slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level:       slog.LevelDebug,
+		ReplaceAttr: logfmtReplaceAttr,
+	})
+}
+
+// logfmtReplaceAttr renames and formats the built-in attributes of records for logfmt.
+func logfmtReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+
+	switch a.Key {
+	case slog.TimeKey:
+		if t, ok := a.Value.Any().(time.Time); ok {
+			return slog.String("ts", t.Format(time.RFC3339Nano))
+		}
+	case slog.LevelKey:
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			return slog.String(slog.LevelKey, strings.ToLower(level.String()))
+		}
+	}
+
+	return a
+}
@@ -0,0 +1,80 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogfmtHandler(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		attrs []any
+		want  string
+	}{
+		{
+			name:  "plain",
+			attrs: []any{"count", 3, "ok", true},
+			want:  `ts=2026-01-02T03:04:05Z level=info msg=hello count=3 ok=true`,
+		},
+		{
+			name:  "nested groups",
+			attrs: []any{slog.Group("request", "method", "GET", slog.Group("client", "ip", "10.0.0.1"))},
+			want:  `ts=2026-01-02T03:04:05Z level=info msg=hello request.method=GET request.client.ip=10.0.0.1`,
+		},
+		{
+			name:  "quotes",
+			attrs: []any{"query", `name="x"`, "path", "/a b"},
+			want:  `ts=2026-01-02T03:04:05Z level=info msg=hello query="name=\"x\"" path="/a b"`,
+		},
+		{
+			name:  "newlines",
+			attrs: []any{"error", "first\nsecond"},
+			want:  `ts=2026-01-02T03:04:05Z level=info msg=hello error="first\nsecond"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			record := slog.NewRecord(ts, slog.LevelInfo, "hello", 0)
+			record.Add(tt.attrs...)
+			if err := newLogfmtHandler(&buf).Handle(context.Background(), record); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.TrimSuffix(buf.String(), "\n"); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogfmtService(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error {
+		Logger(ctx).Info("filtered")
+		Logger(ctx).Warn("disk almost full", "free", "1 GiB")
+		return nil
+	}}
+
+	logs := &logCapture{}
+	opts := testOptions(WithLogRouteWriter("astest", "test", logs), WithLogFormat(LogFormatLogfmt), WithLogLevel("warn"))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// Records are filtered by level and carry the service attributes, like JSON records
+	out := logs.buf.String()
+	if strings.Contains(out, "filtered") {
+		t.Errorf("info record written at level warn:\n%s", out)
+	}
+	want := `level=warn msg="disk almost full" service=test version=v1.0.0 namespace=astest free="1 GiB"`
+	if !strings.Contains(out, want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+}
@@ -2,8 +2,10 @@ package as
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/lmittmann/tint"
 	"github.com/mattn/go-isatty"
@@ -41,58 +43,203 @@ func Logger(ctx context.Context) *slog.Logger {
 	return v
 }
 
-func initLogger(ctx context.Context, opts Options) *slog.Logger {
-	level := slog.LevelInfo
+// logLevel returns the log level configured by opts.
+func logLevel(opts Options) slog.Level {
+	if opts.LogDebug {
+		return slog.LevelDebug
+	}
+
+	level, _ := parseLogLevel(opts.LogLevel)
+	return level
+}
 
-	switch opts.LogLevel {
+// parseLogLevel returns the level named by s, ignoring case, and whether s is a known level. Unknown levels are
+// parsed as info.
+func parseLogLevel(s string) (slog.Level, bool) {
+	switch strings.ToLower(s) {
 	case "error":
-		level = slog.LevelError
-	case "warn":
-		level = slog.LevelWarn
+		return slog.LevelError, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "info":
+		return slog.LevelInfo, true
 	case "debug":
-		level = slog.LevelDebug
+		return slog.LevelDebug, true
 	default:
-		level = slog.LevelInfo
+		return slog.LevelInfo, false
 	}
+}
 
-	if opts.LogDebug {
-		level = slog.LevelDebug
+func initLogger(ctx context.Context, opts Options) *slog.Logger {
+	// The level can be changed later, e.g. when options are reloaded on restart
+	level := new(slog.LevelVar)
+	level.Set(logLevel(opts))
+	if sup := supervisorFrom(ctx); sup != nil {
+		sup.logLevel = level
 	}
 
-	if opts.LogAutoColors || opts.LogDebug {
-		if isatty.IsTerminal(os.Stdout.Fd()) {
-			opts.LogColors = true
+	opts.LogColors = colorsEnabled(opts, os.Stdout)
+
+	var handler slog.Handler
+	var outputErr error
+	switch {
+	case opts.LogOutput == "journald":
+		if h, err := newJournalHandler(slog.LevelDebug, Name(ctx)); err != nil {
+			outputErr = err
+		} else {
+			handler = h
+		}
+	case strings.HasPrefix(opts.LogOutput, "syslog"):
+		if h, err := newSyslogHandler(opts.LogOutput, slog.LevelDebug, Name(ctx)); err != nil {
+			outputErr = err
+		} else {
+			handler = h
+			if sup := supervisorFrom(ctx); sup != nil {
+				sup.mu.Lock()
+				sup.logSyslog = h.writer
+				sup.mu.Unlock()
+			}
 		}
 	}
 
-	var handler slog.Handler
-	if opts.LogJson {
-		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: level,
-		})
-	} else {
-		if opts.LogColors {
-			handler = tint.NewHandler(os.Stdout, &tint.Options{
-				Level: level,
-			})
+	// Field schemas only apply to JSON logs
+	schema := LogSchemaDefault
+	if handler == nil && opts.LogJson && opts.LogFormat == LogFormatDefault && opts.LogSchema != "" {
+		schema = opts.LogSchema
+	}
+
+	if handler == nil {
+		handler = newWriterHandler(os.Stdout, opts, schema)
+	}
+
+	// Records of the service may be routed to their own destination, optionally besides the regular output
+	routed, routeErr := routeLogs(ctx, opts, schema)
+	if routed != nil {
+		if opts.LogRouteCombined {
+			handler = newTeeHandler(routed, handler)
 		} else {
-			handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-				Level: level,
-			})
+			handler = routed
 		}
 	}
 
+	// The output handlers accept all levels, so the level can be lowered for single requests (see WithRequestLogLevel)
+	handler = &levelHandler{Handler: handler, level: level}
+
+	if schema == LogSchemaGCP {
+		handler = newGCPTraceHandler(handler, opts.LogGCPProject)
+	}
+
 	logger := slog.New(handler)
 
-	if svcName := Name(ctx); svcName != "" {
-		logger = logger.With("service", svcName)
+	if attrs := schema.serviceAttrs(Name(ctx), Version(ctx), Namespace(ctx)); len(attrs) > 0 {
+		logger = logger.With(attrs...)
+	}
+	if attrs := labelLogAttrs(Labels(ctx)); len(attrs) > 0 {
+		logger = logger.With(attrs...)
 	}
-	if svcVersion := Version(ctx); svcVersion != "" {
-		logger = logger.With("version", svcVersion)
+
+	if outputErr != nil {
+		logger.Warn("log output unavailable, logging to stdout", "log_output", opts.LogOutput, "error", outputErr)
 	}
-	if svcNamespace := Namespace(ctx); svcNamespace != "" {
-		logger = logger.With("namespace", svcNamespace)
+	if routeErr != nil {
+		logger.Warn("log route unavailable, using the regular log output", "error", routeErr)
 	}
 
 	return logger
 }
+
+// colorsEnabled reports whether output written to f is colored: if LogColors is set, or if LogAutoColors or
+// LogDebug is set, f is a terminal, and the NO_COLOR environment variable is not set.
+func colorsEnabled(opts Options, f *os.File) bool {
+	if opts.LogColors {
+		return true
+	}
+	if !opts.LogAutoColors && !opts.LogDebug {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	return isatty.IsTerminal(f.Fd())
+}
+
+// newWriterHandler returns the handler writing records to w in the format configured by opts: logfmt, JSON using the
+// given schema, colored text, or plain text.
+func newWriterHandler(w io.Writer, opts Options, schema LogSchema) slog.Handler {
+	if opts.LogFormat == LogFormatLogfmt {
+		return newLogfmtHandler(w)
+	}
+	if opts.LogJson {
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level:       slog.LevelDebug,
+			ReplaceAttr: schema.replaceAttr(),
+		})
+	}
+	if opts.LogColors {
+		return tint.NewHandler(w, &tint.Options{
+			Level: slog.LevelDebug,
+		})
+	}
+
+	return slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	})
+}
+
+// teeHandler is a slog.Handler passing each record to all handlers which are enabled for it.
+type teeHandler struct {
+	handlers []slog.Handler
+}
+
+// newTeeHandler returns a handler passing records to all given handlers.
+func newTeeHandler(handlers ...slog.Handler) *teeHandler {
+	return &teeHandler{handlers: handlers}
+}
+
+// Enabled reports whether any handler is enabled for the level.
+func (t *teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handle passes the record to all handlers enabled for its level, returning the first error.
+func (t *teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range t.handlers {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// WithAttrs returns a handler passing the attributes to all handlers.
+func (t *teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(t.handlers))
+	for i, h := range t.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+
+	return &teeHandler{handlers: handlers}
+}
+
+// WithGroup returns a handler passing the group to all handlers.
+func (t *teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(t.handlers))
+	for i, h := range t.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+
+	return &teeHandler{handlers: handlers}
+}
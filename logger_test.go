@@ -0,0 +1,80 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestLogLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		env  map[string]string
+		want slog.Level
+	}{
+		{name: "default", want: slog.LevelInfo},
+		{name: "option", opts: []Option{WithLogLevel("error")}, want: slog.LevelError},
+		{name: "env overrides the default", env: map[string]string{"ASTEST_TEST_LOG_LEVEL": "debug"}, want: slog.LevelDebug},
+		{name: "case ignored", opts: []Option{WithLogLevel("WARN")}, want: slog.LevelWarn},
+		{name: "warning alias", opts: []Option{WithLogLevel("Warning")}, want: slog.LevelWarn},
+		{name: "unknown", opts: []Option{WithLogLevel("verbose")}, want: slog.LevelInfo},
+		{name: "debug takes precedence", opts: []Option{WithLogLevel("error"), WithLogDebug(true)}, want: slog.LevelDebug},
+		{
+			name: "debug env takes precedence",
+			env:  map[string]string{"ASTEST_TEST_LOG_LEVEL": "error", "ASTEST_TEST_LOG_DEBUG": "true"},
+			want: slog.LevelDebug,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			if got := logLevel(applyOptions("test", "astest", tt.opts)); got != tt.want {
+				t.Errorf("logLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogLevelRunC(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error {
+		Logger(ctx).Info("info record")
+		Logger(ctx).Warn("warn record")
+		return nil
+	}}
+
+	logs := &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs), WithLogLevel("warning"))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if logs.find("info record") != nil || logs.find("warn record") == nil {
+		t.Errorf("records = %v, want only those at warn or above", logs.records())
+	}
+}
+
+func TestLogLevelUnknownWarning(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error { return nil }}
+
+	// Unknown levels fall back to info with a warning instead of stopping the service
+	logs := &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs), WithLogLevel("verbose"))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if warning := logs.find(`unknown LogLevel "verbose", using the default level info`); warning == nil || warning["level"] != "WARN" {
+		t.Errorf("warning logged as %v", warning)
+	}
+
+	logs = &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs), WithLogLevel("ERROR"))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	for _, record := range logs.records() {
+		if record["msg"] == `unknown LogLevel "ERROR", using the default level info` {
+			t.Error("known level in another case reported as unknown")
+		}
+	}
+}
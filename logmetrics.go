@@ -0,0 +1,99 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+)
+
+// logRecordCounter counts warn and error log records on the as.log.records counter.
+// Records logged before the counter is bound to a meter are not counted.
+type logRecordCounter struct {
+	instrument atomic.Pointer[logRecordInstrument]
+}
+
+// logRecordInstrument is the counter with the pre-built attribute sets of both levels, so counting does not
+// allocate attribute sets.
+type logRecordInstrument struct {
+	counter metric.Int64Counter
+	warn    metric.AddOption
+	error   metric.AddOption
+}
+
+// bind creates the counter on the meter of ctx. It is a no-op on a nil counter.
+func (c *logRecordCounter) bind(ctx context.Context) {
+	if c == nil {
+		return
+	}
+
+	counter, err := Meter(ctx).Int64Counter(
+		"as.log.records",
+		metric.WithDescription("Number of log records at warn level and above"),
+	)
+	if err != nil {
+		Logger(ctx).Warn("failed to create log record counter", "error", err)
+		return
+	}
+
+	service := semconv.ServiceNameKey.String(Name(ctx))
+	c.instrument.Store(&logRecordInstrument{
+		counter: counter,
+		warn:    metric.WithAttributeSet(attribute.NewSet(attribute.String("level", "warn"), service)),
+		error:   metric.WithAttributeSet(attribute.NewSet(attribute.String("level", "error"), service)),
+	})
+}
+
+// count adds a record of the given level, which must be at least slog.LevelWarn.
+func (c *logRecordCounter) count(ctx context.Context, level slog.Level) {
+	inst := c.instrument.Load()
+	if inst == nil {
+		return
+	}
+
+	if level >= slog.LevelError {
+		inst.counter.Add(ctx, 1, inst.error)
+	} else {
+		inst.counter.Add(ctx, 1, inst.warn)
+	}
+}
+
+// logMetricsHandler is a slog.Handler counting warn and error records before passing them to the wrapped handler.
+type logMetricsHandler struct {
+	slog.Handler
+	records *logRecordCounter
+}
+
+// initLogMetrics wraps the logger to count warn and error records, if enabled by opts.LogMetrics.
+// The counter is created once OTEL is initialized, see logRecordCounter.bind.
+func initLogMetrics(sup *supervisor, opts Options, logger *slog.Logger) *slog.Logger {
+	if !opts.LogMetrics {
+		return logger
+	}
+
+	sup.logRecords = &logRecordCounter{}
+
+	return slog.New(&logMetricsHandler{Handler: logger.Handler(), records: sup.logRecords})
+}
+
+// Handle counts the record if it is at warn level or above and passes it to the wrapped handler.
+func (h *logMetricsHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		h.records.count(ctx, r.Level)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler counting records of the wrapped handler with the attributes.
+func (h *logMetricsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logMetricsHandler{Handler: h.Handler.WithAttrs(attrs), records: h.records}
+}
+
+// WithGroup returns a handler counting records of the wrapped handler with the group.
+func (h *logMetricsHandler) WithGroup(name string) slog.Handler {
+	return &logMetricsHandler{Handler: h.Handler.WithGroup(name), records: h.records}
+}
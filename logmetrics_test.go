@@ -0,0 +1,60 @@
+package as
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestLogMetrics(t *testing.T) {
+	ctx, reader := testMeterContext(t, withName(context.Background(), "test"))
+
+	records := &logRecordCounter{}
+	handler := slog.NewTextHandler(io.Discard, nil)
+	logger := slog.New(&logMetricsHandler{Handler: handler, records: records}).With("component", "test")
+
+	// Records logged before OTEL is initialized are not counted
+	logger.ErrorContext(ctx, "before bind")
+
+	records.bind(ctx)
+	logger.InfoContext(ctx, "info")
+	logger.WarnContext(ctx, "warn")
+	logger.WarnContext(ctx, "warn")
+	logger.ErrorContext(ctx, "error")
+
+	sum, ok := collectMetric(t, reader, "as.log.records").(metricdata.Sum[int64])
+	if !ok {
+		t.Fatal("as.log.records not recorded")
+	}
+
+	got := make(map[string]int64)
+	for _, point := range sum.DataPoints {
+		level, _ := point.Attributes.Value("level")
+		service, _ := point.Attributes.Value("service.name")
+		if service.AsString() != "test" {
+			t.Errorf("service.name = %q, want test", service.AsString())
+		}
+		got[level.AsString()] += point.Value
+	}
+
+	if len(got) != 2 || got["warn"] != 2 || got["error"] != 1 {
+		t.Errorf("counts = %v, want warn 2 and error 1", got)
+	}
+}
+
+func TestLogMetricsBelowThresholdAllocs(t *testing.T) {
+	ctx, _ := testMeterContext(t, context.Background())
+
+	records := &logRecordCounter{}
+	records.bind(ctx)
+	h := &logMetricsHandler{Handler: slog.DiscardHandler, records: records}
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "info", 0)
+
+	if allocs := testing.AllocsPerRun(100, func() { _ = h.Handle(ctx, record) }); allocs != 0 {
+		t.Errorf("Handle allocated %v times for an info record, want 0", allocs)
+	}
+}
@@ -0,0 +1,83 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"go.aledante.io/ae"
+)
+
+// logRouteKey returns the key routing the records of a service: "<namespace>-<name>", or the name if the namespace
+// is empty. It is the key of LogRouteWriters and the base name of the log file in LogRouteDir.
+func logRouteKey(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+
+	return namespace + "-" + name
+}
+
+// routeLogs returns the handler writing the records of the service of ctx to its own destination: the writer in
+// opts.LogRouteWriters for its route key, or the file <key>.log in opts.LogRouteDir. Files are closed by
+// closeLogRoutes. It returns nil if no route is configured for the service.
+func routeLogs(ctx context.Context, opts Options, schema LogSchema) (slog.Handler, error) {
+	key := logRouteKey(Namespace(ctx), Name(ctx))
+
+	// Routed destinations are not terminals
+	opts.LogColors = false
+
+	if w, ok := opts.LogRouteWriters[key]; ok {
+		return newWriterHandler(w, opts, schema), nil
+	}
+	if opts.LogRouteDir == "" {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(opts.LogRouteDir, 0o755); err != nil {
+		return nil, ae.Wrap("failed to create log directory", err)
+	}
+
+	path := filepath.Join(opts.LogRouteDir, key+".log")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, ae.Wrap("failed to open log file", err)
+	}
+
+	if sup := supervisorFrom(ctx); sup != nil {
+		sup.mu.Lock()
+		sup.logFiles = append(sup.logFiles, f)
+		sup.mu.Unlock()
+	}
+
+	return newWriterHandler(f, opts, schema), nil
+}
+
+// closeLogRoutes flushes and closes the log files opened by routeLogs for the supervisor, and drains and closes
+// the syslog output, if any. Records logged afterward are written to stderr, see initLogShutdown.
+func (s *supervisor) closeLogRoutes() {
+	s.mu.Lock()
+	files := s.logFiles
+	s.logFiles = nil
+	syslog := s.logSyslog
+	s.logSyslog = nil
+	shutdown := s.logShutdown
+	s.mu.Unlock()
+
+	// Handlers must stop writing to the files before they are closed
+	shutdown.close()
+
+	if syslog != nil {
+		syslog.close(syslogTimeout)
+	}
+
+	for _, f := range files {
+		if err := errors.Join(f.Sync(), f.Close()); err != nil {
+			// The logger may write to the file being closed
+			_, _ = io.WriteString(os.Stderr, "failed to close log file "+f.Name()+": "+err.Error()+"\n")
+		}
+	}
+}
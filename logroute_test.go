@@ -0,0 +1,104 @@
+package as
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// logFileServices returns the service attribute of every JSON record in the file at path.
+func logFileServices(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var services []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid record %q: %v", scanner.Text(), err)
+		}
+		service, _ := record["service"].(string)
+		services = append(services, service)
+	}
+
+	return services
+}
+
+func TestLogRouteDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+
+	// Both services run in one process at the same time, each logging to its own file
+	var started sync.WaitGroup
+	started.Add(2)
+	var wg sync.WaitGroup
+	for _, name := range []string{"api", "worker"} {
+		wg.Go(func() {
+			svc := &testService{name: name, run: func(ctx context.Context) error {
+				started.Done()
+				started.Wait()
+				Logger(ctx).Info("hello from " + name)
+				return nil
+			}}
+
+			opts := testOptions(WithLogRouteDir(dir), WithLogJson(true))
+			if err := RunC(svc, context.Background(), opts...); err != nil {
+				t.Errorf("RunC(%s) = %v", name, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	for _, name := range []string{"api", "worker"} {
+		services := logFileServices(t, filepath.Join(dir, "astest-"+name+".log"))
+		if len(services) == 0 {
+			t.Errorf("no records in the log file of %s", name)
+		}
+		for _, service := range services {
+			if service != name {
+				t.Errorf("log file of %s contains a record of %q", name, service)
+			}
+		}
+	}
+}
+
+func TestLogRouteWriter(t *testing.T) {
+	dir := t.TempDir()
+	logs := &logCapture{}
+
+	svc := &testService{run: func(ctx context.Context) error {
+		Logger(ctx).Info("routed")
+		return nil
+	}}
+
+	// A writer takes precedence over the directory
+	opts := testOptions(WithLogRouteDir(dir), WithLogRouteWriter("astest", "test", logs), WithLogJson(true))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if logs.find("routed") == nil {
+		t.Error("record not written to the route writer")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("log directory contains %d files, want none", len(entries))
+	}
+}
+
+func TestLogRouteKey(t *testing.T) {
+	if got := logRouteKey("shop", "api"); got != "shop-api" {
+		t.Errorf("logRouteKey() = %q, want %q", got, "shop-api")
+	}
+	if got := logRouteKey("", "api"); got != "api" {
+		t.Errorf("logRouteKey() without namespace = %q, want %q", got, "api")
+	}
+}
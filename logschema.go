@@ -0,0 +1,169 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogSchema selects the field names used by the JSON log handler.
+type LogSchema string
+
+const (
+	// LogSchemaDefault uses the field names of slog.JSONHandler.
+	LogSchemaDefault LogSchema = "default"
+	// LogSchemaECS uses Elastic Common Schema field names (@timestamp, log.level, message, service.name).
+	LogSchemaECS LogSchema = "ecs"
+	// LogSchemaGCP uses the field names expected by Google Cloud Logging (time, severity, message).
+	LogSchemaGCP LogSchema = "gcp"
+	// LogSchemaDatadog uses the field names expected by Datadog (timestamp, status, message, service).
+	LogSchemaDatadog LogSchema = "datadog"
+)
+
+// replaceAttr returns the slog.HandlerOptions.ReplaceAttr func renaming the built-in keys of a record and mapping
+// its level to the vocabulary of the schema. It returns nil for the default schema.
+func (s LogSchema) replaceAttr() func(groups []string, a slog.Attr) slog.Attr {
+	var timeKey, levelKey, msgKey string
+	var levelValue func(slog.Level) string
+
+	switch s {
+	case LogSchemaECS:
+		timeKey, levelKey, msgKey = "@timestamp", "log.level", "message"
+		levelValue = lowerLevel
+	case LogSchemaGCP:
+		timeKey, levelKey, msgKey = "time", "severity", "message"
+		levelValue = gcpSeverity
+	case LogSchemaDatadog:
+		timeKey, levelKey, msgKey = "timestamp", "status", "message"
+		levelValue = lowerLevel
+	default:
+		return nil
+	}
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) > 0 {
+			return a
+		}
+
+		switch a.Key {
+		case slog.TimeKey:
+			a.Key = timeKey
+		case slog.LevelKey:
+			a.Key = levelKey
+			if level, ok := a.Value.Any().(slog.Level); ok {
+				a.Value = slog.StringValue(levelValue(level))
+			}
+		case slog.MessageKey:
+			a.Key = msgKey
+		}
+
+		return a
+	}
+}
+
+// serviceAttrs returns the attributes identifying the service, nested as expected by the schema.
+// Empty values are omitted.
+func (s LogSchema) serviceAttrs(name, version, namespace string) []any {
+	var attrs []any
+	add := func(key, value string) {
+		if value != "" {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+
+	switch s {
+	case LogSchemaECS:
+		add("name", name)
+		add("version", version)
+		add("namespace", namespace)
+		if len(attrs) > 0 {
+			return []any{slog.Group("service", attrs...)}
+		}
+		return nil
+	case LogSchemaGCP:
+		add("service", name)
+		add("version", version)
+
+		var result []any
+		if len(attrs) > 0 {
+			result = append(result, slog.Group("serviceContext", attrs...))
+		}
+		if namespace != "" {
+			result = append(result, slog.Group("logging.googleapis.com/labels", "namespace", namespace))
+		}
+		return result
+	default:
+		// The default and Datadog schemas use flat service, version and namespace keys
+		add("service", name)
+		add("version", version)
+		add("namespace", namespace)
+		return attrs
+	}
+}
+
+// lowerLevel returns the lower-case name of the level, e.g. "warn".
+func lowerLevel(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// gcpSeverity maps a slog level to a Google Cloud Logging severity.
+func gcpSeverity(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "DEBUG"
+	case level < slog.LevelWarn:
+		return "INFO"
+	case level < slog.LevelError:
+		return "WARNING"
+	case level < slog.LevelError+4:
+		return "ERROR"
+	default:
+		return "CRITICAL"
+	}
+}
+
+// gcpTraceHandler adds the Google Cloud Logging trace correlation fields of the span active in the context of a
+// record, so logs are shown alongside their traces.
+type gcpTraceHandler struct {
+	slog.Handler
+	project string
+}
+
+// newGCPTraceHandler wraps handler, adding trace correlation fields for the project. If project is empty, the
+// GOOGLE_CLOUD_PROJECT environment variable is used. Without a project, only the span ID is added.
+func newGCPTraceHandler(handler slog.Handler, project string) *gcpTraceHandler {
+	if project == "" {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+
+	return &gcpTraceHandler{Handler: handler, project: project}
+}
+
+// Handle adds the trace correlation fields to the record and passes it to the wrapped handler.
+func (h *gcpTraceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r = r.Clone()
+		if h.project != "" {
+			r.AddAttrs(slog.String("logging.googleapis.com/trace", "projects/"+h.project+"/traces/"+sc.TraceID().String()))
+		}
+		r.AddAttrs(
+			slog.String("logging.googleapis.com/spanId", sc.SpanID().String()),
+			slog.Bool("logging.googleapis.com/trace_sampled", sc.IsSampled()),
+		)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler adding trace correlation fields to the wrapped handler with the attributes.
+func (h *gcpTraceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &gcpTraceHandler{Handler: h.Handler.WithAttrs(attrs), project: h.project}
+}
+
+// WithGroup returns a handler adding trace correlation fields to the wrapped handler with the group.
+func (h *gcpTraceHandler) WithGroup(name string) slog.Handler {
+	return &gcpTraceHandler{Handler: h.Handler.WithGroup(name), project: h.project}
+}
@@ -0,0 +1,150 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// logSchemaRecord writes a single warning record with the service attributes through the JSON handler of the
+// schema and returns the output.
+func logSchemaRecord(t *testing.T, schema LogSchema) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	handler := slog.New(newWriterHandler(&buf, Options{LogJson: true}, schema)).
+		With(schema.serviceAttrs("test", "v1.0.0", "astest")...).
+		Handler()
+
+	record := slog.NewRecord(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), slog.LevelWarn, "disk almost full", 0)
+	record.AddAttrs(slog.Int("free_bytes", 1024))
+	if err := handler.Handle(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestLogSchema(t *testing.T) {
+	tests := []struct {
+		schema LogSchema
+		want   string
+	}{
+		{
+			schema: LogSchemaDefault,
+			want: `{"time":"2026-01-02T03:04:05Z","level":"WARN","msg":"disk almost full",` +
+				`"service":"test","version":"v1.0.0","namespace":"astest","free_bytes":1024}`,
+		},
+		{
+			schema: LogSchemaECS,
+			want: `{"@timestamp":"2026-01-02T03:04:05Z","log.level":"warn","message":"disk almost full",` +
+				`"service":{"name":"test","version":"v1.0.0","namespace":"astest"},"free_bytes":1024}`,
+		},
+		{
+			schema: LogSchemaGCP,
+			want: `{"time":"2026-01-02T03:04:05Z","severity":"WARNING","message":"disk almost full",` +
+				`"serviceContext":{"service":"test","version":"v1.0.0"},` +
+				`"logging.googleapis.com/labels":{"namespace":"astest"},"free_bytes":1024}`,
+		},
+		{
+			schema: LogSchemaDatadog,
+			want: `{"timestamp":"2026-01-02T03:04:05Z","status":"warn","message":"disk almost full",` +
+				`"service":"test","version":"v1.0.0","namespace":"astest","free_bytes":1024}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.schema), func(t *testing.T) {
+			if got := logSchemaRecord(t, tt.schema); got != tt.want+"\n" {
+				t.Errorf("record =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGCPSeverity(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{slog.LevelDebug, "DEBUG"},
+		{slog.LevelInfo, "INFO"},
+		{slog.LevelWarn, "WARNING"},
+		{slog.LevelError, "ERROR"},
+		{slog.LevelError + 4, "CRITICAL"},
+	}
+
+	for _, tt := range tests {
+		if got := gcpSeverity(tt.level); got != tt.want {
+			t.Errorf("gcpSeverity(%s) = %s, want %s", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestGCPTraceHandler(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	tests := []struct {
+		name    string
+		project string
+		env     string
+		ctx     context.Context
+		want    string
+	}{
+		{
+			name:    "project",
+			project: "my-project",
+			ctx:     ctx,
+			want: `{"time":"2026-01-02T03:04:05Z","severity":"INFO","message":"handled",` +
+				`"logging.googleapis.com/trace":"projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",` +
+				`"logging.googleapis.com/spanId":"00f067aa0ba902b7","logging.googleapis.com/trace_sampled":true}`,
+		},
+		{
+			name: "project from env",
+			env:  "env-project",
+			ctx:  ctx,
+			want: `{"time":"2026-01-02T03:04:05Z","severity":"INFO","message":"handled",` +
+				`"logging.googleapis.com/trace":"projects/env-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",` +
+				`"logging.googleapis.com/spanId":"00f067aa0ba902b7","logging.googleapis.com/trace_sampled":true}`,
+		},
+		{
+			name: "without project",
+			ctx:  ctx,
+			want: `{"time":"2026-01-02T03:04:05Z","severity":"INFO","message":"handled",` +
+				`"logging.googleapis.com/spanId":"00f067aa0ba902b7","logging.googleapis.com/trace_sampled":true}`,
+		},
+		{
+			name:    "without span",
+			project: "my-project",
+			ctx:     context.Background(),
+			want:    `{"time":"2026-01-02T03:04:05Z","severity":"INFO","message":"handled"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOOGLE_CLOUD_PROJECT", tt.env)
+
+			var buf bytes.Buffer
+			handler := newGCPTraceHandler(newWriterHandler(&buf, Options{LogJson: true}, LogSchemaGCP), tt.project)
+			record := slog.NewRecord(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), slog.LevelInfo, "handled", 0)
+			if err := handler.Handle(tt.ctx, record); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.String(); got != tt.want+"\n" {
+				t.Errorf("record =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
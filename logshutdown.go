@@ -0,0 +1,110 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// lateLogRecords counts the records logged by any service after its log outputs were closed, see
+// reportLateLogRecords.
+var lateLogRecords atomic.Int64
+
+// logShutdown is the state shared by a shutdownAwareHandler and all handlers derived from it. Handlers hold mu for
+// reading while passing a record to the log outputs, so that close waits for the records in flight.
+type logShutdown struct {
+	mu     sync.RWMutex
+	closed bool
+}
+
+// shutdownAwareHandler is a slog.Handler passing records to the log outputs of the service until they are closed,
+// and to a plain text handler writing to stderr afterward, e.g. for records of goroutines outliving the service.
+// It never panics: a panic of the wrapped handler is recovered and the record is written to stderr instead.
+type shutdownAwareHandler struct {
+	slog.Handler
+	fallback slog.Handler
+	state    *logShutdown
+}
+
+// initLogShutdown wraps logger so that records logged after the supervisor closed the log outputs (see
+// closeLogRoutes) are written to stderr.
+func initLogShutdown(ctx context.Context, sup *supervisor, logger *slog.Logger) *slog.Logger {
+	sup.logShutdown = &logShutdown{}
+
+	fallback := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}).
+		WithAttrs([]slog.Attr{slog.String("service", Name(ctx)), slog.Bool("late", true)})
+
+	return slog.New(&shutdownAwareHandler{Handler: logger.Handler(), fallback: fallback, state: sup.logShutdown})
+}
+
+// Handle passes the record to the wrapped handler, or to stderr once the log outputs are closed.
+func (h *shutdownAwareHandler) Handle(ctx context.Context, r slog.Record) (err error) {
+	h.state.mu.RLock()
+	if h.state.closed {
+		h.state.mu.RUnlock()
+		return h.handleLate(ctx, r)
+	}
+	defer h.state.mu.RUnlock()
+
+	defer func() {
+		if cause := recover(); cause != nil {
+			r.AddAttrs(slog.String("log_handler_panic", fmt.Sprint(cause)))
+			err = h.handleLate(ctx, r)
+		}
+	}()
+
+	return h.Handler.Handle(ctx, r)
+}
+
+// handleLate counts the record and writes it to stderr, dropping it if even that panics.
+func (h *shutdownAwareHandler) handleLate(ctx context.Context, r slog.Record) (err error) {
+	lateLogRecords.Add(1)
+
+	defer func() {
+		if recover() != nil {
+			err = nil
+		}
+	}()
+
+	return h.fallback.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler passing records with the attributes to the wrapped handler or stderr.
+func (h *shutdownAwareHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &shutdownAwareHandler{
+		Handler:  h.Handler.WithAttrs(attrs),
+		fallback: h.fallback.WithAttrs(attrs),
+		state:    h.state,
+	}
+}
+
+// WithGroup returns a handler passing records with the group to the wrapped handler or stderr.
+func (h *shutdownAwareHandler) WithGroup(name string) slog.Handler {
+	return &shutdownAwareHandler{
+		Handler:  h.Handler.WithGroup(name),
+		fallback: h.fallback.WithGroup(name),
+		state:    h.state,
+	}
+}
+
+// close switches the handlers to stderr, waiting for the records being written to the log outputs. It must be
+// called before the log outputs are closed.
+func (s *logShutdown) close() {
+	if s != nil {
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+	}
+}
+
+// reportLateLogRecords writes a line to stderr if records were logged after the log outputs of a service were
+// closed, and resets the count. It is called by RunAndExit right before it returns or exits, so it is best-effort:
+// records logged later are not counted.
+func reportLateLogRecords() {
+	if n := lateLogRecords.Swap(0); n > 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "%d log records were written to stderr after shutdown\n", n)
+	}
+}
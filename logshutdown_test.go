@@ -0,0 +1,164 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// panicHandler is a slog.Handler panicking on every record.
+type panicHandler struct{ slog.Handler }
+
+func (panicHandler) Handle(context.Context, slog.Record) error { panic("boom") }
+
+func TestLateLogRecords(t *testing.T) {
+	lateLogRecords.Store(0)
+	output := captureStreams(t)
+	dir := t.TempDir()
+
+	var late *slog.Logger
+	svc := &testService{run: func(ctx context.Context) error {
+		late = Logger(ctx).With("worker", 1)
+		late.Info("running")
+		return nil
+	}}
+
+	if err := RunC(svc, context.Background(), testOptions(WithLogRouteDir(dir))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// A goroutine outliving the service logs after the log file was closed
+	late.Info("late record", "key", "value")
+
+	logFile, err := os.ReadFile(filepath.Join(dir, "astest-test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logFile), "running") || strings.Contains(string(logFile), "late record") {
+		t.Errorf("log file = %q, want only the record logged while running", logFile)
+	}
+
+	_, stderr := output()
+	for _, want := range []string{"late record", "key=value", "worker=1", "service=test", "late=true"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr = %q, want it to contain %q", stderr, want)
+		}
+	}
+
+	reportLateLogRecords()
+	if _, stderr := output(); !strings.Contains(stderr, "1 log records were written to stderr after shutdown") {
+		t.Errorf("stderr = %q, want the count of late records", stderr)
+	}
+
+	// The count is reset once reported
+	reportLateLogRecords()
+	if _, stderr := output(); strings.Count(stderr, "after shutdown") != 1 {
+		t.Errorf("stderr = %q, want the count reported once", stderr)
+	}
+}
+
+func TestShutdownAwareHandlerPanic(t *testing.T) {
+	lateLogRecords.Store(0)
+	var fallback bytes.Buffer
+	h := &shutdownAwareHandler{
+		Handler:  panicHandler{slog.NewTextHandler(io.Discard, nil)},
+		fallback: slog.NewTextHandler(&fallback, nil),
+		state:    &logShutdown{},
+	}
+
+	// A panic of the wrapped handler is recovered and the record written to the fallback
+	slog.New(h).Info("record")
+	if got := fallback.String(); !strings.Contains(got, "record") || !strings.Contains(got, "log_handler_panic=boom") {
+		t.Errorf("fallback = %q, want the record and the panic", got)
+	}
+
+	// If even the fallback panics, the record is dropped
+	h.fallback = panicHandler{slog.NewTextHandler(io.Discard, nil)}
+	if err := h.Handle(context.Background(), slog.Record{}); err != nil {
+		t.Errorf("Handle() = %v, want the record dropped", err)
+	}
+	if n := lateLogRecords.Swap(0); n != 2 {
+		t.Errorf("late records = %d, want 2", n)
+	}
+}
+
+func TestShutdownAwareHandlerClosed(t *testing.T) {
+	var logs, fallback bytes.Buffer
+	state := &logShutdown{}
+	logger := slog.New(&shutdownAwareHandler{
+		Handler:  slog.NewTextHandler(&logs, nil),
+		fallback: slog.NewTextHandler(&fallback, nil),
+		state:    state,
+	}).WithGroup("group").With("key", "value")
+
+	logger.Info("before")
+	state.close()
+	logger.Info("after")
+	lateLogRecords.Store(0)
+
+	// Derived loggers share the state, keeping their attributes and groups on stderr
+	if got := logs.String(); !strings.Contains(got, "before") || strings.Contains(got, "after") {
+		t.Errorf("output = %q, want only the record before closing", got)
+	}
+	if got := fallback.String(); !strings.Contains(got, "after") || !strings.Contains(got, "group.key=value") {
+		t.Errorf("fallback = %q, want the record after closing", got)
+	}
+
+	// Closing the state of a supervisor without a logger does not panic
+	var none *logShutdown
+	none.close()
+}
+
+// blockingHandler is a slog.Handler blocking on every record until release is closed.
+type blockingHandler struct {
+	slog.Handler
+	handling chan struct{}
+	release  chan struct{}
+}
+
+func (h blockingHandler) Handle(context.Context, slog.Record) error {
+	close(h.handling)
+	<-h.release
+	return nil
+}
+
+func TestShutdownAwareHandlerDrain(t *testing.T) {
+	h := blockingHandler{Handler: slog.NewTextHandler(io.Discard, nil), handling: make(chan struct{}), release: make(chan struct{})}
+	state := &logShutdown{}
+	logger := slog.New(&shutdownAwareHandler{Handler: h, fallback: slog.DiscardHandler, state: state})
+
+	logged := make(chan struct{})
+	go func() {
+		logger.Info("in flight")
+		close(logged)
+	}()
+	<-h.handling
+
+	// Closing waits for the record being written, so the log outputs are not closed under it
+	closed := make(chan struct{})
+	go func() {
+		state.close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("close returned while a record was being written")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(h.release)
+	<-logged
+	<-closed
+
+	lateLogRecords.Store(0)
+	logger.Info("late")
+	if n := lateLogRecords.Swap(0); n != 1 {
+		t.Errorf("late records = %d, want 1", n)
+	}
+}
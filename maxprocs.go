@@ -0,0 +1,72 @@
+package as
+
+import (
+	"context"
+	"math"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// initMaxProcs sets GOMAXPROCS to the CPU quota of the cgroup the process is running in, if any.
+// It returns a function restoring the default of the runtime, which is a no-op if nothing was changed.
+//
+// Since Go 1.25, the default GOMAXPROCS of the runtime already follows the CPU limit of the cgroup, and setting it
+// would stop the runtime from updating it when the limit changes. So nothing is changed unless the cgroup-aware
+// default is disabled with GODEBUG=cgroupgomaxprocs=0. Nothing is changed either when AutoMaxProcs is disabled,
+// when the GOMAXPROCS environment variable is set explicitly, when not running on Linux, or when the process is not
+// subject to a CPU quota.
+func initMaxProcs(ctx context.Context, opts Options) func() {
+	restore := func() {}
+	if !opts.AutoMaxProcs || runtime.GOOS != "linux" {
+		return restore
+	}
+
+	if v, ok := os.LookupEnv("GOMAXPROCS"); ok {
+		Logger(ctx).Debug("GOMAXPROCS set explicitly, not adjusting to CPU quota", "gomaxprocs", v)
+		return restore
+	}
+	if !cgroupMaxProcsDisabled() {
+		Logger(ctx).Debug("GOMAXPROCS follows the CPU quota by default", "gomaxprocs", runtime.GOMAXPROCS(0))
+		return restore
+	}
+
+	quota, ok, err := cgroupCPUQuota("/")
+	if err != nil {
+		Logger(ctx).Warn("failed to read cgroup CPU quota, not adjusting GOMAXPROCS", "error", err)
+		return restore
+	}
+	if !ok {
+		return restore
+	}
+
+	procs := max(int(math.Floor(quota)), 1)
+	previous := runtime.GOMAXPROCS(0)
+	if procs == previous {
+		return restore
+	}
+
+	runtime.GOMAXPROCS(procs)
+	Logger(ctx).Info(
+		"adjusted GOMAXPROCS to CPU quota",
+		"cpu_quota", quota,
+		"gomaxprocs_previous", previous,
+		"gomaxprocs", procs,
+	)
+
+	return runtime.SetDefaultGOMAXPROCS
+}
+
+// cgroupMaxProcsDisabled reports whether the cgroup-aware default GOMAXPROCS of the runtime is disabled by the
+// GODEBUG environment variable. Settings of //go:debug directives are not visible.
+func cgroupMaxProcsDisabled() bool {
+	disabled := false
+	for _, setting := range strings.Split(os.Getenv("GODEBUG"), ",") {
+		// The last setting wins
+		if key, value, ok := strings.Cut(strings.TrimSpace(setting), "="); ok && key == "cgroupgomaxprocs" {
+			disabled = value == "0"
+		}
+	}
+
+	return disabled
+}
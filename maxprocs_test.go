@@ -0,0 +1,58 @@
+package as
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestInitMaxProcsUnchanged(t *testing.T) {
+	previous := runtime.GOMAXPROCS(0)
+
+	tests := []struct {
+		name string
+		opts Options
+		env  map[string]string
+	}{
+		{name: "disabled", opts: Options{AutoMaxProcs: false}},
+		{name: "explicit GOMAXPROCS", opts: Options{AutoMaxProcs: true}, env: map[string]string{"GOMAXPROCS": "1"}},
+		// The runtime follows the CPU quota itself
+		{name: "runtime default", opts: Options{AutoMaxProcs: true}, env: map[string]string{"GODEBUG": "cgroupgomaxprocs=1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			restore := initMaxProcs(context.Background(), tt.opts)
+			if got := runtime.GOMAXPROCS(0); got != previous {
+				t.Errorf("GOMAXPROCS = %d, want %d", got, previous)
+			}
+			restore()
+		})
+	}
+}
+
+func TestCgroupMaxProcsDisabled(t *testing.T) {
+	tests := []struct {
+		godebug string
+		want    bool
+	}{
+		{godebug: ""},
+		{godebug: "cgroupgomaxprocs=0", want: true},
+		{godebug: "madvdontneed=1, cgroupgomaxprocs=0", want: true},
+		{godebug: "cgroupgomaxprocs=0,cgroupgomaxprocs=1"},
+		{godebug: "cgroupgomaxprocs=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.godebug, func(t *testing.T) {
+			t.Setenv("GODEBUG", tt.godebug)
+			if got := cgroupMaxProcsDisabled(); got != tt.want {
+				t.Errorf("cgroupMaxProcsDisabled() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
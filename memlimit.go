@@ -0,0 +1,109 @@
+package as
+
+import (
+	"context"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"go.aledante.io/ae"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// initMemLimit sets the Go runtime soft memory limit (see debug.SetMemoryLimit) to MemLimitRatio of the memory
+// limit of the cgroup the process is running in, if any. It returns a function restoring the original limit,
+// which is a no-op if nothing was changed. The chosen limit is recorded on the supervisor, published via expvar
+// and reported by the as.memory.limit metric (see initMemLimitMetric); the effective limit is also reported by
+// the go.memory.limit runtime metric.
+//
+// Nothing is changed when AutoMemLimit is disabled, when the GOMEMLIMIT environment variable is set explicitly,
+// when not running on Linux, or when the process is not subject to a memory limit.
+func initMemLimit(ctx context.Context, opts Options) func() {
+	restore := func() {}
+	if !opts.AutoMemLimit || runtime.GOOS != "linux" {
+		return restore
+	}
+
+	if v, ok := os.LookupEnv("GOMEMLIMIT"); ok {
+		Logger(ctx).Debug("GOMEMLIMIT set explicitly, not adjusting to cgroup memory limit", "gomemlimit", v)
+		return restore
+	}
+
+	if opts.MemLimitRatio <= 0 || opts.MemLimitRatio > 1 {
+		Logger(ctx).Warn(
+			"invalid memory limit ratio, not adjusting GOMEMLIMIT",
+			"memlimit_ratio", opts.MemLimitRatio,
+		)
+		return restore
+	}
+
+	limit, ok, err := cgroupMemoryLimit("/")
+	if err != nil {
+		Logger(ctx).Warn("failed to read cgroup memory limit, not adjusting GOMEMLIMIT", "error", err)
+		return restore
+	}
+	if !ok {
+		return restore
+	}
+
+	memLimit := memLimitFraction(limit, opts.MemLimitRatio)
+	previous := debug.SetMemoryLimit(memLimit)
+	sup := supervisorFrom(ctx)
+	if sup != nil {
+		sup.mu.Lock()
+		sup.memLimit = memLimit
+		sup.mu.Unlock()
+	}
+	Logger(ctx).Info(
+		"adjusted GOMEMLIMIT to cgroup memory limit",
+		"cgroup_memory_limit", limit,
+		"memlimit_ratio", opts.MemLimitRatio,
+		"gomemlimit_previous", previous,
+		"gomemlimit", memLimit,
+	)
+
+	return func() {
+		debug.SetMemoryLimit(previous)
+		if sup != nil {
+			sup.mu.Lock()
+			sup.memLimit = 0
+			sup.mu.Unlock()
+		}
+	}
+}
+
+// initMemLimitMetric registers the as.memory.limit gauge, reporting the GOMEMLIMIT chosen by initMemLimit in bytes
+// on every collection while one is set. It is called once the meter of the service is available.
+func initMemLimitMetric(ctx context.Context) error {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		return nil
+	}
+
+	_, err := Meter(ctx).Int64ObservableGauge(
+		"as.memory.limit",
+		metric.WithDescription("Go runtime soft memory limit derived from the cgroup memory limit"),
+		metric.WithUnit("By"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			sup.mu.Lock()
+			limit := sup.memLimit
+			sup.mu.Unlock()
+
+			if limit > 0 {
+				o.Observe(limit)
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return ae.Wrap("failed to register memory limit metric", err)
+	}
+
+	return nil
+}
+
+// memLimitFraction returns ratio of limit in bytes, rounded down.
+func memLimitFraction(limit int64, ratio float64) int64 {
+	return int64(math.Floor(float64(limit) * ratio))
+}
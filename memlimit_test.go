@@ -0,0 +1,69 @@
+package as
+
+import (
+	"context"
+	"testing"
+
+	metricSdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMemLimitFraction(t *testing.T) {
+	tests := []struct {
+		limit int64
+		ratio float64
+		want  int64
+	}{
+		{limit: 1000, ratio: 1, want: 1000},
+		{limit: 1000, ratio: 0.9, want: 900},
+		{limit: 1001, ratio: 0.5, want: 500},
+		{limit: 536870912, ratio: 0.9, want: 483183820},
+	}
+
+	for _, tt := range tests {
+		if got := memLimitFraction(tt.limit, tt.ratio); got != tt.want {
+			t.Errorf("memLimitFraction(%d, %v) = %d, want %d", tt.limit, tt.ratio, got, tt.want)
+		}
+	}
+}
+
+func TestMemLimitMetric(t *testing.T) {
+	reader := metricSdk.NewManualReader()
+	provider := metricSdk.NewMeterProvider(metricSdk.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	sup := newSupervisor()
+	ctx := withSupervisor(context.Background(), sup)
+	ctx = withMeter(ctx, provider.Meter("test"))
+	if err := initMemLimitMetric(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func() []metricdata.DataPoint[int64] {
+		var rm metricdata.ResourceMetrics
+		if err := reader.Collect(context.Background(), &rm); err != nil {
+			t.Fatal(err)
+		}
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if m.Name == "as.memory.limit" {
+					return m.Data.(metricdata.Gauge[int64]).DataPoints
+				}
+			}
+		}
+		return nil
+	}
+
+	if points := collect(); len(points) != 0 {
+		t.Fatalf("got %d data points without a limit, want none", len(points))
+	}
+
+	sup.mu.Lock()
+	sup.memLimit = 483183820
+	sup.mu.Unlock()
+
+	points := collect()
+	if len(points) != 1 || points[0].Value != 483183820 {
+		t.Fatalf("data points = %+v, want one with the memory limit", points)
+	}
+}
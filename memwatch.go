@@ -0,0 +1,157 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MemorySample is a sample of the memory usage of the process taken by the memory watchdog.
+type MemorySample struct {
+	// HeapInuse is the number of bytes in in-use heap spans.
+	HeapInuse uint64
+	// RSS is the resident set size of the process in bytes, or zero where it is not available.
+	RSS uint64
+}
+
+// usage returns the larger of the heap in use and the RSS, which is compared to the limits.
+func (s MemorySample) usage() uint64 {
+	return max(s.HeapInuse, s.RSS)
+}
+
+// memoryLimitError is the cause of a restart of the service by the memory watchdog. It wraps ErrRestartRequested,
+// so the service is closed and run again like after a requested restart.
+type memoryLimitError struct {
+	sample MemorySample
+	limit  uint64
+}
+
+// Error returns the error message, including the sampled usage and the limit.
+func (e *memoryLimitError) Error() string {
+	return fmt.Sprintf("memory limit exceeded: heap in use %d bytes, rss %d bytes, limit %d bytes",
+		e.sample.HeapInuse, e.sample.RSS, e.limit)
+}
+
+// Unwrap returns ErrRestartRequested.
+func (e *memoryLimitError) Unwrap() error {
+	return ErrRestartRequested
+}
+
+// sampleMemory returns the heap in use and, on systems exposing /proc/self/statm, the RSS of the process.
+func sampleMemory() MemorySample {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	sample := MemorySample{HeapInuse: ms.HeapInuse}
+	if data, err := os.ReadFile("/proc/self/statm"); err == nil {
+		// statm lists sizes in pages: total program size, then resident set size
+		if fields := strings.Fields(string(data)); len(fields) > 1 {
+			if pages, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				sample.RSS = pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+
+	return sample
+}
+
+// initMemoryWatchdog starts sampling the memory usage every MemoryCheckInterval if MemoryLimit is set. Usage above
+// MemorySoftLimitRatio of the limit is logged as a warning once per crossing; usage above the limit while the
+// service is running restarts it gracefully. It returns a function stopping the watchdog.
+func initMemoryWatchdog(ctx context.Context, opts Options) func() {
+	if opts.MemoryLimit == 0 || opts.MemoryCheckInterval <= 0 {
+		return func() {}
+	}
+
+	sampler := opts.MemorySampler
+	if sampler == nil {
+		sampler = sampleMemory
+	}
+	softLimit := uint64(float64(opts.MemoryLimit) * opts.MemorySoftLimitRatio)
+
+	restarts, _ := Meter(ctx).Int64Counter(
+		"as.memory.restarts",
+		metric.WithDescription("Number of restarts of the service caused by exceeding the memory limit"),
+	)
+
+	sup := supervisorFrom(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(opts.MemoryCheckInterval)
+		defer ticker.Stop()
+
+		aboveSoft := false
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			sample := sampler()
+			usage := sample.usage()
+
+			if softLimit > 0 && usage > softLimit && usage <= opts.MemoryLimit {
+				if !aboveSoft {
+					Logger(ctx).Warn("memory usage above soft limit",
+						"heap_inuse", sample.HeapInuse,
+						"rss", sample.RSS,
+						"soft_limit", softLimit,
+						"limit", opts.MemoryLimit,
+					)
+				}
+				aboveSoft = true
+			} else if usage <= softLimit {
+				aboveSoft = false
+			}
+
+			// The limit is only enforced while Run executes, so a restart in progress is not interrupted
+			if usage <= opts.MemoryLimit || sup.State() != StateRunning {
+				continue
+			}
+
+			Logger(ctx).Error("memory limit exceeded, restarting service",
+				"heap_inuse", sample.HeapInuse,
+				"rss", sample.RSS,
+				"limit", opts.MemoryLimit,
+			)
+			if restarts != nil {
+				restarts.Add(ctx, 1)
+			}
+			sup.failAttempt(&memoryLimitError{sample: sample, limit: opts.MemoryLimit})
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// flushBeforeRestart flushes the OTEL providers of the service, if initialized, for at most ShutdownTimeout, so
+// telemetry recorded before a restart by the memory watchdog is exported.
+func (s *supervisor) flushBeforeRestart(ctx context.Context, opts Options) {
+	s.mu.Lock()
+	flush := s.flushTelemetry
+	s.mu.Unlock()
+
+	if flush == nil {
+		return
+	}
+
+	flushCtx, cancel := shutdownContext(ctx, opts.ShutdownTimeout)
+	defer cancel()
+
+	if err := flush(flushCtx); err != nil {
+		Logger(ctx).Warn("failed to flush OTEL before restart", "error", err)
+	}
+}
@@ -0,0 +1,154 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// growingSampler returns a sampler reporting the usages in turn, repeating the last one.
+func growingSampler(usages ...uint64) func() MemorySample {
+	var calls atomic.Int64
+	return func() MemorySample {
+		i := min(int(calls.Add(1))-1, len(usages)-1)
+		return MemorySample{HeapInuse: usages[i]}
+	}
+}
+
+// memoryOptions returns options running the memory watchdog every 10 seconds with a limit of 100 bytes.
+func memoryOptions(sampler func() MemorySample) Options {
+	opts := DefaultOptions()
+	opts.GraceCount = 0
+	opts.GracePeriod = 0
+	opts.RequestedRestartDelay = 0
+	opts.MemoryLimit = 100
+	opts.MemoryCheckInterval = 10 * time.Second
+	opts.MemorySampler = sampler
+
+	return opts
+}
+
+func TestMemoryWatchdog(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, reader := testMeterContext(t, context.Background())
+		logs := &logCapture{}
+		ctx = WithLogger(ctx, slog.New(slog.NewJSONHandler(logs, nil)))
+		ctx = withSupervisor(ctx, newSupervisor())
+
+		// Usage grows above the soft limit of 80 bytes, then above the limit; it is low again after the restart
+		opts := memoryOptions(growingSampler(50, 90, 95, 150, 10))
+		defer initMemoryWatchdog(ctx, opts)()
+
+		runs, closes := 0, 0
+		var restartedAt time.Duration
+		start := time.Now()
+		svc := &testService{
+			run: func(ctx context.Context) error {
+				if runs++; runs == 1 {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				restartedAt = time.Since(start)
+				return nil
+			},
+			close: func(ctx context.Context) error {
+				closes++
+				return nil
+			},
+		}
+
+		if err := runLoop(svc, ctx, opts, nil); err != nil {
+			t.Fatalf("runLoop() = %v", err)
+		}
+
+		// The fourth sample exceeds the limit, restarting the service gracefully; Close is called for both runs
+		if runs != 2 || closes != 2 || restartedAt != 40*time.Second {
+			t.Errorf("runs = %d, closes = %d, restarted after %s, want a graceful restart after 40s", runs, closes, restartedAt)
+		}
+
+		var soft, exceeded int
+		for _, record := range logs.records() {
+			switch record["msg"] {
+			case "memory usage above soft limit":
+				soft++
+			case "memory limit exceeded, restarting service":
+				exceeded++
+				if record["heap_inuse"] != float64(150) || record["limit"] != float64(100) {
+					t.Errorf("limit logged as %v", record)
+				}
+			}
+		}
+		if soft != 1 || exceeded != 1 {
+			t.Errorf("logged %d soft limit warnings and %d restarts, want one each", soft, exceeded)
+		}
+
+		restart := logs.find("service requested restart")
+		if restart == nil || restart["reason"] != (&memoryLimitError{sample: MemorySample{HeapInuse: 150}, limit: 100}).Error() {
+			t.Errorf("restart logged as %v", restart)
+		}
+		if got := counterTotal(t, reader, "as.memory.restarts"); got != 1 {
+			t.Errorf("as.memory.restarts = %d, want 1", got)
+		}
+	})
+}
+
+func TestMemoryWatchdogGrace(t *testing.T) {
+	tests := []struct {
+		name          string
+		countsAgainst bool
+		wantErr       bool
+		wantRuns      int
+	}{
+		// Exempt restarts continue until the service succeeds on its fourth run
+		{name: "exempt", wantRuns: 4},
+		// Counted restarts exceed the grace count on the second one
+		{name: "counted", countsAgainst: true, wantErr: true, wantRuns: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+				ctx = withSupervisor(ctx, newSupervisor())
+
+				opts := memoryOptions(growingSampler(150))
+				opts.GraceCount = 1
+				opts.MemoryRestartCountsAgainstGrace = tt.countsAgainst
+				defer initMemoryWatchdog(ctx, opts)()
+
+				runs := 0
+				svc := &testService{run: func(ctx context.Context) error {
+					if runs++; runs == 4 {
+						return nil
+					}
+					<-ctx.Done()
+					return nil
+				}}
+
+				err := runLoop(svc, ctx, opts, nil)
+				var memErr *memoryLimitError
+				if tt.wantErr != errors.As(err, &memErr) {
+					t.Errorf("runLoop() = %v, want the memory limit error %t", err, tt.wantErr)
+				}
+				if runs != tt.wantRuns {
+					t.Errorf("runs = %d, want %d", runs, tt.wantRuns)
+				}
+			})
+		})
+	}
+}
+
+func TestMemoryWatchdogDisabled(t *testing.T) {
+	opts := memoryOptions(func() MemorySample {
+		t.Error("memory sampled with the watchdog disabled")
+		return MemorySample{}
+	})
+	opts.MemoryLimit = 0
+
+	stop := initMemoryWatchdog(context.Background(), opts)
+	stop()
+}
@@ -0,0 +1,76 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMinimumRunDuration(t *testing.T) {
+	errFailed := errors.New("failed to listen")
+
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context) error
+		wantErr error
+	}{
+		{name: "quick nil", run: func(ctx context.Context) error { return nil }},
+		{name: "quick error", run: func(ctx context.Context) error { return errFailed }, wantErr: errFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			svc := &testService{run: func(ctx context.Context) error {
+				attempts++
+				return tt.run(ctx)
+			}}
+
+			err := RunC(svc, context.Background(), testOptions(
+				WithMinimumRunDuration(time.Minute),
+				WithGraceCount(2),
+			)...)
+			if err == nil {
+				t.Fatal("RunC() = nil, want an error")
+			}
+			if !strings.Contains(err.Error(), "below minimum run duration") {
+				t.Errorf("RunC() = %v, want the minimum run duration error", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("RunC() = %v, want it to wrap %v", err, tt.wantErr)
+			}
+
+			// The early exits are failures subject to the restart policy
+			if attempts != 3 {
+				t.Errorf("attempts = %d, want 3", attempts)
+			}
+		})
+	}
+}
+
+func TestMinimumRunDurationCancelled(t *testing.T) {
+	running := make(chan struct{})
+	svc := &testService{run: func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		return nil
+	}}
+
+	cancel, done := runTest(t, svc, WithMinimumRunDuration(time.Minute))
+	<-running
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("RunC() = %v, want nil after cancellation", err)
+	}
+}
+
+func TestMinimumRunDurationDisabled(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error { return nil }}
+
+	if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+		t.Errorf("RunC() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,52 @@
+package as
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"go.aledante.io/ae"
+)
+
+// namespaceDefaults is the process-global registry of default options per namespace.
+var namespaceDefaults = struct {
+	mu      sync.Mutex
+	opts    map[string][]Option
+	started bool
+}{opts: make(map[string][]Option)}
+
+// RegisterNamespaceDefaults registers options applied to every service of the namespace, after DefaultOptions and
+// before the options passed to Run, so both those options and env vars take precedence. It is typically called
+// from the init function of a package shared by the services of a namespace; repeated calls append options.
+//
+// Registering after the first service started returns an error, since services already running would not see
+// the defaults.
+func RegisterNamespaceDefaults(namespace string, opts ...Option) error {
+	namespaceDefaults.mu.Lock()
+	defer namespaceDefaults.mu.Unlock()
+
+	if namespaceDefaults.started {
+		return ae.New().Msg(fmt.Sprintf("cannot register defaults of namespace %s after a service started", namespace))
+	}
+
+	key := normalizeIdentity(namespace)
+	namespaceDefaults.opts[key] = append(namespaceDefaults.opts[key], opts...)
+
+	return nil
+}
+
+// namespaceOptions returns the default options registered for the namespace.
+func namespaceOptions(namespace string) []Option {
+	namespaceDefaults.mu.Lock()
+	defer namespaceDefaults.mu.Unlock()
+
+	return slices.Clone(namespaceDefaults.opts[namespace])
+}
+
+// closeNamespaceDefaults rejects further registrations of namespace defaults once a service starts.
+func closeNamespaceDefaults() {
+	namespaceDefaults.mu.Lock()
+	defer namespaceDefaults.mu.Unlock()
+
+	namespaceDefaults.started = true
+}
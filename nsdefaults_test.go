@@ -0,0 +1,76 @@
+package as
+
+import (
+	"context"
+	"testing"
+)
+
+// resetNamespaceDefaults clears the namespace defaults registry for the test, restoring it afterwards.
+func resetNamespaceDefaults(t *testing.T) {
+	t.Helper()
+
+	namespaceDefaults.mu.Lock()
+	opts, started := namespaceDefaults.opts, namespaceDefaults.started
+	namespaceDefaults.opts, namespaceDefaults.started = make(map[string][]Option), false
+	namespaceDefaults.mu.Unlock()
+
+	t.Cleanup(func() {
+		namespaceDefaults.mu.Lock()
+		defer namespaceDefaults.mu.Unlock()
+
+		namespaceDefaults.opts, namespaceDefaults.started = opts, started
+	})
+}
+
+func TestNamespaceDefaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		env       string
+		opts      []Option
+		wantGrace int
+	}{
+		{name: "applied", namespace: "billing", wantGrace: 5},
+		{name: "other namespace", namespace: "tools", wantGrace: DefaultOptions().GraceCount},
+		{name: "explicit option", namespace: "billing", opts: []Option{WithGraceCount(2)}, wantGrace: 2},
+		{name: "env var", namespace: "billing", env: "9", opts: []Option{WithGraceCount(2)}, wantGrace: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetNamespaceDefaults(t)
+			if err := RegisterNamespaceDefaults("Billing", WithGraceCount(5)); err != nil {
+				t.Fatalf("RegisterNamespaceDefaults() = %v", err)
+			}
+			if err := RegisterNamespaceDefaults("billing", WithRestartOnError(false)); err != nil {
+				t.Fatalf("RegisterNamespaceDefaults() = %v", err)
+			}
+			if tt.env != "" {
+				t.Setenv("BILLING_API_GRACE_COUNT", tt.env)
+			}
+
+			o := applyOptions("api", tt.namespace, tt.opts)
+			if o.GraceCount != tt.wantGrace {
+				t.Errorf("GraceCount = %d, want %d", o.GraceCount, tt.wantGrace)
+			}
+			// Repeated registrations are combined
+			if o.RestartOnError != (tt.namespace != "billing") {
+				t.Errorf("RestartOnError = %t, want it set by the second registration", o.RestartOnError)
+			}
+		})
+	}
+}
+
+func TestNamespaceDefaultsAfterStart(t *testing.T) {
+	resetNamespaceDefaults(t)
+
+	if err := RunC(&testService{run: func(ctx context.Context) error { return nil }}, context.Background(), testOptions()...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if err := RegisterNamespaceDefaults("billing", WithGraceCount(5)); err == nil {
+		t.Error("RegisterNamespaceDefaults() = nil after a service started")
+	}
+	if opts := namespaceOptions("billing"); len(opts) != 0 {
+		t.Errorf("%d options registered after a service started", len(opts))
+	}
+}
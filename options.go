@@ -1,9 +1,15 @@
 package as
 
 import (
+	"context"
+	"io"
+	"os"
+	"syscall"
 	"time"
 
 	"github.com/caarlos0/env/v11"
+	"go.aledante.io/ae"
+	traceSdk "go.opentelemetry.io/otel/sdk/trace"
 )
 
 // Options defines the configuration parameters for the lifecycle and supervision
@@ -36,22 +42,151 @@ type Options struct {
 	// GraceCount is the total number of allowed restarts after the first start.
 	// If set to zero, there is no limit.
 	GraceCount int `env:"GRACE_COUNT"`
+	// MaxLifetimeRestarts is a hard limit on the number of restarts since the process started, independent of
+	// GracePeriod and GraceCount. Once exceeded, the supervisor gives up. Zero disables the limit.
+	MaxLifetimeRestarts int `env:"MAX_LIFETIME_RESTARTS"`
+	// LeakCheckAttempts enables the detection of goroutines leaked by restarting services: if the number of
+	// goroutines grows after each of this many consecutive attempts, and by more than LeakCheckThreshold in total,
+	// a warning with the stacks of the new goroutines is logged and as.goroutine.leaks is incremented. Zero
+	// disables the check.
+	LeakCheckAttempts int `env:"LEAK_CHECK_ATTEMPTS"`
+	// LeakCheckThreshold is the goroutine growth across LeakCheckAttempts attempts tolerated as fluctuation.
+	// Defaults to 10.
+	LeakCheckThreshold int `env:"LEAK_CHECK_THRESHOLD"`
+	// RestartBudget is a restart limit shared with other services run in the same process, see NewRestartBudget.
+	// Failure restarts are counted against it in addition to GracePeriod and GraceCount.
+	RestartBudget *RestartBudget `json:"-"`
+	// StopOnFirstExit is a group of services run in the same process which are stopped together once one of them
+	// exits successfully, see NewExitGroup. It cannot be combined with RestartOnSuccess.
+	StopOnFirstExit *ExitGroup `json:"-"`
+	// ReloadOptionsOnRestart re-evaluates the options (including the environment) before each restart, so
+	// e.g. RESTART_ON_ERROR_DELAY or LOG_LEVEL can be changed for a crash-looping service. Changed fields are
+	// logged. Settings applied once at startup, like the log output and OTEL exporters, are not changed. If the
+	// reloaded options are invalid (see Validate), the error is logged and the current options are kept.
+	ReloadOptionsOnRestart bool `env:"RELOAD_OPTIONS_ON_RESTART"`
+	// SharedValues are the values constructed once before the service is first initialized, registered with
+	// WithSharedValue.
+	SharedValues []sharedValue `json:"-"`
+	// ContextDecorators derive the context of the service before Init of every run, registered with
+	// WithContextValue and WithContextDecorator.
+	ContextDecorators []func(ctx context.Context) context.Context `json:"-"`
+	// HealthHistorySize is the number of lifecycle state and health status transitions retained per service,
+	// see HealthHistory. Older transitions are evicted. Zero disables the history. Defaults to 64.
+	HealthHistorySize int `env:"HEALTH_HISTORY_SIZE"`
+	// FlapWindow is the window over which health status transitions are counted as flaps, see HealthFlaps.
+	// Defaults to 1 hour.
+	FlapWindow time.Duration `env:"FLAP_WINDOW"`
+	// FlapThreshold is the number of flaps within FlapWindow at which a service reporting healthy is set to
+	// degraded instead. Zero disables this.
+	FlapThreshold int `env:"FLAP_THRESHOLD"`
+	// PausedReadiness keeps a paused service (see Pauser) ready: the ready file, the gRPC health status, and
+	// registrations are kept while it is paused. By default, a paused service is reported as not ready.
+	PausedReadiness bool `env:"PAUSED_READINESS"`
 	// ShutdownTimeout is the maximum duration to wait when shutting down the service gracefully.
 	// If the service shutdown takes longer than this, it will be forcefully terminated. Any restart config
 	// will be ignored.
+	//
+	// Close is called with a context expiring after ShutdownTimeout, which is not cancelled with the service. A
+	// Close still running after the timeout is abandoned with an error classified as ErrShutdownTimeout; in
+	// RunAndExit, the process then exits with ExitShutdownTimeout. The OTEL shutdown and the closers of shared
+	// values are limited the same way. Zero does not limit the shutdown.
 	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT"`
+	// ExitCodeFunc maps errors to exit codes of RunAndExit, taking precedence over ExitCoder and the error classes
+	// (see ExitOK). Errors for which it returns false are classified as usual.
+	ExitCodeFunc func(err error) (code int, ok bool) `json:"-"`
+	// ExitFunc exits the process in RunAndExit, including on its forced shutdowns. Defaults to os.Exit.
+	ExitFunc func(code int) `json:"-"`
+	// ShutdownSignals are the signals starting the graceful shutdown. Defaults to SIGINT and SIGTERM. Empty
+	// disables the signal handling. Signals are only handled if the context passed to RunC cannot be cancelled,
+	// e.g. context.Background(); a cancellable context is assumed to be cancelled by the caller on signals.
+	ShutdownSignals []os.Signal `json:"-"`
+	// ForceExitSignals is the number of shutdown signals at which a hanging shutdown is forced: the remaining
+	// shutdown (including Close) is skipped, OTEL is flushed for at most a second, and the process exits with
+	// ForceExitCode. RunC does not exit the process, but returns an error classified as ErrForcedShutdown. Values
+	// below 2 disable forcing. Defaults to 2.
+	ForceExitSignals int `env:"FORCE_EXIT_SIGNALS"`
+	// ImmediateExitSignals is the number of shutdown signals at which the process exits with ForceExitCode at
+	// once, without flushing; RunC returns ErrForcedShutdown at once instead. Values below 2 disable this.
+	// Defaults to 3.
+	ImmediateExitSignals int `env:"IMMEDIATE_EXIT_SIGNALS"`
+	// ForceExitCode is the exit code of forced exits. Defaults to 130.
+	ForceExitCode int `env:"FORCE_EXIT_CODE"`
+	// DrainDelay is the time between a shutdown signal and the cancellation of the service context. During the
+	// delay, Stopping is closed and readiness is withdrawn, so the service can stop accepting new work and
+	// finish work in flight.
+	DrainDelay time.Duration `env:"DRAIN_DELAY"`
 	// LogDebug enables verbose debug logging for this service.
 	// Defaults to true when the source tree has local modifications.
 	// Implicitly disables JSON logging when enabled.
 	// Implicitly sets the log level to debug, ignoring any other log level settings.
 	LogDebug bool `env:"LOG_DEBUG"`
 	// LogLevel is the level of logging to use.
-	// Valid values are: debug, info, warn, error, fatal, panic
-	// Invalid values will be ignored and the default will be used.
+	// Valid values are: debug, info, warn (or warning), error; case is ignored.
+	// Invalid values are ignored with a warning and the default is used. LogDebug takes precedence.
 	// Defaults to "info"
 	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+	// LogSchema selects the field names of JSON logs: "default", "ecs", "gcp" or "datadog".
+	// The schema renames the built-in time, level and message keys, maps levels to the severity vocabulary of the
+	// target and nests the service metadata as expected by it. It has no effect on text logs.
+	LogSchema LogSchema `env:"LOG_SCHEMA"`
+	// ShowBanner logs a single record on startup describing the service: its identity, build, runtime, OTEL
+	// exporters, key options, and the prefixed environment, with likely secrets redacted. Defaults to true.
+	ShowBanner bool `env:"SHOW_BANNER"`
+	// TraceSampler is the sampler of the TracerProvider of the service. Each supervised service has its own
+	// TracerProvider, so services run in the same process can sample at different ratios. If nil, the sampler is
+	// configured by OTEL_TRACES_SAMPLER, defaulting to sampling all traces.
+	TraceSampler traceSdk.Sampler `json:"-"`
+	// MetricExportInterval is the interval of the periodic metric reader, e.g. for short-lived binaries exiting
+	// before the first export. If zero, OTEL_METRIC_EXPORT_INTERVAL (or the OTEL default of 60s) applies.
+	// Sub-second intervals are ignored with a warning. Metrics are exported on shutdown regardless of the interval.
+	MetricExportInterval time.Duration `env:"METRIC_EXPORT_INTERVAL"`
+	// BuildInfoMetric is the name of the constant gauge reporting the build info of the service (value 1, labeled
+	// with version, revision, go_version, name, and namespace), e.g. "build_info" to match existing conventions.
+	// An empty name disables the metric. Defaults to "service_build_info".
+	BuildInfoMetric string `env:"BUILD_INFO_METRIC"`
+	// LogRouteDir routes the records of the service to its own file <namespace>-<name>.log in the directory
+	// instead of the regular log output, e.g. to separate the logs of several services in one process during
+	// development. The file is appended to, and flushed and closed when the service exits.
+	LogRouteDir string `env:"LOG_ROUTE_DIR"`
+	// LogRouteWriters routes the records of services to writers, keyed by "<namespace>-<name>". A writer takes
+	// precedence over LogRouteDir.
+	LogRouteWriters map[string]io.Writer `json:"-"`
+	// LogRouteCombined writes routed records to the regular log output as well.
+	LogRouteCombined bool `env:"LOG_ROUTE_COMBINED"`
+	// LogGCPProject is the Google Cloud project ID used for the trace correlation fields of the "gcp" log schema
+	// (logging.googleapis.com/trace). If empty, the GOOGLE_CLOUD_PROJECT environment variable is used.
+	LogGCPProject string `env:"LOG_GCP_PROJECT"`
+	// LogOutput selects where logs are written:
+	//   - "stdout" (default)
+	//   - "journald" writes records to the systemd journal using its native protocol, keeping priorities and
+	//     attributes as structured fields.
+	//   - "syslog" (local /dev/log), "syslog:///path/to/socket" or "syslog://host[:port][?proto=udp|tcp]" sends
+	//     RFC 5424 messages carrying the attributes as structured data. Messages are buffered and dropped if the
+	//     endpoint is unreachable, so logging never blocks the service. On shutdown, the buffered messages are
+	//     sent for at most 5 seconds before the connection is closed.
+	//
+	// If the output is unavailable, logs are written to stdout and a warning is logged.
+	LogOutput string `env:"LOG_OUTPUT"`
+	// LogMetrics counts log records at warn level and above on the as.log.records counter, labeled by level and
+	// service name.
+	LogMetrics bool `env:"LOG_METRICS"`
+	// LogLevelHeader is the name of an HTTP header / gRPC metadata key whose value (e.g. "debug") overrides the log
+	// level for a single request, see WithRequestLogLevel. Empty disables the header.
+	LogLevelHeader string `env:"LOG_LEVEL_HEADER"`
+	// HTTPClientTimeout is the default timeout of clients created by HTTPClient. Zero disables the timeout.
+	// Defaults to 30s.
+	HTTPClientTimeout time.Duration `env:"HTTP_CLIENT_TIMEOUT"`
+	// HTTPClientSlowThreshold is the default duration above which requests of clients created by HTTPClient are
+	// logged as slow. Zero (default) disables the logging.
+	HTTPClientSlowThreshold time.Duration `env:"HTTP_CLIENT_SLOW_THRESHOLD"`
+	// LogFormat selects the log format. LogFormatLogfmt writes logfmt lines, taking precedence over LogJson and
+	// LogColors. By default, LogJson and LogColors select the format.
+	LogFormat LogFormat `env:"LOG_FORMAT"`
 	// LogJson enables JSON-formatted logging output.
 	LogJson bool `env:"LOG_JSON"`
+	// LogAutoFormat selects LogJson from the runtime environment: JSON in containers or if stdout is not a terminal,
+	// text on a local terminal. It is ignored if LOG_JSON is set in the environment; WithLogJson disables it.
+	LogAutoFormat bool `env:"LOG_FORMAT_AUTO"`
 	// LogColors enables colorized logging output. Does nothing when using JSON logging.
 	LogColors bool `env:"LOG_COLORS"`
 	// LogAutoColors enables colorized logging output if stdout is a terminal.
@@ -69,25 +204,259 @@ type Options struct {
 	// as defined by the `env` struct tags.
 	// As with all env options, this will also impact the EnvPrefix behavior for the service context.
 	DisableEnvPrefix bool
+	// EnvPrefixFallback makes option fields and GetEnv / LookupEnv fall back to less specific env vars: a field
+	// not set under the prefix of the service (e.g. BILLING_INVOICER_GRACE_PERIOD) is read with the namespace-only
+	// prefix (BILLING_GRACE_PERIOD), then without any prefix (GRACE_PERIOD). The most specific variable wins, per
+	// field. Fields set from a fallback are logged at debug level.
+	EnvPrefixFallback bool `env:"ENV_PREFIX_FALLBACK"`
+	// OptionPrecedence selects whether env vars override options set in code (EnvWins, the default) or only set
+	// the fields no option set (CodeWins). Conflicts are logged either way.
+	OptionPrecedence OptionPrecedence `env:"OPTION_PRECEDENCE"`
+	// DevMode applies the local development preset (see WithDevMode) to the fields set neither in code nor by an
+	// env var.
+	DevMode bool `env:"DEV"`
+	// DevModeAuto enables DevMode for builds with uncommitted changes (vcs.modified) running on a terminal, unless
+	// DevMode is set in code or by an env var.
+	DevModeAuto bool `env:"DEV_AUTO"`
+	// ServiceVersion overrides the version of services whose Version method returns "" or "dev", taking precedence
+	// over SetVersion and the version derived from the build info.
+	ServiceVersion string `env:"SERVICE_VERSION"`
+	// IdentityMode selects how a service name or namespace not matching the identity pattern (lowercase
+	// alphanumerics, dashes, and dots, at most 63 characters) is handled: IdentityNormalize (the default) rewrites
+	// it and logs a warning, IdentityStrict rejects it. It cannot be set using the environment, since the
+	// identity determines the env prefix.
+	IdentityMode IdentityMode
+	// RuntimeEnv overrides the detected runtime environment returned by RuntimeEnvironment (local, container, or
+	// kubernetes). If empty, it is detected from the environment and the filesystem.
+	RuntimeEnv RuntimeEnv `env:"RUNTIME_ENV"`
+	// AutoMaxProcs sets GOMAXPROCS to the CPU quota of the container during startup and restores the default of
+	// the runtime during shutdown. Since Go 1.25, the runtime follows the CPU quota by default, so this only
+	// applies if that is disabled with GODEBUG=cgroupgomaxprocs=0. This is a no-op when not running on Linux, when
+	// no CPU quota is configured, or when the GOMAXPROCS environment variable is set.
+	AutoMaxProcs bool `env:"AUTO_MAXPROCS"`
+	// AutoMemLimit sets the Go runtime soft memory limit (GOMEMLIMIT) to MemLimitRatio of the container memory
+	// limit during startup and restores the original limit during shutdown. This is a no-op when not running on
+	// Linux, when no memory limit is configured, or when the GOMEMLIMIT environment variable is set.
+	AutoMemLimit bool `env:"AUTO_MEMLIMIT"`
+	// MemLimitRatio is the fraction of the container memory limit used as the Go runtime soft memory limit
+	// when AutoMemLimit is enabled. Must be in (0, 1]. Defaults to 0.9.
+	MemLimitRatio float64 `env:"MEMLIMIT_RATIO"`
+	// InstanceLock is the path of a lock file used to ensure only a single instance of the service runs on the
+	// machine. During startup, an exclusive lock is taken on the file and the pid is written into it; startup fails
+	// if the lock is held by another process. The file is removed on shutdown.
+	// If empty, no instance lock is used.
+	InstanceLock string `env:"INSTANCE_LOCK"`
+	// PIDFile is the path of a file the pid of the process is written to after the service was initialized.
+	// Startup fails if the file already exists and points at a running process, unless PIDFileOverride is set.
+	// The parent directory is created if necessary. The file is removed on shutdown.
+	// If empty, no PID file is written.
+	PIDFile string `env:"PID_FILE"`
+	// PIDFileMode is the permission mode of the PID file. Defaults to 0644.
+	PIDFileMode os.FileMode
+	// PIDFileOverride allows starting even if the PID file points at a running process.
+	PIDFileOverride bool `env:"PID_FILE_OVERRIDE"`
+	// ReadyFile is the path of a file which exists exactly while the service is running and not unhealthy, for
+	// file-based readiness probes. It is created once Init succeeded and removed as soon as the service starts
+	// stopping or restarting, becomes unhealthy (see SetHealth), or the supervisor exits. Parent directories are
+	// created as needed.
+	ReadyFile string `env:"READY_FILE"`
+	// ReadyFileMode is the permission of the ready file.
+	ReadyFileMode os.FileMode
+	// DataDir is the data directory returned by DataDir. Defaults to <namespace>/<name> in os.UserCacheDir().
+	DataDir string `env:"DATA_DIR"`
+	// DataDirMode is the permission of the data directory, if it is created. Defaults to 0700.
+	DataDirMode os.FileMode
+	// WipeDataDir removes the data directory with its contents on start, e.g. for caches.
+	WipeDataDir bool `env:"WIPE_DATA_DIR"`
+	// WorkingDir is the working directory of the process while the service runs, e.g. for relative paths of
+	// packaged services. It is changed before the instance lock and PID file are created, so relative paths of
+	// those resolve in it as well, and restored on shutdown. Startup fails if the directory is not accessible.
+	// The working directory is process-global: services running concurrently must use the same one.
+	WorkingDir string `env:"WORKING_DIR"`
+	// Umask is the umask of the process while the service runs, restored on shutdown. If nil, the umask is not
+	// changed. Like WorkingDir, it is process-global. It is ignored on platforms without umask, e.g. Windows.
+	Umask *os.FileMode `json:"-"`
+	// Registrars register the service with external systems (e.g. a service discovery) while it is running.
+	// See Registrar.
+	Registrars []Registrar `json:"-"`
+	// Reporters receive recovered panics and the errors the supervisor stops the service with, e.g. to forward
+	// them to an error tracking system. See Reporter.
+	Reporters []Reporter `json:"-"`
+	// EscalateGoroutineErrors makes goroutines started by Go which fail or panic fail the service: the context
+	// passed to Run is cancelled and the goroutine error becomes the service error, subject to the restart policy.
+	// If false, such failures are only logged and counted.
+	EscalateGoroutineErrors bool `env:"ESCALATE_GOROUTINE_ERRORS"`
+	// BreakerFailures is the number of failures within BreakerWindow after which the restart circuit breaker opens.
+	// Unlike GracePeriod and GraceCount, the window slides, so services failing regularly but rarely are caught too.
+	// If set to zero, the circuit breaker is disabled.
+	BreakerFailures int `env:"RESTART_BREAKER_FAILURES"`
+	// BreakerWindow is the duration of the sliding window in which failures are counted by the circuit breaker.
+	// If set to zero, all failures are counted.
+	BreakerWindow time.Duration `env:"RESTART_BREAKER_WINDOW"`
+	// BreakerPolicy defines what happens when the circuit breaker opens: OpenPolicyGiveUp (the default) stops
+	// restarting the service, OpenPolicyCooldown pauses restarts for BreakerCooldown.
+	BreakerPolicy OpenPolicy `env:"RESTART_BREAKER_POLICY"`
+	// BreakerCooldown is the duration restarts are paused for when the circuit breaker opens with OpenPolicyCooldown.
+	BreakerCooldown time.Duration `env:"RESTART_BREAKER_COOLDOWN"`
+	// CrashDir is the directory crash reports are written to when a panic is recovered. A report contains the panic
+	// value and stack, a dump of all goroutines, the service identity, build information, the key options, the
+	// prefixed environment variables with likely secrets redacted, and the most recent log records. Failing to write a report never masks the original error.
+	// If empty, no crash reports are written.
+	CrashDir string `env:"CRASH_DIR"`
+	// GlobalPanicHandler writes the crash output of panics no recover intercepted (e.g. in goroutines started
+	// without Go, TaskGroup, or a deferred Recover) to a file in CrashDir, besides stderr. Such panics still crash
+	// the process, skipping Close and all cleanup; the next process started with the same CrashDir logs the
+	// output, passes it to the Reporters, and keeps it as crash report. Requires CrashDir. The crash output is
+	// process-global: services running concurrently share the file of the first of them.
+	GlobalPanicHandler bool `env:"GLOBAL_PANIC_HANDLER"`
+	// CrashReportOnGiveUp additionally writes a crash report when the supervisor gives up restarting the service
+	// because the restart budget (grace period, grace count, or circuit breaker) is exhausted.
+	CrashReportOnGiveUp bool `env:"CRASH_REPORT_ON_GIVE_UP"`
+	// CrashRetain is the number of crash reports kept in CrashDir; older ones are removed.
+	// If set to zero, all reports are kept. Defaults to 10.
+	CrashRetain int `env:"CRASH_RETAIN"`
+	// CrashLogLines is the number of most recent log records included in crash reports. Defaults to 100.
+	CrashLogLines int `env:"CRASH_LOG_LINES"`
+	// SignalDump changes the behavior of SIGQUIT: instead of the Go runtime default of dumping all goroutines and
+	// terminating the process, a dump of all goroutines and heap statistics is written to CrashDir (or stderr, if no
+	// crash directory is set) and the process keeps running. Dumps are rate-limited to one every 10 seconds.
+	//
+	// Note that this deliberately overrides a well-known default: with SignalDump enabled, SIGQUIT (Ctrl-\) no
+	// longer terminates the process.
+	SignalDump bool `env:"SIGNAL_DUMP"`
+	// SignalTrace is the duration of the runtime execution trace captured when the process receives SIGUSR2.
+	// Traces are written to CrashDir, see CaptureTrace. Zero disables the signal.
+	SignalTrace time.Duration `env:"SIGNAL_TRACE"`
+	// DiagnosticsInterval is the interval at which a debug record with runtime diagnostics (goroutines, heap in-use,
+	// GC pauses, open file descriptors) is logged. Zero disables the diagnostics, unless LogDebug is enabled, in
+	// which case it defaults to one minute. A negative value always disables them.
+	DiagnosticsInterval time.Duration `env:"DIAGNOSTICS_INTERVAL"`
+	// MinimumRunDuration is the minimum time Run is expected to keep running. If Run returns sooner, with or without
+	// an error, and the context was not cancelled, the exit is treated as a failure subject to the restart policy.
+	// Zero disables the check.
+	MinimumRunDuration time.Duration `env:"MINIMUM_RUN_DURATION"`
+	// MemoryLimit is the memory usage (the larger of the heap in use and, on Linux, the RSS) in bytes above which
+	// the service is restarted gracefully, like after a requested restart. Zero (default) disables the watchdog.
+	MemoryLimit uint64 `env:"MEMORY_LIMIT"`
+	// MemoryCheckInterval is the interval at which the memory usage is sampled. Defaults to 10s.
+	MemoryCheckInterval time.Duration `env:"MEMORY_CHECK_INTERVAL"`
+	// MemorySoftLimitRatio is the fraction of MemoryLimit above which a warning is logged. Defaults to 0.8.
+	MemorySoftLimitRatio float64 `env:"MEMORY_SOFT_LIMIT_RATIO"`
+	// MemoryRestartCountsAgainstGrace counts restarts caused by MemoryLimit against GracePeriod and GraceCount.
+	// By default, they are exempt like requested restarts, and only count against MaxLifetimeRestarts.
+	MemoryRestartCountsAgainstGrace bool `env:"MEMORY_RESTART_COUNTS_AGAINST_GRACE"`
+	// MemorySampler samples the memory usage for the watchdog, e.g. to simulate growth in tests. Defaults to
+	// sampling the runtime memory statistics and /proc/self/statm.
+	MemorySampler func() MemorySample `json:"-"`
+	// StartupProbes are run before each Init until all of them succeed, see WithStartupProbe.
+	StartupProbes []Probe `json:"-"`
+	// StartupProbeBudget is the time the startup probes may take before the initialization fails. Zero waits until
+	// the service is stopped. Defaults to 1m.
+	StartupProbeBudget time.Duration `env:"STARTUP_PROBE_BUDGET"`
+	// MaxLifetime is the time after which the service is shut down gracefully, like after a shutdown signal, and
+	// the supervisor exits cleanly with the stop reason "max lifetime reached", e.g. for canary and soak tests.
+	// The lifetime starts once the service is running for the first time. Zero (default) disables the limit.
+	MaxLifetime time.Duration `env:"MAX_LIFETIME"`
+	// HealthCommands are external commands checking the health of the service, see WithHealthCommand.
+	HealthCommands []HealthCommand `json:"-"`
+	// HeartbeatURL is the URL of an external heartbeat monitor, pinged with a GET request every HeartbeatInterval
+	// while the service is ready. While the service is unhealthy, and when it exits with an error, a POST request
+	// with a summary of the failure is sent to <url>/fail instead. A final success ping is sent on clean shutdown.
+	// Pings never block the lifecycle; failed pings are logged and retried with backoff. Empty (default) disables
+	// the heartbeat.
+	HeartbeatURL string `env:"HEARTBEAT_URL"`
+	// HeartbeatInterval is the interval between heartbeat pings. Defaults to 1m.
+	HeartbeatInterval time.Duration `env:"HEARTBEAT_INTERVAL"`
+	// InitWarnAfter logs a warning if Init is still running after this duration, and its final duration once it
+	// returns. Zero (default) disables the warning.
+	InitWarnAfter time.Duration `env:"INIT_WARN_AFTER"`
+	// CloseWarnAfter is InitWarnAfter for Close.
+	CloseWarnAfter time.Duration `env:"CLOSE_WARN_AFTER"`
+	// MaxDegradations is the number of consecutive errors marked with Degraded after which Run is not called again,
+	// but the error is handled like any other error. Zero handles degraded errors like any other error.
+	// Defaults to 3.
+	MaxDegradations int `env:"MAX_DEGRADATIONS"`
+	// RequestedRestartDelay is the delay before restarting after Run returned ErrRestartRequested.
+	RequestedRestartDelay time.Duration `env:"REQUESTED_RESTART_DELAY"`
+	// RestartOnSuccess restarts the service when Run returns nil without the context being cancelled, after
+	// RestartOnErrorDelay. Restarts are still limited by GracePeriod and GraceCount; cancellation always stops the
+	// service.
+	RestartOnSuccess bool `env:"RESTART_ON_SUCCESS"`
+	// OTELFallback selects the exporters used if the environment configures no OTEL exporter: "noop" discards spans
+	// and metrics, "console" writes them to stdout and "error" fails the startup. Defaults to "console" if LogDebug
+	// is enabled and to "noop" otherwise.
+	OTELFallback OTELFallback `env:"OTEL_FALLBACK"`
+	// QuietErrors lists errors (matched with errors.Is) that RunAndExit treats like context.Canceled: they are not
+	// printed and do not cause a non-zero exit. Useful for errors such as http.ErrServerClosed that are returned
+	// during a normal shutdown. context.DeadlineExceeded is always quiet if the service was shut down, e.g. by
+	// drain deadlines.
+	QuietErrors []error `json:"-"`
+	// QuietErrorFunc, if set, reports additional errors RunAndExit treats like context.Canceled.
+	QuietErrorFunc func(error) bool `json:"-"`
+	// ErrorPrintFrameFilters are applied, in addition to hiding frames of this package, to the stack frames of errors
+	// printed by RunAndExit. A frame is printed only if all filters return true.
+	ErrorPrintFrameFilters []func(*ae.StackFrame) bool `json:"-"`
+	// ErrorPrintFullStacks disables all stack frame filtering of errors printed by RunAndExit.
+	ErrorPrintFullStacks bool `env:"ERROR_PRINT_FULL_STACKS"`
+
+	// exitProcess exits the process once Close exceeds ShutdownTimeout and on forced shutdowns; set by RunAndExit.
+	// Without it, RunC returns an error instead, so embedding callers keep control of the process.
+	exitProcess bool
+	// explicit holds the indices of the fields set by the With* helpers, see setOption.
+	explicit map[int]bool
 }
 
 // DefaultOptions returns an Options struct pre-populated with recommended default values
 // for robust service supervision. Callers may further modify the returned struct.
 func DefaultOptions() Options {
 	return Options{
-		RestartOnError:      true,
-		RestartOnErrorDelay: 10 * time.Second,
-		RestartOnPanic:      true,
-		RecoverPanic:        true,
-		GracePeriod:         1 * time.Minute,
-		GraceCount:          3,
-		ShutdownTimeout:     30 * time.Second,
-		LogDebug:            false,
-		LogColors:           false,
-		LogAutoColors:       true,
-		LogJson:             true,
-		EnvPrefix:           "",
-		DisableEnvPrefix:    false,
+		RestartOnError:       true,
+		RestartOnErrorDelay:  10 * time.Second,
+		RestartOnPanic:       true,
+		RecoverPanic:         true,
+		GracePeriod:          1 * time.Minute,
+		GraceCount:           3,
+		ShutdownTimeout:      30 * time.Second,
+		ShutdownSignals:      []os.Signal{os.Interrupt, syscall.SIGTERM},
+		ForceExitSignals:     2,
+		ImmediateExitSignals: 3,
+		ForceExitCode:        130,
+		LogDebug:             false,
+		LogLevel:             "info",
+		LogColors:            false,
+		LogAutoColors:        true,
+		LogJson:              true,
+		LogAutoFormat:        true,
+		LogSchema:            LogSchemaDefault,
+		LogOutput:            "stdout",
+		LogMetrics:           true,
+		EnvPrefix:            "",
+		DisableEnvPrefix:     false,
+		IdentityMode:         IdentityNormalize,
+		OptionPrecedence:     EnvWins,
+		DevModeAuto:          true,
+		AutoMaxProcs:         true,
+		AutoMemLimit:         true,
+		MemLimitRatio:        0.9,
+		PIDFileMode:          0o644,
+		ReadyFileMode:        0o644,
+		DataDirMode:          0o700,
+		ShowBanner:           true,
+		BuildInfoMetric:      "service_build_info",
+		HealthHistorySize:    64,
+		LeakCheckThreshold:   10,
+		MemoryCheckInterval:  10 * time.Second,
+		MemorySoftLimitRatio: 0.8,
+		HTTPClientTimeout:    30 * time.Second,
+		StartupProbeBudget:   time.Minute,
+		HeartbeatInterval:    time.Minute,
+		FlapWindow:           time.Hour,
+		MaxDegradations:      3,
+		BreakerWindow:        10 * time.Minute,
+		BreakerPolicy:        OpenPolicyGiveUp,
+		BreakerCooldown:      5 * time.Minute,
+		CrashRetain:          10,
+		CrashLogLines:        100,
 	}
 }
 
@@ -97,67 +466,556 @@ type Option func(*Options)
 
 // WithRestartOnError sets the RestartOnError field, enabling or disabling automatic service restarts on error.
 func WithRestartOnError(v bool) Option {
-	return func(o *Options) { o.RestartOnError = v }
+	return func(o *Options) { setOption(o, &o.RestartOnError, v) }
 }
 
 // WithRestartOnErrorDelay sets the delay between consecutive restarts due to errors.
 func WithRestartOnErrorDelay(v time.Duration) Option {
-	return func(o *Options) { o.RestartOnErrorDelay = v }
+	return func(o *Options) { setOption(o, &o.RestartOnErrorDelay, v) }
 }
 
 // WithRestartOnPanic sets the RestartOnPanic field, enabling or disabling restarts when the service panics.
 func WithRestartOnPanic(v bool) Option {
-	return func(o *Options) { o.RestartOnPanic = v }
+	return func(o *Options) { setOption(o, &o.RestartOnPanic, v) }
 }
 
 // WithRestartOnPanicDelay sets the delay between restarts triggered by a panic.
 func WithRestartOnPanicDelay(v time.Duration) Option {
-	return func(o *Options) { o.RestartOnPanicDelay = v }
+	return func(o *Options) { setOption(o, &o.RestartOnPanicDelay, v) }
 }
 
 // WithRecoverPanic sets the RecoverPanic field, enabling or disabling panic recovery.
 func WithRecoverPanic(v bool) Option {
-	return func(o *Options) { o.RecoverPanic = v }
+	return func(o *Options) { setOption(o, &o.RecoverPanic, v) }
 }
 
 // WithGracePeriod sets the maximum duration after the initial start in which restarts are allowed.
 func WithGracePeriod(v time.Duration) Option {
-	return func(o *Options) { o.GracePeriod = v }
+	return func(o *Options) { setOption(o, &o.GracePeriod, v) }
 }
 
 // WithGraceCount sets the maximum number of allowed restarts after the initial start.
 func WithGraceCount(v int) Option {
-	return func(o *Options) { o.GraceCount = v }
+	return func(o *Options) { setOption(o, &o.GraceCount, v) }
+}
+
+// WithDrainDelay sets the DrainDelay field, the time between a shutdown signal and the cancellation of the context.
+func WithDrainDelay(v time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.DrainDelay, v) }
 }
 
 // WithShutdownTimeout sets the maximum time to wait for graceful service shutdown.
 func WithShutdownTimeout(v time.Duration) Option {
-	return func(o *Options) { o.ShutdownTimeout = v }
+	return func(o *Options) { setOption(o, &o.ShutdownTimeout, v) }
 }
 
 // WithLogDebug sets the LogDebug field, enabling or disabling verbose debug logging.
 func WithLogDebug(v bool) Option {
-	return func(o *Options) { o.LogDebug = v }
+	return func(o *Options) { setOption(o, &o.LogDebug, v) }
+}
+
+// WithLogLevel sets the LogLevel field, the level of logging to use: debug, info, warn or error.
+func WithLogLevel(v string) Option {
+	return func(o *Options) { setOption(o, &o.LogLevel, v) }
+}
+
+// WithLogFormat sets the LogFormat field, selecting the log format.
+func WithLogFormat(v LogFormat) Option {
+	return func(o *Options) { setOption(o, &o.LogFormat, v) }
 }
 
-// WithLogJson sets the LogJson field, enabling or disabling JSON-formatted logging output.
+// WithLogJson sets the LogJson field, enabling or disabling JSON-formatted logging output. It disables
+// LogAutoFormat, so the format is not selected from the runtime environment.
 func WithLogJson(v bool) Option {
-	return func(o *Options) { o.LogJson = v }
+	return func(o *Options) {
+		setOption(o, &o.LogJson, v)
+		setOption(o, &o.LogAutoFormat, false)
+	}
+}
+
+// WithLogAutoFormat sets the LogAutoFormat field, enabling or disabling the selection of the log format from the
+// runtime environment.
+func WithLogAutoFormat(v bool) Option {
+	return func(o *Options) { setOption(o, &o.LogAutoFormat, v) }
+}
+
+// WithRuntimeEnv sets the RuntimeEnv field, overriding the detected runtime environment.
+func WithRuntimeEnv(v RuntimeEnv) Option {
+	return func(o *Options) { setOption(o, &o.RuntimeEnv, v) }
 }
 
 // WithLogColors sets the LogColors field, enabling or disabling colorized logging output.
 func WithLogColors(v bool) Option {
-	return func(o *Options) { o.LogColors = v }
+	return func(o *Options) { setOption(o, &o.LogColors, v) }
 }
 
 // WithLogAutoColors sets the LogAutoColors field, enabling or disabling automatic colorization if stdout is a terminal.
 func WithLogAutoColors(v bool) Option {
-	return func(o *Options) { o.LogAutoColors = v }
+	return func(o *Options) { setOption(o, &o.LogAutoColors, v) }
 }
 
 // WithDisableEnvPrefix sets the DisableEnvPrefix field, preventing any environment variable prefix from being applied.
 func WithDisableEnvPrefix(v bool) Option {
-	return func(o *Options) { o.DisableEnvPrefix = v }
+	return func(o *Options) { setOption(o, &o.DisableEnvPrefix, v) }
+}
+
+// WithHealthHistorySize sets the HealthHistorySize field, the number of transitions retained in the health history.
+func WithHealthHistorySize(v int) Option {
+	return func(o *Options) { setOption(o, &o.HealthHistorySize, v) }
+}
+
+// WithFlapDetection sets the FlapThreshold and FlapWindow fields, degrading a service whose health changed at least
+// threshold times within window.
+func WithFlapDetection(threshold int, window time.Duration) Option {
+	return func(o *Options) {
+		setOption(o, &o.FlapThreshold, threshold)
+		setOption(o, &o.FlapWindow, window)
+	}
+}
+
+// WithExitCodeFunc sets the ExitCodeFunc field, mapping errors to exit codes.
+func WithExitCodeFunc(fn func(err error) (code int, ok bool)) Option {
+	return func(o *Options) { setOption(o, &o.ExitCodeFunc, fn) }
+}
+
+// WithExitFunc sets the ExitFunc field, the function exiting the process.
+func WithExitFunc(fn func(code int)) Option {
+	return func(o *Options) { setOption(o, &o.ExitFunc, fn) }
+}
+
+// withExitProcess sets the exitProcess field, exiting the process once Close exceeds the ShutdownTimeout and on
+// forced shutdowns.
+func withExitProcess() Option {
+	return func(o *Options) { o.exitProcess = true }
+}
+
+// WithShutdownSignals sets the ShutdownSignals field, the signals starting the graceful shutdown. Without
+// signals, the signal handling is disabled.
+func WithShutdownSignals(signals ...os.Signal) Option {
+	return func(o *Options) { setOption(o, &o.ShutdownSignals, signals) }
+}
+
+// WithForceExit sets the ForceExitSignals and ImmediateExitSignals fields, the numbers of shutdown signals at which
+// the shutdown is forced and the process exits at once. Values below 2 disable the respective escalation.
+func WithForceExit(forceSignals, immediateSignals int) Option {
+	return func(o *Options) {
+		setOption(o, &o.ForceExitSignals, forceSignals)
+		setOption(o, &o.ImmediateExitSignals, immediateSignals)
+	}
+}
+
+// WithForceExitOnSecondSignal enables or disables the escalation of a hanging shutdown on repeated shutdown
+// signals. Enabled, the default, the second signal forces the shutdown and the third exits at once (see
+// WithForceExit); disabled, further signals are ignored and the shutdown always runs to completion.
+func WithForceExitOnSecondSignal(enabled bool) Option {
+	if enabled {
+		return WithForceExit(2, 3)
+	}
+	return WithForceExit(0, 0)
+}
+
+// WithForceExitCode sets the ForceExitCode field, the exit code of forced exits.
+func WithForceExitCode(code int) Option {
+	return func(o *Options) { setOption(o, &o.ForceExitCode, code) }
+}
+
+// WithPausedReadiness sets the PausedReadiness field, keeping paused services ready.
+func WithPausedReadiness(v bool) Option {
+	return func(o *Options) { setOption(o, &o.PausedReadiness, v) }
+}
+
+// WithTraceSampler sets the TraceSampler field, the sampler of the TracerProvider of the service.
+func WithTraceSampler(sampler traceSdk.Sampler) Option {
+	return func(o *Options) { setOption(o, &o.TraceSampler, sampler) }
+}
+
+// WithTraceSampleRatio samples the given fraction of traces started by the service, following the sampling
+// decision of the parent span if there is one.
+func WithTraceSampleRatio(ratio float64) Option {
+	return WithTraceSampler(traceSdk.ParentBased(traceSdk.TraceIDRatioBased(ratio)))
+}
+
+// WithMetricExportInterval sets the MetricExportInterval field, the interval of the periodic metric reader.
+func WithMetricExportInterval(d time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.MetricExportInterval, d) }
+}
+
+// WithBuildInfoMetric sets the BuildInfoMetric field, the name of the build info gauge. An empty name disables it.
+func WithBuildInfoMetric(name string) Option {
+	return func(o *Options) { setOption(o, &o.BuildInfoMetric, name) }
+}
+
+// WithLogRouteDir sets the LogRouteDir field, routing the records of the service to its own file in dir.
+func WithLogRouteDir(dir string) Option {
+	return func(o *Options) { setOption(o, &o.LogRouteDir, dir) }
+}
+
+// WithLogRouteWriter routes the records of the service with the given namespace and name to w, see LogRouteWriters.
+func WithLogRouteWriter(namespace, name string, w io.Writer) Option {
+	return func(o *Options) {
+		if o.LogRouteWriters == nil {
+			o.LogRouteWriters = make(map[string]io.Writer)
+		}
+		o.LogRouteWriters[logRouteKey(namespace, name)] = w
+	}
+}
+
+// WithLogRouteCombined sets the LogRouteCombined field, writing routed records to the regular log output as well.
+func WithLogRouteCombined(v bool) Option {
+	return func(o *Options) { setOption(o, &o.LogRouteCombined, v) }
+}
+
+// WithEnvPrefixFallback sets the EnvPrefixFallback field, enabling the fallback to the namespace-only prefix and
+// no prefix for env vars not set under the prefix of the service.
+func WithEnvPrefixFallback(v bool) Option {
+	return func(o *Options) { setOption(o, &o.EnvPrefixFallback, v) }
+}
+
+// WithOptionPrecedence sets the OptionPrecedence field, selecting whether env vars override options set in code.
+func WithOptionPrecedence(v OptionPrecedence) Option {
+	return func(o *Options) { setOption(o, &o.OptionPrecedence, v) }
+}
+
+// WithServiceVersion sets the ServiceVersion field, overriding the version of services without a version.
+func WithServiceVersion(v string) Option {
+	return func(o *Options) { setOption(o, &o.ServiceVersion, v) }
+}
+
+// WithIdentityMode sets the IdentityMode field, selecting whether invalid service names and namespaces are
+// normalized or rejected.
+func WithIdentityMode(v IdentityMode) Option {
+	return func(o *Options) { setOption(o, &o.IdentityMode, v) }
+}
+
+// WithAutoMaxProcs sets the AutoMaxProcs field, enabling or disabling the adjustment of GOMAXPROCS to the CPU quota.
+func WithAutoMaxProcs(v bool) Option {
+	return func(o *Options) { setOption(o, &o.AutoMaxProcs, v) }
+}
+
+// WithAutoMemLimit sets the AutoMemLimit field, enabling or disabling the adjustment of GOMEMLIMIT to the
+// container memory limit.
+func WithAutoMemLimit(v bool) Option {
+	return func(o *Options) { setOption(o, &o.AutoMemLimit, v) }
+}
+
+// WithMemLimitRatio sets the fraction of the container memory limit used as the Go runtime soft memory limit.
+func WithMemLimitRatio(v float64) Option {
+	return func(o *Options) { setOption(o, &o.MemLimitRatio, v) }
+}
+
+// WithInstanceLock sets the path of the lock file used to ensure only a single instance of the service runs.
+func WithInstanceLock(path string) Option {
+	return func(o *Options) { setOption(o, &o.InstanceLock, path) }
+}
+
+// WithPIDFile sets the path of the file the pid of the process is written to.
+func WithPIDFile(path string) Option {
+	return func(o *Options) { setOption(o, &o.PIDFile, path) }
+}
+
+// WithPIDFileMode sets the permission mode of the PID file.
+func WithPIDFileMode(v os.FileMode) Option {
+	return func(o *Options) { setOption(o, &o.PIDFileMode, v) }
+}
+
+// WithPIDFileOverride sets the PIDFileOverride field, allowing to start even if the PID file points at a
+// running process.
+func WithPIDFileOverride(v bool) Option {
+	return func(o *Options) { setOption(o, &o.PIDFileOverride, v) }
+}
+
+// WithEscalateGoroutineErrors sets the EscalateGoroutineErrors field, enabling or disabling the escalation of
+// failed goroutines started by Go to service errors.
+func WithEscalateGoroutineErrors(v bool) Option {
+	return func(o *Options) { setOption(o, &o.EscalateGoroutineErrors, v) }
+}
+
+// WithBreakerFailures sets the number of failures within BreakerWindow after which the restart circuit breaker opens.
+func WithBreakerFailures(v int) Option {
+	return func(o *Options) { setOption(o, &o.BreakerFailures, v) }
+}
+
+// WithBreakerWindow sets the duration of the sliding window in which failures are counted by the circuit breaker.
+func WithBreakerWindow(v time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.BreakerWindow, v) }
+}
+
+// WithBreakerPolicy sets what happens when the restart circuit breaker opens.
+func WithBreakerPolicy(v OpenPolicy) Option {
+	return func(o *Options) { setOption(o, &o.BreakerPolicy, v) }
+}
+
+// WithBreakerCooldown sets the duration restarts are paused for when the circuit breaker opens.
+func WithBreakerCooldown(v time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.BreakerCooldown, v) }
+}
+
+// WithCrashDir sets the directory crash reports are written to.
+func WithCrashDir(path string) Option {
+	return func(o *Options) { setOption(o, &o.CrashDir, path) }
+}
+
+// WithGlobalPanicHandler sets the GlobalPanicHandler field, persisting the crash output of unrecovered panics to
+// CrashDir and reporting it on the next start.
+func WithGlobalPanicHandler(v bool) Option {
+	return func(o *Options) { setOption(o, &o.GlobalPanicHandler, v) }
+}
+
+// WithCrashReportOnGiveUp sets the CrashReportOnGiveUp field, enabling or disabling crash reports when the
+// supervisor gives up restarting the service.
+func WithCrashReportOnGiveUp(v bool) Option {
+	return func(o *Options) { setOption(o, &o.CrashReportOnGiveUp, v) }
+}
+
+// WithCrashRetain sets the number of crash reports kept in the crash directory.
+func WithCrashRetain(v int) Option {
+	return func(o *Options) { setOption(o, &o.CrashRetain, v) }
+}
+
+// WithCrashLogLines sets the number of most recent log records included in crash reports.
+func WithCrashLogLines(v int) Option {
+	return func(o *Options) { setOption(o, &o.CrashLogLines, v) }
+}
+
+// WithSignalTrace sets the SignalTrace field, the duration of the execution trace captured on SIGUSR2.
+func WithSignalTrace(v time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.SignalTrace, v) }
+}
+
+// WithSignalDump sets the SignalDump field. When enabled, SIGQUIT writes a goroutine dump and the process keeps
+// running instead of terminating. See Options.SignalDump.
+func WithSignalDump(v bool) Option {
+	return func(o *Options) { setOption(o, &o.SignalDump, v) }
+}
+
+// WithDiagnosticsInterval sets the DiagnosticsInterval field, the interval of the runtime diagnostics debug record.
+func WithDiagnosticsInterval(d time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.DiagnosticsInterval, d) }
+}
+
+// WithMaxDegradations sets the MaxDegradations field, the number of consecutive degraded errors before a restart.
+func WithMaxDegradations(v int) Option {
+	return func(o *Options) { setOption(o, &o.MaxDegradations, v) }
+}
+
+// WithRequestedRestartDelay sets the RequestedRestartDelay field, the delay of restarts requested by the service.
+func WithRequestedRestartDelay(v time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.RequestedRestartDelay, v) }
+}
+
+// WithMinimumRunDuration sets the MinimumRunDuration field. Exits of Run sooner than d are treated as failures.
+func WithMinimumRunDuration(d time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.MinimumRunDuration, d) }
+}
+
+// WithStartupProbe adds startup probes, e.g. TCPProbe or HTTPProbe, which are run before each Init until all of
+// them succeed. Only probes still failing after StartupProbeBudget fail the initialization.
+func WithStartupProbe(probes ...Probe) Option {
+	return func(o *Options) { o.StartupProbes = append(o.StartupProbes, probes...) }
+}
+
+// WithStartupProbeBudget sets the StartupProbeBudget field, the time the startup probes may take.
+func WithStartupProbeBudget(d time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.StartupProbeBudget, d) }
+}
+
+// WithInitWarnAfter sets the InitWarnAfter field, logging a warning if Init takes longer than d.
+func WithInitWarnAfter(d time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.InitWarnAfter, d) }
+}
+
+// WithCloseWarnAfter sets the CloseWarnAfter field, logging a warning if Close takes longer than d.
+func WithCloseWarnAfter(d time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.CloseWarnAfter, d) }
+}
+
+// WithMemoryLimit sets the MemoryLimit and MemoryCheckInterval fields, restarting the service gracefully once its
+// memory usage exceeds limit bytes.
+func WithMemoryLimit(limit uint64, checkInterval time.Duration) Option {
+	return func(o *Options) {
+		setOption(o, &o.MemoryLimit, limit)
+		setOption(o, &o.MemoryCheckInterval, checkInterval)
+	}
+}
+
+// WithMaxLifetime sets the MaxLifetime field, shutting the service down cleanly once it ran for d.
+func WithMaxLifetime(d time.Duration) Option {
+	return func(o *Options) { setOption(o, &o.MaxLifetime, d) }
+}
+
+// WithHealthCommand adds a HealthCommand to the HealthCommands field: while the service is running, cmd is run
+// every interval and killed after timeout. Exit status 0 is healthy; any other outcome sets the service unhealthy
+// (see SetHealth) with the stderr output of the command as reason, until all health commands succeed again.
+// Runs of a command never overlap.
+func WithHealthCommand(name string, cmd []string, interval, timeout time.Duration) Option {
+	return func(o *Options) {
+		o.HealthCommands = append(o.HealthCommands, HealthCommand{
+			Name:     name,
+			Command:  cmd,
+			Interval: interval,
+			Timeout:  timeout,
+		})
+	}
+}
+
+// WithHeartbeatURL sets the HeartbeatURL and HeartbeatInterval fields, pinging url every interval while the
+// service is ready.
+func WithHeartbeatURL(url string, interval time.Duration) Option {
+	return func(o *Options) {
+		setOption(o, &o.HeartbeatURL, url)
+		setOption(o, &o.HeartbeatInterval, interval)
+	}
+}
+
+// WithMemorySampler sets the MemorySampler field, replacing the sampling of the memory usage by the watchdog.
+func WithMemorySampler(fn func() MemorySample) Option {
+	return func(o *Options) { setOption(o, &o.MemorySampler, fn) }
+}
+
+// WithRestartOnSuccess sets the RestartOnSuccess field, restarting the service when Run returns nil.
+func WithRestartOnSuccess(v bool) Option {
+	return func(o *Options) { setOption(o, &o.RestartOnSuccess, v) }
+}
+
+// WithQuietErrors adds errors that RunAndExit treats like context.Canceled. See Options.QuietErrors.
+func WithQuietErrors(errs ...error) Option {
+	return func(o *Options) { o.QuietErrors = append(o.QuietErrors, errs...) }
+}
+
+// WithQuietErrorFunc sets the QuietErrorFunc field, a predicate for errors RunAndExit treats like context.Canceled.
+func WithQuietErrorFunc(fn func(error) bool) Option {
+	return func(o *Options) { setOption(o, &o.QuietErrorFunc, fn) }
+}
+
+// WithErrorPrintFrameFilters adds filters for the stack frames of errors printed by RunAndExit.
+// A frame is printed only if all filters return true.
+func WithErrorPrintFrameFilters(filters ...func(*ae.StackFrame) bool) Option {
+	return func(o *Options) { o.ErrorPrintFrameFilters = append(o.ErrorPrintFrameFilters, filters...) }
+}
+
+// WithErrorPrintFullStacks sets the ErrorPrintFullStacks field, disabling stack frame filtering of printed errors.
+func WithErrorPrintFullStacks(v bool) Option {
+	return func(o *Options) { setOption(o, &o.ErrorPrintFullStacks, v) }
+}
+
+// WithShowBanner sets the ShowBanner field, enabling or disabling the startup record.
+func WithShowBanner(v bool) Option {
+	return func(o *Options) { setOption(o, &o.ShowBanner, v) }
+}
+
+// WithLogSchema sets the LogSchema field, selecting the field names of JSON logs.
+func WithLogSchema(v LogSchema) Option {
+	return func(o *Options) { setOption(o, &o.LogSchema, v) }
+}
+
+// WithLogGCPProject sets the LogGCPProject field, the project ID of the trace correlation fields of the "gcp" log schema.
+func WithLogGCPProject(v string) Option {
+	return func(o *Options) { setOption(o, &o.LogGCPProject, v) }
+}
+
+// WithLogOutput sets the LogOutput field, selecting where logs are written ("stdout", "journald" or a
+// syslog endpoint). See Options.LogOutput.
+func WithLogOutput(v string) Option {
+	return func(o *Options) { setOption(o, &o.LogOutput, v) }
+}
+
+// WithLogMetrics sets the LogMetrics field, enabling or disabling the as.log.records counter.
+func WithLogMetrics(v bool) Option {
+	return func(o *Options) { setOption(o, &o.LogMetrics, v) }
+}
+
+// WithLogLevelHeader sets the LogLevelHeader field, the request header overriding the log level of a request.
+func WithLogLevelHeader(v string) Option {
+	return func(o *Options) { setOption(o, &o.LogLevelHeader, v) }
+}
+
+// WithHTTPClientDefaults sets the HTTPClientTimeout and HTTPClientSlowThreshold fields, the defaults of clients
+// created by HTTPClient.
+func WithHTTPClientDefaults(timeout, slowThreshold time.Duration) Option {
+	return func(o *Options) {
+		setOption(o, &o.HTTPClientTimeout, timeout)
+		setOption(o, &o.HTTPClientSlowThreshold, slowThreshold)
+	}
+}
+
+// WithOTELFallback sets the OTELFallback field, selecting the exporters used if none is configured.
+func WithOTELFallback(v OTELFallback) Option {
+	return func(o *Options) { setOption(o, &o.OTELFallback, v) }
+}
+
+// WithReadyFile sets the ReadyFile field, the path of a file existing while the service is ready.
+func WithReadyFile(path string) Option {
+	return func(o *Options) { setOption(o, &o.ReadyFile, path) }
+}
+
+// WithDataDir sets the DataDir field, the data directory returned by DataDir.
+func WithDataDir(path string) Option {
+	return func(o *Options) { setOption(o, &o.DataDir, path) }
+}
+
+// WithDataDirMode sets the DataDirMode field, the permission of the data directory.
+func WithDataDirMode(mode os.FileMode) Option {
+	return func(o *Options) { setOption(o, &o.DataDirMode, mode) }
+}
+
+// WithWipeDataDir sets the WipeDataDir field, removing the data directory on start.
+func WithWipeDataDir(v bool) Option {
+	return func(o *Options) { setOption(o, &o.WipeDataDir, v) }
+}
+
+// WithWorkingDir sets the WorkingDir field, the working directory of the process while the service runs.
+func WithWorkingDir(path string) Option {
+	return func(o *Options) { setOption(o, &o.WorkingDir, path) }
+}
+
+// WithUmask sets the Umask field, the umask of the process while the service runs, e.g. 0o027.
+func WithUmask(mask os.FileMode) Option {
+	return func(o *Options) { setOption(o, &o.Umask, &mask) }
+}
+
+// WithReadyFileMode sets the ReadyFileMode field, the permission of the ready file.
+func WithReadyFileMode(mode os.FileMode) Option {
+	return func(o *Options) { setOption(o, &o.ReadyFileMode, mode) }
+}
+
+// WithRegistrar adds a Registrar registering the service with an external system while it is running.
+func WithRegistrar(r Registrar) Option {
+	return func(o *Options) { o.Registrars = append(o.Registrars, r) }
+}
+
+// WithReporter adds a Reporter receiving recovered panics and terminal errors. It can be used multiple times.
+func WithReporter(r Reporter) Option {
+	return func(o *Options) { o.Reporters = append(o.Reporters, r) }
+}
+
+// WithMaxLifetimeRestarts sets the MaxLifetimeRestarts field, the limit of restarts since the process started.
+func WithMaxLifetimeRestarts(v int) Option {
+	return func(o *Options) { setOption(o, &o.MaxLifetimeRestarts, v) }
+}
+
+// WithLeakCheck sets the LeakCheckAttempts and LeakCheckThreshold fields, warning about goroutines leaked across
+// attempts consecutive restart attempts once they grew by more than threshold.
+func WithLeakCheck(attempts, threshold int) Option {
+	return func(o *Options) {
+		setOption(o, &o.LeakCheckAttempts, attempts)
+		setOption(o, &o.LeakCheckThreshold, threshold)
+	}
+}
+
+// WithRestartBudget sets the RestartBudget field, sharing the restart budget b with other services.
+func WithRestartBudget(b *RestartBudget) Option {
+	return func(o *Options) { setOption(o, &o.RestartBudget, b) }
+}
+
+// WithStopOnFirstExit sets the StopOnFirstExit field, stopping all services of the exit group g once one of them
+// exits successfully.
+func WithStopOnFirstExit(g *ExitGroup) Option {
+	return func(o *Options) { setOption(o, &o.StopOnFirstExit, g) }
+}
+
+// WithReloadOptionsOnRestart sets the ReloadOptionsOnRestart field, re-evaluating options before each restart.
+func WithReloadOptionsOnRestart(v bool) Option {
+	return func(o *Options) { setOption(o, &o.ReloadOptionsOnRestart, v) }
 }
 
 // applyOptions builds Options by applying the given Option funcs to DefaultOptions(),
@@ -166,6 +1024,18 @@ func WithDisableEnvPrefix(v bool) Option {
 // normalized with NormalizeEnvKey and passed to env.ParseWithOptions so that
 // Options fields (e.g. RESTART_ON_ERROR, GRACE_PERIOD) can be set via prefixed env vars.
 func applyOptions(name, namespace string, opts []Option) Options {
+	// Invalid env vars are reported by RunC; the fields parsed successfully still apply
+	o, _, _, _ := loadOptions(name, namespace, opts)
+	return o
+}
+
+// loadOptions builds Options like applyOptions, and additionally returns the fields set from a fallback env var
+// if EnvPrefixFallback is set, and the fields set both in code and by an env var. Env vars which cannot be parsed
+// are reported as an error; the returned Options then contain the fields parsed successfully.
+func loadOptions(name, namespace string, opts []Option) (Options, []envFallback, []optionConflict, error) {
+	// Namespace defaults count as options set in code, applied before the options of the service
+	opts = append(namespaceOptions(namespace), opts...)
+
 	o := DefaultOptions()
 	for _, opt := range opts {
 		opt(&o)
@@ -186,9 +1056,48 @@ func applyOptions(name, namespace string, opts []Option) Options {
 		o.EnvPrefix = NormalizeEnvKey(envPrefix) + "_"
 	}
 
-	_ = env.ParseWithOptions(&o, env.Options{
+	code := o
+	parseErr := env.ParseWithOptions(&o, env.Options{
 		Prefix: o.EnvPrefix,
 	})
 
-	return o
+	// Fields not set under the prefix fall back to the namespace-only prefix, then to no prefix
+	var fallbacks []envFallback
+	if prefixes := envFallbackPrefixes(o, namespace); len(prefixes) > 0 {
+		var environ map[string]string
+		environ, fallbacks = fallbackEnvironment(&o, o.EnvPrefix, prefixes)
+
+		// Invalid values under the prefix fail again, so only errors of the fallback values are added
+		if err := env.ParseWithOptions(&o, env.Options{
+			Prefix:      o.EnvPrefix,
+			Environment: environ,
+		}); err != nil && parseErr == nil {
+			parseErr = err
+		}
+	}
+
+	// Env vars override the fields set in code, unless OptionPrecedence is CodeWins
+	explicit := o.explicit
+	conflicts := resolveOptionConflicts(&o, code, explicit, namespace)
+
+	// The development preset only sets fields set neither in code nor by an env var
+	applyDevMode(&o, explicit, namespace)
+	applyRuntimeEnv(&o, namespace)
+
+	if o.LogDebug && o.DiagnosticsInterval == 0 {
+		o.DiagnosticsInterval = time.Minute
+	}
+
+	if o.OTELFallback == "" {
+		o.OTELFallback = OTELFallbackNoop
+		if o.LogDebug {
+			o.OTELFallback = OTELFallbackConsole
+		}
+	}
+
+	if parseErr != nil {
+		return o, fallbacks, conflicts, ae.Wrap("failed to parse options from the environment", parseErr)
+	}
+
+	return o, fallbacks, conflicts, nil
 }
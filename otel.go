@@ -4,10 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"go.aledante.io/ae"
 	"go.opentelemetry.io/contrib/exporters/autoexport"
 	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/metric"
 	metricNoop "go.opentelemetry.io/otel/metric/noop"
 	"go.opentelemetry.io/otel/propagation"
@@ -52,7 +59,7 @@ type tracerKey struct{}
 // If tracer is nil, a default tracer from the context's TracerProvider is used.
 func withTracer(ctx context.Context, tracer trace.Tracer) context.Context {
 	if tracer == nil {
-		tracer = TracerProvider(ctx).Tracer(tracerName)
+		tracer = scopedTracer(ctx, TracerProvider(ctx))
 	}
 	return context.WithValue(ctx, tracerKey{}, tracer)
 }
@@ -61,11 +68,17 @@ func withTracer(ctx context.Context, tracer trace.Tracer) context.Context {
 func Tracer(ctx context.Context) trace.Tracer {
 	v, ok := ctx.Value(tracerKey{}).(trace.Tracer)
 	if !ok {
-		return TracerProvider(ctx).Tracer(tracerName)
+		return scopedTracer(ctx, TracerProvider(ctx))
 	}
 	return v
 }
 
+// scopedTracer returns the tracer of provider whose instrumentation scope carries the name and namespace of the
+// service of ctx, so spans of services sharing a resource can be told apart.
+func scopedTracer(ctx context.Context, provider trace.TracerProvider) trace.Tracer {
+	return provider.Tracer(tracerName, trace.WithInstrumentationAttributes(scopeAttributes(ctx)...))
+}
+
 // meterProviderKey is the key type for storing the MeterProvider in context.
 type meterProviderKey struct{}
 
@@ -94,7 +107,7 @@ type meterKey struct{}
 // If meter is nil, a default meter from the context's MeterProvider is used.
 func withMeter(ctx context.Context, meter metric.Meter) context.Context {
 	if meter == nil {
-		meter = MeterProvider(ctx).Meter(meterName)
+		meter = scopedMeter(ctx, MeterProvider(ctx))
 	}
 	return context.WithValue(ctx, meterKey{}, meter)
 }
@@ -103,11 +116,30 @@ func withMeter(ctx context.Context, meter metric.Meter) context.Context {
 func Meter(ctx context.Context) metric.Meter {
 	v, ok := ctx.Value(meterKey{}).(metric.Meter)
 	if !ok {
-		return MeterProvider(ctx).Meter(meterName)
+		return scopedMeter(ctx, MeterProvider(ctx))
 	}
 	return v
 }
 
+// scopedMeter returns the meter of provider whose instrumentation scope carries the name and namespace of the
+// service of ctx, like scopedTracer.
+func scopedMeter(ctx context.Context, provider metric.MeterProvider) metric.Meter {
+	return provider.Meter(meterName, metric.WithInstrumentationAttributes(scopeAttributes(ctx)...))
+}
+
+// scopeAttributes returns the instrumentation scope attributes identifying the service of ctx.
+func scopeAttributes(ctx context.Context) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if name := Name(ctx); name != "" {
+		attrs = append(attrs, semconv.ServiceNameKey.String(name))
+	}
+	if namespace := Namespace(ctx); namespace != "" {
+		attrs = append(attrs, semconv.ServiceNamespaceKey.String(namespace))
+	}
+
+	return attrs
+}
+
 type textMapPropagatorKey struct{}
 
 func withTextMapPropagator(ctx context.Context, propagator propagation.TextMapPropagator) context.Context {
@@ -129,7 +161,7 @@ func TextMapPropagator(ctx context.Context) propagation.TextMapPropagator {
 
 // initOtel initializes OpenTelemetry providers or resources for the given context.
 // This currently panics as it is not implemented.
-func initOtel(ctx context.Context) (context.Context, func(context.Context) error, error) {
+func initOtel(ctx context.Context, opts Options) (context.Context, func(context.Context) error, error) {
 	var shutdownFuncs []func(context.Context) error
 	shutdown := func(shutdownCtx context.Context) error {
 		var errs []error
@@ -144,11 +176,13 @@ func initOtel(ctx context.Context) (context.Context, func(context.Context) error
 		return ae.WrapMany("OTEL shutdown failed", errs...)
 	}
 
-	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+	resourceAttrs := append([]attribute.KeyValue{
 		semconv.ServiceNameKey.String(Name(ctx)),
 		semconv.ServiceVersionKey.String(Version(ctx)),
 		semconv.ServiceNamespaceKey.String(Namespace(ctx)),
-	))
+	}, labelResourceAttrs(Labels(ctx))...)
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, resourceAttrs...))
 	if err != nil {
 		if errors.Is(err, resource.ErrSchemaURLConflict) {
 			// As defined by resource.Merge, the Merge returns a resource with an empty schema URL when the error
@@ -168,10 +202,19 @@ func initOtel(ctx context.Context) (context.Context, func(context.Context) error
 
 	propagator := propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{},
+		propagation.Baggage{},
 	)
 	ctx = withTextMapPropagator(ctx, propagator)
 
-	spanExporter, err := autoexport.NewSpanExporter(ctx, autoexport.WithFallbackSpanExporter(noopSpanExporterFunc))
+	// The fallback funcs are wrapped to record whether autoexport used them, see OTELInfo
+	spanFallback := fallbackSpanExporterFunc(opts.OTELFallback)
+	spanFallbackUsed := false
+	spanExporter, err := autoexport.NewSpanExporter(ctx,
+		autoexport.WithFallbackSpanExporter(func(ctx context.Context) (traceSdk.SpanExporter, error) {
+			spanFallbackUsed = true
+			return spanFallback(ctx)
+		}),
+	)
 	if err != nil {
 		return ctx, noopShutdown, ae.Wrap("failed to create OTEL span exporter", err)
 	}
@@ -181,21 +224,37 @@ func initOtel(ctx context.Context) (context.Context, func(context.Context) error
 		Logger(ctx).Warn(
 			fmt.Sprintf(
 				"using a no-op OTEL span exporter. Set %s and related env vars as required",
-				EnvKey(ctx, "OTEL_EXPORTER_OTLP_ENDPOINT"),
+				FullEnvKey(ctx, "OTEL_EXPORTER_OTLP_ENDPOINT"),
 			),
 		)
 	}
 
-	tracerProvider := traceSdk.NewTracerProvider(
+	tracerProviderOpts := []traceSdk.TracerProviderOption{
 		traceSdk.WithBatcher(spanExporter),
 		traceSdk.WithResource(res),
-	)
-	ctx = withTracerProvider(ctx, tracerProvider)
-	ctx = withTracer(ctx, tracerProvider.Tracer(tracerName))
+	}
+	if opts.TraceSampler != nil {
+		tracerProviderOpts = append(tracerProviderOpts, traceSdk.WithSampler(opts.TraceSampler))
+	}
 
-	metricReader, err := autoexport.NewMetricReader(ctx,
-		autoexport.WithFallbackMetricReader(noopMetricReaderFunc),
-	)
+	tracerProvider := traceSdk.NewTracerProvider(tracerProviderOpts...)
+	ctx = withTracerProvider(ctx, tracerProvider)
+	ctx = withTracer(ctx, scopedTracer(ctx, tracerProvider))
+
+	interval := metricExportInterval(ctx, opts)
+	var metricReader metricSdk.Reader
+	metricFallback := fallbackMetricReaderFunc(opts.OTELFallback, interval)
+	metricFallbackUsed := false
+	err = withMetricExportIntervalEnv(interval, func() error {
+		var err error
+		metricReader, err = autoexport.NewMetricReader(ctx,
+			autoexport.WithFallbackMetricReader(func(ctx context.Context) (metricSdk.Reader, error) {
+				metricFallbackUsed = true
+				return metricFallback(ctx)
+			}),
+		)
+		return err
+	})
 	if err != nil {
 		return ctx, noopShutdown, ae.Wrap("failed to create OTEL metric reader", err)
 	}
@@ -206,17 +265,28 @@ func initOtel(ctx context.Context) (context.Context, func(context.Context) error
 		Logger(ctx).Warn(
 			fmt.Sprintf(
 				"using a no-op OTEL metric exporter. Set %s and related env vars as required",
-				EnvKey(ctx, "OTEL_METRICS_EXPORTER"),
+				FullEnvKey(ctx, "OTEL_METRICS_EXPORTER"),
 			),
 		)
 	}
 
+	info := OTELConfig{
+		Traces:  spanExporterInfo(spanExporter, opts.OTELFallback, spanFallbackUsed),
+		Metrics: metricReaderInfo(metricReader, opts.OTELFallback, metricFallbackUsed),
+	}
+	ctx = withOTELInfo(ctx, info)
+	logOTELInfo(ctx, info)
+
 	meterProvider := metricSdk.NewMeterProvider(
 		metricSdk.WithReader(metricReader),
 		metricSdk.WithResource(res),
 	)
 	ctx = withMeterProvider(ctx, meterProvider)
-	ctx = withMeter(ctx, meterProvider.Meter(meterName))
+	ctx = withMeter(ctx, scopedMeter(ctx, meterProvider))
+
+	if err := initBuildInfoMetric(ctx, opts, Meter(ctx)); err != nil {
+		Logger(ctx).Warn("build info metric unavailable", "error", err)
+	}
 
 	// We will be missing go.schedule.duration, but that is only exposed by runtime.NewProducer which we cannot add
 	// to the autoexport reader... but it does not seem to be of much use
@@ -227,6 +297,136 @@ func initOtel(ctx context.Context) (context.Context, func(context.Context) error
 	return ctx, shutdown, nil
 }
 
+// OTELFallback selects the exporters used if no OTEL exporter is configured by the environment.
+type OTELFallback string
+
+const (
+	// OTELFallbackNoop discards spans and metrics, logging a warning.
+	OTELFallbackNoop OTELFallback = "noop"
+	// OTELFallbackConsole writes spans and metrics to stdout.
+	OTELFallbackConsole OTELFallback = "console"
+	// OTELFallbackError fails the startup.
+	OTELFallbackError OTELFallback = "error"
+)
+
+// fallbackSpanExporterFunc returns the func creating the span exporter used if none is configured.
+func fallbackSpanExporterFunc(fallback OTELFallback) func(context.Context) (traceSdk.SpanExporter, error) {
+	switch fallback {
+	case OTELFallbackConsole:
+		return func(ctx context.Context) (traceSdk.SpanExporter, error) {
+			Logger(ctx).Info("using the console OTEL span exporter")
+			return stdouttrace.New()
+		}
+	case OTELFallbackError:
+		return func(ctx context.Context) (traceSdk.SpanExporter, error) {
+			return nil, ae.New().Msg(fmt.Sprintf(
+				"no OTEL span exporter configured. Set %s and related env vars as required",
+				FullEnvKey(ctx, "OTEL_EXPORTER_OTLP_ENDPOINT"),
+			))
+		}
+	default:
+		return noopSpanExporterFunc
+	}
+}
+
+// fallbackMetricReaderFunc returns the func creating the metric reader used if none is configured.
+// A positive interval overrides the export interval of the periodic reader.
+func fallbackMetricReaderFunc(fallback OTELFallback, interval time.Duration) func(context.Context) (metricSdk.Reader, error) {
+	switch fallback {
+	case OTELFallbackConsole:
+		return func(ctx context.Context) (metricSdk.Reader, error) {
+			exporter, err := stdoutmetric.New()
+			if err != nil {
+				return nil, err
+			}
+
+			var readerOpts []metricSdk.PeriodicReaderOption
+			if interval > 0 {
+				readerOpts = append(readerOpts, metricSdk.WithInterval(interval))
+			}
+
+			Logger(ctx).Info("using the console OTEL metric exporter")
+			return metricSdk.NewPeriodicReader(exporter, readerOpts...), nil
+		}
+	case OTELFallbackError:
+		return func(ctx context.Context) (metricSdk.Reader, error) {
+			return nil, ae.New().Msg(fmt.Sprintf(
+				"no OTEL metric exporter configured. Set %s and related env vars as required",
+				FullEnvKey(ctx, "OTEL_METRICS_EXPORTER"),
+			))
+		}
+	default:
+		return noopMetricReaderFunc
+	}
+}
+
+// flushOtel flushes the spans and metrics buffered by the TracerProvider and MeterProvider of ctx.
+func flushOtel(ctx context.Context) error {
+	type flusher interface {
+		ForceFlush(ctx context.Context) error
+	}
+
+	var errs []error
+	for _, provider := range []any{TracerProvider(ctx), MeterProvider(ctx)} {
+		if f, ok := provider.(flusher); ok {
+			if err := f.ForceFlush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return ae.WrapMany("OTEL flush failed", errs...)
+}
+
+// minMetricExportInterval is the shortest accepted MetricExportInterval.
+const minMetricExportInterval = time.Second
+
+// metricExportInterval returns opts.MetricExportInterval, or zero if it is unset or shorter than
+// minMetricExportInterval, in which case a warning is logged and the OTEL default applies.
+func metricExportInterval(ctx context.Context, opts Options) time.Duration {
+	if opts.MetricExportInterval <= 0 {
+		return 0
+	}
+	if opts.MetricExportInterval < minMetricExportInterval {
+		Logger(ctx).Warn("ignoring sub-second metric export interval",
+			"metric_export_interval", opts.MetricExportInterval.String(),
+		)
+		return 0
+	}
+
+	return opts.MetricExportInterval
+}
+
+// metricExportIntervalEnvMu serializes withMetricExportIntervalEnv, so services started concurrently in one process
+// do not observe or restore each other's OTEL_METRIC_EXPORT_INTERVAL.
+var metricExportIntervalEnvMu sync.Mutex
+
+// withMetricExportIntervalEnv calls fn with OTEL_METRIC_EXPORT_INTERVAL set to interval, so periodic readers
+// created by autoexport use it. The previous value is restored afterward. A zero interval leaves the environment
+// unchanged, so the OTEL_METRIC_EXPORT_INTERVAL set by the user applies. Calls are serialized, since the
+// environment is shared by the process.
+func withMetricExportIntervalEnv(interval time.Duration, fn func() error) error {
+	metricExportIntervalEnvMu.Lock()
+	defer metricExportIntervalEnvMu.Unlock()
+
+	if interval <= 0 {
+		return fn()
+	}
+
+	const key = "OTEL_METRIC_EXPORT_INTERVAL"
+	prev, hadPrev := os.LookupEnv(key)
+	_ = os.Setenv(key, strconv.FormatInt(interval.Milliseconds(), 10))
+	defer func() {
+		if hadPrev {
+			_ = os.Setenv(key, prev)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	}()
+
+	return fn()
+}
+
 func noopShutdown(ctx context.Context) error {
 	return nil
 }
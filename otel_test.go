@@ -0,0 +1,258 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	metricSdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	traceSdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+)
+
+// unsetOTELExporterEnv unsets the OTEL exporter env vars for the test, so the OTELFallback applies.
+func unsetOTELExporterEnv(t *testing.T) {
+	t.Helper()
+
+	for _, key := range []string{"OTEL_TRACES_EXPORTER", "OTEL_METRICS_EXPORTER"} {
+		t.Setenv(key, "")
+		_ = os.Unsetenv(key)
+	}
+}
+
+func TestOTELFallback(t *testing.T) {
+	unsetOTELExporterEnv(t)
+
+	tests := []struct {
+		fallback   OTELFallback
+		wantTraces string
+		wantReader string
+		wantNoop   bool
+		wantErr    bool
+	}{
+		{fallback: OTELFallbackNoop, wantTraces: "as.noopSpanExporter", wantReader: "as.noopMetricReader", wantNoop: true},
+		{fallback: OTELFallbackConsole, wantTraces: "*stdouttrace.Exporter", wantReader: "*metric.PeriodicReader"},
+		{fallback: OTELFallbackError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.fallback), func(t *testing.T) {
+			ctx := WithLogger(withName(context.Background(), "test"), slog.New(slog.DiscardHandler))
+			ctx, shutdown, err := initOtel(ctx, Options{OTELFallback: tt.fallback})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initOtel() error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() {
+				if err := shutdown(context.Background()); err != nil {
+					t.Errorf("shutdown() = %v", err)
+				}
+			})
+
+			info := OTELInfo(ctx)
+			for _, exporter := range []OTELExporterInfo{info.Traces, info.Metrics} {
+				if exporter.Exporter != string(tt.fallback) || !exporter.Fallback || exporter.Noop != tt.wantNoop {
+					t.Errorf("exporter = %+v, want fallback %s with noop %t", exporter, tt.fallback, tt.wantNoop)
+				}
+			}
+			if info.Traces.Type != tt.wantTraces {
+				t.Errorf("span exporter type = %s, want %s", info.Traces.Type, tt.wantTraces)
+			}
+			if info.Metrics.Type != tt.wantReader {
+				t.Errorf("metric reader type = %s, want %s", info.Metrics.Type, tt.wantReader)
+			}
+		})
+	}
+}
+
+func TestOTELFallbackDefault(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		opts []Option
+		want OTELFallback
+	}{
+		{name: "default", want: OTELFallbackNoop},
+		{name: "debug", opts: []Option{WithLogDebug(true)}, want: OTELFallbackConsole},
+		{name: "option", opts: []Option{WithLogDebug(true), WithOTELFallback(OTELFallbackNoop)}, want: OTELFallbackNoop},
+		{name: "env", env: map[string]string{"ASTEST_TEST_OTEL_FALLBACK": "error"}, want: OTELFallbackError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			if got := applyOptions("test", "astest", tt.opts).OTELFallback; got != tt.want {
+				t.Errorf("OTELFallback = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricExportInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+		wantWarn bool
+	}{
+		{name: "unset", interval: 0, want: 0},
+		{name: "valid", interval: 2 * time.Second, want: 2 * time.Second},
+		{name: "minimum", interval: time.Second, want: time.Second},
+		{name: "sub-second", interval: 100 * time.Millisecond, want: 0, wantWarn: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := &logCapture{}
+			ctx := WithLogger(context.Background(), slog.New(slog.NewJSONHandler(logs, nil)))
+
+			if got := metricExportInterval(ctx, Options{MetricExportInterval: tt.interval}); got != tt.want {
+				t.Errorf("metricExportInterval() = %s, want %s", got, tt.want)
+			}
+			if warned := logs.find("ignoring sub-second metric export interval") != nil; warned != tt.wantWarn {
+				t.Errorf("warned = %t, want %t", warned, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestMetricExportIntervalEnv(t *testing.T) {
+	const key = "OTEL_METRIC_EXPORT_INTERVAL"
+	t.Setenv(key, "30000")
+
+	// Concurrent calls each observe their own interval, or the value set by the user
+	var wg sync.WaitGroup
+	for i := range 20 {
+		interval := time.Duration(i) * time.Second
+		want := strconv.FormatInt(interval.Milliseconds(), 10)
+		if interval == 0 {
+			want = "30000"
+		}
+
+		wg.Go(func() {
+			_ = withMetricExportIntervalEnv(interval, func() error {
+				for range 5 {
+					if got := os.Getenv(key); got != want {
+						t.Errorf("%s = %q, want %q", key, got, want)
+					}
+					time.Sleep(time.Millisecond)
+				}
+				return nil
+			})
+		})
+	}
+	wg.Wait()
+
+	if got := os.Getenv(key); got != "30000" {
+		t.Errorf("%s = %q after the calls, want it restored", key, got)
+	}
+}
+
+func TestTraceSampleRatio(t *testing.T) {
+	unsetOTELExporterEnv(t)
+
+	// Both services run in one process at the same time, each sampling at its own ratio
+	var started sync.WaitGroup
+	started.Add(2)
+	var wg sync.WaitGroup
+	for name, ratio := range map[string]float64{"proxy": 0, "control": 1} {
+		wg.Go(func() {
+			sampled := 0
+			svc := &testService{name: name, run: func(ctx context.Context) error {
+				started.Done()
+				started.Wait()
+				for range 100 {
+					_, span := Tracer(ctx).Start(ctx, "operation")
+					if span.SpanContext().IsSampled() {
+						sampled++
+					}
+					span.End()
+				}
+				return nil
+			}}
+
+			if err := RunC(svc, context.Background(), testOptions(WithTraceSampleRatio(ratio))...); err != nil {
+				t.Errorf("RunC(%s) = %v", name, err)
+			}
+			if want := int(ratio * 100); sampled != want {
+				t.Errorf("%s sampled %d of 100 spans, want %d", name, sampled, want)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// scopeService returns the service in the instrumentation scope attributes, as "<namespace>/<name>".
+func scopeService(attrs attribute.Set) string {
+	name, _ := attrs.Value(semconv.ServiceNameKey)
+	namespace, _ := attrs.Value(semconv.ServiceNamespaceKey)
+
+	return namespace.AsString() + "/" + name.AsString()
+}
+
+func TestScopedTracerAndMeter(t *testing.T) {
+	// Both services share the providers, and thereby the resource
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := traceSdk.NewTracerProvider(traceSdk.WithSpanProcessor(recorder))
+	reader := metricSdk.NewManualReader()
+	meterProvider := metricSdk.NewMeterProvider(metricSdk.WithReader(reader))
+	t.Cleanup(func() {
+		_ = tracerProvider.Shutdown(context.Background())
+		_ = meterProvider.Shutdown(context.Background())
+	})
+
+	for _, name := range []string{"api", "worker"} {
+		ctx := withNamespace(withName(context.Background(), name), "shop")
+		ctx = withTracerProvider(ctx, tracerProvider)
+		ctx = withTracer(ctx, nil)
+		ctx = withMeterProvider(ctx, meterProvider)
+		ctx = withMeter(ctx, nil)
+
+		_, span := Tracer(ctx).Start(ctx, name)
+		span.End()
+
+		counter, err := Meter(ctx).Int64Counter("requests")
+		if err != nil {
+			t.Fatal(err)
+		}
+		counter.Add(ctx, 1)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	for _, span := range spans {
+		scope := span.InstrumentationScope()
+		if scope.Name != tracerName {
+			t.Errorf("scope name = %q, want %q", scope.Name, tracerName)
+		}
+		if got, want := scopeService(scope.Attributes), "shop/"+span.Name(); got != want {
+			t.Errorf("scope of span %s = %s, want %s", span.Name(), got, want)
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	var scopes []string
+	for _, sm := range rm.ScopeMetrics {
+		scopes = append(scopes, scopeService(sm.Scope.Attributes))
+	}
+	if len(scopes) != 2 || scopes[0] == scopes[1] {
+		t.Errorf("metric scopes = %v, want one per service", scopes)
+	}
+}
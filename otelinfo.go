@@ -0,0 +1,148 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/exporters/autoexport"
+	metricSdk "go.opentelemetry.io/otel/sdk/metric"
+	traceSdk "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OTELExporterInfo describes the exporter chosen for a signal (traces or metrics) when OTEL was initialized.
+type OTELExporterInfo struct {
+	// Exporter is the name of the exporter: the value of OTEL_TRACES_EXPORTER or OTEL_METRICS_EXPORTER (e.g. otlp,
+	// console, none), or the OTELFallback if the environment configures none.
+	Exporter string `json:"exporter"`
+	// Type is the Go type of the exporter or metric reader.
+	Type string `json:"type"`
+	// Fallback reports whether the OTELFallback was used.
+	Fallback bool `json:"fallback"`
+	// Noop reports whether spans or metrics are discarded.
+	Noop bool `json:"noop"`
+	// Endpoint is the OTLP endpoint from the standard OTEL env vars, if any.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Protocol is the OTLP protocol from the standard OTEL env vars, if any.
+	Protocol string `json:"protocol,omitempty"`
+	// Headers are the OTLP headers from the standard OTEL env vars. Values of headers carrying credentials are
+	// redacted.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// OTELConfig is the effective OTEL exporter configuration of a service, see OTELInfo.
+type OTELConfig struct {
+	Traces  OTELExporterInfo `json:"traces"`
+	Metrics OTELExporterInfo `json:"metrics"`
+}
+
+// otelInfoKey is the context key of the OTELConfig.
+type otelInfoKey struct{}
+
+// withOTELInfo returns a new context carrying the effective OTEL exporter configuration.
+func withOTELInfo(ctx context.Context, info OTELConfig) context.Context {
+	return context.WithValue(ctx, otelInfoKey{}, info)
+}
+
+// OTELInfo returns the exporters the OTEL span exporter and metric reader of the service were created with, as
+// decided by the OTEL env vars and the OTELFallback. It is also logged at startup and included in the expvar
+// state. If OTEL was not initialized for the context, the zero value is returned.
+func OTELInfo(ctx context.Context) OTELConfig {
+	info, _ := ctx.Value(otelInfoKey{}).(OTELConfig)
+	return info
+}
+
+// spanExporterInfo describes the span exporter created by autoexport; fallback reports whether it was created by
+// the fallback func.
+func spanExporterInfo(exporter traceSdk.SpanExporter, fallbackName OTELFallback, fallback bool) OTELExporterInfo {
+	info := OTELExporterInfo{
+		Exporter: os.Getenv("OTEL_TRACES_EXPORTER"),
+		Type:     fmt.Sprintf("%T", exporter),
+		Fallback: fallback,
+		Noop:     isNoopSpanExporter(exporter) || autoexport.IsNoneSpanExporter(exporter),
+	}
+
+	return otlpExporterInfo(info, fallbackName, "TRACES")
+}
+
+// metricReaderInfo describes the metric reader created by autoexport; fallback reports whether it was created by
+// the fallback func.
+func metricReaderInfo(reader metricSdk.Reader, fallbackName OTELFallback, fallback bool) OTELExporterInfo {
+	info := OTELExporterInfo{
+		Exporter: os.Getenv("OTEL_METRICS_EXPORTER"),
+		Type:     fmt.Sprintf("%T", reader),
+		Fallback: fallback,
+		Noop:     isNoopMetricReader(reader) || autoexport.IsNoneMetricReader(reader),
+	}
+
+	return otlpExporterInfo(info, fallbackName, "METRICS")
+}
+
+// otlpExporterInfo completes info with the exporter name and, for OTLP exporters, the endpoint, protocol and
+// headers configured by the signal-specific or generic OTLP env vars.
+func otlpExporterInfo(info OTELExporterInfo, fallbackName OTELFallback, signal string) OTELExporterInfo {
+	switch {
+	case info.Fallback:
+		info.Exporter = string(fallbackName)
+		if info.Exporter == "" {
+			info.Exporter = string(OTELFallbackNoop)
+		}
+		return info
+	case info.Exporter == "":
+		info.Exporter = "otlp"
+	}
+	if info.Exporter != "otlp" {
+		return info
+	}
+
+	lookup := func(key string) string {
+		if v := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_" + key); v != "" {
+			return v
+		}
+		return os.Getenv("OTEL_EXPORTER_OTLP_" + key)
+	}
+
+	info.Endpoint = lookup("ENDPOINT")
+	info.Protocol = lookup("PROTOCOL")
+	info.Headers = redactedOTLPHeaders(lookup("HEADERS"))
+
+	return info
+}
+
+// redactedOTLPHeaders parses OTLP headers in the "key1=value1,key2=value2" form of OTEL_EXPORTER_OTLP_HEADERS,
+// redacting the values of headers which look like credentials, e.g. Authorization or X-Api-Key.
+func redactedOTLPHeaders(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, _ := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+
+		if isSecretEnvKey(key) || strings.Contains(NormalizeEnvKey(key), "KEY") {
+			value = redactedValue
+		}
+		headers[key] = strings.TrimSpace(value)
+	}
+
+	return headers
+}
+
+// logOTELInfo logs the effective OTEL exporter configuration.
+func logOTELInfo(ctx context.Context, info OTELConfig) {
+	Logger(ctx).Info("OTEL exporters configured",
+		"traces_exporter", info.Traces.Exporter,
+		"traces_type", info.Traces.Type,
+		"traces_endpoint", info.Traces.Endpoint,
+		"metrics_exporter", info.Metrics.Exporter,
+		"metrics_type", info.Metrics.Type,
+		"metrics_endpoint", info.Metrics.Endpoint,
+		"fallback", info.Traces.Fallback || info.Metrics.Fallback,
+	)
+}
@@ -0,0 +1,133 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOTELInfo(t *testing.T) {
+	unsetOTELExporterEnv(t)
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantTraces  OTELExporterInfo
+		wantMetrics OTELExporterInfo
+	}{
+		{
+			name: "otlp",
+			env: map[string]string{
+				"OTEL_TRACES_EXPORTER":                "otlp",
+				"OTEL_METRICS_EXPORTER":               "otlp",
+				"OTEL_EXPORTER_OTLP_ENDPOINT":         "http://127.0.0.1:1",
+				"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT": "http://127.0.0.1:2",
+				"OTEL_EXPORTER_OTLP_PROTOCOL":         "http/protobuf",
+				"OTEL_EXPORTER_OTLP_HEADERS":          "Authorization=Bearer secret, X-Tenant=acme",
+			},
+			wantTraces: OTELExporterInfo{
+				Exporter: "otlp",
+				Endpoint: "http://127.0.0.1:1",
+				Protocol: "http/protobuf",
+				Headers:  map[string]string{"Authorization": redactedValue, "X-Tenant": "acme"},
+			},
+			// The signal-specific env vars take precedence
+			wantMetrics: OTELExporterInfo{
+				Exporter: "otlp",
+				Endpoint: "http://127.0.0.1:2",
+				Protocol: "http/protobuf",
+				Headers:  map[string]string{"Authorization": redactedValue, "X-Tenant": "acme"},
+			},
+		},
+		{
+			name:        "none",
+			env:         map[string]string{"OTEL_TRACES_EXPORTER": "none", "OTEL_METRICS_EXPORTER": "none"},
+			wantTraces:  OTELExporterInfo{Exporter: "none", Noop: true},
+			wantMetrics: OTELExporterInfo{Exporter: "none", Noop: true},
+		},
+		{
+			name:        "fallback",
+			env:         map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://127.0.0.1:1"},
+			wantTraces:  OTELExporterInfo{Exporter: "noop", Fallback: true, Noop: true},
+			wantMetrics: OTELExporterInfo{Exporter: "noop", Fallback: true, Noop: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			ctx := WithLogger(withName(context.Background(), "test"), slog.New(slog.DiscardHandler))
+			ctx, shutdown, err := initOtel(ctx, Options{OTELFallback: OTELFallbackNoop})
+			if err != nil {
+				t.Fatalf("initOtel() = %v", err)
+			}
+			t.Cleanup(func() {
+				// Nothing listens on the OTLP endpoints, so the final export may fail
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				_ = shutdown(ctx)
+			})
+
+			info := OTELInfo(ctx)
+			for _, got := range []struct {
+				signal    string
+				got, want OTELExporterInfo
+			}{{"traces", info.Traces, tt.wantTraces}, {"metrics", info.Metrics, tt.wantMetrics}} {
+				if got.got.Type == "" {
+					t.Errorf("%s type is empty", got.signal)
+				}
+				got.got.Type = ""
+				if !reflect.DeepEqual(got.got, got.want) {
+					t.Errorf("%s = %+v, want %+v", got.signal, got.got, got.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOTELInfoPublished(t *testing.T) {
+	unsetOTELExporterEnv(t)
+
+	var info OTELConfig
+	var published expvarStatus
+	svc := &testService{
+		name: "otelinfo",
+		run: func(ctx context.Context) error {
+			info = OTELInfo(ctx)
+			published, _ = debugVars(t, "astest/otelinfo")
+			return nil
+		},
+	}
+
+	logs := &logCapture{}
+	opts := testOptions(captureLogs(svc, logs), WithOTELFallback(OTELFallbackNoop))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if info.Traces.Exporter != "noop" || !info.Traces.Fallback || info.Metrics.Exporter != "noop" {
+		t.Errorf("OTELInfo() = %+v, want the noop fallback", info)
+	}
+	if !reflect.DeepEqual(published.OTEL, info) {
+		t.Errorf("expvar OTEL = %+v, want %+v", published.OTEL, info)
+	}
+
+	record := logs.find("OTEL exporters configured")
+	if record == nil {
+		t.Fatal("OTEL exporters not logged")
+	}
+	if record["traces_exporter"] != "noop" || record["metrics_type"] != info.Metrics.Type || record["fallback"] != true {
+		t.Errorf("logged configuration = %v", record)
+	}
+}
+
+func TestOTELInfoWithoutOTEL(t *testing.T) {
+	if info := OTELInfo(context.Background()); !reflect.DeepEqual(info, OTELConfig{}) {
+		t.Errorf("OTELInfo() = %+v, want the zero value", info)
+	}
+}
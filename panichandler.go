@@ -0,0 +1,195 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fatalOutputPrefix is the file name prefix of the crash output of the runtime, see GlobalPanicHandler.
+const fatalOutputPrefix = "fatal-"
+
+// Recover recovers a panic in a goroutine started by the service without Go or TaskGroup, and converts it into an
+// error of the service. It must be deferred directly at the top of the goroutine:
+//
+//	go func() {
+//		defer as.Recover(ctx)
+//		...
+//	}()
+//
+// The panic is written to the crash report and passed to the reporters, counted, and cancels the context passed
+// to Run with an error carrying the panic value and stack, which becomes the error of the service, subject to the
+// restart policy. If ctx was not created by the supervisor, the panic is logged and the goroutine exits.
+func Recover(ctx context.Context) {
+	cause := recover()
+	if cause == nil {
+		return
+	}
+
+	stack := debug.Stack()
+	err := &goroutineError{name: "unsupervised", err: panicError(ctx, cause, nil)}
+
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		Logger(ctx).Error("recovered panic in goroutine outside of a supervised service", "error", err)
+		return
+	}
+
+	Logger(ctx).Error("recovered panic in goroutine", "error", err)
+	sup.recordPanic(ctx, cause, stack)
+	sup.failAttempt(err)
+}
+
+// recordPanic counts a panic recovered in a goroutine of the service, writes its crash report, and passes it to
+// the reporters. It is shared by Recover and the goroutines started by Go and TaskGroup.
+func (s *supervisor) recordPanic(ctx context.Context, cause any, stack []byte) {
+	s.countPanic()
+
+	s.mu.Lock()
+	handle := s.handlePanic
+	s.mu.Unlock()
+	if handle != nil {
+		handle(ctx, cause, stack)
+	}
+}
+
+// globalPanicHandler is the process-wide state of the crash output installed by initGlobalPanicHandler. The crash
+// output is process-global, so it is installed by the first service enabling GlobalPanicHandler and kept until the
+// last of them stopped.
+var globalPanicHandler struct {
+	mu    sync.Mutex
+	users int
+	path  string
+}
+
+// initGlobalPanicHandler prepares the last-resort handling of panics no recover intercepts, if GlobalPanicHandler
+// is enabled and a crash directory is configured. Go cannot run code after such a panic, so the crash output of the
+// runtime (the panic value and the traceback otherwise only written to stderr) is additionally written to a file
+// in the crash directory with debug.SetCrashOutput. Files left by previous processes are logged, passed to the
+// reporters as panics, and kept as crash reports. It returns a function releasing the crash output; the file is
+// removed once no service of the process uses it anymore. Services running concurrently share the file in the
+// crash directory of the first of them.
+func initGlobalPanicHandler(ctx context.Context, opts Options) func() {
+	if !opts.GlobalPanicHandler {
+		return func() {}
+	}
+	if opts.CrashDir == "" {
+		Logger(ctx).Warn("global panic handler requires a crash directory, not enabling it")
+		return func() {}
+	}
+
+	if err := os.MkdirAll(opts.CrashDir, 0o755); err != nil {
+		Logger(ctx).Error("failed to create crash directory", "path", opts.CrashDir, "error", err)
+		return func() {}
+	}
+
+	reportFatalOutputs(ctx, opts)
+
+	g := &globalPanicHandler
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.users > 0 {
+		g.users++
+		return releaseGlobalPanicHandler
+	}
+
+	name := fmt.Sprintf("%s%s-%d.txt", fatalOutputPrefix, time.Now().UTC().Format("20060102T150405.000000000Z"), os.Getpid())
+	path := filepath.Join(opts.CrashDir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		Logger(ctx).Error("failed to create crash output file", "path", path, "error", err)
+		return func() {}
+	}
+
+	if err := debug.SetCrashOutput(f, debug.CrashOptions{}); err != nil {
+		Logger(ctx).Error("failed to set crash output", "path", path, "error", err)
+		_ = f.Close()
+		_ = os.Remove(path)
+		return func() {}
+	}
+
+	// SetCrashOutput duplicates the file descriptor
+	_ = f.Close()
+
+	g.users = 1
+	g.path = path
+
+	return releaseGlobalPanicHandler
+}
+
+// releaseGlobalPanicHandler clears the crash output and removes its file once the last service using it stopped.
+func releaseGlobalPanicHandler() {
+	g := &globalPanicHandler
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.users--; g.users > 0 {
+		return
+	}
+
+	_ = debug.SetCrashOutput(nil, debug.CrashOptions{})
+	_ = os.Remove(g.path)
+	g.path = ""
+}
+
+// fatalOutputPid returns the pid of the process which created the crash output file with the given name.
+func fatalOutputPid(name string) (int, bool) {
+	name = strings.TrimSuffix(name, ".txt")
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return 0, false
+	}
+
+	pid, err := strconv.Atoi(name[i+1:])
+	return pid, err == nil && pid > 0
+}
+
+// reportFatalOutputs reports the crash output files left in the crash directory by processes which crashed, and
+// renames them to crash reports. Empty files, left by processes killed without a crash, are removed. Files of
+// processes still running, including this one, are in use and skipped, as are files not naming a process.
+func reportFatalOutputs(ctx context.Context, opts Options) {
+	entries, err := os.ReadDir(opts.CrashDir)
+	if err != nil {
+		Logger(ctx).Error("failed to list crash directory", "path", opts.CrashDir, "error", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), fatalOutputPrefix) {
+			continue
+		}
+
+		if pid, ok := fatalOutputPid(entry.Name()); !ok || processAlive(pid) {
+			continue
+		}
+
+		path := filepath.Join(opts.CrashDir, entry.Name())
+		output, err := os.ReadFile(path)
+		if err != nil {
+			Logger(ctx).Error("failed to read crash output", "path", path, "error", err)
+			continue
+		}
+		if len(output) == 0 {
+			_ = os.Remove(path)
+			continue
+		}
+
+		value := "fatal error in a previous process: " + firstLine(string(output))
+		Logger(ctx).Error("previous process crashed", "path", path, "error", value)
+		reportPanic(ctx, opts, value, output)
+
+		report := filepath.Join(opts.CrashDir, crashReportPrefix+strings.TrimPrefix(entry.Name(), fatalOutputPrefix))
+		if err := os.Rename(path, report); err != nil {
+			Logger(ctx).Error("failed to keep crash output as crash report", "path", path, "error", err)
+		}
+	}
+
+	pruneCrashReports(ctx, opts)
+}
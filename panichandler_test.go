@@ -0,0 +1,198 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	dir := t.TempDir()
+	reporter := &recordingReporter{}
+
+	attempts := 0
+	svc := &testService{run: func(ctx context.Context) error {
+		attempts++
+		go func() {
+			defer Recover(ctx)
+			panic("unsupervised boom")
+		}()
+
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	logs := &logCapture{}
+	opts := testOptions(captureLogs(svc, logs), WithCrashDir(dir), WithReporter(reporter), WithRestartOnError(false))
+	err := RunC(svc, context.Background(), opts...)
+
+	// The panic becomes the error of the service instead of crashing the process
+	if err == nil || !strings.Contains(err.Error(), "unsupervised boom") {
+		t.Fatalf("RunC() = %v, want the panic", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+	if logs.find("recovered panic in goroutine") == nil {
+		t.Error("panic not logged")
+	}
+	if summary := logs.find("service exited"); summary == nil || summary["panics"] != float64(1) {
+		t.Errorf("exit summary = %v, want one panic", summary)
+	}
+
+	reporter.mu.Lock()
+	panics := reporter.panics
+	reporter.mu.Unlock()
+	if len(panics) != 1 || panics[0] != "unsupervised boom" {
+		t.Errorf("reported panics = %v", panics)
+	}
+	if reports, _ := filepath.Glob(filepath.Join(dir, crashReportPrefix+"*.txt")); len(reports) != 1 {
+		t.Errorf("crash reports = %v, want one", reports)
+	}
+}
+
+func TestRecoverWithoutSupervisor(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer Recover(context.Background())
+		panic("boom")
+	}()
+	<-done
+}
+
+// deadPid is the pid of a process which does not exist, above the maximum pid of common systems.
+const deadPid = 1 << 30
+
+func TestGlobalPanicHandler(t *testing.T) {
+	dir := t.TempDir()
+	output := "panic: boom\n\ngoroutine 7 [running]:\nmain.main()\n"
+	files := map[string]string{
+		fmt.Sprintf("%s20260101T000000.000000000Z-%d.txt", fatalOutputPrefix, deadPid):   output,
+		fmt.Sprintf("%s20260101T000000.000000000Z-%d.txt", fatalOutputPrefix, deadPid+1): "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reporter := &recordingReporter{}
+	var during []string
+	svc := &testService{run: func(ctx context.Context) error {
+		during, _ = filepath.Glob(filepath.Join(dir, fatalOutputPrefix+"*"))
+		return nil
+	}}
+	opts := testOptions(WithCrashDir(dir), WithGlobalPanicHandler(true), WithReporter(reporter))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// The output of the crashed process is reported and kept as crash report; the empty one is removed
+	reporter.mu.Lock()
+	panics := reporter.panics
+	reporter.mu.Unlock()
+	if len(panics) != 1 || panics[0] != "fatal error in a previous process: panic: boom" {
+		t.Errorf("reported panics = %v", panics)
+	}
+	report, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("%s20260101T000000.000000000Z-%d.txt", crashReportPrefix, deadPid)))
+	if err != nil || string(report) != output {
+		t.Errorf("crash report = %q, %v", report, err)
+	}
+
+	// The crash output of the running process goes to its own file, removed once it exits normally
+	if len(during) != 1 || !strings.HasSuffix(during[0], fmt.Sprintf("-%d.txt", os.Getpid())) {
+		t.Errorf("crash output files while running = %v, want the file of this process", during)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, fatalOutputPrefix+"*")); len(left) != 0 {
+		t.Errorf("crash output files left = %v", left)
+	}
+}
+
+func TestGlobalPanicHandlerLiveFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	// The files of running processes are in use, even if still empty
+	live := []string{
+		fmt.Sprintf("%s20260101T000000.000000000Z-%d.txt", fatalOutputPrefix, os.Getpid()),
+		fatalOutputPrefix + "unknown.txt",
+	}
+	for _, name := range live {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reporter := &recordingReporter{}
+	svc := &testService{run: func(ctx context.Context) error { return nil }}
+	opts := testOptions(WithCrashDir(dir), WithGlobalPanicHandler(true), WithReporter(reporter))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	for _, name := range live {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("file %s of a running process removed: %v", name, err)
+		}
+	}
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+	if len(reporter.panics) != 0 {
+		t.Errorf("reported panics = %v, want none", reporter.panics)
+	}
+}
+
+func TestGlobalPanicHandlerShared(t *testing.T) {
+	dir := t.TempDir()
+	crashOutputs := func() []string {
+		files, _ := filepath.Glob(filepath.Join(dir, fatalOutputPrefix+"*"))
+		return files
+	}
+
+	running := make(chan struct{})
+	first := &testService{name: "first", run: func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		return nil
+	}}
+	cancel, done := runTest(t, first, WithCrashDir(dir), WithGlobalPanicHandler(true))
+	<-running
+
+	// The second service shares the crash output, and stopping it keeps it installed for the first one
+	var during []string
+	second := &testService{name: "second", run: func(ctx context.Context) error {
+		during = crashOutputs()
+		return nil
+	}}
+	if err := RunC(second, context.Background(), testOptions(WithCrashDir(dir), WithGlobalPanicHandler(true))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if len(during) != 1 {
+		t.Errorf("crash output files while both run = %v, want one", during)
+	}
+	if files := crashOutputs(); len(files) != 1 || files[0] != during[0] {
+		t.Errorf("crash output files after the second service stopped = %v, want %v kept", files, during)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if files := crashOutputs(); len(files) != 0 {
+		t.Errorf("crash output files after both stopped = %v", files)
+	}
+}
+
+func TestGlobalPanicHandlerWithoutCrashDir(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error { return nil }}
+	logs := &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs), WithGlobalPanicHandler(true))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if logs.find("global panic handler requires a crash directory, not enabling it") == nil {
+		t.Error("missing crash directory not logged")
+	}
+}
@@ -0,0 +1,122 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.aledante.io/ae"
+)
+
+// Pauser is an optional interface a Service can implement to stop pulling new work without exiting, e.g. during
+// maintenance windows. Pause and Resume are called by Pause, Resume, and PauseHandler while the service is running;
+// Run keeps running while the service is paused.
+//
+// Shutting down a paused service works as usual: the context of Run is cancelled, and Close is called. A restart
+// resumes the service, since it is initialized again.
+type Pauser interface {
+	// Pause stops the service from pulling new work.
+	Pause(ctx context.Context) error
+	// Resume continues pulling work after Pause.
+	Resume(ctx context.Context) error
+}
+
+var (
+	// ErrPauseNotSupported is returned by Pause and Resume if the service does not implement Pauser.
+	ErrPauseNotSupported = errors.New("service does not support pausing")
+	// ErrNotPausable is returned by Pause if the service is not running, and by Resume if it is not paused.
+	ErrNotPausable = errors.New("service cannot be paused or resumed in its current state")
+)
+
+// Pause pauses the service the context belongs to by calling its Pause method, and transitions it to StatePaused.
+// Depending on PausedReadiness, the service stays ready or is reported as not ready while paused. It returns
+// ErrPauseNotSupported if the service does not implement Pauser, and ErrNotPausable if it is not running.
+func Pause(ctx context.Context) error {
+	return pauseOrResume(ctx, StateRunning, StatePaused, "paused", Pauser.Pause)
+}
+
+// Resume resumes the service the context belongs to by calling its Resume method, and transitions it back to
+// StateRunning. It returns ErrPauseNotSupported if the service does not implement Pauser, and ErrNotPausable if it
+// is not paused.
+func Resume(ctx context.Context) error {
+	return pauseOrResume(ctx, StatePaused, StateRunning, "resumed", Pauser.Resume)
+}
+
+// pauseOrResume calls fn on the Pauser of the service if it is in state from, then transitions it to state to.
+func pauseOrResume(ctx context.Context, from, to State, action string, fn func(Pauser, context.Context) error) error {
+	sup := supervisorFrom(ctx)
+	if sup == nil || sup.pauser == nil {
+		return ErrPauseNotSupported
+	}
+
+	// Only one pause or resume is in progress at a time, so the state cannot change in between
+	sup.pauseMu.Lock()
+	defer sup.pauseMu.Unlock()
+
+	if state := sup.State(); state != from {
+		return ae.New().
+			Cause(ErrNotPausable).
+			Msg(fmt.Sprintf("cannot %s service in state %s", strings.TrimSuffix(action, "d"), state))
+	}
+
+	if err := fn(sup.pauser, ctx); err != nil {
+		return ae.Wrap(fmt.Sprintf("service could not be %s", action), err)
+	}
+
+	// The service may have started shutting down meanwhile; the stopping state must not be overwritten
+	if !sup.setStateIf(from, to) {
+		return ae.New().
+			Cause(ErrNotPausable).
+			Msg(fmt.Sprintf("service state changed while being %s", action))
+	}
+
+	Logger(ctx).Info("service " + action)
+	return nil
+}
+
+// PauseHandler returns an HTTP handler pausing and resuming the service of serviceCtx, intended to be mounted on an
+// admin server. It serves POST /services/{name}/pause and POST /services/{name}/resume, where name is the name of
+// the service. It responds with 204 on success, 409 if the service does not implement Pauser or is not in a
+// state allowing the transition, and 404 for other services.
+func PauseHandler(serviceCtx context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /services/{name}/{action}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") != Name(serviceCtx) {
+			http.NotFound(w, r)
+			return
+		}
+
+		ctx := WithServiceContext(r.Context(), serviceCtx)
+
+		var err error
+		switch r.PathValue("action") {
+		case "pause":
+			err = Pause(ctx)
+		case "resume":
+			err = Resume(ctx)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, ErrPauseNotSupported), errors.Is(err, ErrNotPausable):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			Logger(ctx).Error("failed to pause or resume service", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	return mux
+}
+
+// isReady reports whether a service in the given state is ready to serve, i.e. running, or paused with
+// pausedReadiness set.
+func isReady(state State, pausedReadiness bool) bool {
+	return state == StateRunning || (state == StatePaused && pausedReadiness)
+}
@@ -0,0 +1,179 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// pausableService is a testService implementing Pauser, counting the calls of Pause and Resume.
+type pausableService struct {
+	testService
+	pauses, resumes atomic.Int32
+}
+
+func (s *pausableService) Pause(ctx context.Context) error {
+	s.pauses.Add(1)
+	return nil
+}
+
+func (s *pausableService) Resume(ctx context.Context) error {
+	s.resumes.Add(1)
+	return nil
+}
+
+func TestPause(t *testing.T) {
+	for _, pausedReadiness := range []bool{false, true} {
+		name := "not ready"
+		if pausedReadiness {
+			name = "ready"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ready")
+
+			svc := &pausableService{}
+			svc.run = func(ctx context.Context) error {
+				sup := supervisorFrom(ctx)
+				waitFor(t, "ready file", func() bool { return readyFileExists(t, path) })
+
+				if err := Resume(ctx); !errors.Is(err, ErrNotPausable) {
+					t.Errorf("Resume() of a running service = %v, want ErrNotPausable", err)
+				}
+
+				if err := Pause(ctx); err != nil {
+					t.Fatalf("Pause() = %v", err)
+				}
+				if state := sup.State(); state != StatePaused {
+					t.Errorf("state after Pause() = %s, want paused", state)
+				}
+				if ready := readyFileExists(t, path); ready != pausedReadiness {
+					t.Errorf("ready while paused = %t, want %t", ready, pausedReadiness)
+				}
+				if err := Pause(ctx); !errors.Is(err, ErrNotPausable) {
+					t.Errorf("Pause() of a paused service = %v, want ErrNotPausable", err)
+				}
+
+				if err := Resume(ctx); err != nil {
+					t.Fatalf("Resume() = %v", err)
+				}
+				if state := sup.State(); state != StateRunning {
+					t.Errorf("state after Resume() = %s, want running", state)
+				}
+				if !readyFileExists(t, path) {
+					t.Error("not ready after Resume()")
+				}
+				return nil
+			}
+
+			opts := testOptions(WithReadyFile(path), WithPausedReadiness(pausedReadiness))
+			if err := RunC(svc, context.Background(), opts...); err != nil {
+				t.Fatalf("RunC() = %v", err)
+			}
+			if svc.pauses.Load() != 1 || svc.resumes.Load() != 1 {
+				t.Errorf("pauses = %d, resumes = %d, want one each", svc.pauses.Load(), svc.resumes.Load())
+			}
+		})
+	}
+}
+
+func TestPauseNotSupported(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error {
+		if err := Pause(ctx); !errors.Is(err, ErrPauseNotSupported) {
+			t.Errorf("Pause() = %v, want ErrPauseNotSupported", err)
+		}
+		return nil
+	}}
+
+	if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if err := Resume(context.Background()); !errors.Is(err, ErrPauseNotSupported) {
+		t.Errorf("Resume() outside of a service = %v, want ErrPauseNotSupported", err)
+	}
+}
+
+func TestPauseShutdown(t *testing.T) {
+	closes := 0
+	paused := make(chan struct{})
+	svc := &pausableService{}
+	svc.run = func(ctx context.Context) error {
+		if err := Pause(ctx); err != nil {
+			return err
+		}
+		close(paused)
+		<-ctx.Done()
+		return nil
+	}
+	svc.close = func(ctx context.Context) error {
+		closes++
+		return nil
+	}
+
+	// A paused service shuts down as usual
+	cancel, done := runTest(t, svc)
+	<-paused
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if closes != 1 {
+		t.Errorf("closes = %d, want 1", closes)
+	}
+}
+
+func TestPauseHandler(t *testing.T) {
+	serve := func(h http.Handler, path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec.Code
+	}
+
+	t.Run("pausable", func(t *testing.T) {
+		svc := &pausableService{}
+		svc.run = func(ctx context.Context) error {
+			h := PauseHandler(ctx)
+			steps := []struct {
+				path string
+				want int
+			}{
+				{"/services/other/pause", http.StatusNotFound},
+				{"/services/test/stop", http.StatusNotFound},
+				{"/services/test/resume", http.StatusConflict},
+				{"/services/test/pause", http.StatusNoContent},
+				{"/services/test/pause", http.StatusConflict},
+				{"/services/test/resume", http.StatusNoContent},
+			}
+			for _, step := range steps {
+				if got := serve(h, step.path); got != step.want {
+					t.Errorf("POST %s = %d, want %d", step.path, got, step.want)
+				}
+			}
+			return nil
+		}
+
+		if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+			t.Fatalf("RunC() = %v", err)
+		}
+		if svc.pauses.Load() != 1 || svc.resumes.Load() != 1 {
+			t.Errorf("pauses = %d, resumes = %d, want one each", svc.pauses.Load(), svc.resumes.Load())
+		}
+	})
+
+	t.Run("not pausable", func(t *testing.T) {
+		svc := &testService{run: func(ctx context.Context) error {
+			if got := serve(PauseHandler(ctx), "/services/test/pause"); got != http.StatusConflict {
+				t.Errorf("POST pause = %d, want %d", got, http.StatusConflict)
+			}
+			return nil
+		}}
+
+		if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+			t.Fatalf("RunC() = %v", err)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// phaseDurationBuckets are the histogram bucket boundaries of the phase durations in seconds, covering fast starts
+// as well as long runs. They can be overridden with a view of the meter provider.
+var phaseDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800, 3600}
+
+// Phase is a phase of a single run of a service, used to attribute recovered panics.
+type Phase string
+
+const (
+	// PhaseInit is the call to Service.Init.
+	PhaseInit Phase = "init"
+	// PhaseRun is the call to Service.Run.
+	PhaseRun Phase = "run"
+	// PhaseClose is the call to Service.Close.
+	PhaseClose Phase = "close"
+)
+
+// phaseError is the error of a panic recovered in a phase of the service.
+type phaseError struct {
+	phase Phase
+	err   error
+}
+
+// Error returns the error message, including the phase.
+func (e *phaseError) Error() string {
+	return string(e.phase) + ": " + e.err.Error()
+}
+
+// Unwrap returns the error of the recovered panic.
+func (e *phaseError) Unwrap() error {
+	return e.err
+}
+
+// PanicPhase returns the phase of the service in which the panic causing err was recovered.
+// It returns false if err was not caused by a panic in Init, Run, or Close.
+func PanicPhase(err error) (Phase, bool) {
+	var pErr *phaseError
+	if errors.As(err, &pErr) {
+		return pErr.phase, true
+	}
+
+	return "", false
+}
+
+// callPhase calls fn as the given phase of the service and records its duration. If RecoverPanic is enabled, a panic
+// in fn is recovered, written to the crash report and reported, and returned as an error attributed to the phase.
+func callPhase(ctx context.Context, opts Options, phase Phase, fn func() error) (err error, isPanic bool) {
+	start := time.Now()
+	returned := false
+	defer warnSlowPhase(ctx, phase, phaseWarnAfter(opts, phase))()
+	defer func() {
+		recordPhaseDuration(ctx, phase, time.Since(start), phaseOutcome(err, isPanic || !returned))
+	}()
+
+	if opts.RecoverPanic {
+		defer func() {
+			if cause := recover(); cause != nil {
+				stack := debug.Stack()
+				writeCrashReport(ctx, opts, "panic in "+string(phase), cause, stack)
+				reportPanic(ctx, opts, cause, stack)
+				err, isPanic = &phaseError{phase: phase, err: panicError(ctx, cause, nil)}, true
+			}
+		}()
+	}
+
+	err = fn()
+	returned = true
+
+	return err, false
+}
+
+// phaseWarnAfter returns the duration after which a still running phase is logged as slow, or zero.
+func phaseWarnAfter(opts Options, phase Phase) time.Duration {
+	switch phase {
+	case PhaseInit:
+		return opts.InitWarnAfter
+	case PhaseClose:
+		return opts.CloseWarnAfter
+	default:
+		return 0
+	}
+}
+
+// warnSlowPhase logs a warning and adds an event to the current span if the phase is still running after
+// warnAfter. It returns a function to be called once the phase returned, which stops the timer and, if the warning
+// was logged, logs the final duration. A warnAfter of zero or less disables the warning.
+func warnSlowPhase(ctx context.Context, phase Phase, warnAfter time.Duration) func() {
+	if warnAfter <= 0 {
+		return func() {}
+	}
+
+	start := time.Now()
+	warned := make(chan struct{})
+	timer := time.AfterFunc(warnAfter, func() {
+		defer close(warned)
+
+		elapsed := time.Since(start)
+		Logger(ctx).Warn("service "+string(phase)+" is slow",
+			"phase", phase,
+			"elapsed", elapsed.String(),
+			"threshold", warnAfter.String(),
+		)
+		trace.SpanFromContext(ctx).AddEvent("slow "+string(phase), trace.WithAttributes(
+			attribute.String("phase", string(phase)),
+			attribute.Float64("elapsed", elapsed.Seconds()),
+			attribute.Float64("threshold", warnAfter.Seconds()),
+		))
+	})
+
+	return func() {
+		if timer.Stop() {
+			return
+		}
+
+		// The warning was logged, or is being logged; the final duration follows it
+		<-warned
+		Logger(ctx).Info("slow service "+string(phase)+" finished",
+			"phase", phase,
+			"duration", time.Since(start).String(),
+			"threshold", warnAfter.String(),
+		)
+	}
+}
+
+// phaseOutcome returns the outcome of a phase for the duration metrics: ok, error, panic, or timeout.
+// Cancellation is considered a regular outcome of a phase, since it is how services are asked to stop.
+func phaseOutcome(err error, isPanic bool) string {
+	switch {
+	case isPanic:
+		return "panic"
+	case err == nil, errors.Is(err, context.Canceled):
+		return "ok"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "error"
+	}
+}
+
+// recordPhaseDuration records the duration of a phase on the as.service.<phase>.duration histogram.
+func recordPhaseDuration(ctx context.Context, phase Phase, d time.Duration, outcome string) {
+	histogram, err := Meter(ctx).Float64Histogram(
+		"as.service."+string(phase)+".duration",
+		metric.WithDescription("Duration of the "+string(phase)+" phase of the service"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(phaseDurationBuckets...),
+	)
+	if err != nil {
+		return
+	}
+
+	histogram.Record(ctx, d.Seconds(), metric.WithAttributes(
+		semconv.ServiceNameKey.String(Name(ctx)),
+		semconv.ServiceNamespaceKey.String(Namespace(ctx)),
+		attribute.String("outcome", outcome),
+	))
+}
@@ -0,0 +1,241 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestPanicPhase(t *testing.T) {
+	boom := func(ctx context.Context) error { panic("boom") }
+
+	tests := []struct {
+		name      string
+		init      func(ctx context.Context) error
+		run       func(ctx context.Context) error
+		wantPhase Phase
+		wantRuns  int
+	}{
+		{name: "init", init: boom, wantPhase: PhaseInit},
+		{name: "run", run: boom, wantPhase: PhaseRun, wantRuns: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 0
+			svc := &testService{
+				init: tt.init,
+				run: func(ctx context.Context) error {
+					runs++
+					if tt.run == nil {
+						return nil
+					}
+					return tt.run(ctx)
+				},
+			}
+
+			reporter := &recordingReporter{}
+			err := RunC(svc, context.Background(), testOptions(WithGraceCount(1), WithReporter(reporter))...)
+
+			phase, ok := PanicPhase(err)
+			if !ok || phase != tt.wantPhase {
+				t.Fatalf("PanicPhase(%v) = %q, %t, want %q", err, phase, ok, tt.wantPhase)
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("service ran %d times, want %d", runs, tt.wantRuns)
+			}
+			// Panics are restarted; the restart budget allows two attempts
+			if len(reporter.panics) != 2 {
+				t.Errorf("reported %d panics, want 2", len(reporter.panics))
+			}
+		})
+	}
+}
+
+func TestPanicPhaseClose(t *testing.T) {
+	runs := 0
+	svc := &testService{
+		run: func(ctx context.Context) error {
+			runs++
+			return nil
+		},
+		close: func(ctx context.Context) error { panic("boom") },
+	}
+
+	// A panic in Close after Run completed is logged and reported, but neither fails nor restarts the service
+	logs := &logCapture{}
+	reporter := &recordingReporter{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs), WithReporter(reporter))...); err != nil {
+		t.Fatalf("RunC() = %v, want nil", err)
+	}
+
+	if runs != 1 {
+		t.Errorf("service ran %d times, want once", runs)
+	}
+	if len(reporter.panics) != 1 {
+		t.Errorf("reported %d panics, want 1", len(reporter.panics))
+	}
+	if record := logs.find("service shutdown panicked"); record == nil || record["phase"] != string(PhaseClose) {
+		t.Errorf("close panic record = %v", record)
+	}
+	if summary := logs.find("service exited"); summary == nil || summary["panics"] != float64(1) {
+		t.Errorf("exit summary = %v, want one panic", summary)
+	}
+}
+
+func TestPanicPhaseOfError(t *testing.T) {
+	if _, ok := PanicPhase(errors.New("failed")); ok {
+		t.Error("PanicPhase() of a regular error = true, want false")
+	}
+
+	err := &phaseError{phase: PhaseRun, err: errors.New("panic: boom")}
+	if got, want := err.Error(), "run: panic: boom"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if phase, ok := PanicPhase(errors.Join(errors.New("wrapped"), err)); !ok || phase != PhaseRun {
+		t.Errorf("PanicPhase() of a wrapped error = %q, %t, want run", phase, ok)
+	}
+}
+
+func TestPhaseDurations(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name string
+		svc  *testService
+		// want are the outcomes recorded per phase; phases not listed must not be recorded
+		want map[Phase]string
+	}{
+		{
+			name: "ok",
+			svc:  &testService{run: func(ctx context.Context) error { return nil }},
+			want: map[Phase]string{PhaseInit: "ok", PhaseRun: "ok", PhaseClose: "ok"},
+		},
+		{
+			name: "init error",
+			svc:  &testService{init: func(ctx context.Context) error { return errFailed }},
+			want: map[Phase]string{PhaseInit: "error"},
+		},
+		{
+			name: "run panic",
+			svc:  &testService{run: func(ctx context.Context) error { panic("boom") }},
+			want: map[Phase]string{PhaseInit: "ok", PhaseRun: "panic"},
+		},
+		{
+			name: "close timeout",
+			svc: &testService{
+				run:   func(ctx context.Context) error { return nil },
+				close: func(ctx context.Context) error { return context.DeadlineExceeded },
+			},
+			want: map[Phase]string{PhaseInit: "ok", PhaseRun: "ok", PhaseClose: "timeout"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, reader := testMeterContext(t, withName(context.Background(), "test"))
+			ctx = WithLogger(ctx, slog.New(slog.DiscardHandler))
+			ctx = withSupervisor(ctx, newSupervisor())
+
+			_, _ = runOnce(tt.svc, ctx, DefaultOptions())
+
+			for _, phase := range []Phase{PhaseInit, PhaseRun, PhaseClose} {
+				name := "as.service." + string(phase) + ".duration"
+				hist, ok := collectMetric(t, reader, name).(metricdata.Histogram[float64])
+
+				want, recorded := tt.want[phase]
+				if !recorded {
+					if ok {
+						t.Errorf("%s recorded, want no measurement", name)
+					}
+					continue
+				}
+				if !ok || len(hist.DataPoints) != 1 || hist.DataPoints[0].Count != 1 {
+					t.Errorf("%s = %+v, want one measurement", name, hist)
+					continue
+				}
+
+				point := hist.DataPoints[0]
+				if outcome, _ := point.Attributes.Value(attribute.Key("outcome")); outcome.AsString() != want {
+					t.Errorf("%s outcome = %q, want %q", name, outcome.AsString(), want)
+				}
+				if service, _ := point.Attributes.Value(attribute.Key("service.name")); service.AsString() != "test" {
+					t.Errorf("%s service = %q, want test", name, service.AsString())
+				}
+				if len(point.Bounds) != len(phaseDurationBuckets) {
+					t.Errorf("%s has %d bucket bounds, want %d", name, len(point.Bounds), len(phaseDurationBuckets))
+				}
+			}
+		})
+	}
+}
+
+func TestSlowPhaseWarning(t *testing.T) {
+	tests := []struct {
+		name     string
+		phase    Phase
+		opts     Options
+		duration time.Duration
+		wantWarn bool
+	}{
+		{name: "init fired", phase: PhaseInit, opts: Options{InitWarnAfter: 10 * time.Second}, duration: 34 * time.Second, wantWarn: true},
+		{name: "init not fired", phase: PhaseInit, opts: Options{InitWarnAfter: 10 * time.Second}, duration: time.Second},
+		{name: "close fired", phase: PhaseClose, opts: Options{CloseWarnAfter: time.Second}, duration: 2 * time.Second, wantWarn: true},
+		{name: "close not fired", phase: PhaseClose, opts: Options{CloseWarnAfter: time.Second}, duration: 500 * time.Millisecond},
+		{name: "disabled", phase: PhaseInit, duration: time.Hour},
+		// Run has no threshold
+		{name: "run", phase: PhaseRun, opts: Options{InitWarnAfter: time.Second, CloseWarnAfter: time.Second}, duration: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, recorder := testServiceContext(t)
+				logs := &logCapture{}
+				ctx = WithLogger(ctx, slog.New(slog.NewJSONHandler(logs, nil)))
+				ctx, span := Tracer(ctx).Start(ctx, string(tt.phase))
+
+				err, _ := callPhase(ctx, tt.opts, tt.phase, func() error {
+					time.Sleep(tt.duration)
+					return nil
+				})
+				span.End()
+				if err != nil {
+					t.Fatalf("callPhase() = %v", err)
+				}
+
+				warning := logs.find("service " + string(tt.phase) + " is slow")
+				finished := logs.find("slow service " + string(tt.phase) + " finished")
+				if !tt.wantWarn {
+					if warning != nil || finished != nil {
+						t.Errorf("logged %v, %v for a fast phase", warning, finished)
+					}
+					if events := recorder.Ended()[0].Events(); len(events) != 0 {
+						t.Errorf("span events = %v, want none", events)
+					}
+					return
+				}
+
+				threshold := phaseWarnAfter(tt.opts, tt.phase)
+				if warning == nil || warning["level"] != "WARN" || warning["elapsed"] != threshold.String() ||
+					warning["threshold"] != threshold.String() {
+					t.Errorf("warning = %v", warning)
+				}
+				if finished == nil || finished["level"] != "INFO" || finished["duration"] != tt.duration.String() {
+					t.Errorf("final duration logged as %v", finished)
+				}
+
+				events := recorder.Ended()[0].Events()
+				if len(events) != 1 || events[0].Name != "slow "+string(tt.phase) {
+					t.Errorf("span events = %v, want the slow event", events)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"go.aledante.io/ae"
+)
+
+// initPIDFile prepares the PID file configured by opts.PIDFile, if any: it creates the parent directory and fails
+// if an existing PID file points at a running process, unless opts.PIDFileOverride is set.
+// It returns a function removing the PID file during shutdown, if it still contains the pid of this process.
+func initPIDFile(ctx context.Context, opts Options) (func(), error) {
+	if opts.PIDFile == "" {
+		return func() {}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.PIDFile), 0o755); err != nil {
+		return func() {}, ae.Wrap("failed to create PID file directory", err)
+	}
+
+	pid, err := readPIDFile(opts.PIDFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		Logger(ctx).Warn("ignoring invalid PID file", "path", opts.PIDFile, "error", err)
+	case pid != os.Getpid() && processAlive(pid):
+		if !opts.PIDFileOverride {
+			return func() {}, ae.Msgf("PID file %s points at running process %d", opts.PIDFile, pid)
+		}
+
+		Logger(ctx).Warn("overriding PID file of running process", "path", opts.PIDFile, "pid", pid)
+	default:
+		Logger(ctx).Debug("taking over stale PID file", "path", opts.PIDFile, "pid", pid)
+	}
+
+	return func() {
+		pid, err := readPIDFile(opts.PIDFile)
+		if err != nil || pid != os.Getpid() {
+			return
+		}
+
+		if err := os.Remove(opts.PIDFile); err != nil {
+			Logger(ctx).Error("failed to remove PID file", "path", opts.PIDFile, "error", err)
+		}
+	}, nil
+}
+
+// writePIDFile atomically writes the pid of the current process to path, by writing to a temporary file in the
+// same directory and renaming it.
+func writePIDFile(path string, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(strconv.Itoa(os.Getpid()) + "\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// readPIDFile reads the pid stored in the PID file at path.
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// processAlive reports whether a process with the given pid exists.
+// Always returns false on platforms not supporting signal 0, such as Windows.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
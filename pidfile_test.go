@@ -0,0 +1,98 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// exitedPID returns the pid of a process which has exited.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot run a child process: %v", err)
+	}
+
+	return cmd.Process.Pid
+}
+
+func TestPIDFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing func(t *testing.T) string
+		override bool
+		runErr   error
+		wantErr  error
+	}{
+		{name: "clean exit"},
+		{name: "error exit", runErr: errors.New("failed"), wantErr: errors.New("failed")},
+		{
+			name:     "stale file",
+			existing: func(t *testing.T) string { return strconv.Itoa(exitedPID(t)) },
+		},
+		{
+			name:     "running process",
+			existing: func(t *testing.T) string { return strconv.Itoa(os.Getppid()) },
+			wantErr:  ErrInitInternal,
+		},
+		{
+			name:     "running process overridden",
+			existing: func(t *testing.T) string { return strconv.Itoa(os.Getppid()) },
+			override: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "run", "test.pid")
+			if tt.existing != nil {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.existing(t)+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var pid int
+			var readErr error
+			svc := &testService{run: func(ctx context.Context) error {
+				pid, readErr = readPIDFile(path)
+				return tt.runErr
+			}}
+
+			err := RunC(svc, context.Background(), testOptions(
+				WithPIDFile(path),
+				WithPIDFileOverride(tt.override),
+				WithRestartOnError(false),
+			)...)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("RunC() = %v, want nil", err)
+			case tt.wantErr != nil && err == nil:
+				t.Fatalf("RunC() = nil, want %v", tt.wantErr)
+			case errors.Is(tt.wantErr, ErrInitInternal):
+				if !errors.Is(err, ErrInitInternal) {
+					t.Fatalf("RunC() = %v, want ErrInitInternal", err)
+				}
+				if _, statErr := os.Stat(path); statErr != nil {
+					t.Errorf("PID file of the running process was removed: %v", statErr)
+				}
+				return
+			}
+
+			if readErr != nil || pid != os.Getpid() {
+				t.Errorf("PID file while running = %d, %v, want %d", pid, readErr, os.Getpid())
+			}
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("PID file not removed on exit: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package as
+
+import (
+	"context"
+	"maps"
+	"os"
+	"reflect"
+)
+
+// OptionPrecedence selects whether options set in code or env vars take precedence.
+type OptionPrecedence string
+
+const (
+	// EnvWins lets env vars override options set in code.
+	EnvWins OptionPrecedence = "env"
+	// CodeWins keeps options set in code; env vars only set the fields no option set.
+	CodeWins OptionPrecedence = "code"
+)
+
+// optionConflict records an option field set both in code and by an env var to different values.
+type optionConflict struct {
+	// field is the name of the Options field.
+	field string
+	// env is the env var setting the field.
+	env string
+	// code and envValue are the values set in code and by the env var.
+	code, envValue any
+	// winner is the source whose value was kept.
+	winner OptionPrecedence
+}
+
+// setOption sets the field of o pointed to by field to v and marks it as set in code, so that it is kept under
+// CodeWins and by the development preset. The With* helpers set fields this way; fields set by custom Option funcs
+// are not marked and are overridden by env vars.
+func setOption[T any](o *Options, field *T, v T) {
+	*field = v
+
+	base := reflect.ValueOf(o).Pointer()
+	addr := reflect.ValueOf(field).Pointer()
+	t := reflect.TypeFor[Options]()
+	for i := range t.NumField() {
+		if base+t.Field(i).Offset != addr {
+			continue
+		}
+		// Only fields set by env vars can conflict with code
+		if _, ok := t.Field(i).Tag.Lookup("env"); !ok {
+			return
+		}
+
+		// Copies of o share the map, so it is cloned instead of changed in place
+		explicit := maps.Clone(o.explicit)
+		if explicit == nil {
+			explicit = make(map[int]bool)
+		}
+		explicit[i] = true
+		o.explicit = explicit
+		return
+	}
+}
+
+// resolveOptionConflicts compares the options parsed from the environment with the options set in code for the
+// explicit fields. Under CodeWins, the values set in code are restored, as are values set from envDefault tags
+// under either precedence. All conflicts with a set env var are returned, whichever source won.
+func resolveOptionConflicts(o *Options, code Options, explicit map[int]bool, namespace string) []optionConflict {
+	winner := EnvWins
+	if o.OptionPrecedence == CodeWins {
+		winner = CodeWins
+	}
+
+	t := reflect.TypeFor[Options]()
+	parsed := reflect.ValueOf(o).Elem()
+	set := reflect.ValueOf(code)
+
+	var conflicts []optionConflict
+	for i := range t.NumField() {
+		if !explicit[i] || parsed.Field(i).Equal(set.Field(i)) {
+			continue
+		}
+
+		// Without an env var, the field was set from an envDefault tag, which must not override code either way
+		name, ok := optionEnvVar(*o, namespace, t.Field(i).Tag.Get("env"))
+		if !ok {
+			parsed.Field(i).Set(set.Field(i))
+			continue
+		}
+
+		conflicts = append(conflicts, optionConflict{
+			field:    t.Field(i).Name,
+			env:      name,
+			code:     set.Field(i).Interface(),
+			envValue: parsed.Field(i).Interface(),
+			winner:   winner,
+		})
+
+		if winner == CodeWins {
+			parsed.Field(i).Set(set.Field(i))
+		}
+	}
+
+	return conflicts
+}
+
+// optionEnvVar returns the name of the env var setting the option with the given key: the key under the env
+// prefix, or under one of the fallback prefixes if EnvPrefixFallback is set. It returns false if none is set.
+func optionEnvVar(o Options, namespace, key string) (string, bool) {
+	for _, prefix := range append([]string{o.EnvPrefix}, envFallbackPrefixes(o, namespace)...) {
+		if _, ok := os.LookupEnv(prefix + key); ok {
+			return prefix + key, true
+		}
+	}
+
+	return "", false
+}
+
+// logOptionConflicts logs the option fields set both in code and by an env var, and which value was kept.
+func logOptionConflicts(ctx context.Context, conflicts []optionConflict) {
+	for _, conflict := range conflicts {
+		msg := "option set in code overridden by env var"
+		if conflict.winner == CodeWins {
+			msg = "env var ignored for option set in code"
+		}
+
+		Logger(ctx).WarnContext(ctx, msg,
+			"field", conflict.field,
+			"env", conflict.env,
+			"code_value", conflict.code,
+			"env_value", conflict.envValue,
+			"precedence", conflict.winner,
+		)
+	}
+}
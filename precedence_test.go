@@ -0,0 +1,144 @@
+package as
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOptionPrecedence(t *testing.T) {
+	env := map[string]string{
+		"ASTEST_TEST_RESTART_ON_ERROR":       "false",
+		"ASTEST_TEST_GRACE_COUNT":            "7",
+		"ASTEST_TEST_RESTART_ON_ERROR_DELAY": "3s",
+		"ASTEST_TEST_LOG_LEVEL":              "debug",
+		"ASTEST_TEST_SHUTDOWN_TIMEOUT":       "42s",
+	}
+	code := []Option{
+		WithRestartOnError(true),
+		WithGraceCount(2),
+		WithRestartOnErrorDelay(time.Second),
+		WithLogLevel("warn"),
+	}
+
+	tests := []struct {
+		precedence    OptionPrecedence
+		wantRestart   bool
+		wantGrace     int
+		wantDelay     time.Duration
+		wantLevel     string
+		wantConflicts int
+	}{
+		{precedence: EnvWins, wantRestart: false, wantGrace: 7, wantDelay: 3 * time.Second, wantLevel: "debug", wantConflicts: 4},
+		{precedence: CodeWins, wantRestart: true, wantGrace: 2, wantDelay: time.Second, wantLevel: "warn", wantConflicts: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.precedence), func(t *testing.T) {
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+
+			o, _, conflicts, err := loadOptions("test", "astest", append(code, WithOptionPrecedence(tt.precedence)))
+			if err != nil {
+				t.Fatalf("loadOptions() = %v", err)
+			}
+
+			if o.RestartOnError != tt.wantRestart || o.GraceCount != tt.wantGrace ||
+				o.RestartOnErrorDelay != tt.wantDelay || o.LogLevel != tt.wantLevel {
+				t.Errorf("RestartOnError = %t, GraceCount = %d, RestartOnErrorDelay = %s, LogLevel = %q",
+					o.RestartOnError, o.GraceCount, o.RestartOnErrorDelay, o.LogLevel)
+			}
+
+			// Fields not set in code are set by env vars either way
+			if o.ShutdownTimeout != 42*time.Second {
+				t.Errorf("ShutdownTimeout = %s, want 42s", o.ShutdownTimeout)
+			}
+
+			if len(conflicts) != tt.wantConflicts {
+				t.Fatalf("conflicts = %+v, want %d", conflicts, tt.wantConflicts)
+			}
+			for _, conflict := range conflicts {
+				if conflict.winner != tt.precedence {
+					t.Errorf("conflict %+v won by %s, want %s", conflict, conflict.winner, tt.precedence)
+				}
+			}
+		})
+	}
+}
+
+func TestOptionPrecedenceEnvDefault(t *testing.T) {
+	// Without an env var, the envDefault tag does not override the level set in code under either precedence
+	for _, precedence := range []OptionPrecedence{EnvWins, CodeWins} {
+		o, _, conflicts, err := loadOptions("test", "astest", []Option{WithLogLevel("warn"), WithOptionPrecedence(precedence)})
+		if err != nil {
+			t.Fatalf("loadOptions() = %v", err)
+		}
+		if o.LogLevel != "warn" || len(conflicts) != 0 {
+			t.Errorf("%s: LogLevel = %q, conflicts = %+v", precedence, o.LogLevel, conflicts)
+		}
+	}
+}
+
+func TestExplicitOptionFields(t *testing.T) {
+	field := func(name string) int {
+		f, _ := reflect.TypeFor[Options]().FieldByName(name)
+		return f.Index[0]
+	}
+
+	// The With* helpers mark the fields they set, even to the default value; custom Option funcs do not
+	calls := 0
+	o := DefaultOptions()
+	for _, opt := range []Option{
+		WithGraceCount(DefaultOptions().GraceCount),
+		WithMemoryLimit(1<<30, time.Second),
+		func(o *Options) { o.ShutdownTimeout = time.Minute },
+		func(o *Options) { o.GracePeriod++ },
+		func(o *Options) { calls++ },
+	} {
+		opt(&o)
+	}
+	for _, name := range []string{"GraceCount", "MemoryLimit", "MemoryCheckInterval"} {
+		if !o.explicit[field(name)] {
+			t.Errorf("%s not marked as set", name)
+		}
+	}
+	for _, name := range []string{"ShutdownTimeout", "GracePeriod"} {
+		if o.explicit[field(name)] {
+			t.Errorf("%s marked as set", name)
+		}
+	}
+
+	// Option funcs with side effects run once when loading options
+	if _, _, _, err := loadOptions("test", "astest", []Option{func(o *Options) { calls++ }}); err != nil {
+		t.Fatalf("loadOptions() = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Option funcs called %d times, want 2", calls)
+	}
+
+	// Copies do not share the markers
+	copied := o
+	WithShutdownTimeout(time.Minute)(&copied)
+	if o.explicit[field("ShutdownTimeout")] || !copied.explicit[field("ShutdownTimeout")] {
+		t.Error("marker of a copy changed the original")
+	}
+}
+
+func TestOptionConflictsLogged(t *testing.T) {
+	t.Setenv("ASTEST_TEST_GRACE_COUNT", "7")
+
+	svc := &testService{run: func(ctx context.Context) error { return nil }}
+	logs := &logCapture{}
+	opts := testOptions(captureLogs(svc, logs), WithGraceCount(2), WithOptionPrecedence(CodeWins))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	record := logs.find("env var ignored for option set in code")
+	if record == nil || record["field"] != "GraceCount" || record["env"] != "ASTEST_TEST_GRACE_COUNT" ||
+		record["code_value"] != float64(2) || record["env_value"] != float64(7) {
+		t.Errorf("conflict logged as %v", record)
+	}
+}
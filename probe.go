@@ -0,0 +1,163 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aledante.io/ae"
+)
+
+// Probe is a startup probe checking whether a dependency of the service is available, e.g. a database accepting
+// connections. Probes are run before Init with WithStartupProbe, or from Init with WaitForProbes.
+type Probe struct {
+	// Name is the name of the probe, used for logging and errors.
+	Name string
+	// Check returns nil if the dependency is available.
+	Check func(ctx context.Context) error
+	// Timeout is the timeout of a single check. Defaults to 1s.
+	Timeout time.Duration
+	// Interval is the interval between checks. Defaults to 1s.
+	Interval time.Duration
+}
+
+// ProbeOption is a function which applies a configuration change to a Probe.
+type ProbeOption func(*Probe)
+
+// WithProbeTimeout sets the timeout of a single check of the probe.
+func WithProbeTimeout(d time.Duration) ProbeOption {
+	return func(p *Probe) { p.Timeout = d }
+}
+
+// WithProbeInterval sets the interval between checks of the probe.
+func WithProbeInterval(d time.Duration) ProbeOption {
+	return func(p *Probe) { p.Interval = d }
+}
+
+// newProbe returns a probe with the default timeout and interval and opts applied.
+func newProbe(name string, check func(ctx context.Context) error, opts []ProbeOption) Probe {
+	p := Probe{Name: name, Check: check, Timeout: time.Second, Interval: time.Second}
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return p
+}
+
+// TCPProbe returns a probe succeeding once a TCP connection to addr (host:port) can be established.
+func TCPProbe(addr string, opts ...ProbeOption) Probe {
+	return newProbe("tcp "+addr, func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}, opts)
+}
+
+// HTTPProbe returns a probe succeeding once a GET request to url returns a status below 400.
+func HTTPProbe(url string, opts ...ProbeOption) Probe {
+	return newProbe("http "+url, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return ae.New().Msg(fmt.Sprintf("unexpected status %s", resp.Status))
+		}
+
+		return nil
+	}, opts)
+}
+
+// WaitForProbes runs the probes concurrently until all of them succeeded, e.g. from Init. Each probe is checked
+// immediately and then every Interval until it succeeds. If budget is positive and not all probes succeeded within
+// it, an error naming the failing probes and wrapping their last errors is returned; a budget of zero waits until
+// ctx is done.
+func WaitForProbes(ctx context.Context, budget time.Duration, probes ...Probe) error {
+	errs := make([]error, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			errs[i] = waitForProbe(ctx, budget, probe)
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	var probeErrs []error
+	for i, probe := range probes {
+		if errs[i] != nil {
+			failed = append(failed, probe.Name)
+			probeErrs = append(probeErrs, errs[i])
+		}
+	}
+
+	if len(probeErrs) > 0 {
+		return ae.WrapMany(fmt.Sprintf("startup probes failed: %s", strings.Join(failed, ", ")), probeErrs...)
+	}
+
+	return nil
+}
+
+// waitForProbe checks the probe until it succeeds or the budget is used up, logging its progress.
+func waitForProbe(ctx context.Context, budget time.Duration, probe Probe) error {
+	start := time.Now()
+	attempts := 0
+	var lastErr error
+
+	err := WaitFor(ctx, max(probe.Interval, time.Millisecond), budget, func(ctx context.Context) (bool, error) {
+		attempts++
+
+		checkCtx := ctx
+		if probe.Timeout > 0 {
+			var cancel context.CancelFunc
+			checkCtx, cancel = context.WithTimeout(ctx, probe.Timeout)
+			defer cancel()
+		}
+
+		lastErr = probe.Check(checkCtx)
+		if lastErr != nil {
+			if attempts == 1 {
+				Logger(ctx).Info("waiting for startup probe", "probe", probe.Name, "error", lastErr)
+			} else {
+				Logger(ctx).Debug("startup probe not ready", "probe", probe.Name, "attempt", attempts, "error", lastErr)
+			}
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			err = ae.WrapMany(fmt.Sprintf("probe %s not ready after %d attempts", probe.Name, attempts), err, lastErr)
+		}
+		return err
+	}
+
+	if attempts > 1 {
+		Logger(ctx).Info("startup probe ready",
+			"probe", probe.Name,
+			"attempts", attempts,
+			"duration", time.Since(start).String(),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lateListener returns the address of a TCP listener that starts accepting connections after delay.
+func lateListener(t *testing.T, delay time.Duration) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	started := make(chan struct{})
+	go func() {
+		defer close(started)
+		time.Sleep(delay)
+
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		t.Cleanup(func() { _ = l.Close() })
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.Close()
+			}
+		}()
+	}()
+	t.Cleanup(func() { <-started })
+
+	return addr
+}
+
+// unavailableServer returns an httptest server responding 503 to the first n requests and 200 afterwards.
+func unavailableServer(t *testing.T, n int64) *httptest.Server {
+	t.Helper()
+
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= n {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestStartupProbes(t *testing.T) {
+	addr := lateListener(t, 50*time.Millisecond)
+	srv := unavailableServer(t, 3)
+
+	inits := 0
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			inits++
+			return nil
+		},
+		run: func(ctx context.Context) error { return nil },
+	}
+
+	logs := &logCapture{}
+	opts := testOptions(captureLogs(svc, logs), WithRestartOnError(false), WithStartupProbe(
+		TCPProbe(addr, WithProbeInterval(10*time.Millisecond)),
+		HTTPProbe(srv.URL, WithProbeInterval(10*time.Millisecond), WithProbeTimeout(time.Second)),
+	))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// Waiting for the dependencies is not a failure of the service
+	if inits != 1 {
+		t.Errorf("inits = %d, want 1", inits)
+	}
+	for _, name := range []string{"tcp " + addr, "http " + srv.URL} {
+		waiting, ready := false, false
+		for _, record := range logs.records() {
+			if record["probe"] != name {
+				continue
+			}
+			waiting = waiting || record["msg"] == "waiting for startup probe"
+			ready = ready || record["msg"] == "startup probe ready"
+		}
+		if !waiting || !ready {
+			t.Errorf("probe %s logged waiting %t, ready %t", name, waiting, ready)
+		}
+	}
+}
+
+func TestStartupProbesBudget(t *testing.T) {
+	errDown := errors.New("down")
+	checks := 0
+	probe := Probe{Name: "db", Interval: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+		checks++
+		return errDown
+	}}
+
+	inits := 0
+	svc := &testService{init: func(ctx context.Context) error {
+		inits++
+		return nil
+	}}
+
+	// Probes still failing after the budget fail the initialization, without calling Init
+	opts := testOptions(WithRestartOnError(false), WithStartupProbe(probe), WithStartupProbeBudget(50*time.Millisecond))
+	err := RunC(svc, context.Background(), opts...)
+	if !errors.Is(err, errDown) {
+		t.Fatalf("RunC() = %v, want the probe error", err)
+	}
+	if inits != 0 || checks < 2 {
+		t.Errorf("inits = %d, checks = %d, want no init and repeated checks", inits, checks)
+	}
+}
+
+func TestWaitForProbes(t *testing.T) {
+	srv := unavailableServer(t, 2)
+
+	// Probes can be used from Init as well
+	probe := HTTPProbe(srv.URL, WithProbeInterval(5*time.Millisecond))
+	if err := WaitForProbes(context.Background(), time.Second, probe); err != nil {
+		t.Errorf("WaitForProbes() = %v", err)
+	}
+
+	down := unavailableServer(t, 1<<30)
+	err := WaitForProbes(context.Background(), 30*time.Millisecond, probe, HTTPProbe(down.URL, WithProbeInterval(5*time.Millisecond)))
+	if err == nil {
+		t.Error("WaitForProbes() = nil for an unavailable server")
+	}
+
+	// A cancelled context stops waiting without a budget
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Close()
+	if err := WaitForProbes(ctx, 0, TCPProbe(l.Addr().String(), WithProbeInterval(5*time.Millisecond))); err == nil {
+		t.Error("WaitForProbes() = nil after the context was cancelled")
+	}
+}
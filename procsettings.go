@@ -0,0 +1,132 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.aledante.io/ae"
+)
+
+// processSettings are the process-global settings applied by WorkingDir and Umask. Services running concurrently
+// in the process share them, so they must agree on their values; the original values are restored once the last
+// of them stopped.
+var processSettings struct {
+	mu    sync.Mutex
+	users int
+
+	dir   string
+	umask *os.FileMode
+
+	origDir   string
+	origUmask os.FileMode
+}
+
+// applyProcessSettings changes the working directory of the process to WorkingDir and sets its umask to Umask. It
+// returns a function restoring the original values, or an error if the directory is not accessible or another
+// running service applied different values. It runs before the logger is created, so the changes are logged by
+// logProcessSettings.
+func applyProcessSettings(opts Options) (func(), error) {
+	if opts.WorkingDir == "" && opts.Umask == nil {
+		return func() {}, nil
+	}
+
+	dir := opts.WorkingDir
+	if dir != "" {
+		var err error
+		if dir, err = filepath.Abs(dir); err != nil {
+			return nil, ae.Wrap("failed to resolve working directory", err)
+		}
+	}
+
+	p := &processSettings
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.users > 0 {
+		if dir != p.dir {
+			return nil, ae.New().Msg(fmt.Sprintf("working directory %q conflicts with %q of another running service", dir, p.dir))
+		}
+		if !equalUmask(opts.Umask, p.umask) {
+			return nil, ae.New().Msg("umask conflicts with the umask of another running service")
+		}
+
+		p.users++
+		return releaseProcessSettings, nil
+	}
+
+	if dir != "" {
+		info, err := os.Stat(dir)
+		switch {
+		case err != nil:
+			return nil, ae.Wrap("working directory is not accessible", err)
+		case !info.IsDir():
+			return nil, ae.New().Msg(fmt.Sprintf("working directory %q is not a directory", dir))
+		}
+
+		if p.origDir, err = os.Getwd(); err != nil {
+			return nil, ae.Wrap("failed to get the current working directory", err)
+		}
+		if err := os.Chdir(dir); err != nil {
+			return nil, ae.Wrap("failed to change the working directory", err)
+		}
+	}
+
+	if opts.Umask != nil {
+		p.origUmask = setUmask(*opts.Umask)
+	}
+
+	p.users = 1
+	p.dir = dir
+	p.umask = opts.Umask
+
+	return releaseProcessSettings, nil
+}
+
+// logProcessSettings logs the working directory and umask applied by applyProcessSettings, with the original values.
+func logProcessSettings(ctx context.Context, opts Options) {
+	p := &processSettings
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if opts.WorkingDir != "" {
+		Logger(ctx).Info("changed working directory", "working_dir", p.dir, "previous", p.origDir)
+	}
+	if opts.Umask != nil {
+		Logger(ctx).Info("changed umask", "umask", fmt.Sprintf("%04o", *opts.Umask), "previous", fmt.Sprintf("%04o", p.origUmask))
+	}
+}
+
+// releaseProcessSettings restores the original working directory and umask once the last service which applied
+// them stopped.
+func releaseProcessSettings() {
+	p := &processSettings
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.users--; p.users > 0 {
+		return
+	}
+
+	if p.dir != "" {
+		// Restoring is best-effort; the original directory may have been removed meanwhile
+		_ = os.Chdir(p.origDir)
+	}
+	if p.umask != nil {
+		setUmask(p.origUmask)
+	}
+
+	p.dir = ""
+	p.umask = nil
+}
+
+// equalUmask reports whether both umasks are unset or set to the same value.
+func equalUmask(a, b *os.FileMode) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
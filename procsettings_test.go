@@ -0,0 +1,206 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// workingDir returns the working directory of the process with symlinks resolved.
+func workingDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resolved(t, dir)
+}
+
+// resolved returns path with symlinks resolved, e.g. for temporary directories on macOS.
+func resolved(t *testing.T, path string) string {
+	t.Helper()
+
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestWorkingDir(t *testing.T) {
+	orig := workingDir(t)
+	dir := resolved(t, t.TempDir())
+
+	var inInit, inRun string
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			inInit = workingDir(t)
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			inRun = workingDir(t)
+			return nil
+		},
+	}
+
+	if err := RunC(svc, context.Background(), testOptions(WithWorkingDir(dir))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if inInit != dir || inRun != dir {
+		t.Errorf("working directory = %q in Init, %q in Run, want %q", inInit, inRun, dir)
+	}
+	if got := workingDir(t); got != orig {
+		t.Errorf("working directory after RunC = %q, want %q restored", got, orig)
+	}
+}
+
+func TestWorkingDirInaccessible(t *testing.T) {
+	orig := workingDir(t)
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"missing":       filepath.Join(t.TempDir(), "missing"),
+		"not directory": file,
+	}
+
+	for name, dir := range tests {
+		t.Run(name, func(t *testing.T) {
+			initialized := false
+			svc := &testService{init: func(ctx context.Context) error {
+				initialized = true
+				return nil
+			}}
+
+			err := RunC(svc, context.Background(), testOptions(WithWorkingDir(dir))...)
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("RunC() = %v, want ErrInvalidConfig", err)
+			}
+			if initialized {
+				t.Error("Init called although the working directory is inaccessible")
+			}
+			if got := workingDir(t); got != orig {
+				t.Errorf("working directory = %q, want %q unchanged", got, orig)
+			}
+		})
+	}
+}
+
+func TestWorkingDirConflict(t *testing.T) {
+	orig := workingDir(t)
+	dir, other := t.TempDir(), t.TempDir()
+
+	running := make(chan struct{})
+	first := &testService{name: "first", run: func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		return nil
+	}}
+	cancel, done := runTest(t, first, WithWorkingDir(dir))
+	<-running
+
+	// Services running concurrently must agree on the process-global settings
+	second := &testService{name: "second"}
+	if err := RunC(second, context.Background(), testOptions(WithWorkingDir(other))...); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("RunC() with another working directory = %v, want ErrInvalidConfig", err)
+	}
+	if err := RunC(second, context.Background(), testOptions(WithWorkingDir(dir), WithUmask(0o077))...); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("RunC() with another umask = %v, want ErrInvalidConfig", err)
+	}
+	if err := RunC(&testService{name: "third", run: func(context.Context) error { return nil }}, context.Background(),
+		testOptions(WithWorkingDir(dir))...); err != nil {
+		t.Errorf("RunC() with the same working directory = %v", err)
+	}
+
+	// The working directory is kept until the last service stopped
+	if got, want := workingDir(t), resolved(t, dir); got != want {
+		t.Errorf("working directory = %q after the third service stopped, want %q kept", got, want)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if got := workingDir(t); got != orig {
+		t.Errorf("working directory = %q, want %q restored", got, orig)
+	}
+}
+
+func TestUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("umask is a no-op on windows")
+	}
+
+	// createdMode creates a file with mode 0666 in a new directory and returns its permissions.
+	createdMode := func() os.FileMode {
+		path := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(path, nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Mode().Perm()
+	}
+
+	before := createdMode()
+	var inInit os.FileMode
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			inInit = createdMode()
+			return nil
+		},
+		run: func(ctx context.Context) error { return nil },
+	}
+
+	if err := RunC(svc, context.Background(), testOptions(WithUmask(0o027))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if inInit != 0o640 {
+		t.Errorf("mode in Init = %v, want 0640", inInit)
+	}
+	if got := createdMode(); got != before {
+		t.Errorf("mode after RunC = %v, want %v with the umask restored", got, before)
+	}
+}
+
+func TestUmaskValidation(t *testing.T) {
+	opts := DefaultOptions()
+	WithUmask(os.ModeDir | 0o022)(&opts)
+	if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), "Umask") {
+		t.Errorf("Validate() = %v, want an error for a umask with non-permission bits", err)
+	}
+}
+
+func TestWorkingDirBeforeFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "logs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &testService{run: func(ctx context.Context) error {
+		Logger(ctx).Info("running")
+		return nil
+	}}
+
+	// Relative paths of files created during startup resolve against the working directory
+	if err := RunC(svc, context.Background(), testOptions(WithWorkingDir(dir), WithLogRouteDir("logs"))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	logFile, err := os.ReadFile(filepath.Join(dir, "logs", "astest-test.log"))
+	if err != nil {
+		t.Fatalf("log file not created in the working directory: %v", err)
+	}
+	if !strings.Contains(string(logFile), "changed working directory") || !strings.Contains(string(logFile), "running") {
+		t.Errorf("log file = %q, want the working directory change and the records of the service", logFile)
+	}
+}
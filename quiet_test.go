@@ -0,0 +1,104 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsQuietError(t *testing.T) {
+	errQuiet := errors.New("quiet")
+	errLoud := errors.New("loud")
+	opts := Options{
+		QuietErrors:    []error{http.ErrServerClosed},
+		QuietErrorFunc: func(err error) bool { return errors.Is(err, errQuiet) },
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		shutdown bool
+		want     bool
+	}{
+		{name: "canceled", err: context.Canceled, want: true},
+		{name: "wrapped canceled", err: errors.Join(errLoud, context.Canceled), want: true},
+		{name: "quiet error", err: http.ErrServerClosed, want: true},
+		{name: "quiet func", err: errQuiet, want: true},
+		{name: "deadline after shutdown", err: context.DeadlineExceeded, shutdown: true, want: true},
+		{name: "deadline while running", err: context.DeadlineExceeded},
+		{name: "other error", err: errLoud, shutdown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isQuietError(tt.err, opts, tt.shutdown); got != tt.want {
+				t.Errorf("isQuietError() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// runAndExitTest runs svc with RunAndExitC and returns the exit code, or -1 if the process would not have exited.
+func runAndExitTest(t *testing.T, svc Service, opts ...Option) int {
+	t.Helper()
+
+	code := -1
+	opts = append(testOptions(opts...), WithRestartOnError(false), WithExitFunc(func(c int) { code = c }))
+	RunAndExitC(svc, context.Background(), opts...)
+
+	return code
+}
+
+func TestRunAndExitQuietErrors(t *testing.T) {
+	errQuiet := errors.New("quiet")
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+		opts []Option
+	}{
+		{
+			name: "canceled",
+			run:  func(ctx context.Context) error { return context.Canceled },
+		},
+		{
+			name: "quiet error",
+			run:  func(ctx context.Context) error { return http.ErrServerClosed },
+			opts: []Option{WithQuietErrors(http.ErrServerClosed)},
+		},
+		{
+			name: "quiet func",
+			run:  func(ctx context.Context) error { return errQuiet },
+			opts: []Option{WithQuietErrorFunc(func(err error) bool { return errors.Is(err, errQuiet) })},
+		},
+		{
+			// The drain deadline of the service expires after shutdown was requested
+			name: "deadline after shutdown",
+			run: func(ctx context.Context) error {
+				supervisorFrom(ctx).beginStopping()
+				drainCtx, cancel := context.WithTimeout(ctx, time.Millisecond)
+				defer cancel()
+				<-drainCtx.Done()
+				return drainCtx.Err()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := runAndExitTest(t, &testService{run: tt.run}, tt.opts...); code != -1 {
+				t.Errorf("exited with %d, want no exit", code)
+			}
+		})
+	}
+}
+
+func TestRunAndExitDeadlineWhileRunning(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error { return context.DeadlineExceeded }}
+
+	if code := runAndExitTest(t, svc); code == -1 || code == ExitOK {
+		t.Errorf("exit code = %d, want a failure", code)
+	}
+}
@@ -0,0 +1,108 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// readyFile maintains a file which exists exactly while the service is running and not unhealthy, for file-based
+// readiness probes. While the service is paused, the file exists if PausedReadiness is set.
+type readyFile struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	path      string
+	mode      os.FileMode
+	state     State
+	unhealthy bool
+	// pausedReady keeps the file while the service is paused, see PausedReadiness.
+	pausedReady bool
+	stop        func()
+}
+
+// initReadyFile binds the ready file configured in opts to the lifecycle of the service.
+// It returns a function unbinding the file and removing it; it must be called before the supervisor exits.
+func initReadyFile(ctx context.Context, opts Options) func() {
+	sup := supervisorFrom(ctx)
+	if opts.ReadyFile == "" || sup == nil {
+		return func() {}
+	}
+
+	rf := &readyFile{
+		ctx:         ctx,
+		path:        opts.ReadyFile,
+		mode:        opts.ReadyFileMode,
+		pausedReady: opts.PausedReadiness,
+	}
+
+	// Never report a stale file of a previous run as ready
+	rf.remove()
+
+	sup.mu.Lock()
+	sup.readyFile = rf
+	rf.unhealthy = sup.health == HealthUnhealthy
+	sup.mu.Unlock()
+
+	rf.stop = sup.onStateChange(rf.update)
+
+	return func() {
+		rf.stop()
+
+		rf.mu.Lock()
+		defer rf.mu.Unlock()
+
+		rf.state = StateStopped
+		rf.remove()
+	}
+}
+
+// update applies the given lifecycle state to the ready file.
+func (r *readyFile) update(state State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.state = state
+	r.apply()
+}
+
+// setUnhealthy applies the health status set with SetHealth to the ready file.
+func (r *readyFile) setUnhealthy(unhealthy bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.unhealthy = unhealthy
+	r.apply()
+}
+
+// apply creates or removes the ready file according to the lifecycle state and health status. r.mu must be held.
+func (r *readyFile) apply() {
+	if isReady(r.state, r.pausedReady) && !r.unhealthy {
+		r.create()
+	} else {
+		r.remove()
+	}
+}
+
+// create creates the ready file and its parent directory.
+func (r *readyFile) create() {
+	err := os.MkdirAll(filepath.Dir(r.path), 0o755)
+	if err == nil {
+		err = os.WriteFile(r.path, nil, r.mode)
+	}
+	if err == nil {
+		err = os.Chmod(r.path, r.mode)
+	}
+	if err != nil {
+		Logger(r.ctx).Error("failed to create ready file", "path", r.path, "error", err)
+	}
+}
+
+// remove removes the ready file, if it exists.
+func (r *readyFile) remove() {
+	if err := os.Remove(r.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		Logger(r.ctx).Error("failed to remove ready file", "path", r.path, "error", err)
+	}
+}
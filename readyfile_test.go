@@ -0,0 +1,137 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readyFileExists reports whether the ready file exists.
+func readyFileExists(t *testing.T, path string) bool {
+	t.Helper()
+
+	_, err := os.Stat(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+
+	return err == nil
+}
+
+func TestReadyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "ready")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	observed := make(map[string]bool)
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			observed["init"] = readyFileExists(t, path)
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			waitFor(t, "ready file", func() bool { return readyFileExists(t, path) })
+
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if perm := info.Mode().Perm(); perm != 0o600 {
+				t.Errorf("ready file mode = %v, want 0600", perm)
+			}
+
+			// The file is removed as soon as draining begins
+			supervisorFrom(ctx).beginStopping()
+			observed["draining"] = readyFileExists(t, path)
+			return nil
+		},
+		close: func(ctx context.Context) error {
+			observed["close"] = readyFileExists(t, path)
+			return nil
+		},
+	}
+
+	if err := RunC(svc, context.Background(), testOptions(WithReadyFile(path), WithReadyFileMode(0o600))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	for step, exists := range observed {
+		if exists {
+			t.Errorf("ready file exists during %s", step)
+		}
+	}
+	if len(observed) != 3 {
+		t.Errorf("observed %v, want init, draining and close", observed)
+	}
+	if readyFileExists(t, path) {
+		t.Error("ready file exists after exit")
+	}
+}
+
+func TestReadyFileParentDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "dir", "ready")
+
+	svc := &testService{run: func(ctx context.Context) error {
+		waitFor(t, "ready file", func() bool { return readyFileExists(t, path) })
+		return nil
+	}}
+	if err := RunC(svc, context.Background(), testOptions(WithReadyFile(path))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+}
+
+func TestReadyFileFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+		opts []Option
+	}{
+		{
+			name: "panic",
+			run:  func(ctx context.Context) error { panic("boom") },
+			opts: []Option{WithRestartOnPanic(false)},
+		},
+		{
+			name: "give up",
+			run:  func(ctx context.Context) error { return errors.New("failed") },
+			opts: []Option{WithGraceCount(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ready")
+
+			var initObserved []bool
+			svc := &testService{
+				init: func(ctx context.Context) error {
+					initObserved = append(initObserved, readyFileExists(t, path))
+					return nil
+				},
+				run: func(ctx context.Context) error {
+					waitFor(t, "ready file", func() bool { return readyFileExists(t, path) })
+					return tt.run(ctx)
+				},
+			}
+
+			if err := RunC(svc, context.Background(), testOptions(append(tt.opts, WithReadyFile(path))...)...); err == nil {
+				t.Fatal("RunC() = nil, want an error")
+			}
+
+			for i, exists := range initObserved {
+				if exists {
+					t.Errorf("ready file exists during init of attempt %d", i+1)
+				}
+			}
+			if readyFileExists(t, path) {
+				t.Error("ready file exists after exit")
+			}
+		})
+	}
+}
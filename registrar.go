@@ -0,0 +1,188 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// registrarCallTimeout bounds a single Register or Deregister call.
+	registrarCallTimeout = 10 * time.Second
+	// registrarMaxAttempts is the number of attempts of Register and Deregister.
+	registrarMaxAttempts = 5
+)
+
+// ServiceInfo describes a running service instance for registration with a Registrar.
+type ServiceInfo struct {
+	// Name is the name of the service.
+	Name string
+	// Namespace is the namespace of the service.
+	Namespace string
+	// Version is the version of the service.
+	Version string
+	// InstanceID identifies this instance of the service, in the form <hostname>-<pid>.
+	InstanceID string
+	// Labels are the labels of the service, if it implements Labeler.
+	Labels map[string]string
+}
+
+// Registrar registers a service with an external system like a service discovery (e.g. Consul).
+//
+// The supervisor calls Register once the service is running and Deregister as soon as it starts stopping or
+// restarting. Deregister is guaranteed to be called before the supervisor exits if Register was called, including
+// after panics and when giving up restarting the service. Both calls are retried on errors, with each attempt
+// bounded by a timeout.
+type Registrar interface {
+	// Register registers the service instance.
+	Register(ctx context.Context, info ServiceInfo) error
+	// Deregister removes the registration of the service instance.
+	Deregister(ctx context.Context, info ServiceInfo) error
+}
+
+// registration keeps the registrations of a service in sync with its lifecycle state. Calls are made by a
+// background goroutine, so slow registrars do not delay the start of the service. Leaving the running state
+// waits for the deregistration, so it completes before the service is closed.
+type registration struct {
+	ctx        context.Context
+	info       ServiceInfo
+	registrars []Registrar
+	// pausedReady keeps the registration while the service is paused, see PausedReadiness.
+	pausedReady bool
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	want      bool
+	seq       int
+	processed int
+
+	changed chan struct{}
+	closed  chan struct{}
+	done    chan struct{}
+}
+
+// initRegistrars binds the registrars configured in opts to the lifecycle of the service.
+// It returns a function deregistering the service if required and waiting for all calls to return.
+func initRegistrars(ctx context.Context, opts Options) func() {
+	sup := supervisorFrom(ctx)
+	if len(opts.Registrars) == 0 || sup == nil {
+		return func() {}
+	}
+
+	hostname, _ := os.Hostname()
+	r := &registration{
+		// Registrations must be removed even when the service context is cancelled
+		ctx: context.WithoutCancel(ctx),
+		info: ServiceInfo{
+			Name:       Name(ctx),
+			Namespace:  Namespace(ctx),
+			Version:    Version(ctx),
+			InstanceID: hostname + "-" + strconv.Itoa(os.Getpid()),
+			Labels:     Labels(ctx),
+		},
+		registrars:  opts.Registrars,
+		pausedReady: opts.PausedReadiness,
+		changed:     make(chan struct{}, 1),
+		closed:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	r.cond = sync.NewCond(&r.mu)
+
+	go r.run()
+	stop := sup.onStateChange(r.update)
+
+	return func() {
+		stop()
+		close(r.closed)
+		<-r.done
+	}
+}
+
+// update records whether the service should be registered in the given lifecycle state. If not, it waits until
+// the service is deregistered.
+func (r *registration) update(state State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.want = isReady(state, r.pausedReady)
+	r.seq++
+	seq := r.seq
+
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+
+	if r.want {
+		return
+	}
+
+	for r.processed < seq {
+		r.cond.Wait()
+	}
+}
+
+// run registers and deregisters the service until the registration is closed, deregistering it on exit if
+// it is still registered.
+func (r *registration) run() {
+	defer close(r.done)
+
+	registered := false
+	for {
+		select {
+		case <-r.changed:
+		case <-r.closed:
+			if registered {
+				r.call("deregister", Registrar.Deregister)
+			}
+			return
+		}
+
+		r.mu.Lock()
+		want, seq := r.want, r.seq
+		r.mu.Unlock()
+
+		switch {
+		case want && !registered:
+			r.call("register", Registrar.Register)
+			registered = true
+		case !want && registered:
+			r.call("deregister", Registrar.Deregister)
+			registered = false
+		}
+
+		r.mu.Lock()
+		r.processed = seq
+		r.cond.Broadcast()
+		r.mu.Unlock()
+	}
+}
+
+// call calls fn for each registrar, retrying failed calls.
+func (r *registration) call(op string, fn func(Registrar, context.Context, ServiceInfo) error) {
+	for i, registrar := range r.registrars {
+		name := fmt.Sprintf("%s registrar %d", op, i)
+
+		err := Retry(r.ctx, name, func(ctx context.Context) (err error) {
+			ctx, cancel := context.WithTimeout(ctx, registrarCallTimeout)
+			defer cancel()
+
+			defer func() {
+				if cause := recover(); cause != nil {
+					err = panicError(ctx, cause, err)
+				}
+			}()
+
+			return fn(registrar, ctx, r.info)
+		}, WithRetryMaxAttempts(registrarMaxAttempts))
+		if err != nil {
+			Logger(r.ctx).Error("failed to "+op+" service", "registrar", fmt.Sprintf("%T", registrar), "error", err)
+			continue
+		}
+
+		Logger(r.ctx).Info("service "+op+"ed", "registrar", fmt.Sprintf("%T", registrar), "instance_id", r.info.InstanceID)
+	}
+}
@@ -0,0 +1,154 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// eventLog records events of a test in order.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) all() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return slices.Clone(l.events)
+}
+
+// recordingRegistrar is a Registrar recording its calls. The first failures calls of Register fail, so they are
+// retried.
+type recordingRegistrar struct {
+	log      *eventLog
+	failures int
+	infos    []ServiceInfo
+}
+
+func (r *recordingRegistrar) Register(ctx context.Context, info ServiceInfo) error {
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("registry unavailable")
+	}
+
+	r.log.add("register")
+	r.infos = append(r.infos, info)
+	return nil
+}
+
+func (r *recordingRegistrar) Deregister(ctx context.Context, info ServiceInfo) error {
+	r.log.add("deregister")
+	return nil
+}
+
+func TestRegistrar(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context, attempt int, log *eventLog) error
+		opts    []Option
+		wantErr bool
+		want    []string
+	}{
+		{
+			name: "clean shutdown",
+			run: func(ctx context.Context, _ int, log *eventLog) error {
+				// Deregistration completes when draining begins, before the context is cancelled
+				supervisorFrom(ctx).beginStopping()
+				if ctx.Err() == nil {
+					log.add("drained")
+				}
+				return nil
+			},
+			want: []string{"init", "register", "run", "deregister", "drained", "close"},
+		},
+		{
+			name: "crash",
+			run: func(ctx context.Context, _ int, log *eventLog) error {
+				panic("boom")
+			},
+			opts:    []Option{WithRestartOnPanic(false)},
+			wantErr: true,
+			want:    []string{"init", "register", "run", "deregister"},
+		},
+		{
+			name: "restart",
+			run: func(ctx context.Context, attempt int, log *eventLog) error {
+				if attempt == 1 {
+					return errFailed
+				}
+				return nil
+			},
+			want: []string{"init", "register", "run", "deregister", "init", "register", "run", "deregister", "close"},
+		},
+		{
+			name: "give up",
+			run: func(ctx context.Context, attempt int, log *eventLog) error {
+				return errFailed
+			},
+			opts:    []Option{WithGraceCount(1)},
+			wantErr: true,
+			want:    []string{"init", "register", "run", "deregister", "init", "register", "run", "deregister"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &eventLog{}
+			registrar := &recordingRegistrar{log: log, failures: 1}
+
+			attempt := 0
+			svc := &testService{
+				init: func(ctx context.Context) error {
+					log.add("init")
+					return nil
+				},
+				run: func(ctx context.Context) error {
+					attempt++
+					waitFor(t, "registration", func() bool {
+						events := log.all()
+						return len(events) > 0 && events[len(events)-1] == "register"
+					})
+					log.add("run")
+					return tt.run(ctx, attempt, log)
+				},
+				close: func(ctx context.Context) error {
+					log.add("close")
+					return nil
+				},
+			}
+
+			opts := append([]Option{WithRegistrar(registrar)}, tt.opts...)
+			err := RunC(svc, context.Background(), testOptions(opts...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunC() = %v, want error %t", err, tt.wantErr)
+			}
+
+			got := log.all()
+			// Close may or may not be called after a crash; only the registrations are compared then
+			if tt.wantErr {
+				got = slices.DeleteFunc(got, func(event string) bool { return event == "close" })
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+
+			info := registrar.infos[0]
+			if info.Name != "test" || info.Namespace != "astest" || info.Version != "v1.0.0" || info.InstanceID == "" {
+				t.Errorf("service info = %+v", info)
+			}
+		})
+	}
+}
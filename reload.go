@@ -0,0 +1,53 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// reloadRestartOptions replaces the options of a restarting service with the reloaded ones, logging the changed
+// fields and applying a changed log level. Settings applied once at startup (e.g. the log output or OTEL
+// exporters) keep their initial values. Invalid reloaded options are logged and the current ones kept.
+func reloadRestartOptions(ctx context.Context, sup *supervisor, current, reloaded Options) Options {
+	if err := reloaded.Validate(); err != nil {
+		Logger(ctx).Error("reloaded options are invalid, keeping the current ones", "error", err)
+		return current
+	}
+
+	changes := diffOptions(current, reloaded)
+	if len(changes) == 0 {
+		return current
+	}
+
+	if sup.logLevel != nil {
+		sup.logLevel.Set(logLevel(reloaded))
+	}
+
+	Logger(ctx).Info("options changed, applying on restart", changes...)
+
+	return reloaded
+}
+
+// diffOptions returns the fields whose values differ between a and b as logger attributes of the form
+// "<field>" = "<old> -> <new>". Unexported fields and fields which cannot be compared, e.g. funcs, are skipped.
+func diffOptions(a, b Options) []any {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+
+	var changes []any
+	for i := range va.NumField() {
+		field := va.Type().Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		fa, fb := va.Field(i).Interface(), vb.Field(i).Interface()
+		if reflect.DeepEqual(fa, fb) {
+			continue
+		}
+
+		changes = append(changes, field.Name, fmt.Sprintf("%v -> %v", fa, fb))
+	}
+
+	return changes
+}
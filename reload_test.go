@@ -0,0 +1,132 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReloadOptionsOnRestart(t *testing.T) {
+	const delay = 200 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		reload    bool
+		wantDelay bool
+	}{
+		{name: "enabled", reload: true, wantDelay: true},
+		{name: "disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASTEST_TEST_RESTART_ON_ERROR_DELAY", "0s")
+
+			attempt := 0
+			var failed time.Time
+			var restartDelay time.Duration
+			svc := &testService{run: func(ctx context.Context) error {
+				attempt++
+				if attempt == 1 {
+					// The operator patches the environment of the crash-looping service
+					if err := os.Setenv("ASTEST_TEST_RESTART_ON_ERROR_DELAY", delay.String()); err != nil {
+						return err
+					}
+					failed = time.Now()
+					return errors.New("failed")
+				}
+
+				restartDelay = time.Since(failed)
+				return nil
+			}}
+
+			logs := &logCapture{}
+			opts := testOptions(captureLogs(svc, logs), WithReloadOptionsOnRestart(tt.reload))
+			if err := RunC(svc, context.Background(), opts...); err != nil {
+				t.Fatalf("RunC() = %v", err)
+			}
+
+			if got := restartDelay >= delay; got != tt.wantDelay {
+				t.Errorf("restarted after %s, want the reloaded delay of %s applied: %t", restartDelay, delay, tt.wantDelay)
+			}
+
+			record := logs.find("options changed, applying on restart")
+			if !tt.reload {
+				if record != nil {
+					t.Errorf("options reloaded although disabled: %v", record)
+				}
+				return
+			}
+			if record == nil || record["RestartOnErrorDelay"] != "0s -> "+delay.String() {
+				t.Errorf("reload record = %v", record)
+			}
+		})
+	}
+}
+
+func TestDiffOptions(t *testing.T) {
+	a := DefaultOptions()
+	a.QuietErrorFunc = func(error) bool { return true }
+	b := a
+	b.LogLevel = "debug"
+	b.GraceCount = 5
+	b.QuietErrorFunc = nil
+
+	got := diffOptions(a, b)
+	want := []any{"GraceCount", "3 -> 5", "LogLevel", a.LogLevel + " -> debug"}
+	if len(got) != len(want) {
+		t.Fatalf("diffOptions() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diffOptions()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if got := diffOptions(a, a); len(got) != 0 {
+		t.Errorf("diffOptions() of equal options = %v, want none", got)
+	}
+}
+
+func TestReloadOptionsInvalid(t *testing.T) {
+	t.Setenv("ASTEST_TEST_RESTART_ON_ERROR_DELAY", "0s")
+
+	attempt := 0
+	var failed time.Time
+	var restartDelay time.Duration
+	svc := &testService{run: func(ctx context.Context) error {
+		attempt++
+		if attempt == 1 {
+			// The patched value is parsed, but fails validation
+			if err := os.Setenv("ASTEST_TEST_RESTART_ON_ERROR_DELAY", "-1h"); err != nil {
+				return err
+			}
+			failed = time.Now()
+			return errors.New("failed")
+		}
+
+		restartDelay = time.Since(failed)
+		return nil
+	}}
+
+	logs := &logCapture{}
+	opts := testOptions(captureLogs(svc, logs), WithReloadOptionsOnRestart(true))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// The service is restarted with the current options
+	if attempt != 2 || restartDelay > time.Second {
+		t.Errorf("attempts = %d, restarted after %s, want a restart without delay", attempt, restartDelay)
+	}
+	if record := logs.find("reloaded options are invalid, keeping the current ones"); record == nil ||
+		!strings.Contains(record["error"].(string), "RestartOnErrorDelay -1h0m0s: must not be negative") {
+		t.Errorf("invalid options logged as %v", record)
+	}
+	if record := logs.find("options changed, applying on restart"); record != nil {
+		t.Errorf("invalid options applied: %v", record)
+	}
+}
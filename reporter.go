@@ -0,0 +1,98 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// reporterTimeout bounds a single call of a Reporter or Flusher.
+const reporterTimeout = 5 * time.Second
+
+// Reporter receives recovered panics and terminal errors of a service, e.g. to forward them to an error tracking
+// system. Calls are bounded by a timeout and protected against panics.
+type Reporter interface {
+	// ReportPanic is called with the value and stack of every panic recovered by the supervisor.
+	ReportPanic(ctx context.Context, value any, stack []byte)
+	// ReportError is called with the error the supervisor stops the service with, e.g. after giving up restarting
+	// it or on unrecoverable errors.
+	ReportError(ctx context.Context, err error)
+}
+
+// Flusher is an optional interface of a Reporter buffering reports. Flush is called before the supervisor exits.
+type Flusher interface {
+	// Flush sends all buffered reports.
+	Flush(ctx context.Context) error
+}
+
+// LogReporter is a Reporter logging panics and errors with the logger of the context.
+type LogReporter struct{}
+
+// ReportPanic logs the panic at error level.
+func (LogReporter) ReportPanic(ctx context.Context, value any, stack []byte) {
+	Logger(ctx).Error("service panicked", "panic", fmt.Sprint(value), "stack", string(stack))
+}
+
+// ReportError logs the error at error level.
+func (LogReporter) ReportError(ctx context.Context, err error) {
+	Logger(ctx).Error("service failed", "error", err)
+}
+
+// reportPanic passes a recovered panic to all reporters configured in opts.
+func reportPanic(ctx context.Context, opts Options, value any, stack []byte) {
+	for _, r := range opts.Reporters {
+		callReporter(ctx, r, func(ctx context.Context) {
+			r.ReportPanic(ctx, value, stack)
+		})
+	}
+}
+
+// reportError passes a terminal error to all reporters configured in opts.
+func reportError(ctx context.Context, opts Options, err error) {
+	for _, r := range opts.Reporters {
+		callReporter(ctx, r, func(ctx context.Context) {
+			r.ReportError(ctx, err)
+		})
+	}
+}
+
+// flushReporters flushes all reporters configured in opts implementing Flusher.
+func flushReporters(ctx context.Context, opts Options) {
+	for _, r := range opts.Reporters {
+		f, ok := r.(Flusher)
+		if !ok {
+			continue
+		}
+
+		callReporter(ctx, r, func(ctx context.Context) {
+			if err := f.Flush(ctx); err != nil {
+				Logger(ctx).Error("failed to flush reporter", "reporter", fmt.Sprintf("%T", r), "error", err)
+			}
+		})
+	}
+}
+
+// callReporter calls fn with a context bounded by reporterTimeout, recovering panics. It returns once fn returned
+// or the timeout expired, whichever happens first.
+func callReporter(ctx context.Context, r Reporter, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reporterTimeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if cause := recover(); cause != nil {
+				Logger(ctx).Error("reporter panicked", "reporter", fmt.Sprintf("%T", r), "panic", fmt.Sprint(cause))
+			}
+		}()
+
+		fn(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		Logger(ctx).Warn("reporter timed out", "reporter", fmt.Sprintf("%T", r), "timeout", reporterTimeout.String())
+	}
+}
@@ -0,0 +1,166 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingReporter is a Reporter and Flusher recording its calls.
+type recordingReporter struct {
+	mu      sync.Mutex
+	panics  []any
+	errs    []error
+	flushes int
+}
+
+func (r *recordingReporter) ReportPanic(ctx context.Context, value any, stack []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.panics = append(r.panics, value)
+}
+
+func (r *recordingReporter) ReportError(ctx context.Context, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errs = append(r.errs, err)
+}
+
+func (r *recordingReporter) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flushes++
+	return nil
+}
+
+func TestReporter(t *testing.T) {
+	errFailed := errors.New("failed")
+	errQuiet := errors.New("quiet")
+
+	tests := []struct {
+		name       string
+		run        func(ctx context.Context) error
+		opts       []Option
+		wantPanics int
+		wantErr    error
+	}{
+		{
+			name: "panic and give up",
+			run: func(ctx context.Context) error {
+				panic("boom")
+			},
+			opts:       []Option{WithGraceCount(1)},
+			wantPanics: 2,
+			wantErr:    ErrRestartBudgetExhausted,
+		},
+		{
+			name: "give up",
+			run: func(ctx context.Context) error {
+				return errFailed
+			},
+			opts:    []Option{WithGraceCount(1)},
+			wantErr: errFailed,
+		},
+		{
+			name: "quiet error",
+			run: func(ctx context.Context) error {
+				return errQuiet
+			},
+			opts: []Option{WithRestartOnError(false), WithQuietErrors(errQuiet)},
+		},
+		{
+			name: "clean shutdown",
+			run: func(ctx context.Context) error {
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &recordingReporter{}
+			svc := &testService{run: tt.run}
+
+			opts := append([]Option{WithReporter(reporter)}, tt.opts...)
+			_ = RunC(svc, context.Background(), testOptions(opts...)...)
+
+			reporter.mu.Lock()
+			defer reporter.mu.Unlock()
+
+			if len(reporter.panics) != tt.wantPanics {
+				t.Errorf("reported %d panics, want %d", len(reporter.panics), tt.wantPanics)
+			}
+			for _, value := range reporter.panics {
+				if value != "boom" {
+					t.Errorf("reported panic %v, want boom", value)
+				}
+			}
+
+			switch {
+			case tt.wantErr == nil && len(reporter.errs) > 0:
+				t.Errorf("reported errors %v, want none", reporter.errs)
+			case tt.wantErr != nil && (len(reporter.errs) != 1 || !errors.Is(reporter.errs[0], tt.wantErr)):
+				t.Errorf("reported errors %v, want %v", reporter.errs, tt.wantErr)
+			}
+
+			if reporter.flushes != 1 {
+				t.Errorf("flushed %d times, want 1", reporter.flushes)
+			}
+		})
+	}
+}
+
+// blockingReporter is a Reporter whose calls block until their context is done, or panic.
+type blockingReporter struct {
+	panic bool
+}
+
+func (r blockingReporter) ReportPanic(ctx context.Context, value any, stack []byte) {
+	r.ReportError(ctx, nil)
+}
+
+func (r blockingReporter) ReportError(ctx context.Context, err error) {
+	if r.panic {
+		panic("reporter")
+	}
+	<-ctx.Done()
+}
+
+func TestCallReporterPanic(t *testing.T) {
+	// A panicking reporter must neither crash the supervisor nor keep the following reporters from being called
+	reporter := &recordingReporter{}
+	opts := Options{Reporters: []Reporter{blockingReporter{panic: true}, reporter}}
+
+	reportError(context.Background(), opts, errors.New("failed"))
+
+	if len(reporter.errs) != 1 {
+		t.Errorf("reported errors %v, want one", reporter.errs)
+	}
+}
+
+func TestCallReporterDetached(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The parent context is ignored, so reporting still happens while shutting down; only the timeout applies
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		callReporter(ctx, blockingReporter{}, func(ctx context.Context) {
+			if ctx.Err() != nil {
+				t.Error("reporter context is done at once")
+			}
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("callReporter did not return")
+	}
+}
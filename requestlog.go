@@ -0,0 +1,102 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// requestLogLevelBaggageKey is the baggage member overriding the log level of records logged with a context.
+const requestLogLevelBaggageKey = "log.level"
+
+// requestLogLevelKey is the key type for storing the request log level in the context.
+type requestLogLevelKey struct{}
+
+// WithRequestLogLevel returns a new context overriding the log level of the service for records logged with it,
+// e.g. using Logger(ctx).DebugContext(ctx, ...). This allows debug logs for a single request while the service
+// logs at info level. The override can only lower the level, records below the service level are otherwise
+// dropped as usual.
+//
+// The level can also be set by the log.level baggage member of incoming requests, or by the header configured
+// with WithLogLevelHeader when using HTTPMiddleware or the gRPC server interceptors.
+func WithRequestLogLevel(ctx context.Context, level slog.Level) context.Context {
+	return context.WithValue(ctx, requestLogLevelKey{}, level)
+}
+
+// requestLogLevel returns the log level override of the context, set either by WithRequestLogLevel or by the
+// log.level baggage member.
+func requestLogLevel(ctx context.Context) (slog.Level, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+
+	if level, ok := ctx.Value(requestLogLevelKey{}).(slog.Level); ok {
+		return level, true
+	}
+
+	if v := baggage.FromContext(ctx).Member(requestLogLevelBaggageKey).Value(); v != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(v)); err == nil {
+			return level, true
+		}
+	}
+
+	return 0, false
+}
+
+// withRequestLogLevelHeader returns ctx with the log level override set from the value of the header configured
+// with WithLogLevelHeader. Invalid or empty values are ignored.
+func withRequestLogLevelHeader(ctx context.Context, value string) context.Context {
+	if value == "" {
+		return ctx
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return ctx
+	}
+
+	return WithRequestLogLevel(ctx, level)
+}
+
+// logLevelHeader returns the name of the header overriding the log level of requests, or an empty string if none
+// is configured.
+func logLevelHeader(ctx context.Context) string {
+	if sup := supervisorFrom(ctx); sup != nil {
+		return sup.logLevelHeader
+	}
+
+	return ""
+}
+
+// levelHandler is a slog.Handler dropping records below the service log level, unless the log level is
+// lowered for the context of the record. The wrapped handler must accept records of all levels.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+// Enabled reports whether records of the level are logged, either because the level is at least the service
+// level or the level override of the context.
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level >= h.level.Level() {
+		return h.Handler.Enabled(ctx, level)
+	}
+
+	if override, ok := requestLogLevel(ctx); ok && level >= override {
+		return h.Handler.Enabled(ctx, level)
+	}
+
+	return false
+}
+
+// WithAttrs returns a handler filtering records of the wrapped handler with the attributes.
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+// WithGroup returns a handler filtering records of the wrapped handler with the group.
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
@@ -0,0 +1,90 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// withBaggageLogLevel returns ctx carrying the log.level baggage member.
+func withBaggageLogLevel(t *testing.T, ctx context.Context, level string) context.Context {
+	t.Helper()
+
+	member, err := baggage.NewMember(requestLogLevelBaggageKey, level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bag, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+func TestLevelHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{name: "without marker", ctx: context.Background()},
+		{name: "request log level", ctx: WithRequestLogLevel(context.Background(), slog.LevelDebug), want: true},
+		{name: "request log level above record", ctx: WithRequestLogLevel(context.Background(), slog.LevelWarn)},
+		{name: "baggage", ctx: withBaggageLogLevel(t, context.Background(), "debug"), want: true},
+		{name: "invalid baggage", ctx: withBaggageLogLevel(t, context.Background(), "verbose")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			level := new(slog.LevelVar)
+			level.Set(slog.LevelInfo)
+			handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+			logger := slog.New(&levelHandler{Handler: handler, level: level}).With("service", "test")
+
+			logger.DebugContext(tt.ctx, "debug")
+			logger.InfoContext(tt.ctx, "info")
+
+			if got := bytes.Contains(buf.Bytes(), []byte("msg=debug")); got != tt.want {
+				t.Errorf("debug record emitted = %t, want %t", got, tt.want)
+			}
+			if !bytes.Contains(buf.Bytes(), []byte("msg=info")) {
+				t.Error("info record suppressed")
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareLogLevelHeader(t *testing.T) {
+	svc := &testService{}
+	svc.run = func(ctx context.Context) error {
+		handler := HTTPMiddleware(ctx)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Logger(r.Context()).DebugContext(r.Context(), "debug "+r.URL.Path)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/marked", nil)
+		req.Header.Set("X-Log-Level", "debug")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unmarked", nil))
+
+		return nil
+	}
+
+	logs := &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs), WithLogLevelHeader("X-Log-Level"))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if logs.find("debug /marked") == nil {
+		t.Error("debug record of the request with the header suppressed")
+	}
+	if logs.find("debug /unmarked") != nil {
+		t.Error("debug record of the request without the header emitted")
+	}
+}
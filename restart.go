@@ -0,0 +1,9 @@
+package as
+
+import "errors"
+
+// ErrRestartRequested is returned by Run, optionally wrapped with a reason, to request a restart of the service,
+// e.g. after a configuration change. The supervisor calls Close and restarts the service after
+// RequestedRestartDelay, logging the reason at Info level. Requested restarts do not count against the grace
+// limits, but against MaxLifetimeRestarts. While the service is shutting down, the request is ignored.
+var ErrRestartRequested = errors.New("restart requested")
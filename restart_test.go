@@ -0,0 +1,84 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRestartRequested(t *testing.T) {
+	errFailed := errors.New("failed")
+	runs, closes := 0, 0
+	svc := &testService{
+		run: func(ctx context.Context) error {
+			if runs++; runs <= 3 {
+				return fmt.Errorf("config changed: %w", ErrRestartRequested)
+			}
+			return errFailed
+		},
+		close: func(ctx context.Context) error {
+			closes++
+			return nil
+		},
+	}
+
+	logs := &logCapture{}
+	err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs), WithGraceCount(1))...)
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("RunC() = %v, want the run error", err)
+	}
+
+	// Requested restarts leave the grace budget untouched, so the first failure is still restarted
+	if runs != 5 {
+		t.Errorf("runs = %d, want 5", runs)
+	}
+	// Close runs before every requested restart, but not after failures
+	if closes != 3 {
+		t.Errorf("closes = %d, want 3", closes)
+	}
+
+	record := logs.find("service requested restart")
+	if record == nil || record["level"] != "INFO" || record["reason"] != "config changed: restart requested" {
+		t.Errorf("restart request logged as %v", record)
+	}
+}
+
+func TestRestartRequestedLifetimeLimit(t *testing.T) {
+	runs := 0
+	svc := &testService{run: func(ctx context.Context) error {
+		runs++
+		return ErrRestartRequested
+	}}
+
+	err := RunC(svc, context.Background(), testOptions(WithMaxLifetimeRestarts(2))...)
+	if err == nil {
+		t.Fatal("RunC() = nil, want the lifetime limit error")
+	}
+	if runs != 3 {
+		t.Errorf("runs = %d, want 3", runs)
+	}
+}
+
+func TestRestartRequestedDuringShutdown(t *testing.T) {
+	runs := 0
+	started := make(chan struct{})
+	svc := &testService{run: func(ctx context.Context) error {
+		if runs++; runs == 1 {
+			close(started)
+		}
+		<-ctx.Done()
+		return ErrRestartRequested
+	}}
+
+	// A restart requested while shutting down is ignored, ending the service cleanly
+	cancel, done := runTest(t, svc)
+	<-started
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v, want nil", err)
+	}
+	if runs != 1 {
+		t.Errorf("runs = %d, want 1", runs)
+	}
+}
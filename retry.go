@@ -0,0 +1,167 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"go.aledante.io/ae"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// retryConfig holds the configuration of Retry.
+type retryConfig struct {
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	multiplier   float64
+	jitter       float64
+	maxAttempts  int
+	maxElapsed   time.Duration
+	retryIf      func(err error) bool
+}
+
+// RetryOption is a function which applies a configuration change to Retry.
+type RetryOption func(*retryConfig)
+
+// WithRetryInitialDelay sets the delay before the first retry. Defaults to 100ms.
+func WithRetryInitialDelay(v time.Duration) RetryOption {
+	return func(c *retryConfig) { c.initialDelay = v }
+}
+
+// WithRetryMaxDelay sets the maximum delay between two attempts. Defaults to 30s.
+func WithRetryMaxDelay(v time.Duration) RetryOption {
+	return func(c *retryConfig) { c.maxDelay = v }
+}
+
+// WithRetryMultiplier sets the factor the delay is multiplied with after each attempt. Defaults to 2.
+func WithRetryMultiplier(v float64) RetryOption {
+	return func(c *retryConfig) { c.multiplier = v }
+}
+
+// WithRetryJitter sets the fraction by which each delay is randomly increased or decreased. Defaults to 0.2.
+func WithRetryJitter(v float64) RetryOption {
+	return func(c *retryConfig) { c.jitter = v }
+}
+
+// WithRetryMaxAttempts sets the maximum number of attempts, including the first one.
+// If set to zero (the default), there is no limit.
+func WithRetryMaxAttempts(v int) RetryOption {
+	return func(c *retryConfig) { c.maxAttempts = v }
+}
+
+// WithRetryMaxElapsed sets the maximum duration after the first attempt during which retries are started.
+// If set to zero (the default), there is no limit.
+func WithRetryMaxElapsed(v time.Duration) RetryOption {
+	return func(c *retryConfig) { c.maxElapsed = v }
+}
+
+// WithRetryIf sets a predicate deciding whether an error is retryable. Retry stops and returns the error as soon
+// as the predicate returns false. By default, all errors are retryable.
+func WithRetryIf(fn func(err error) bool) RetryOption {
+	return func(c *retryConfig) { c.retryIf = fn }
+}
+
+// Retry calls fn until it succeeds, using exponential backoff with jitter between attempts.
+// It stops once the maximum number of attempts or the maximum elapsed time is reached, when fn returns an error
+// which is not retryable (see WithRetryIf), or when ctx is cancelled; in the latter case, the context error is
+// returned with the last error of fn attached as related error.
+//
+// Each attempt is logged at debug level with the given name using the context logger. The number of attempts is
+// recorded by the as.retry.attempts counter and the as.retry.call.attempts histogram on the context meter.
+func Retry(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...RetryOption) error {
+	cfg := retryConfig{
+		initialDelay: 100 * time.Millisecond,
+		maxDelay:     30 * time.Second,
+		multiplier:   2,
+		jitter:       0.2,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	nameAttr := attribute.String("retry", name)
+	attemptCounter, _ := Meter(ctx).Int64Counter(
+		"as.retry.attempts",
+		metric.WithDescription("Number of attempts made by as.Retry"),
+	)
+	attemptHistogram, _ := Meter(ctx).Int64Histogram(
+		"as.retry.call.attempts",
+		metric.WithDescription("Number of attempts needed per as.Retry call"),
+	)
+
+	start := time.Now()
+	attempt := 0
+	for {
+		attempt++
+
+		err := fn(ctx)
+		outcome := "success"
+		if err != nil {
+			outcome = "failure"
+		}
+		if attemptCounter != nil {
+			attemptCounter.Add(ctx, 1, metric.WithAttributes(nameAttr, attribute.String("outcome", outcome)))
+		}
+
+		if err == nil {
+			Logger(ctx).Debug("attempt succeeded", "retry", name, "attempt", attempt)
+			recordRetryAttempts(ctx, attemptHistogram, attempt, nameAttr, outcome)
+			return nil
+		}
+
+		if cfg.retryIf != nil && !cfg.retryIf(err) {
+			recordRetryAttempts(ctx, attemptHistogram, attempt, nameAttr, "permanent")
+			return ae.Wrap(fmt.Sprintf("%s failed with a permanent error after %d attempts", name, attempt), err)
+		}
+		if cfg.maxAttempts > 0 && attempt >= cfg.maxAttempts {
+			recordRetryAttempts(ctx, attemptHistogram, attempt, nameAttr, "exhausted")
+			return ae.Wrap(fmt.Sprintf("%s failed after %d attempts", name, attempt), err)
+		}
+		if cfg.maxElapsed > 0 && time.Since(start) >= cfg.maxElapsed {
+			recordRetryAttempts(ctx, attemptHistogram, attempt, nameAttr, "exhausted")
+			return ae.Wrap(fmt.Sprintf("%s failed after %d attempts in %s", name, attempt, time.Since(start)), err)
+		}
+
+		delay := cfg.delay(attempt)
+		Logger(ctx).Debug(
+			"attempt failed, retrying after delay",
+			"retry", name,
+			"attempt", attempt,
+			"retry_delay", delay.String(),
+			"error", err,
+		)
+
+		if Sleep(ctx, delay) != nil {
+			recordRetryAttempts(ctx, attemptHistogram, attempt, nameAttr, "cancelled")
+			return ae.NewC(ctx).
+				Cause(context.Cause(ctx)).
+				Related(err).
+				Msg(fmt.Sprintf("%s cancelled after %d attempts", name, attempt))
+		}
+	}
+}
+
+// delay returns the delay after the given attempt, including jitter.
+func (c retryConfig) delay(attempt int) time.Duration {
+	delay := float64(c.initialDelay) * math.Pow(c.multiplier, float64(attempt-1))
+	if c.maxDelay > 0 {
+		delay = min(delay, float64(c.maxDelay))
+	}
+	if c.jitter > 0 {
+		delay *= 1 + c.jitter*(2*rand.Float64()-1)
+	}
+
+	return time.Duration(max(delay, 0))
+}
+
+// recordRetryAttempts records the number of attempts of a finished Retry call.
+func recordRetryAttempts(ctx context.Context, h metric.Int64Histogram, attempts int, name attribute.KeyValue, outcome string) {
+	if h == nil {
+		return
+	}
+
+	h.Record(ctx, int64(attempts), metric.WithAttributes(name, attribute.String("outcome", outcome)))
+}
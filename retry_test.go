@@ -0,0 +1,195 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	metricSdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// testMeterContext returns a context whose meter is read by the returned reader.
+func testMeterContext(t *testing.T, ctx context.Context) (context.Context, *metricSdk.ManualReader) {
+	t.Helper()
+
+	reader := metricSdk.NewManualReader()
+	provider := metricSdk.NewMeterProvider(metricSdk.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	return withMeter(ctx, provider.Meter("test")), reader
+}
+
+// collectMetric returns the data of the named metric collected by reader, or nil if it was not recorded.
+func collectMetric(t *testing.T, reader *metricSdk.ManualReader, name string) metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+
+	return nil
+}
+
+// counterTotal returns the sum of all data points of the named int64 counter.
+func counterTotal(t *testing.T, reader *metricSdk.ManualReader, name string) int64 {
+	t.Helper()
+
+	sum, _ := collectMetric(t, reader, name).(metricdata.Sum[int64])
+	var total int64
+	for _, point := range sum.DataPoints {
+		total += point.Value
+	}
+
+	return total
+}
+
+// testRetry are retry options with delays of 100ms, 200ms, 400ms, ... up to 1s, without jitter. The tests run in
+// a synctest bubble, so the delays take no real time.
+var testRetry = []RetryOption{
+	WithRetryInitialDelay(100 * time.Millisecond),
+	WithRetryMaxDelay(time.Second),
+	WithRetryMultiplier(2),
+	WithRetryJitter(0),
+}
+
+func TestRetry(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		opts         []RetryOption
+		wantAttempts int
+		wantElapsed  time.Duration
+		wantErr      error
+	}{
+		{name: "first attempt succeeds", wantAttempts: 1},
+		{name: "succeeds after failures", failures: 3, err: errTemporary, wantAttempts: 4, wantElapsed: 700 * time.Millisecond},
+		{
+			name:         "max attempts",
+			failures:     10,
+			err:          errTemporary,
+			opts:         []RetryOption{WithRetryMaxAttempts(3)},
+			wantAttempts: 3,
+			wantElapsed:  300 * time.Millisecond,
+			wantErr:      errTemporary,
+		},
+		{
+			name:         "permanent error",
+			failures:     10,
+			err:          errPermanent,
+			opts:         []RetryOption{WithRetryIf(func(err error) bool { return !errors.Is(err, errPermanent) })},
+			wantAttempts: 1,
+			wantErr:      errPermanent,
+		},
+		{
+			// Attempts at 0, 100ms, 300ms, 700ms and 1.5s, after which the limit is exceeded
+			name:         "max elapsed",
+			failures:     1000,
+			err:          errTemporary,
+			opts:         []RetryOption{WithRetryMaxElapsed(time.Second)},
+			wantAttempts: 5,
+			wantElapsed:  1500 * time.Millisecond,
+			wantErr:      errTemporary,
+		},
+		{
+			name:         "max delay",
+			failures:     6,
+			err:          errTemporary,
+			wantAttempts: 7,
+			wantElapsed:  (100 + 200 + 400 + 800 + 1000 + 1000) * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, reader := testMeterContext(t, context.Background())
+
+				attempts := 0
+				start := time.Now()
+				err := Retry(ctx, "test", func(ctx context.Context) error {
+					if attempts++; attempts <= tt.failures {
+						return tt.err
+					}
+					return nil
+				}, append(slices.Clip(testRetry), tt.opts...)...)
+
+				if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+					t.Fatalf("Retry() = %v, want %v", err, tt.wantErr)
+				}
+				if attempts != tt.wantAttempts {
+					t.Errorf("got %d attempts, want %d", attempts, tt.wantAttempts)
+				}
+				if elapsed := time.Since(start); elapsed != tt.wantElapsed {
+					t.Errorf("Retry returned after %s, want %s", elapsed, tt.wantElapsed)
+				}
+				if got := counterTotal(t, reader, "as.retry.attempts"); got != int64(attempts) {
+					t.Errorf("as.retry.attempts = %d, want %d", got, attempts)
+				}
+
+				histogram, _ := collectMetric(t, reader, "as.retry.call.attempts").(metricdata.Histogram[int64])
+				if len(histogram.DataPoints) != 1 || histogram.DataPoints[0].Count != 1 || histogram.DataPoints[0].Sum != int64(attempts) {
+					t.Errorf("as.retry.call.attempts = %+v, want one call with %d attempts", histogram.DataPoints, attempts)
+				}
+			})
+		})
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errTemporary := errors.New("temporary")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		attempts := 0
+		start := time.Now()
+		err := Retry(ctx, "test", func(ctx context.Context) error {
+			attempts++
+			return errTemporary
+		}, WithRetryInitialDelay(time.Hour))
+
+		if elapsed := time.Since(start); elapsed != 20*time.Millisecond {
+			t.Errorf("Retry returned after %s, want at the cancellation after 20ms", elapsed)
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Retry() = %v, want context.Canceled", err)
+		}
+		if attempts != 1 {
+			t.Errorf("got %d attempts, want 1", attempts)
+		}
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	cfg := retryConfig{initialDelay: 100 * time.Millisecond, maxDelay: time.Second, multiplier: 2}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := cfg.delay(i + 1); got != w*time.Millisecond {
+			t.Errorf("delay(%d) = %s, want %s", i+1, got, w*time.Millisecond)
+		}
+	}
+
+	cfg.jitter = 0.2
+	for range 100 {
+		if got := cfg.delay(1); got < 80*time.Millisecond || got > 120*time.Millisecond {
+			t.Fatalf("delay(1) with jitter = %s, want within 20%% of 100ms", got)
+		}
+	}
+}
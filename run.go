@@ -3,17 +3,22 @@ package as
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"slices"
 	"strings"
 	"time"
 
 	"go.aledante.io/ae"
+	"go.opentelemetry.io/otel/metric"
 	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
 )
 
 // Run starts the service in a new background context with the given options.
-// The context is cancelled on SIGINT or SIGKILL so the service can shut down gracefully.
+// The context is cancelled on SIGINT or SIGTERM (see ShutdownSignals) so the service can shut down gracefully.
 // Blocks until the service exits. Returns any error encountered during execution
 // or initialization. Convenience wrapper for RunC.
 func Run(svc Service, opts ...Option) error {
@@ -21,107 +26,582 @@ func Run(svc Service, opts ...Option) error {
 }
 
 // RunAndExit starts the service in a background context. The context is cancelled
-// on SIGINT or SIGKILL for graceful shutdown. Exits the process only if the service
-// returns an error other than context.Canceled. Intended for main; errors are reported, then ae.Exit is called.
+// on SIGINT or SIGTERM (see ShutdownSignals) for graceful shutdown. Exits the process only if the service
+// returns an error other than context.Canceled. Intended for main; errors are reported, then the process exits with
+// the code of the error class (see ExitOK).
 func RunAndExit(svc Service, opts ...Option) {
 	RunAndExitC(svc, context.Background(), opts...)
 }
 
-// RunAndExitC starts the service; the run context is cancelled on SIGINT or SIGKILL.
-// Exits the process only if the service returns an error other than context.Canceled.
-// Used for robust always-on daemons; prints errors and performs ae.Exit.
+// RunAndExitC starts the service; the run context is cancelled when ctx is done or on SIGINT or SIGTERM.
+// Exits the process only if the service returns an error other than context.Canceled, context.DeadlineExceeded
+// after a shutdown, or one of the errors configured with WithQuietErrors / WithQuietErrorFunc.
+// Used for robust always-on daemons; prints errors and exits with the code of the error class, see ExitOK.
 func RunAndExitC(svc Service, ctx context.Context, opts ...Option) {
-	if err := RunC(svc, ctx, opts...); err != nil {
-		if !errors.Is(err, context.Canceled) {
-			ae.Print(err, ae.PrintFrameFilters(func(frame *ae.StackFrame) bool {
-				return !strings.HasPrefix(frame.Func, "go.aledante.io/as.")
-			}))
+	defer reportLateLogRecords()
+
+	// The caller's backing array must not be written to
+	res, err := runC(svc, ctx, append(slices.Clip(opts), withExitProcess()))
+	if err != nil {
+		if isQuietError(err, res.options, res.shutdown) {
+			return
 		}
 
-		ae.Exit(err)
+		options, id := res.options, res.id
+		code := exitCode(err, options)
+		logTerminalError(err, code, options, id.name, resolveVersion(svc.Version(), options), id.namespace)
+		printError(err, options)
+		reportLateLogRecords()
+		exitFunc(options)(code)
+	}
+}
+
+// logTerminalError logs err as a final Error record if the logs are structured (JSON or logfmt) and written to
+// stdout, so log pipelines do not lose the error printed to stderr by printError.
+func logTerminalError(err error, code int, opts Options, name, version, namespace string) {
+	structured := opts.LogFormat == LogFormatLogfmt || opts.LogJson
+	if !structured || opts.LogOutput != "stdout" {
+		return
+	}
+
+	schema := LogSchemaDefault
+	if opts.LogFormat == LogFormatDefault && opts.LogSchema != "" {
+		schema = opts.LogSchema
+	}
+
+	slog.New(newWriterHandler(os.Stdout, opts, schema)).
+		With(schema.serviceAttrs(name, version, namespace)...).
+		Error("service terminated", "error", err.Error(), "exit_code", code)
+}
+
+// printError prints err to stderr, hiding stack frames of this package and frames rejected by
+// opts.ErrorPrintFrameFilters, unless opts.ErrorPrintFullStacks is set. If colors are enabled for stderr (see
+// LogColors), the error is introduced by a colored header, so it stands out from the logs above it.
+func printError(err error, opts Options) {
+	if colorsEnabled(opts, os.Stderr) {
+		_, _ = fmt.Fprintf(os.Stderr, "\n%s✗ service failed:%s %s\n\n", ansiBoldRed, ansiReset, firstLine(err.Error()))
+	}
+
+	if opts.ErrorPrintFullStacks {
+		ae.Print(err)
+		return
+	}
+
+	ae.Print(err, ae.PrintFrameFilters(errorFrameFilter(opts)))
+}
+
+// errorFrameFilter returns the filter of the stack frames printed by printError: it hides frames of this package and
+// frames rejected by any of opts.ErrorPrintFrameFilters.
+func errorFrameFilter(opts Options) func(*ae.StackFrame) bool {
+	return func(frame *ae.StackFrame) bool {
+		if strings.HasPrefix(frame.Func, "go.aledante.io/as.") {
+			return false
+		}
+
+		for _, filter := range opts.ErrorPrintFrameFilters {
+			if !filter(frame) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+const (
+	// ansiBoldRed starts bold red terminal output.
+	ansiBoldRed = "\x1b[1;31m"
+	// ansiReset resets the terminal output style.
+	ansiReset = "\x1b[0m"
+)
+
+// firstLine returns s up to the first line break.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// isQuietError reports whether err is expected during a normal shutdown and should neither be printed nor
+// cause a non-zero exit: context.Canceled, context.DeadlineExceeded if the service was shut down (e.g. by the
+// shutdown deadline), any of opts.QuietErrors, or an error matched by opts.QuietErrorFunc.
+func isQuietError(err error, opts Options, shutdown bool) bool {
+	if errors.Is(err, context.Canceled) {
+		return true
 	}
+	if shutdown && errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	for _, quiet := range opts.QuietErrors {
+		if errors.Is(err, quiet) {
+			return true
+		}
+	}
+
+	return opts.QuietErrorFunc != nil && opts.QuietErrorFunc(err)
 }
 
-// RunC starts the service with the given options. The run context is cancelled
-// when the process receives SIGINT or SIGKILL, so Run can return and Close runs for cleanup.
+// RunC starts the service with the given options. The run context is derived from ctx and cancelled
+// when ctx is done so Run can return and Close runs for cleanup. If ctx cannot be cancelled (e.g.
+// context.Background()), it is also cancelled when the process receives one of the ShutdownSignals
+// (SIGINT or SIGTERM by default). Both are a clean shutdown. A shutdown forced by repeated signals
+// does not exit the process, but makes RunC return an error classified as ErrForcedShutdown at once.
 // Returns when the service exits, with any final error.
 func RunC(svc Service, ctx context.Context, opts ...Option) error {
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Kill, os.Interrupt)
+	_, err := runC(svc, ctx, opts)
+	return err
+}
+
+// runResult describes how runC ended, so RunAndExitC can report its error without loading the options again.
+type runResult struct {
+	// id is the normalized identity of the service, or its unnormalized one if it is invalid.
+	id identity
+	// options are the effective options of the service.
+	options Options
+	// shutdown is true if the service was stopped by a shutdown signal or the cancellation of its context.
+	shutdown bool
+}
+
+// runC implements RunC, additionally returning the effective options and whether the service was shut down.
+func runC(svc Service, ctx context.Context, opts []Option) (runResult, error) {
+	// A cancellable context is cancelled by the caller, e.g. with signal.NotifyContext
+	handleSignals := ctx.Done() == nil
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	if err := validateService(svc); err != nil {
-		return ae.New().
+	id, err := validateService(svc, identityModeOf(opts))
+	if err != nil {
+		// The options are loaded for the unnormalized identity, so the error is still reported as configured
+		id = identity{name: svc.Name(), namespace: svc.Namespace()}
+		res := runResult{id: id, options: applyOptions(id.name, id.namespace, opts)}
+		return res, classify(ErrInvalidConfig, ae.New().
 			Fatal().
 			Cause(err).
-			Msg("invalid service")
+			Msg("invalid service"))
 	}
 
-	options := applyOptions(svc.Name(), svc.Namespace(), opts)
+	closeNamespaceDefaults()
+	options, envFallbacks, optionConflicts, err := loadOptions(id.name, id.namespace, opts)
+	res := runResult{id: id, options: options}
+	if err != nil {
+		return res, classify(ErrInvalidConfig, ae.New().
+			Fatal().
+			Cause(err).
+			Msg("invalid options"))
+	}
+	if err := options.Validate(); err != nil {
+		return res, classify(ErrInvalidConfig, ae.New().
+			Fatal().
+			Cause(err).
+			Msg("invalid options"))
+	}
+
+	// Without signals, signal.Notify would relay all of them
+	signals := make(chan os.Signal, 1)
+	if handleSignals && len(options.ShutdownSignals) > 0 {
+		signal.Notify(signals, options.ShutdownSignals...)
+		defer signal.Stop(signals)
+	}
+
+	version := resolveVersion(svc.Version(), options)
 
 	// Add error attributes to the contextÏ
 	ctx = ae.WithOtelAttribute(ctx,
-		semconv.ServiceNameKey.String(svc.Name()),
-		semconv.ServiceVersionKey.String(svc.Version()),
-		semconv.ServiceNamespaceKey.String(svc.Namespace()),
+		semconv.ServiceNameKey.String(id.name),
+		semconv.ServiceVersionKey.String(version),
+		semconv.ServiceNamespaceKey.String(id.namespace),
 	)
 
 	// Add service attributes to the context
-	ctx = withName(ctx, svc.Name())
-	ctx = withVersion(ctx, svc.Version())
-	ctx = withNamespace(ctx, svc.Namespace())
+	ctx = withName(ctx, id.name)
+	ctx = withVersion(ctx, version)
+	ctx = withNamespace(ctx, id.namespace)
+	if d, ok := svc.(Describer); ok {
+		ctx = withDescription(ctx, d.Description())
+	}
+	if l, ok := svc.(Labeler); ok {
+		ctx = withLabels(ctx, l.Labels())
+	}
 	ctx = withEnvPrefix(ctx, options.EnvPrefix)
+	ctx = withEnvFallbackPrefixes(ctx, envFallbackPrefixes(options, id.namespace))
+	ctx = withRuntimeEnv(ctx, options.RuntimeEnv)
+
+	// Change the working directory and umask before any file is created, including the log files
+	restoreProcessSettings, err := applyProcessSettings(options)
+	if err != nil {
+		return res, classify(ErrInvalidConfig, ae.New().
+			Fatal().
+			Cause(err).
+			Msg("failed to apply working directory or umask"))
+	}
+	defer restoreProcessSettings()
+
+	sup := newSupervisor()
+	defer sup.closeLogRoutes()
+	sup.logLevelHeader = options.LogLevelHeader
+	sup.httpClientTimeout = options.HTTPClientTimeout
+	sup.shutdownTimeout = options.ShutdownTimeout
+	sup.httpClientSlowThreshold = options.HTTPClientSlowThreshold
+	sup.pausedReadiness = options.PausedReadiness
+	sup.flapWindow = options.FlapWindow
+	sup.flapThreshold = options.FlapThreshold
+	if p, ok := svc.(Pauser); ok {
+		sup.pauser = p
+	}
+	ctx = withSupervisor(ctx, sup)
+	defer sup.setState(StateStopped)
 
 	// Create initial logger
-	ctx = WithLogger(ctx, initLogger(ctx, options))
+	ctx = WithLogger(ctx, initLogShutdown(ctx, sup, initLogMetrics(sup, options, initCrashLog(sup, options, initLogger(ctx, options)))))
+	logBanner(ctx, options)
+	for _, warning := range id.warnings {
+		Logger(ctx).WarnContext(ctx, warning)
+	}
+	logEnvFallbacks(ctx, envFallbacks)
+	logOptionConflicts(ctx, optionConflicts)
+	logProcessSettings(ctx, options)
+	for _, warning := range options.validationWarnings() {
+		Logger(ctx).WarnContext(ctx, warning)
+	}
+
+	// Begin stopping on signals, cancelling the context after the drain delay
+	defer handleShutdownSignals(ctx, options, signals, cancel)()
+
+	// Adjust runtime settings to the container limits
+	defer initMaxProcs(ctx, options)()
+	defer initMemLimit(ctx, options)()
+
+	// Periodically log runtime diagnostics, if enabled
+	defer initDiagnostics(ctx, options)()
+
+	// Dump goroutines on SIGQUIT instead of exiting, if enabled
+	defer initSignalDump(ctx, options)()
+
+	// Capture execution traces on demand, if a crash directory is configured
+	defer initTraceCapture(ctx, options)()
+
+	// Persist the crash output of unrecovered panics, and report those of previous processes
+	defer initGlobalPanicHandler(ctx, options)()
+
+	// Ensure only a single instance is running
+	releaseInstanceLock, err := initInstanceLock(ctx, options)
+	if err != nil {
+		return res, classify(ErrInitInternal, ae.New().
+			Fatal().
+			Cause(err).
+			Msg("failed to acquire instance lock"))
+	}
+	defer releaseInstanceLock()
+
+	removePIDFile, err := initPIDFile(ctx, options)
+	if err != nil {
+		return res, classify(ErrInitInternal, ae.New().
+			Fatal().
+			Cause(err).
+			Msg("failed to prepare PID file"))
+	}
+	defer removePIDFile()
+
+	// Resolve the data directory, wiping it if configured
+	initDataDir(ctx, options)
 
 	// Initialize OTEL
-	ctx, otelShutdown, err := initOtel(ctx)
+	ctx, otelShutdown, err := initOtel(ctx, options)
 	if err != nil {
-		return ae.New().
+		return res, classify(ErrInitInternal, ae.New().
 			Fatal().
 			Cause(err).
-			Msg("failed to initialize OTEL")
+			Msg("failed to initialize OTEL"))
+	}
+	otelCtx := ctx
+	sup.mu.Lock()
+	sup.flushTelemetry = func(flushCtx context.Context) error {
+		return flushOtel(WithServiceContext(flushCtx, otelCtx))
 	}
+	sup.handlePanic = func(ctx context.Context, value any, stack []byte) {
+		writeCrashReport(ctx, options, "panic in goroutine", value, stack)
+		reportPanic(ctx, options, value, stack)
+	}
+	sup.mu.Unlock()
 	if otelShutdown != nil {
-		defer func() {
-			if shutdownErr := otelShutdown(ctx); shutdownErr != nil {
-				Logger(ctx).Error(
-					"OTEL shutdown failed",
-					"error", shutdownErr,
-				)
-			}
-		}()
+		defer shutdownOtel(ctx, otelShutdown, options.ShutdownTimeout)
 	}
 
-	return runLoop(svc, ctx, options)
-}
+	// Count warn and error log records now that the meter is available
+	sup.logRecords.bind(ctx)
+	if err := initMemLimitMetric(ctx); err != nil {
+		Logger(ctx).Warn("memory limit metric unavailable", "error", err)
+	}
+
+	// Restart the service if it exceeds the memory limit, if enabled
+	defer initMemoryWatchdog(ctx, options)()
+
+	// Record state and health transitions in the health history
+	defer initHealthHistory(ctx, options)()
+
+	// Publish the supervisor state at /debug/vars
+	publishExpvar(ctx, sup, options)
+
+	// Maintain the ready file while the service is running
+	defer initReadyFile(ctx, options)()
+
+	// Derive the health status from external health commands while the service is running
+	defer initHealthCommands(ctx, options)()
+
+	// Register the service with external systems while it is running
+	defer initRegistrars(ctx, options)()
 
-func validateService(svc Service) error {
-	var errs []error
+	// Reporters are flushed last, so they receive all reports
+	defer flushReporters(ctx, options)
 
-	if svc.Name() == "" {
-		errs = append(errs, errors.New("service name cannot be empty"))
+	// Construct shared values once; they are closed after the service has closed for the last time
+	ctx, closeSharedValues, err := initSharedValues(ctx, options)
+	if err != nil {
+		return res, classify(ErrInitInternal, ae.New().
+			Fatal().
+			Cause(err).
+			Msg("failed to initialize shared values"))
+	}
+	defer closeSharedValues()
+
+	// Services sharing a restart budget are stopped together once it is exhausted
+	if options.RestartBudget != nil {
+		sup.restartBudget = options.RestartBudget
+		defer options.RestartBudget.join(ctx, cancel)()
+	}
+
+	// Services of an exit group are stopped together once one of them exits successfully
+	if options.StopOnFirstExit != nil {
+		defer options.StopOnFirstExit.join(ctx, cancel)()
+	}
+
+	// Shut the service down cleanly once its maximum lifetime elapsed
+	lifetime := initMaxLifetime(ctx, options, cancel)
+	defer lifetime.stop()
+
+	// Ping the heartbeat URL while the service is ready; the final ping reports how it exited
+	heartbeat := initHeartbeat(ctx, options)
+
+	// A forced shutdown abandons the service instead of exiting the process, see forceExit
+	loopDone := make(chan error, 1)
+	go func() {
+		loopDone <- runLoop(svc, ctx, options, func() Options {
+			return applyOptions(id.name, id.namespace, opts)
+		})
+	}()
+	select {
+	case err = <-loopDone:
+	case <-sup.forced:
+		res.shutdown = true
+		return res, classify(ErrForcedShutdown, ae.New().Msg("service shutdown forced by repeated shutdown signals"))
+	}
+	if group := options.StopOnFirstExit; group != nil {
+		if err == nil && ctx.Err() == nil {
+			group.exit(ctx)
+		} else if exitedBy := group.exited(); exitedBy != "" && (err == nil || isCancellation(ctx, err)) {
+			sup.setStopReason(exitedBy + " exited")
+			err = nil
+		}
+	}
+	if lifetime.reached() && (err == nil || isCancellation(ctx, err)) {
+		sup.setStopReason("max lifetime reached")
+		err = nil
+	}
+	if options.RestartBudget != nil && (err == nil || isCancellation(ctx, err)) {
+		if budgetErr := options.RestartBudget.err(); budgetErr != nil {
+			sup.setStopReason("shared restart budget exceeded")
+			err = classify(ErrRestartBudgetExhausted, budgetErr)
+		}
 	}
-	if svc.Namespace() == "" {
-		errs = append(errs, errors.New("service namespace cannot be empty"))
+	heartbeat.stop(err)
+	sup.runShutdownHooks(ctx, options.ShutdownTimeout)
+	logExitSummary(ctx, sup, err)
+	res.shutdown = ctx.Err() != nil || sup.isStopping()
+	if err != nil && !isQuietError(err, options, res.shutdown) {
+		reportError(ctx, options, err)
 	}
 
-	return ae.WrapMany("invalid service", errs...)
+	return res, err
 }
 
 // runLoop is the internal orchestration entry point. It handles logger creation,
 // tracks running state, and enforces debug level, and supervises the lifecycle loop.
-func runLoop(svc Service, ctx context.Context, opts Options) error {
+func runLoop(svc Service, ctx context.Context, opts Options, reloadOptions func() Options) error {
+	sup := supervisorFrom(ctx)
 	graceStart := time.Now()
 	graceCount := 0
+	breaker := &restartBreaker{}
+	attempts := &attemptErrors{start: time.Now()}
+	leaks := newLeakDetector(opts)
+
+	// Restart delays end early once shutdown was requested
+	restartCtx, cancelRestart := context.WithCancel(ctx)
+	defer cancelRestart()
+	go func() {
+		select {
+		case <-sup.stopping:
+			cancelRestart()
+		case <-restartCtx.Done():
+		}
+	}()
+
+	// giveUp stops restarting the service after the restart budget was used up
+	giveUp := func(err error, reason string) error {
+		sup.setStopReason(reason)
+		err = classify(ErrRestartBudgetExhausted, attempts.wrap(ctx, err))
+		if opts.CrashReportOnGiveUp {
+			writeCrashReport(ctx, opts, "gave up restarting service ("+reason+")", err, nil)
+		}
+
+		return err
+	}
+
+	breakerOpened, _ := Meter(ctx).Int64Counter(
+		"as.restart.breaker.opened",
+		metric.WithDescription("Number of times the restart circuit breaker opened"),
+	)
+	restartCounter, _ := Meter(ctx).Int64Counter(
+		"as.restarts",
+		metric.WithDescription("Number of restarts of the service since the process started"),
+	)
 
+	countRestart := func() {
+		sup.countRestart()
+		if restartCounter != nil {
+			restartCounter.Add(ctx, 1)
+		}
+	}
+
+	// lifetimeExceeded reports whether the service was restarted MaxLifetimeRestarts times already
+	lifetimeExceeded := func() bool {
+		return opts.MaxLifetimeRestarts > 0 && sup.restartCount() >= opts.MaxLifetimeRestarts
+	}
+	lifetimeMsg := func() string {
+		return fmt.Sprintf("exceeded the limit of %d lifetime restarts", opts.MaxLifetimeRestarts)
+	}
+
+	var previousErr error
 	for {
-		err, isPanic := runOnce(svc, ctx, opts)
+		err, isPanic := runOnce(svc, withAttempt(ctx, graceCount+1, previousErr), opts)
+		// Errors caused by the cancellation of the supervisor context, e.g. from Init, end the service cleanly
+		if err != nil && !isPanic && isCancellation(ctx, err) {
+			err = nil
+		}
+
+		// Restarts requested by the service are not failures, and ignored during shutdown
+		restartRequested := err != nil && !isPanic && errors.Is(err, ErrRestartRequested)
+		if restartRequested && (ctx.Err() != nil || sup.isStopping()) {
+			err, restartRequested = nil, false
+		}
+
+		if restartRequested {
+			attempts.record(nil)
+		} else {
+			attempts.record(err)
+		}
+		previousErr = err
+		if ctx.Err() == nil {
+			leaks.check(ctx)
+		}
+		if isPanic {
+			sup.countPanic()
+		}
+		if err != nil && !restartRequested {
+			sup.setLastError(err)
+		}
+
+		// The service is not restarted while draining after a shutdown signal
+		if sup.isStopping() && ctx.Err() == nil {
+			sup.setStopReason("shutdown requested")
+			return err
+		}
+
+		if opts.ReloadOptionsOnRestart && ctx.Err() == nil {
+			opts = reloadRestartOptions(ctx, sup, opts, reloadOptions())
+		}
+
+		if restartRequested {
+			if lifetimeExceeded() {
+				Logger(ctx).Error("service requested restart, exceeded lifetime restart limit", "max_lifetime_restarts", opts.MaxLifetimeRestarts)
+				return giveUp(ae.New().Msg(lifetimeMsg()), "lifetime restart limit exceeded")
+			}
+
+			// Restarts by the memory watchdog optionally count against the grace limits, and flush telemetry first
+			var memErr *memoryLimitError
+			if errors.As(err, &memErr) {
+				if opts.MemoryRestartCountsAgainstGrace {
+					graceCount++
+					if opts.GracePeriod > 0 && time.Since(graceStart) > opts.GracePeriod {
+						return giveUp(memErr, "grace period exceeded")
+					}
+					if opts.GraceCount > 0 && graceCount > opts.GraceCount {
+						return giveUp(memErr, "grace count exceeded")
+					}
+				}
+				sup.flushBeforeRestart(ctx, opts)
+			}
+
+			Logger(ctx).Info("service requested restart",
+				"reason", err.Error(),
+				"restart_delay", opts.RequestedRestartDelay.String(),
+			)
+			sup.setState(StateRestarting)
+			countRestart()
+			if Sleep(restartCtx, opts.RequestedRestartDelay) != nil {
+				sup.setStopReason("context cancelled")
+				return nil
+			}
+			continue
+		}
+
 		if err == nil {
-			return nil
+			if ctx.Err() != nil {
+				sup.setStopReason("context cancelled")
+				return nil
+			}
+			if !opts.RestartOnSuccess {
+				sup.setStopReason("completed")
+				return nil
+			}
+
+			// Restarting after clean exits is still subject to the grace limits, to prevent hot-looping
+			graceCount++
+			if opts.GracePeriod > 0 && time.Since(graceStart) > opts.GracePeriod {
+				Logger(ctx).Warn("service completed, exceeded grace period", "grace_period", opts.GracePeriod.String())
+				sup.setStopReason("grace period exceeded")
+				return nil
+			}
+			if opts.GraceCount > 0 && graceCount > opts.GraceCount {
+				Logger(ctx).Warn("service completed, exceeded grace count", "grace_count", opts.GraceCount)
+				sup.setStopReason("grace count exceeded")
+				return nil
+			}
+			if lifetimeExceeded() {
+				Logger(ctx).Error("service completed, exceeded lifetime restart limit", "max_lifetime_restarts", opts.MaxLifetimeRestarts)
+				return giveUp(ae.New().Msg(lifetimeMsg()), "lifetime restart limit exceeded")
+			}
+
+			Logger(ctx).Warn("service completed, restarting", "restart_delay", opts.RestartOnErrorDelay.String())
+			sup.setState(StateRestarting)
+			countRestart()
+			if Sleep(restartCtx, opts.RestartOnErrorDelay) != nil {
+				sup.setStopReason("context cancelled")
+				return nil
+			}
+			continue
 		}
 
-		if !opts.RestartOnError || !ae.IsRecoverable(err) {
+		// Restarting is futile once the supervisor context is cancelled
+		if ctx.Err() != nil {
+			sup.setStopReason("context cancelled")
+			return err
+		}
+		if !opts.RestartOnError {
+			sup.setStopReason("restart on error disabled")
+			return err
+		}
+		if !ae.IsRecoverable(err) {
+			sup.setStopReason("unrecoverable error")
 			return err
 		}
 
@@ -130,6 +610,12 @@ func runLoop(svc Service, ctx context.Context, opts Options) error {
 		logAttrs := []any{
 			"error", err,
 		}
+		if phase, ok := PanicPhase(err); ok {
+			logAttrs = append(logAttrs, "phase", phase)
+		}
+
+		// Errors may indicate a lower level, e.g. for expected failures
+		level := errorLogLevel(err)
 		if opts.GracePeriod > 0 {
 			logAttrs = append(logAttrs, "grace_period", opts.GracePeriod.String())
 		}
@@ -138,24 +624,46 @@ func runLoop(svc Service, ctx context.Context, opts Options) error {
 		}
 
 		if opts.GracePeriod > 0 && time.Since(graceStart) > opts.GracePeriod {
-			Logger(ctx).Error(
+			Logger(ctx).Log(
+				ctx,
+				level,
 				"service failed, exceeded grace period",
 				logAttrs...,
 			)
-			return err
+			return giveUp(err, "grace period exceeded")
 		}
 
 		if opts.GraceCount > 0 && graceCount > opts.GraceCount {
-			Logger(ctx).Error(
+			Logger(ctx).Log(
+				ctx,
+				level,
 				"service failed, exceeded grace count",
 				logAttrs...,
 			)
-			return err
+			return giveUp(err, "grace count exceeded")
+		}
+
+		if lifetimeExceeded() {
+			Logger(ctx).Log(
+				ctx,
+				level,
+				"service failed, exceeded lifetime restart limit",
+				append(logAttrs, "max_lifetime_restarts", opts.MaxLifetimeRestarts)...,
+			)
+			return giveUp(ae.Wrap(lifetimeMsg(), err), "lifetime restart limit exceeded")
+		}
+
+		if opts.RestartBudget != nil {
+			if budgetErr := opts.RestartBudget.take(ctx, err); budgetErr != nil {
+				Logger(ctx).Log(ctx, level, "service failed, exceeded shared restart budget", logAttrs...)
+				return giveUp(budgetErr, "shared restart budget exceeded")
+			}
 		}
 
 		restartDelay := opts.RestartOnErrorDelay
 		if isPanic {
 			if !opts.RestartOnPanic {
+				sup.setStopReason("restart on panic disabled")
 				return err
 			}
 
@@ -164,58 +672,279 @@ func runLoop(svc Service, ctx context.Context, opts Options) error {
 			}
 		}
 
+		if breaker.recordFailure(time.Now(), opts) {
+			if breakerOpened != nil {
+				breakerOpened.Add(ctx, 1)
+			}
+
+			logAttrs = append(logAttrs,
+				"breaker_failures", opts.BreakerFailures,
+				"breaker_window", opts.BreakerWindow.String(),
+			)
+
+			if opts.BreakerPolicy != OpenPolicyCooldown {
+				Logger(ctx).Log(ctx, level, "service failed, restart circuit breaker opened", logAttrs...)
+				return giveUp(err, "restart circuit breaker opened")
+			}
+
+			logAttrs = append(logAttrs, "breaker_cooldown", opts.BreakerCooldown.String())
+			Logger(ctx).Log(ctx, level, "service failed, restart circuit breaker opened, cooling down", logAttrs...)
+			sup.setState(StateDegraded)
+			countRestart()
+
+			if Sleep(restartCtx, opts.BreakerCooldown) != nil {
+				sup.setStopReason("context cancelled")
+				return err
+			}
+			breaker.startProbe(time.Now())
+			continue
+		}
+
 		logAttrs = append(logAttrs, "restart_delay", restartDelay.String())
+		sup.setState(StateRestarting)
+		countRestart()
 
 		if restartDelay > 0 {
-			Logger(ctx).Error("service failed, restarting after delay", logAttrs...)
-			time.Sleep(restartDelay)
+			Logger(ctx).Log(ctx, level, "service failed, restarting after delay", logAttrs...)
 		} else {
-			Logger(ctx).Error("service failed, restarting immediately", logAttrs...)
+			Logger(ctx).Log(ctx, level, "service failed, restarting immediately", logAttrs...)
+		}
+
+		// Sleep returns at once if the supervisor context was cancelled or shutdown was requested meanwhile, even
+		// without a delay, so the service is not restarted into a cancelled context
+		if Sleep(restartCtx, restartDelay) != nil {
+			sup.setStopReason("context cancelled")
+			return err
 		}
 	}
 }
 
 func runOnce(svc Service, ctx context.Context, opts Options) (err error, isPanic bool) {
+	// Panics in Init, Run, and Close are recovered by callPhase; this recovers panics in between.
+	// It uses the supervisor context, since the context of the attempt is cancelled by then.
 	if opts.RecoverPanic {
-		defer func() {
+		defer func(ctx context.Context) {
 			if cause := recover(); cause != nil {
-				var errCause error
-				switch x := cause.(type) {
-				case error:
-					errCause = x
-				default:
-					errCause = ae.Msgf("%v", x)
-				}
-
 				isPanic = true
-				err = ae.NewC(ctx).
-					Cause(errCause).
-					Stack().
-					Related(err).
-					Msg("panic")
+				stack := debug.Stack()
+				writeCrashReport(ctx, opts, "panic", cause, stack)
+				reportPanic(ctx, opts, cause, stack)
+				err = panicError(ctx, cause, err)
 			}
-		}()
+		}(ctx)
 	}
 
+	sup := supervisorFrom(ctx)
+
+	// Each attempt gets a fresh context derived from the supervisor context, so nothing attached to the context of
+	// an attempt leaks into the next one
+	ctx, endAttempt := context.WithCancel(decorateContext(ctx, opts))
+	defer endAttempt()
+	defer sup.dataDir.removeTempDir(ctx)
+	defer sup.runCleanups(ctx)
+
+	// Init and Run use a context cancelled when Run returns, so goroutines started by Go stop before Close
+	runCtx, cancelRun := context.WithCancelCause(ctx)
+	defer cancelRun(nil)
+
+	sup.setAttempt(cancelRun, opts.EscalateGoroutineErrors)
+	defer sup.setAttempt(nil, false)
+
 	Logger(ctx).Debug("initializing service")
-	if err := svc.Init(ctx); err != nil {
+	sup.setState(StateStarting)
+
+	// Dependencies are waited for before Init; probes still failing after the budget fail the initialization
+	if len(opts.StartupProbes) > 0 {
+		if err := WaitForProbes(runCtx, opts.StartupProbeBudget, opts.StartupProbes...); err != nil {
+			return ae.Wrap("service initialization failed", err), false
+		}
+	}
+
+	if err, isPanic := callPhase(ctx, opts, PhaseInit, func() error { return svc.Init(runCtx) }); err != nil {
+		if isPanic {
+			return err, true
+		}
 		return ae.Wrap("service initialization failed", err), false
 	}
 
+	if opts.PIDFile != "" {
+		if err := writePIDFile(opts.PIDFile, opts.PIDFileMode); err != nil {
+			Logger(ctx).Error("failed to write PID file", "path", opts.PIDFile, "error", err)
+		}
+	}
+
 	Logger(ctx).Debug("starting service")
-	if err = svc.Run(ctx); err != nil {
-		// Do not handle context.Canceled errors here, since they are expected and we should clean up on cancellation
-		if !errors.Is(err, context.Canceled) {
+	if !sup.isStopping() {
+		sup.setState(StateRunning)
+	}
+	// The transition must not be delayed into the next attempt, so exiting waits for it if it already started
+	stoppingDone := make(chan struct{})
+	stopStopping := context.AfterFunc(runCtx, func() {
+		defer close(stoppingDone)
+		sup.setState(StateStopping)
+	})
+	defer func() {
+		if !stopStopping() {
+			<-stoppingDone
+		}
+	}()
+
+	// Run is called again after degraded errors; the minimum run duration applies to all calls together
+	runStart := time.Now()
+	err, isPanic = runDegradable(runCtx, opts, func() (error, bool) {
+		return callPhase(ctx, opts, PhaseRun, func() error { return svc.Run(runCtx) })
+	})
+	if isPanic {
+		return err, true
+	}
+	sup.setState(StateStopping)
+	runDuration := time.Since(runStart)
+
+	// A failed goroutine or task takes precedence, since Run most likely returned due to the cancellation it caused
+	// The same applies to restarts by the memory watchdog
+	var goErr *goroutineError
+	var memErr *memoryLimitError
+	if errors.As(context.Cause(runCtx), &goErr) {
+		err = goErr
+	} else if errors.As(context.Cause(runCtx), &memErr) {
+		err = memErr
+	}
+
+	cancelRun(nil)
+	sup.waitGoroutines(ctx, opts.ShutdownTimeout)
+
+	// A service exiting too quickly without being asked to has most likely failed, even if it returned nil
+	// A requested restart is neither a failure nor an early exit, and the service is closed before restarting
+	restartRequested := errors.Is(err, ErrRestartRequested)
+
+	var quickErr error
+	if opts.MinimumRunDuration > 0 && runDuration < opts.MinimumRunDuration && ctx.Err() == nil && !restartRequested {
+		msg := fmt.Sprintf("service exited after %s, below minimum run duration %s", runDuration, opts.MinimumRunDuration)
+		if err != nil {
+			quickErr = ae.Wrap(msg, err)
+		} else {
+			quickErr = ae.New().Msg(msg)
+		}
+	}
+
+	if err != nil {
+		// Errors caused by the cancellation of the service context are expected, even if wrapped by the service, and
+		// we should clean up on cancellation. The same applies to deadlines of the service context, e.g. from drain
+		// deadlines. Cancellations and deadlines of operations within the service are regular errors.
+		if !isCancellation(ctx, err) && !restartRequested {
+			if quickErr != nil {
+				return quickErr, false
+			}
 			return ae.Wrap("service run failed", err), false
 		}
 	}
 
 	// Cleanup is not returned as an error, since it's not critical.
 	Logger(ctx).Debug("shutting down service")
-	err = svc.Close(ctx)
-	if err != nil {
-		Logger(ctx).Error("service shutdown failed", "error", err)
+	closeErr, closePanic := closeService(svc, ctx, opts)
+	if closePanic {
+		// Run already completed, so a panic during cleanup is counted and logged, but does not fail the run
+		sup.countPanic()
+		Logger(ctx).Error("service shutdown panicked", "phase", PhaseClose, "error", closeErr)
+	} else if errors.Is(closeErr, ErrShutdownTimeout) {
+		Logger(ctx).Error("service shutdown timed out", "timeout", opts.ShutdownTimeout.String(), "error", closeErr)
+	} else if closeErr != nil {
+		Logger(ctx).Log(ctx, errorLogLevel(closeErr), "service shutdown failed", "error", closeErr)
+	}
+
+	if restartRequested {
+		return err, false
+	}
+
+	return quickErr, false
+}
+
+// shutdownOtel calls the OTEL shutdown func with a context limited by timeout, which is not cancelled with ctx.
+// Failures are logged, distinguishing an exceeded timeout.
+func shutdownOtel(ctx context.Context, shutdown func(context.Context) error, timeout time.Duration) {
+	shutdownCtx, cancel := shutdownContext(ctx, timeout)
+	defer cancel()
+
+	err := shutdown(shutdownCtx)
+	switch {
+	case err == nil:
+	case shutdownCtx.Err() != nil && errors.Is(err, context.DeadlineExceeded):
+		Logger(ctx).Error("OTEL shutdown timed out", "timeout", timeout.String(), "error", err)
+	default:
+		Logger(ctx).Error("OTEL shutdown failed", "error", err)
+	}
+}
+
+// closeAbandonGrace is the time Close has to return after its context expired before it is abandoned, so a Close
+// returning the context error is not reported as hanging.
+const closeAbandonGrace = 100 * time.Millisecond
+
+// closeService calls Close with a context limited by ShutdownTimeout, which is not cancelled with ctx. A Close
+// still running after the timeout is abandoned, and in RunAndExit the process exits with ExitShutdownTimeout.
+// Errors caused by the timeout, including a Close returning context.DeadlineExceeded once it expired, are
+// classified as ErrShutdownTimeout.
+func closeService(svc Service, ctx context.Context, opts Options) (err error, isPanic bool) {
+	closeCtx, cancel := shutdownContext(ctx, opts.ShutdownTimeout)
+	defer cancel()
+
+	type result struct {
+		err     error
+		isPanic bool
+	}
+	done := make(chan result, 1)
+	go func() {
+		err, isPanic := callPhase(ctx, opts, PhaseClose, func() error { return svc.Close(closeCtx) })
+		done <- result{err: err, isPanic: isPanic}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-closeCtx.Done():
+		grace := time.NewTimer(closeAbandonGrace)
+		defer grace.Stop()
+
+		select {
+		case r = <-done:
+		case <-grace.C:
+			err := classify(ErrShutdownTimeout, ae.New().
+				Msg(fmt.Sprintf("service shutdown did not return within %s", opts.ShutdownTimeout)))
+			if opts.exitProcess {
+				Logger(ctx).Error("service shutdown timed out, exiting", "timeout", opts.ShutdownTimeout.String())
+				supervisorFrom(ctx).forceExit(ctx, opts, ExitShutdownTimeout)
+			}
+			return err, false
+		}
+	}
+
+	if !r.isPanic && closeCtx.Err() != nil && errors.Is(r.err, context.DeadlineExceeded) {
+		return classify(ErrShutdownTimeout, ae.Wrap(
+			fmt.Sprintf("service shutdown exceeded %s", opts.ShutdownTimeout), r.err)), false
+	}
+
+	return r.err, r.isPanic
+}
+
+// isCancellation reports whether err was caused by the cancellation of ctx: ctx is done and err is or wraps
+// context.Canceled or context.DeadlineExceeded.
+func isCancellation(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+}
+
+// panicError converts a value recovered from a panic into an error carrying the stack of the panic.
+// If related is non-nil, it is attached to the returned error as a related error.
+func panicError(ctx context.Context, cause any, related error) error {
+	var errCause error
+	switch x := cause.(type) {
+	case error:
+		errCause = x
+	default:
+		errCause = ae.Msgf("%v", x)
 	}
 
-	return nil, false
+	return ae.NewC(ctx).
+		Cause(errCause).
+		Stack().
+		Related(related).
+		Msg("panic")
 }
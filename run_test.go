@@ -0,0 +1,447 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestMaxLifetimeRestarts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, reader := testMeterContext(t, context.Background())
+		logs := &logCapture{}
+		ctx = WithLogger(ctx, slog.New(slog.NewJSONHandler(logs, nil)))
+		sup := newSupervisor()
+		ctx = withSupervisor(ctx, sup)
+
+		// The service flaps once an hour for days; without grace limits only the lifetime cap stops it
+		runs := 0
+		svc := &testService{run: func(ctx context.Context) error {
+			runs++
+			return errors.New("flapping")
+		}}
+
+		opts := DefaultOptions()
+		opts.GracePeriod = 0
+		opts.GraceCount = 0
+		opts.RestartOnErrorDelay = time.Hour
+		opts.MaxLifetimeRestarts = 48
+
+		start := time.Now()
+		err := runLoop(svc, ctx, opts, nil)
+		if !errors.Is(err, ErrRestartBudgetExhausted) || !strings.Contains(err.Error(), "48 lifetime restarts") {
+			t.Fatalf("runLoop() = %v, want lifetime restart limit error", err)
+		}
+
+		if runs != 49 {
+			t.Errorf("service ran %d times, want 49", runs)
+		}
+		if elapsed := time.Since(start); elapsed != 48*time.Hour {
+			t.Errorf("gave up after %s, want 48h", elapsed)
+		}
+		if got := counterTotal(t, reader, "as.restarts"); got != 48 {
+			t.Errorf("as.restarts = %d, want 48", got)
+		}
+		if got := sup.restartCount(); got != 48 {
+			t.Errorf("restart count = %d, want 48", got)
+		}
+
+		logExitSummary(ctx, sup, err)
+		summary := logs.find("service exited")
+		if summary == nil || summary["reason"] != "lifetime restart limit exceeded" || summary["restarts"] != float64(48) {
+			t.Errorf("exit summary = %v", summary)
+		}
+	})
+}
+
+func TestAttemptContext(t *testing.T) {
+	attempt := 0
+	var previousEnded []bool
+	var contexts []context.Context
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			contexts = append(contexts, ctx)
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			// Each attempt starts after the context of the previous one ended
+			if attempt++; attempt > 1 {
+				previousEnded = append(previousEnded, contexts[attempt-2].Err() != nil)
+			}
+			if ctx.Value(tenantKey{}) != attempt {
+				t.Errorf("attempt %d sees value %v", attempt, ctx.Value(tenantKey{}))
+			}
+			if attempt < 3 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+
+	decorated := 0
+	decorator := WithContextDecorator(func(ctx context.Context) context.Context {
+		if ctx.Value(tenantKey{}) != nil {
+			t.Error("value of a previous attempt leaked into the context")
+		}
+		decorated++
+		return context.WithValue(ctx, tenantKey{}, decorated)
+	})
+	if err := RunC(svc, context.Background(), testOptions(decorator)...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if want := []bool{true, true}; !slices.Equal(previousEnded, want) {
+		t.Errorf("previous attempt contexts ended = %v, want %v", previousEnded, want)
+	}
+	if contexts[0] == contexts[1] || contexts[1] == contexts[2] {
+		t.Error("attempts share a context")
+	}
+}
+
+func TestNoRestartAfterParentCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+	svc := &testService{run: func(context.Context) error {
+		runs++
+		cancel()
+		return errors.New("failed")
+	}}
+
+	logs := &logCapture{}
+	err := RunC(svc, ctx, testOptions(captureLogs(svc, logs))...)
+	if err == nil {
+		t.Fatal("RunC() = nil, want the error of the run")
+	}
+
+	if runs != 1 {
+		t.Errorf("service ran %d times, want once", runs)
+	}
+	summary := logs.find("service exited")
+	if summary == nil || summary["restarts"] != float64(0) || summary["reason"] != "context cancelled" {
+		t.Errorf("exit summary = %v, want no restarts", summary)
+	}
+}
+
+func TestIsCancellation(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "canceled", ctx: cancelled, err: context.Canceled, want: true},
+		{name: "wrapped canceled", ctx: cancelled, err: fmt.Errorf("fetch failed: %w", context.Canceled), want: true},
+		{name: "wrapped deadline", ctx: cancelled, err: fmt.Errorf("fetch failed: %w", context.DeadlineExceeded), want: true},
+		{name: "other error", ctx: cancelled, err: errors.New("failed")},
+		{name: "deadline while running", ctx: context.Background(), err: fmt.Errorf("fetch failed: %w", context.DeadlineExceeded)},
+		{name: "canceled while running", ctx: context.Background(), err: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCancellation(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("isCancellation() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrappedCancellation(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(ctx context.Context, attempt int) error
+		wantRuns int
+		wantErr  bool
+	}{
+		{
+			name: "shutdown",
+			run: func(ctx context.Context, _ int) error {
+				<-ctx.Done()
+				return fmt.Errorf("fetch failed: %w", ctx.Err())
+			},
+			wantRuns: 1,
+		},
+		{
+			name: "deadline of an operation",
+			run: func(ctx context.Context, attempt int) error {
+				if attempt > 1 {
+					<-ctx.Done()
+					return nil
+				}
+
+				opCtx, cancel := context.WithTimeout(ctx, time.Millisecond)
+				defer cancel()
+				<-opCtx.Done()
+				return fmt.Errorf("fetch failed: %w", opCtx.Err())
+			},
+			wantRuns: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 0
+			running := make(chan struct{}, 2)
+			svc := &testService{run: func(ctx context.Context) error {
+				runs++
+				running <- struct{}{}
+				return tt.run(ctx, runs)
+			}}
+
+			cancel, done := runTest(t, svc)
+			for range tt.wantRuns {
+				<-running
+			}
+			cancel()
+
+			if err := <-done; err != nil {
+				t.Errorf("RunC() = %v, want nil", err)
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("service ran %d times, want %d", runs, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestRestartDelayCancelled(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{name: "during delay", delay: 10 * time.Second},
+		{name: "without delay", delay: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				ctx = WithLogger(ctx, slog.New(slog.DiscardHandler))
+				sup := newSupervisor()
+				ctx = withSupervisor(ctx, sup)
+
+				opts := DefaultOptions()
+				opts.RestartOnErrorDelay = tt.delay
+
+				runs := 0
+				svc := &testService{run: func(ctx context.Context) error {
+					runs++
+					if tt.delay == 0 {
+						// Cancelled while the failing run returns
+						cancel()
+					} else {
+						time.AfterFunc(time.Second, cancel)
+					}
+					return errFailed
+				}}
+
+				start := time.Now()
+				err := runLoop(svc, ctx, opts, nil)
+
+				// The loop returns the original error as soon as the context is cancelled, without restarting
+				if !errors.Is(err, errFailed) {
+					t.Errorf("runLoop() = %v, want the original error", err)
+				}
+				if runs != 1 {
+					t.Errorf("runs = %d, want no restart", runs)
+				}
+				if elapsed := time.Since(start); elapsed != min(tt.delay, time.Second) {
+					t.Errorf("returned after %s", elapsed)
+				}
+
+				sup.mu.Lock()
+				reason := sup.stopReason
+				sup.mu.Unlock()
+				if reason != "context cancelled" {
+					t.Errorf("stop reason = %q", reason)
+				}
+			})
+		})
+	}
+}
+
+func TestCloseServiceTimeout(t *testing.T) {
+	const timeout = 5 * time.Second
+	errOwnDeadline := fmt.Errorf("dial: %w", context.DeadlineExceeded)
+
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		close       func(ctx context.Context, release <-chan struct{}) error
+		wantTimeout string
+		wantErr     error
+		wantElapsed time.Duration
+	}{
+		{
+			name: "hanging",
+			close: func(ctx context.Context, release <-chan struct{}) error {
+				<-release
+				return nil
+			},
+			wantTimeout: "service shutdown did not return within 5s",
+			wantElapsed: timeout + closeAbandonGrace,
+		},
+		{
+			name: "returns the deadline",
+			close: func(ctx context.Context, release <-chan struct{}) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			wantTimeout: "service shutdown exceeded 5s",
+			wantErr:     context.DeadlineExceeded,
+			wantElapsed: timeout,
+		},
+		{
+			// A Close failing with a deadline of its own is not reported as shutdown timeout
+			name:        "own deadline",
+			close:       func(ctx context.Context, release <-chan struct{}) error { return errOwnDeadline },
+			wantErr:     errOwnDeadline,
+			wantElapsed: 0,
+		},
+		{
+			name:    "no limit",
+			timeout: -1,
+			close: func(ctx context.Context, release <-chan struct{}) error {
+				if _, ok := ctx.Deadline(); ok {
+					return errors.New("deadline set without a timeout")
+				}
+				time.Sleep(time.Hour)
+				return nil
+			},
+			wantElapsed: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+				ctx = withSupervisor(ctx, newSupervisor())
+
+				opts := DefaultOptions()
+				opts.ShutdownTimeout = timeout
+				if tt.timeout < 0 {
+					opts.ShutdownTimeout = 0
+				}
+
+				release := make(chan struct{})
+				defer close(release)
+				svc := &testService{close: func(ctx context.Context) error { return tt.close(ctx, release) }}
+
+				start := time.Now()
+				err, _ := closeService(svc, ctx, opts)
+				if elapsed := time.Since(start); elapsed != tt.wantElapsed {
+					t.Errorf("closeService() returned after %s, want %s", elapsed, tt.wantElapsed)
+				}
+
+				if tt.wantTimeout != "" {
+					if !errors.Is(err, ErrShutdownTimeout) || !strings.Contains(err.Error(), tt.wantTimeout) {
+						t.Errorf("closeService() = %v, want ErrShutdownTimeout %q", err, tt.wantTimeout)
+					}
+				} else if errors.Is(err, ErrShutdownTimeout) {
+					t.Errorf("closeService() = %v, want no shutdown timeout", err)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("closeService() = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantErr == nil && tt.wantTimeout == "" && err != nil {
+					t.Errorf("closeService() = %v", err)
+				}
+			})
+		})
+	}
+}
+
+func TestShutdownOtelTimeout(t *testing.T) {
+	errExport := errors.New("export failed")
+
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		shutdown    func(ctx context.Context) error
+		wantLog     string
+		wantElapsed time.Duration
+	}{
+		{
+			name:    "timeout",
+			timeout: 5 * time.Second,
+			shutdown: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			wantLog:     "OTEL shutdown timed out",
+			wantElapsed: 5 * time.Second,
+		},
+		{
+			name:        "failure",
+			timeout:     5 * time.Second,
+			shutdown:    func(ctx context.Context) error { return errExport },
+			wantLog:     "OTEL shutdown failed",
+			wantElapsed: 0,
+		},
+		{
+			name: "no limit",
+			shutdown: func(ctx context.Context) error {
+				if _, ok := ctx.Deadline(); ok {
+					return errors.New("deadline set without a timeout")
+				}
+				time.Sleep(time.Hour)
+				return nil
+			},
+			wantElapsed: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				logs := &logCapture{}
+				ctx := WithLogger(context.Background(), slog.New(slog.NewJSONHandler(logs, nil)))
+
+				// The shutdown is not cancelled with the service context
+				ctx, cancel := context.WithCancel(ctx)
+				cancel()
+
+				start := time.Now()
+				shutdownOtel(ctx, tt.shutdown, tt.timeout)
+				if elapsed := time.Since(start); elapsed != tt.wantElapsed {
+					t.Errorf("shutdownOtel() returned after %s, want %s", elapsed, tt.wantElapsed)
+				}
+
+				records := logs.records()
+				if tt.wantLog == "" && len(records) != 0 {
+					t.Errorf("logged %v, want nothing", records)
+				}
+				if tt.wantLog != "" && logs.find(tt.wantLog) == nil {
+					t.Errorf("logged %v, want %q", records, tt.wantLog)
+				}
+			})
+		})
+	}
+}
+
+func TestRunAndExitKeepsOptions(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error { return nil }}
+
+	// Spare capacity of the caller's slice must not be written to
+	opts := append(make([]Option, 0, 64), testOptions()...)
+	RunAndExitC(svc, context.Background(), opts...)
+	if spare := opts[:len(opts)+1][len(opts)]; spare != nil {
+		t.Error("RunAndExitC() appended to the caller's options")
+	}
+}
@@ -0,0 +1,172 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShutdownSignals(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		signal syscall.Signal
+	}{
+		{name: "SIGTERM by default", opts: []Option{WithShutdownSignals(DefaultOptions().ShutdownSignals...)}, signal: syscall.SIGTERM},
+		{name: "SIGINT by default", opts: []Option{WithShutdownSignals(DefaultOptions().ShutdownSignals...)}, signal: syscall.SIGINT},
+		{name: "custom", opts: []Option{WithShutdownSignals(syscall.SIGUSR1)}, signal: syscall.SIGUSR1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			closed := false
+			svc := &testService{
+				run: func(ctx context.Context) error {
+					if err := syscall.Kill(os.Getpid(), tt.signal); err != nil {
+						return err
+					}
+					<-ctx.Done()
+					return ctx.Err()
+				},
+				close: func(ctx context.Context) error {
+					closed = true
+					return nil
+				},
+			}
+
+			// The signal is a clean shutdown, so RunAndExit does not exit with an error
+			if code := runAndExitTest(t, svc, tt.opts...); code != -1 {
+				t.Errorf("exit code = %d, want no exit", code)
+			}
+			if !closed {
+				t.Error("Close not called after the signal")
+			}
+		})
+	}
+}
+
+func TestShutdownSignalsDisabled(t *testing.T) {
+	// Catch the signal in the test, as it would terminate the process otherwise
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, syscall.SIGTERM)
+	defer signal.Stop(caught)
+
+	closed := false
+	running := make(chan struct{})
+	svc := &testService{
+		run: func(ctx context.Context) error {
+			close(running)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		close: func(ctx context.Context) error {
+			closed = true
+			return nil
+		},
+	}
+
+	cancel, done := runTest(t, svc, WithShutdownSignals())
+	<-running
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	<-caught
+
+	select {
+	case err := <-done:
+		t.Fatalf("RunC() = %v after an ignored signal", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The context passed to RunC still stops the service cleanly
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if !closed {
+		t.Error("Close not called")
+	}
+}
+
+func TestShutdownSignalsCancellableContext(t *testing.T) {
+	// Catch the signal in the test, as it would terminate the process otherwise
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, syscall.SIGTERM)
+	defer signal.Stop(caught)
+
+	running := make(chan struct{})
+	svc := &testService{run: func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	// The caller cancelling the context handles the signals
+	cancel, done := runTest(t, svc, WithShutdownSignals(DefaultOptions().ShutdownSignals...))
+	<-running
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	<-caught
+
+	select {
+	case err := <-done:
+		t.Fatalf("RunC() = %v after a signal with a cancellable context", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+}
+
+func TestForcedShutdownRunC(t *testing.T) {
+	closing := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	svc := &testService{
+		run: func(ctx context.Context) error {
+			if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+				return err
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		close: func(ctx context.Context) error {
+			close(closing)
+			<-release
+			return nil
+		},
+	}
+
+	exited := false
+	done := make(chan error, 1)
+	go func() {
+		done <- RunC(svc, context.Background(), testOptions(WithShutdownSignals(syscall.SIGTERM),
+			WithExitFunc(func(int) { exited = true }))...)
+	}()
+
+	// The second signal forces the hanging shutdown, returning an error instead of exiting the process
+	<-closing
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrForcedShutdown) {
+			t.Errorf("RunC() = %v, want ErrForcedShutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunC() did not return after the forced shutdown")
+	}
+	if exited {
+		t.Error("process exited by RunC")
+	}
+}
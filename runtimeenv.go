@@ -0,0 +1,99 @@
+package as
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+)
+
+// RuntimeEnv is the kind of environment the process runs in.
+type RuntimeEnv string
+
+const (
+	// RuntimeLocal is a process running directly on a host, e.g. started with go run.
+	RuntimeLocal RuntimeEnv = "local"
+	// RuntimeContainer is a process running in a container outside of Kubernetes.
+	RuntimeContainer RuntimeEnv = "container"
+	// RuntimeKubernetes is a process running in a Kubernetes pod.
+	RuntimeKubernetes RuntimeEnv = "kubernetes"
+)
+
+// runtimeEnvKey is an unexported type used as a key for storing the runtime environment in a context.
+type runtimeEnvKey struct{}
+
+// withRuntimeEnv returns a new context.Context derived from ctx that contains the runtime environment.
+func withRuntimeEnv(ctx context.Context, env RuntimeEnv) context.Context {
+	return context.WithValue(ctx, runtimeEnvKey{}, env)
+}
+
+// RuntimeEnvironment returns the environment the service runs in, as detected or set by the RuntimeEnv option.
+// Outside of a service context, the detected environment is returned.
+func RuntimeEnvironment(ctx context.Context) RuntimeEnv {
+	if env, ok := ctx.Value(runtimeEnvKey{}).(RuntimeEnv); ok {
+		return env
+	}
+
+	return detectedRuntimeEnv()
+}
+
+// detectedRuntimeEnv returns the runtime environment of the process, detected once.
+var detectedRuntimeEnv = sync.OnceValue(func() RuntimeEnv {
+	return detectRuntimeEnv("/", os.LookupEnv)
+})
+
+// detectRuntimeEnv detects the runtime environment from the filesystem mounted at root and the environment:
+// Kubernetes sets KUBERNETES_SERVICE_HOST in every pod, while Docker and Podman create /.dockerenv and
+// /run/.containerenv respectively. Other runtimes are detected from the cgroup of the init process.
+func detectRuntimeEnv(root string, lookupEnv func(string) (string, bool)) RuntimeEnv {
+	if _, ok := lookupEnv("KUBERNETES_SERVICE_HOST"); ok {
+		return RuntimeKubernetes
+	}
+
+	for _, marker := range []string{".dockerenv", "run/.containerenv"} {
+		if _, err := os.Stat(filepath.Join(root, marker)); err == nil {
+			return RuntimeContainer
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(root, "proc/1/cgroup")); err == nil {
+		cgroup := string(data)
+		if strings.Contains(cgroup, "kubepods") {
+			return RuntimeKubernetes
+		}
+		for _, runtime := range []string{"docker", "containerd", "crio", "libpod", "lxc"} {
+			if strings.Contains(cgroup, runtime) {
+				return RuntimeContainer
+			}
+		}
+	}
+
+	return RuntimeLocal
+}
+
+// autoLogJson reports whether JSON logs are selected automatically: in containers and whenever stdout is not a
+// terminal, so logs are parsed by collectors, and not on a local terminal, where they are read by humans.
+func autoLogJson(env RuntimeEnv, stdoutTerminal bool) bool {
+	return env != RuntimeLocal || !stdoutTerminal
+}
+
+// applyRuntimeEnv resolves the runtime environment of opts and, if LogAutoFormat is set and LOG_JSON is not set in
+// the environment, selects the log format for it.
+func applyRuntimeEnv(o *Options, namespace string) {
+	if o.RuntimeEnv == "" {
+		o.RuntimeEnv = detectedRuntimeEnv()
+	}
+
+	if !o.LogAutoFormat {
+		return
+	}
+
+	if _, ok := optionEnvVar(*o, namespace, "LOG_JSON"); ok {
+		return
+	}
+
+	o.LogJson = autoLogJson(o.RuntimeEnv, isatty.IsTerminal(os.Stdout.Fd()))
+}
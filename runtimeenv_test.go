@@ -0,0 +1,120 @@
+package as
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectRuntimeEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		env   map[string]string
+		want  RuntimeEnv
+	}{
+		{name: "local", want: RuntimeLocal},
+		{name: "local cgroup", files: map[string]string{"proc/1/cgroup": "0::/init.scope\n"}, want: RuntimeLocal},
+		{name: "kubernetes env", env: map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"}, want: RuntimeKubernetes},
+		{name: "docker", files: map[string]string{".dockerenv": ""}, want: RuntimeContainer},
+		{name: "podman", files: map[string]string{"run/.containerenv": ""}, want: RuntimeContainer},
+		{name: "kubepods cgroup", files: map[string]string{"proc/1/cgroup": "0::/kubepods/burstable/pod1234\n"}, want: RuntimeKubernetes},
+		{name: "containerd cgroup", files: map[string]string{"proc/1/cgroup": "0::/system.slice/containerd.service\n"}, want: RuntimeContainer},
+		{
+			name:  "kubernetes before markers",
+			files: map[string]string{".dockerenv": ""},
+			env:   map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"},
+			want:  RuntimeKubernetes,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lookupEnv := func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+
+			if got := detectRuntimeEnv(root, lookupEnv); got != tt.want {
+				t.Errorf("detectRuntimeEnv() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoLogJson(t *testing.T) {
+	tests := []struct {
+		env      RuntimeEnv
+		terminal bool
+		want     bool
+	}{
+		{env: RuntimeLocal, terminal: true, want: false},
+		{env: RuntimeLocal, terminal: false, want: true},
+		{env: RuntimeContainer, terminal: true, want: true},
+		{env: RuntimeKubernetes, terminal: true, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := autoLogJson(tt.env, tt.terminal); got != tt.want {
+			t.Errorf("autoLogJson(%s, terminal %t) = %t, want %t", tt.env, tt.terminal, got, tt.want)
+		}
+	}
+}
+
+func TestLogAutoFormatOverrides(t *testing.T) {
+	// The test output is not a terminal, so JSON is selected automatically
+	tests := []struct {
+		name string
+		env  map[string]string
+		opts []Option
+		want bool
+	}{
+		{name: "auto", opts: []Option{WithRuntimeEnv(RuntimeLocal)}, want: true},
+		{name: "explicit option", opts: []Option{WithRuntimeEnv(RuntimeLocal), WithLogJson(false)}, want: false},
+		{name: "env var", env: map[string]string{"ASTEST_TEST_LOG_JSON": "false"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			if got := applyOptions("test", "astest", tt.opts).LogJson; got != tt.want {
+				t.Errorf("LogJson = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuntimeEnvironment(t *testing.T) {
+	t.Setenv("ASTEST_TEST_RUNTIME_ENV", "kubernetes")
+
+	var env RuntimeEnv
+	svc := &testService{run: func(ctx context.Context) error {
+		env = RuntimeEnvironment(ctx)
+		return nil
+	}}
+	if err := RunC(svc, context.Background(), testOptions(WithRuntimeEnv(RuntimeLocal))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// The env var overrides both the detection and the option
+	if env != RuntimeKubernetes {
+		t.Errorf("RuntimeEnvironment() = %s, want kubernetes", env)
+	}
+	if got := RuntimeEnvironment(context.Background()); got != detectedRuntimeEnv() {
+		t.Errorf("RuntimeEnvironment() outside of a service = %s, want the detected %s", got, detectedRuntimeEnv())
+	}
+}
@@ -0,0 +1,124 @@
+package as
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.aledante.io/ae"
+)
+
+// Dependent is an optional interface a Service can implement to declare the services it requires. SelectServices
+// selects the dependencies of every selected service as well.
+type Dependent interface {
+	// DependsOn returns the services this service requires, as "namespace/name" or plain names.
+	DependsOn() []string
+}
+
+// SelectServices returns the services selected by names, in the order of services. A name is either
+// "namespace/name" or a plain name, which must be unique among the services. The dependencies of selected
+// services implementing Dependent are selected too, transitively. If names is empty, all services are returned.
+//
+// If a name matches no service or is ambiguous, an error listing the available services is returned.
+func SelectServices(services []Service, names ...string) ([]Service, error) {
+	if len(names) == 0 {
+		return services, nil
+	}
+
+	selected := make([]bool, len(services))
+	var errs []error
+
+	queue := slices.Clone(names)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		i, err := findService(services, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if selected[i] {
+			continue
+		}
+
+		selected[i] = true
+		if d, ok := services[i].(Dependent); ok {
+			queue = append(queue, d.DependsOn()...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, ae.WrapMany(fmt.Sprintf("invalid service selection, available services: %s",
+			strings.Join(serviceKeys(services), ", ")), errs...)
+	}
+
+	var result []Service
+	for i, svc := range services {
+		if selected[i] {
+			result = append(result, svc)
+		}
+	}
+
+	return result, nil
+}
+
+// SelectedServiceNames returns the services selected on the command line: the positional arguments, i.e. all
+// arguments up to the first one starting with a dash, or else the comma-separated names of the SERVICES env var
+// under envPrefix (e.g. MYAPP_SERVICES=api,worker). It returns nil if no service is selected.
+func SelectedServiceNames(envPrefix string) []string {
+	var names []string
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "-") {
+			break
+		}
+		names = append(names, arg)
+	}
+	if len(names) > 0 {
+		return names
+	}
+
+	for name := range strings.SplitSeq(os.Getenv(envPrefix+"SERVICES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// findService returns the index of the service matching name, given as "namespace/name" or a plain name.
+func findService(services []Service, name string) (int, error) {
+	match := -1
+	for i, svc := range services {
+		if name != serviceKey(svc) && name != svc.Name() {
+			continue
+		}
+		if match >= 0 {
+			return -1, ae.New().Msg(fmt.Sprintf("service name %s is ambiguous, use namespace/name", name))
+		}
+		match = i
+	}
+
+	if match < 0 {
+		return -1, ae.New().Msg(fmt.Sprintf("unknown service %s", name))
+	}
+
+	return match, nil
+}
+
+// serviceKey returns the "namespace/name" key of the service.
+func serviceKey(svc Service) string {
+	return svc.Namespace() + "/" + svc.Name()
+}
+
+// serviceKeys returns the keys of the services.
+func serviceKeys(services []Service) []string {
+	keys := make([]string, len(services))
+	for i, svc := range services {
+		keys[i] = serviceKey(svc)
+	}
+
+	return keys
+}
@@ -0,0 +1,93 @@
+package as
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// dependentService is an identityService depending on the given services.
+type dependentService struct {
+	identityService
+	deps []string
+}
+
+func (s *dependentService) DependsOn() []string { return s.deps }
+
+func TestSelectServices(t *testing.T) {
+	services := []Service{
+		&dependentService{identityService: identityService{name: "api", namespace: "shop"}, deps: []string{"cache"}},
+		&identityService{name: "worker", namespace: "shop"},
+		&dependentService{identityService: identityService{name: "cache", namespace: "shop"}, deps: []string{"infra/db"}},
+		&identityService{name: "db", namespace: "infra"},
+		&identityService{name: "db", namespace: "shop"},
+	}
+
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr []string
+	}{
+		{name: "all", want: serviceKeys(services)},
+		{name: "plain name", names: []string{"worker"}, want: []string{"shop/worker"}},
+		{name: "qualified name", names: []string{"shop/db"}, want: []string{"shop/db"}},
+		// Transitive dependencies are added, in the order of services
+		{name: "dependencies", names: []string{"worker", "api"}, want: []string{"shop/api", "shop/worker", "shop/cache", "infra/db"}},
+		{name: "duplicates", names: []string{"cache", "shop/cache", "infra/db"}, want: []string{"shop/cache", "infra/db"}},
+		{name: "unknown", names: []string{"api", "billing", "shop/mailer"}, wantErr: []string{"unknown service billing", "unknown service shop/mailer"}},
+		{name: "ambiguous", names: []string{"db"}, wantErr: []string{"service name db is ambiguous"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectServices(services, tt.names...)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("SelectServices() = %v, want an error", serviceKeys(got))
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("error %q lacks %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectServices() = %v", err)
+			}
+
+			keys := serviceKeys(got)
+			if !slices.Equal(keys, tt.want) {
+				t.Errorf("SelectServices() = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectedServiceNames(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  string
+		want []string
+	}{
+		{name: "none"},
+		{name: "args", args: []string{"api", "shop/worker", "-v", "cache"}, env: "db", want: []string{"api", "shop/worker"}},
+		{name: "env", args: []string{"-v"}, env: " api, ,worker", want: []string{"api", "worker"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := os.Args
+			os.Args = append([]string{"astest"}, tt.args...)
+			t.Cleanup(func() { os.Args = args })
+			t.Setenv("ASTEST_SERVICES", tt.env)
+
+			if got := SelectedServiceNames("ASTEST_"); !slices.Equal(got, tt.want) {
+				t.Errorf("SelectedServiceNames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
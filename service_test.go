@@ -0,0 +1,153 @@
+package as
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testService is a scripted Service for tests. Nil funcs succeed at once, except run, which blocks until the
+// context is done and returns nil.
+type testService struct {
+	name  string
+	init  func(ctx context.Context) error
+	run   func(ctx context.Context) error
+	close func(ctx context.Context) error
+}
+
+func (s *testService) Name() string {
+	if s.name == "" {
+		return "test"
+	}
+	return s.name
+}
+
+func (s *testService) Namespace() string { return "astest" }
+
+func (s *testService) Version() string { return "v1.0.0" }
+
+func (s *testService) Init(ctx context.Context) error {
+	if s.init == nil {
+		return nil
+	}
+	return s.init(ctx)
+}
+
+func (s *testService) Run(ctx context.Context) error {
+	if s.run == nil {
+		<-ctx.Done()
+		return nil
+	}
+	return s.run(ctx)
+}
+
+func (s *testService) Close(ctx context.Context) error {
+	if s.close == nil {
+		return nil
+	}
+	return s.close(ctx)
+}
+
+// testOptions are the options of services run in tests: signals are not handled and restarts are immediate.
+func testOptions(opts ...Option) []Option {
+	return append([]Option{
+		WithShutdownSignals(),
+		WithRestartOnErrorDelay(0),
+		WithRestartOnPanicDelay(0),
+		WithShowBanner(false),
+	}, opts...)
+}
+
+// runTest runs svc with testOptions in a goroutine and returns a channel receiving the result of RunC. The
+// service is stopped by cancelling the returned context, and the test fails if it did not return within 10
+// seconds of its end.
+func runTest(t *testing.T, svc Service, opts ...Option) (context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		done <- RunC(svc, ctx, testOptions(opts...)...)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-finished:
+		case <-time.After(10 * time.Second):
+			t.Error("service did not stop")
+		}
+	})
+
+	return cancel, done
+}
+
+// waitFor polls cond until it returns true, failing the test after 5 seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// logCapture collects the JSON records of a service routed to it, see captureLogs.
+type logCapture struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// captureLogs returns an option routing the records of svc to c as JSON.
+func captureLogs(svc Service, c *logCapture) Option {
+	return func(o *Options) {
+		WithLogRouteWriter(svc.Namespace(), svc.Name(), c)(o)
+		o.LogJson = true
+	}
+}
+
+func (c *logCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.buf.Write(p)
+}
+
+// records returns the records written so far.
+func (c *logCapture) records() []map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(c.buf.Bytes()))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var record map[string]any
+		if json.Unmarshal(scanner.Bytes(), &record) == nil {
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+// find returns the last record with the message, or nil.
+func (c *logCapture) find(msg string) map[string]any {
+	var found map[string]any
+	for _, record := range c.records() {
+		if record["msg"] == msg {
+			found = record
+		}
+	}
+
+	return found
+}
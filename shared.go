@@ -0,0 +1,81 @@
+package as
+
+import (
+	"context"
+	"fmt"
+
+	"go.aledante.io/ae"
+)
+
+// sharedValuesKey is the context key of the shared values.
+type sharedValuesKey struct{}
+
+// sharedValue is a value shared by all runs of a service, registered with WithSharedValue.
+type sharedValue struct {
+	key       any
+	construct func(ctx context.Context) (any, func(ctx context.Context) error, error)
+}
+
+// WithSharedValue registers a value constructed once before the service is first initialized, e.g. a database
+// pool or an event bus. The value is kept across restarts, injected into the service context and retrieved with
+// Value. The closer returned by the constructor, if non-nil, runs after the service has closed for the last time;
+// closers run in reverse construction order. A failing constructor aborts the supervisor.
+func WithSharedValue[T any](key any, constructor func(ctx context.Context) (T, func(ctx context.Context) error, error)) Option {
+	return func(o *Options) {
+		o.SharedValues = append(o.SharedValues, sharedValue{
+			key: key,
+			construct: func(ctx context.Context) (any, func(ctx context.Context) error, error) {
+				return constructor(ctx)
+			},
+		})
+	}
+}
+
+// Value returns the shared value registered with WithSharedValue under key, and whether it exists and has type T.
+func Value[T any](ctx context.Context, key any) (T, bool) {
+	values, _ := ctx.Value(sharedValuesKey{}).(map[any]any)
+	v, ok := values[key].(T)
+	return v, ok
+}
+
+// initSharedValues constructs the shared values of opts in order and adds them to the context.
+// It returns a function running the closers in reverse order, which must be called after the service has closed.
+// If a constructor fails, the values constructed so far are closed and the error is returned.
+func initSharedValues(ctx context.Context, opts Options) (context.Context, func(), error) {
+	if len(opts.SharedValues) == 0 {
+		return ctx, func() {}, nil
+	}
+
+	values := make(map[any]any, len(opts.SharedValues))
+	var closers []func(ctx context.Context) error
+	closeAll := func() {
+		closeCtx, cancel := shutdownContext(ctx, opts.ShutdownTimeout)
+		defer cancel()
+
+		for i := len(closers) - 1; i >= 0; i-- {
+			if err := closers[i](closeCtx); err != nil {
+				Logger(ctx).Error("failed to close shared value", "error", err)
+			}
+		}
+	}
+
+	for _, sv := range opts.SharedValues {
+		if _, ok := values[sv.key]; ok {
+			closeAll()
+			return ctx, nil, ae.New().Msg(fmt.Sprintf("shared value %v registered more than once", sv.key))
+		}
+
+		v, closer, err := sv.construct(ctx)
+		if err != nil {
+			closeAll()
+			return ctx, nil, ae.Wrap(fmt.Sprintf("failed to construct shared value %v", sv.key), err)
+		}
+
+		values[sv.key] = v
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+	}
+
+	return context.WithValue(ctx, sharedValuesKey{}, values), closeAll, nil
+}
@@ -0,0 +1,143 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// pool is a shared value of the tests.
+type pool struct {
+	id int
+}
+
+type poolKey struct{}
+
+type busKey struct{}
+
+func TestSharedValue(t *testing.T) {
+	log := &eventLog{}
+	constructed := 0
+
+	newPool := func(ctx context.Context) (*pool, func(ctx context.Context) error, error) {
+		constructed++
+		log.add("construct pool")
+		return &pool{id: constructed}, func(ctx context.Context) error {
+			log.add("close pool")
+			return nil
+		}, nil
+	}
+	newBus := func(ctx context.Context) (string, func(ctx context.Context) error, error) {
+		log.add("construct bus")
+		return "bus", func(ctx context.Context) error {
+			log.add("close bus")
+			return nil
+		}, nil
+	}
+
+	// Every phase of every attempt must observe the same instance
+	var seen []*pool
+	observe := func(ctx context.Context, phase string) {
+		p, ok := Value[*pool](ctx, poolKey{})
+		if !ok {
+			t.Errorf("shared value missing in %s", phase)
+		}
+		if bus, _ := Value[string](ctx, busKey{}); bus != "bus" {
+			t.Errorf("shared value in %s = %q, want bus", phase, bus)
+		}
+		if _, ok := Value[int](ctx, busKey{}); ok {
+			t.Errorf("shared value found with the wrong type in %s", phase)
+		}
+		seen = append(seen, p)
+		log.add(phase)
+	}
+
+	attempt := 0
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			observe(ctx, "init")
+			return nil
+		},
+		run: func(ctx context.Context) error {
+			observe(ctx, "run")
+			if attempt++; attempt == 1 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+		close: func(ctx context.Context) error {
+			observe(ctx, "close")
+			return nil
+		},
+	}
+
+	opts := testOptions(WithSharedValue(poolKey{}, newPool), WithSharedValue(busKey{}, newBus))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if constructed != 1 {
+		t.Errorf("pool constructed %d times, want once", constructed)
+	}
+	for _, p := range seen {
+		if p != seen[0] {
+			t.Errorf("observed different instances %v and %v", p, seen[0])
+		}
+	}
+
+	// Closers run after the last Close, in reverse construction order
+	got := log.all()
+	if got[0] != "construct pool" || got[1] != "construct bus" {
+		t.Errorf("events = %v, want the values constructed first", got)
+	}
+	if tail := got[len(got)-3:]; !slices.Equal(tail, []string{"close", "close bus", "close pool"}) {
+		t.Errorf("events = %v, want the closers to run last in reverse order", got)
+	}
+
+	if _, ok := Value[string](context.Background(), busKey{}); ok {
+		t.Error("shared value found in an unrelated context")
+	}
+}
+
+func TestSharedValueConstructorFailure(t *testing.T) {
+	log := &eventLog{}
+
+	opts := testOptions(
+		WithSharedValue(poolKey{}, func(ctx context.Context) (*pool, func(ctx context.Context) error, error) {
+			return &pool{}, func(ctx context.Context) error {
+				log.add("close pool")
+				return nil
+			}, nil
+		}),
+		WithSharedValue(busKey{}, func(ctx context.Context) (string, func(ctx context.Context) error, error) {
+			return "", nil, errors.New("broker unavailable")
+		}),
+	)
+
+	svc := &testService{init: func(ctx context.Context) error {
+		log.add("init")
+		return nil
+	}}
+	err := RunC(svc, context.Background(), opts...)
+	if err == nil || !strings.Contains(err.Error(), "broker unavailable") {
+		t.Fatalf("RunC() = %v, want the constructor error", err)
+	}
+
+	// The service is not initialized, and the values constructed before are closed
+	if got := log.all(); !slices.Equal(got, []string{"close pool"}) {
+		t.Errorf("events = %v, want [close pool]", got)
+	}
+}
+
+func TestSharedValueDuplicateKey(t *testing.T) {
+	constructor := func(ctx context.Context) (int, func(ctx context.Context) error, error) {
+		return 1, nil, nil
+	}
+
+	opts := testOptions(WithSharedValue(poolKey{}, constructor), WithSharedValue(poolKey{}, constructor))
+	if err := RunC(&testService{}, context.Background(), opts...); err == nil {
+		t.Error("RunC() = nil, want an error for the duplicate key")
+	}
+}
@@ -0,0 +1,145 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// shutdownHook is a function registered with OnShutdown.
+type shutdownHook struct {
+	name    string
+	fn      func(ctx context.Context) error
+	timeout time.Duration
+}
+
+// shutdownHookResult is the outcome of a shutdown hook, reported in the exit summary.
+type shutdownHookResult struct {
+	name      string
+	duration  time.Duration
+	abandoned bool
+	err       error
+}
+
+// String returns the name and duration of the hook, and whether it failed or was abandoned.
+func (r shutdownHookResult) String() string {
+	switch {
+	case r.abandoned:
+		return fmt.Sprintf("%s: abandoned after %s", r.name, r.duration)
+	case r.err != nil:
+		return fmt.Sprintf("%s: failed after %s", r.name, r.duration)
+	default:
+		return fmt.Sprintf("%s: %s", r.name, r.duration)
+	}
+}
+
+// ShutdownHookOption is a function which applies a configuration change to a shutdown hook.
+type ShutdownHookOption func(*shutdownHook)
+
+// WithHookTimeout sets the timeout of the shutdown hook, overriding its share of the ShutdownTimeout.
+func WithHookTimeout(d time.Duration) ShutdownHookOption {
+	return func(h *shutdownHook) { h.timeout = d }
+}
+
+// OnShutdown registers fn to be called once the service the context belongs to stopped for good, after the last
+// Close, e.g. to deregister from service discovery or flush a buffer. Hooks are called in reverse registration
+// order, like deferred functions, and are not called on restarts. If ctx was not created by the supervisor, fn is
+// not called.
+//
+// All hooks together are limited by ShutdownTimeout. Unless set with WithHookTimeout, the timeout of a hook is its
+// share of the remaining time: the remaining time divided by the number of remaining hooks, so a slow hook cannot
+// starve the hooks after it. A hook still running after its timeout is abandoned with a warning and keeps running
+// in its goroutine. Errors are logged, and the duration of each hook is reported in the exit summary.
+func OnShutdown(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...ShutdownHookOption) {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		Logger(ctx).Warn("cannot register shutdown hook outside of a supervised service", "hook", name)
+		return
+	}
+
+	hook := shutdownHook{name: name, fn: fn}
+	for _, opt := range opts {
+		opt(&hook)
+	}
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	sup.shutdownHooks = append(sup.shutdownHooks, hook)
+}
+
+// runShutdownHooks calls the registered shutdown hooks in reverse order within timeout, and records their results
+// for the exit summary. A timeout of zero does not limit the hooks.
+func (s *supervisor) runShutdownHooks(ctx context.Context, timeout time.Duration) {
+	s.mu.Lock()
+	hooks := s.shutdownHooks
+	s.shutdownHooks = nil
+	s.mu.Unlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	deadline := time.Now().Add(timeout)
+
+	results := make([]shutdownHookResult, 0, len(hooks))
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+
+		hookTimeout := hook.timeout
+		if hookTimeout <= 0 && timeout > 0 {
+			// Once the budget is used up, the remaining hooks are abandoned right away
+			hookTimeout = max(time.Until(deadline)/time.Duration(i+1), time.Nanosecond)
+		}
+
+		results = append(results, runShutdownHook(ctx, hook, hookTimeout))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdownHookResults = results
+}
+
+// runShutdownHook calls the hook in its own goroutine and waits for at most timeout, or without a limit if
+// timeout is zero. Panics in the hook are recovered and handled like errors.
+func runShutdownHook(ctx context.Context, hook shutdownHook, timeout time.Duration) shutdownHookResult {
+	hookCtx, cancel := ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		hookCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		defer func() {
+			if cause := recover(); cause != nil {
+				err = panicError(ctx, cause, nil)
+			}
+			done <- err
+		}()
+
+		err = hook.fn(hookCtx)
+	}()
+
+	result := shutdownHookResult{name: hook.name}
+	select {
+	case result.err = <-done:
+		result.duration = time.Since(start)
+		if result.err != nil {
+			Logger(ctx).Error("shutdown hook failed", "hook", hook.name, "error", result.err)
+		}
+	case <-hookCtx.Done():
+		result.duration = time.Since(start)
+		result.abandoned = true
+		Logger(ctx).Warn("shutdown hook timed out, abandoning it; its goroutine may leak",
+			"hook", hook.name,
+			"timeout", timeout.String(),
+		)
+	}
+
+	return result
+}
@@ -0,0 +1,150 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestShutdownHookTimeouts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		logs := &logCapture{}
+		ctx := WithLogger(context.Background(), slog.New(slog.NewJSONHandler(logs, nil)))
+		sup := newSupervisor()
+		ctx = withSupervisor(ctx, sup)
+
+		release := make(chan struct{})
+		defer close(release)
+
+		sleepHook := func(d time.Duration) func(ctx context.Context) error {
+			return func(ctx context.Context) error {
+				time.Sleep(d)
+				return nil
+			}
+		}
+		errFlush := errors.New("flush failed")
+
+		// Hooks run in reverse order: deregister, then the hanging one, then flush and close
+		OnShutdown(ctx, "close", sleepHook(time.Second))
+		OnShutdown(ctx, "flush", func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return errFlush
+		})
+		OnShutdown(ctx, "consul", func(ctx context.Context) error {
+			// Ignores its context, like a client without timeouts
+			<-release
+			return nil
+		})
+		OnShutdown(ctx, "deregister", sleepHook(2*time.Second), WithHookTimeout(time.Minute))
+
+		start := time.Now()
+		sup.runShutdownHooks(ctx, 30*time.Second)
+		elapsed := time.Since(start)
+
+		// The hanging hook gets a third of the 28s left, so the hooks behind it still run within the budget
+		want := []string{
+			"deregister: 2s",
+			"consul: abandoned after 9.333333333s",
+			"flush: failed after 1s",
+			"close: 1s",
+		}
+		var got []string
+		for _, result := range sup.shutdownHookResults {
+			got = append(got, result.String())
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("results = %q, want %q", got, want)
+		}
+		if elapsed > 30*time.Second {
+			t.Errorf("hooks took %s, want at most the timeout", elapsed)
+		}
+
+		abandoned := logs.find("shutdown hook timed out, abandoning it; its goroutine may leak")
+		if abandoned == nil || abandoned["hook"] != "consul" {
+			t.Errorf("abandoned hook logged as %v", abandoned)
+		}
+		if failed := logs.find("shutdown hook failed"); failed == nil || failed["hook"] != "flush" {
+			t.Errorf("failed hook logged as %v", failed)
+		}
+	})
+}
+
+func TestShutdownHooksBudgetUsedUp(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+		sup := newSupervisor()
+		ctx = withSupervisor(ctx, sup)
+
+		OnShutdown(ctx, "late", func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		})
+		OnShutdown(ctx, "slow", func(ctx context.Context) error {
+			time.Sleep(20 * time.Second)
+			return nil
+		}, WithHookTimeout(time.Minute))
+
+		// The explicit timeout may exceed the budget, leaving no time for the hooks after it
+		sup.runShutdownHooks(ctx, 10*time.Second)
+
+		results := sup.shutdownHookResults
+		if len(results) != 2 || results[0].abandoned || !results[1].abandoned {
+			t.Errorf("results = %v, want the late hook abandoned", results)
+		}
+
+		// Let the abandoned hook finish in its goroutine
+		time.Sleep(time.Second)
+	})
+}
+
+func TestOnShutdown(t *testing.T) {
+	log := &eventLog{}
+	attempt := 0
+	svc := &testService{
+		run: func(ctx context.Context) error {
+			if attempt++; attempt == 1 {
+				for _, name := range []string{"first", "second"} {
+					OnShutdown(ctx, name, func(ctx context.Context) error {
+						log.add("hook " + name)
+						return nil
+					})
+				}
+				return errors.New("failed")
+			}
+			return nil
+		},
+		close: func(ctx context.Context) error {
+			log.add("close")
+			return nil
+		},
+	}
+
+	logs := &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// Hooks are not called on restarts, but once after the final Close, in reverse order
+	if got, want := log.all(), []string{"close", "hook second", "hook first"}; !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	summary := logs.find("service exited")
+	hooks, _ := summary["shutdown_hooks"].([]any)
+	if len(hooks) != 2 || !strings.HasPrefix(hooks[0].(string), "second: ") || !strings.HasPrefix(hooks[1].(string), "first: ") {
+		t.Errorf("exit summary hooks = %v", summary["shutdown_hooks"])
+	}
+}
+
+func TestOnShutdownWithoutSupervisor(t *testing.T) {
+	// Hooks registered outside of a supervised service are ignored
+	OnShutdown(context.Background(), "ignored", func(ctx context.Context) error {
+		t.Error("hook called")
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.aledante.io/ae"
+)
+
+// Sleep pauses for the duration d or until ctx is done, whichever happens first.
+// It returns ctx.Err() if ctx is done before d elapsed, and nil otherwise.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Tick calls fn every interval d until ctx is done or fn returns an error. The first call happens after d.
+// It returns ctx.Err() if ctx is done, or the error returned by fn.
+func Tick(ctx context.Context, d time.Duration, fn func(ctx context.Context) error) error {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WaitFor calls probe immediately and then every interval until it returns true, e.g. to wait in Init until a
+// dependency is available. It returns nil once probe returned true, the error returned by probe, or an error
+// wrapping context.DeadlineExceeded if probe did not succeed within timeout. A timeout of zero waits until ctx
+// is done, in which case ctx.Err() is returned.
+func WaitFor(ctx context.Context, interval, timeout time.Duration, probe func(ctx context.Context) (bool, error)) error {
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for {
+		ok, err := probe(waitCtx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		if err := Sleep(waitCtx, interval); err != nil {
+			if ctx.Err() == nil {
+				return ae.NewC(ctx).
+					Cause(err).
+					Msg(fmt.Sprintf("condition not met within %s", timeout))
+			}
+
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestSleep(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		start := time.Now()
+		if err := Sleep(t.Context(), time.Minute); err != nil {
+			t.Errorf("Sleep() = %v, want nil", err)
+		}
+		if elapsed := time.Since(start); elapsed != time.Minute {
+			t.Errorf("slept %s, want 1m", elapsed)
+		}
+	})
+}
+
+func TestSleepCancelled(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		time.AfterFunc(time.Second, cancel)
+
+		start := time.Now()
+		if err := Sleep(ctx, time.Minute); !errors.Is(err, context.Canceled) {
+			t.Errorf("Sleep() = %v, want context.Canceled", err)
+		}
+		if elapsed := time.Since(start); elapsed != time.Second {
+			t.Errorf("slept %s, want 1s", elapsed)
+		}
+
+		// Non-positive durations only report the state of the context
+		if err := Sleep(ctx, 0); !errors.Is(err, context.Canceled) {
+			t.Errorf("Sleep(0) = %v, want context.Canceled", err)
+		}
+		if err := Sleep(t.Context(), -time.Second); err != nil {
+			t.Errorf("Sleep(-1s) = %v, want nil", err)
+		}
+	})
+}
+
+func TestTick(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errStop := errors.New("stop")
+
+		var calls []time.Duration
+		start := time.Now()
+		err := Tick(t.Context(), time.Second, func(ctx context.Context) error {
+			calls = append(calls, time.Since(start))
+			if len(calls) == 3 {
+				return errStop
+			}
+			return nil
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("Tick() = %v, want the error of fn", err)
+		}
+
+		want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+		if len(calls) != len(want) {
+			t.Fatalf("calls at %v, want %v", calls, want)
+		}
+		for i := range want {
+			if calls[i] != want[i] {
+				t.Errorf("call %d at %s, want %s", i, calls[i], want[i])
+			}
+		}
+	})
+}
+
+func TestTickCancelled(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), 2500*time.Millisecond)
+		defer cancel()
+
+		calls := 0
+		err := Tick(ctx, time.Second, func(ctx context.Context) error {
+			calls++
+			return nil
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Tick() = %v, want context.DeadlineExceeded", err)
+		}
+		if calls != 2 {
+			t.Errorf("calls = %d, want 2", calls)
+		}
+	})
+}
+
+func TestWaitFor(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		calls := 0
+		start := time.Now()
+		err := WaitFor(t.Context(), time.Second, time.Minute, func(ctx context.Context) (bool, error) {
+			calls++
+			return calls == 3, nil
+		})
+		if err != nil {
+			t.Errorf("WaitFor() = %v, want nil", err)
+		}
+		if elapsed := time.Since(start); calls != 3 || elapsed != 2*time.Second {
+			t.Errorf("probed %d times within %s, want 3 times within 2s", calls, elapsed)
+		}
+	})
+}
+
+func TestWaitForProbeError(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errProbe := errors.New("misconfigured")
+		err := WaitFor(t.Context(), time.Second, time.Minute, func(ctx context.Context) (bool, error) {
+			return false, errProbe
+		})
+		if !errors.Is(err, errProbe) {
+			t.Errorf("WaitFor() = %v, want the error of probe", err)
+		}
+	})
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		start := time.Now()
+		err := WaitFor(t.Context(), time.Second, 5*time.Second, func(ctx context.Context) (bool, error) {
+			return false, nil
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("WaitFor() = %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(start); elapsed != 5*time.Second {
+			t.Errorf("waited %s, want 5s", elapsed)
+		}
+	})
+}
+
+func TestWaitForCancelled(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		time.AfterFunc(3*time.Second, cancel)
+
+		err := WaitFor(ctx, time.Second, 0, func(ctx context.Context) (bool, error) {
+			return false, nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("WaitFor() = %v, want context.Canceled", err)
+		}
+	})
+}
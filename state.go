@@ -0,0 +1,278 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// State describes the lifecycle state of a supervised service.
+type State int
+
+const (
+	// StateUnknown is reported for contexts which were not created by the supervisor.
+	StateUnknown State = iota
+	// StateStarting is the state of a service while Init is running.
+	StateStarting
+	// StateRunning is the state of a service after Init returned successfully and while Run is executing.
+	StateRunning
+	// StateStopping is the state of a service once its context is cancelled or Run returned, until Close returned.
+	StateStopping
+	// StateRestarting is the state of a service while the supervisor waits to restart it after a failure.
+	StateRestarting
+	// StateStopped is the state of a service after the supervisor stopped supervising it.
+	StateStopped
+	// StateDegraded is the state of a service while restarts are paused because the restart circuit breaker
+	// opened (see OpenPolicyCooldown).
+	StateDegraded
+	// StatePaused is the state of a running service paused using Pause, until it is resumed, see Pauser.
+	StatePaused
+)
+
+// String returns the lower-case name of the state.
+func (s State) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateStopping:
+		return "stopping"
+	case StateRestarting:
+		return "restarting"
+	case StateStopped:
+		return "stopped"
+	case StateDegraded:
+		return "degraded"
+	case StatePaused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+// CurrentState returns the lifecycle state of the service the context belongs to.
+// If the context was not created by the supervisor, StateUnknown is returned.
+func CurrentState(ctx context.Context) State {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		return StateUnknown
+	}
+
+	return sup.State()
+}
+
+// supervisorKey is an unexported type used as the key for storing the supervisor in a context.
+type supervisorKey struct{}
+
+// supervisor holds the state tracked by the supervisor for a single service.
+type supervisor struct {
+	// notifyMu serializes state transitions, so listeners observe them in order.
+	notifyMu sync.Mutex
+
+	mu         sync.Mutex
+	state      State
+	listeners  map[int]func(State)
+	listenerID int
+
+	started    time.Time
+	restarts   int
+	panics     int
+	stopReason string
+	lastError  error
+
+	// goroutines counts the goroutines started by Go and Tasks during the current attempt; nil if none is running.
+	goroutines              *sync.WaitGroup
+	cancelAttempt           context.CancelCauseFunc
+	escalateGoroutineErrors bool
+
+	// restartBudget is the restart budget shared with other services, if any.
+	restartBudget *RestartBudget
+
+	recentLogs *logRing
+	logFiles   []*os.File
+	// logSyslog is the writer of the syslog log output, if any; it is closed with the log files.
+	logSyslog *syslogWriter
+	// logShutdown switches the logger to stderr once the log outputs are closed.
+	logShutdown *logShutdown
+	logRecords  *logRecordCounter
+
+	logLevel       *slog.LevelVar
+	logLevelHeader string
+
+	shutdownHooks       []shutdownHook
+	shutdownHookResults []shutdownHookResult
+
+	// memLimit is the GOMEMLIMIT chosen from the cgroup memory limit, or zero if it was not adjusted.
+	memLimit int64
+
+	httpClientTimeout       time.Duration
+	shutdownTimeout         time.Duration
+	httpClientSlowThreshold time.Duration
+
+	health       HealthStatus
+	healthReason string
+	// history records state and health transitions, see HealthHistory.
+	history       *healthHistory
+	flapWindow    time.Duration
+	flapThreshold int
+	grpcHealth    *grpcHealth
+	readyFile     *readyFile
+	heartbeat     *heartbeat
+	dataDir       *dataDir
+	traces        *traceCapture
+
+	// pauser is the service if it implements Pauser; pauseMu serializes Pause and Resume.
+	pauser          Pauser
+	pauseMu         sync.Mutex
+	pausedReadiness bool
+
+	// cleanups are called once the current run ended, see InitSteps.
+	cleanups []func(ctx context.Context) error
+
+	// flushTelemetry flushes the OTEL providers of the service, once initialized; see forceExit.
+	flushTelemetry func(ctx context.Context) error
+	// handlePanic writes the crash report of a panic recovered by Recover and passes it to the reporters.
+	handlePanic func(ctx context.Context, value any, stack []byte)
+
+	// stopping is closed once shutdown was requested, see Stopping.
+	stopping     chan struct{}
+	stoppingOnce sync.Once
+	// forced is closed once the shutdown was forced without exiting the process, see forceExit.
+	forced     chan struct{}
+	forcedOnce sync.Once
+}
+
+// newSupervisor returns a new supervisor in StateUnknown.
+func newSupervisor() *supervisor {
+	return &supervisor{
+		listeners: make(map[int]func(State)),
+		started:   time.Now(),
+		stopping:  make(chan struct{}),
+		forced:    make(chan struct{}),
+	}
+}
+
+// withSupervisor returns a new context based on ctx that carries the given supervisor.
+func withSupervisor(ctx context.Context, sup *supervisor) context.Context {
+	return context.WithValue(ctx, supervisorKey{}, sup)
+}
+
+// supervisorFrom extracts the supervisor from the context, returning nil if none is set.
+func supervisorFrom(ctx context.Context) *supervisor {
+	v, _ := ctx.Value(supervisorKey{}).(*supervisor)
+	return v
+}
+
+// State returns the current lifecycle state.
+func (s *supervisor) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// setState transitions to the given state and notifies all listeners if the state changed.
+// Listeners are called synchronously and in registration order.
+func (s *supervisor) setState(state State) {
+	s.transition(func(State) bool { return true }, state)
+}
+
+// setStateIf transitions to the given state like setState, but only if the current state is from. It reports
+// whether the state was changed.
+func (s *supervisor) setStateIf(from, state State) bool {
+	return s.transition(func(current State) bool { return current == from }, state)
+}
+
+// transition transitions to the given state if allowed returns true for the current state and notifies all
+// listeners if the state changed. It reports whether the state was changed.
+func (s *supervisor) transition(allowed func(State) bool, state State) bool {
+	s.notifyMu.Lock()
+	defer s.notifyMu.Unlock()
+
+	s.mu.Lock()
+	if s.state == state || !allowed(s.state) {
+		s.mu.Unlock()
+		return false
+	}
+	s.state = state
+
+	listeners := make([]func(State), 0, len(s.listeners))
+	for id := 0; id < s.listenerID; id++ {
+		if fn, ok := s.listeners[id]; ok {
+			listeners = append(listeners, fn)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(state)
+	}
+
+	return true
+}
+
+// onStateChange registers fn to be called on every state transition. fn is called once immediately with the
+// current state. The returned function unregisters fn.
+func (s *supervisor) onStateChange(fn func(State)) func() {
+	s.notifyMu.Lock()
+	defer s.notifyMu.Unlock()
+
+	s.mu.Lock()
+	id := s.listenerID
+	s.listenerID++
+	s.listeners[id] = fn
+	state := s.state
+	s.mu.Unlock()
+
+	fn(state)
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.listeners, id)
+	}
+}
+
+// countRestart records a restart of the service.
+func (s *supervisor) countRestart() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restarts++
+}
+
+// restartCount returns the number of restarts since the supervisor started.
+func (s *supervisor) restartCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.restarts
+}
+
+// countPanic records a recovered panic of the service.
+func (s *supervisor) countPanic() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.panics++
+}
+
+// setLastError records the most recent error of the service.
+func (s *supervisor) setLastError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastError = err
+}
+
+// setStopReason records why the supervisor stopped supervising the service.
+func (s *supervisor) setStopReason(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopReason = reason
+}
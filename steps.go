@@ -0,0 +1,130 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aledante.io/ae"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Step is a named step of the initialization of a service, run by InitSteps.
+type Step struct {
+	// Name is the name of the step, used for logging, spans, and errors.
+	Name string
+	// Run performs the step, e.g. connecting to a database.
+	Run func(ctx context.Context) error
+	// Cleanup optionally reverts the step, e.g. closing the connection. If Run succeeded, it is called once the
+	// current run of the service ended, after Close.
+	Cleanup func(ctx context.Context) error
+}
+
+// InitSteps runs independent initialization steps concurrently, e.g. from Init. It is InitStepsLimit without a
+// limit.
+func InitSteps(ctx context.Context, steps ...Step) error {
+	return InitStepsLimit(ctx, 0, steps...)
+}
+
+// InitStepsLimit runs the steps with at most limit steps running concurrently; a limit of 1 runs them
+// sequentially, in order, and a limit of zero or less does not limit the concurrency. All steps are run, even if
+// some fail.
+//
+// Each step runs in its own span and its duration is logged at debug level. The cleanup functions of successful
+// steps are called in reverse order once the current run of the service ended, after Close; they are not called if
+// ctx was not created by the supervisor. If any step fails, an error naming all failed steps is returned, wrapping
+// their errors.
+func InitStepsLimit(ctx context.Context, limit int, steps ...Step) error {
+	if limit <= 0 || limit > len(steps) {
+		limit = len(steps)
+	}
+
+	errs := make([]error, len(steps))
+	slots := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			errs[i] = runStep(ctx, step)
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	var stepErrs []error
+	for i, step := range steps {
+		if errs[i] != nil {
+			failed = append(failed, step.Name)
+			stepErrs = append(stepErrs, errs[i])
+		} else if step.Cleanup != nil {
+			registerCleanup(ctx, step.Cleanup)
+		}
+	}
+
+	if len(stepErrs) > 0 {
+		return ae.WrapMany(fmt.Sprintf("init steps failed: %s", strings.Join(failed, ", ")), stepErrs...)
+	}
+
+	return nil
+}
+
+// runStep runs a single step in a span, converting panics to errors.
+func runStep(ctx context.Context, step Step) (err error) {
+	ctx, span := Tracer(ctx).Start(ctx, "init "+step.Name)
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		if cause := recover(); cause != nil {
+			err = panicError(ctx, cause, nil)
+		}
+
+		if err != nil {
+			err = ae.Wrap(fmt.Sprintf("init step %s failed", step.Name), err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		Logger(ctx).Debug("init step finished",
+			"step", step.Name,
+			"duration", time.Since(start).String(),
+			"error", err,
+		)
+	}()
+
+	return step.Run(ctx)
+}
+
+// registerCleanup registers fn to be called once the current run of the service ended. If ctx was not created by
+// the supervisor, fn is not called.
+func registerCleanup(ctx context.Context, fn func(ctx context.Context) error) {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		Logger(ctx).Warn("cannot register cleanup outside of a supervised service")
+		return
+	}
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	sup.cleanups = append(sup.cleanups, fn)
+}
+
+// runCleanups calls the registered cleanup functions in reverse order and clears them. Errors are logged.
+func (s *supervisor) runCleanups(ctx context.Context) {
+	s.mu.Lock()
+	cleanups := s.cleanups
+	s.cleanups = nil
+	s.mu.Unlock()
+
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		if err := cleanups[i](ctx); err != nil {
+			Logger(ctx).Error("cleanup failed", "error", err)
+		}
+	}
+}
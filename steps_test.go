@@ -0,0 +1,146 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingStep returns a step adding its name to log when it runs and "cleanup <name>" when it is cleaned up.
+func recordingStep(log *eventLog, name string, err error) Step {
+	return Step{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			log.add(name)
+			return err
+		},
+		Cleanup: func(ctx context.Context) error {
+			log.add("cleanup " + name)
+			return nil
+		},
+	}
+}
+
+func TestInitStepsPartialFailure(t *testing.T) {
+	errDB := errors.New("database unavailable")
+	errCache := errors.New("cache unavailable")
+	log := &eventLog{}
+
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			err := InitSteps(ctx,
+				recordingStep(log, "db", errDB),
+				recordingStep(log, "queue", nil),
+				recordingStep(log, "cache", errCache),
+				Step{Name: "panics", Run: func(ctx context.Context) error { panic("boom") }},
+			)
+
+			// Every failed step is named and its error wrapped, including panics
+			if err == nil {
+				t.Fatal("InitSteps() = nil, want an error")
+			}
+			for _, name := range []string{"db", "cache", "panics"} {
+				if !strings.Contains(err.Error(), name) {
+					t.Errorf("error %q does not name step %s", err, name)
+				}
+			}
+			if strings.Contains(err.Error(), "queue") {
+				t.Errorf("error %q names the successful step", err)
+			}
+			if !errors.Is(err, errDB) || !errors.Is(err, errCache) {
+				t.Errorf("error %v does not wrap the step errors", err)
+			}
+			return err
+		},
+	}
+
+	if err := RunC(svc, context.Background(), testOptions(WithRestartOnError(false))...); err == nil {
+		t.Fatal("RunC() = nil, want the init error")
+	}
+
+	// Only the successful step is cleaned up, once the run ended
+	got := log.all()
+	slices.Sort(got[:3])
+	if want := []string{"cache", "db", "queue", "cleanup queue"}; !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestInitStepsCleanup(t *testing.T) {
+	log := &eventLog{}
+	attempt := 0
+
+	svc := &testService{
+		init: func(ctx context.Context) error {
+			return InitStepsLimit(ctx, 1, recordingStep(log, "db", nil), recordingStep(log, "queue", nil))
+		},
+		run: func(ctx context.Context) error {
+			log.add("run")
+			if attempt++; attempt == 1 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+		close: func(ctx context.Context) error {
+			log.add("close")
+			return nil
+		},
+	}
+
+	if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// Sequential steps run in order; cleanups run in reverse order at the end of every run, after Close
+	run := []string{"db", "queue", "run"}
+	cleanup := []string{"cleanup queue", "cleanup db"}
+	want := slices.Concat(run, cleanup, run, []string{"close"}, cleanup)
+	if got := log.all(); !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestInitStepsLimit(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	step := Step{Name: "step", Run: func(ctx context.Context) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		// Give other steps the chance to run concurrently
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}}
+
+	if err := InitStepsLimit(context.Background(), 2, step, step, step, step, step); err != nil {
+		t.Fatalf("InitStepsLimit() = %v", err)
+	}
+	if peak > 2 {
+		t.Errorf("%d steps ran concurrently, want at most 2", peak)
+	}
+}
+
+func TestInitStepsConcurrent(t *testing.T) {
+	// Both steps must run at the same time to complete
+	var started sync.WaitGroup
+	started.Add(2)
+	step := Step{Name: "step", Run: func(ctx context.Context) error {
+		started.Done()
+		started.Wait()
+		return nil
+	}}
+
+	if err := InitSteps(context.Background(), step, step); err != nil {
+		t.Fatalf("InitSteps() = %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package as
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Stopping returns a channel closed as soon as shutdown of the service the context belongs to was requested, before
+// its context is cancelled after DrainDelay. Services can use it to stop accepting new work while finishing work in
+// flight. If the context was not created by the supervisor, ctx.Done() is returned.
+func Stopping(ctx context.Context) <-chan struct{} {
+	sup := supervisorFrom(ctx)
+	if sup == nil {
+		return ctx.Done()
+	}
+
+	return sup.stopping
+}
+
+// IsStopping reports whether shutdown of the service the context belongs to was requested, see Stopping.
+func IsStopping(ctx context.Context) bool {
+	select {
+	case <-Stopping(ctx):
+		return true
+	default:
+		return false
+	}
+}
+
+// beginStopping starts the first phase of the shutdown: it closes the stopping channel and switches to
+// StateStopping, so readiness is withdrawn while the service drains. It is safe to call multiple times.
+func (s *supervisor) beginStopping() {
+	first := false
+	s.stoppingOnce.Do(func() {
+		close(s.stopping)
+		first = true
+	})
+
+	if first && s.State() != StateStopped {
+		s.setState(StateStopping)
+	}
+}
+
+// isStopping reports whether shutdown was requested.
+func (s *supervisor) isStopping() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdownContext returns a context for cleanup after ctx was cancelled: it keeps the values of ctx, is not
+// cancelled with it, and expires after timeout. A timeout of zero does not limit the cleanup.
+func shutdownContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx = context.WithoutCancel(ctx)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// forceExitFlushTimeout bounds the OTEL flush of a forced shutdown.
+const forceExitFlushTimeout = time.Second
+
+// handleShutdownSignals runs the two-phase shutdown once a signal is received on signals: it begins stopping,
+// waits for opts.DrainDelay, then calls cancel to cancel the service context. Stopping also begins if ctx is
+// cancelled otherwise.
+//
+// Signals keep being handled during the shutdown, so a hanging Close can be escalated: on the
+// opts.ForceExitSignals-th signal, the remaining shutdown is skipped, OTEL is flushed for at most a second, and the
+// process exits with opts.ForceExitCode. On the opts.ImmediateExitSignals-th signal, the process exits at once.
+// The returned function stops handling signals.
+func handleShutdownSignals(ctx context.Context, opts Options, signals <-chan os.Signal, cancel context.CancelFunc) func() {
+	sup := supervisorFrom(ctx)
+	stopAfter := context.AfterFunc(ctx, sup.beginStopping)
+
+	done := make(chan struct{})
+	go func() {
+		received := 0
+		for {
+			select {
+			case sig := <-signals:
+				received++
+				switch {
+				case received == 1:
+					sup.beginStopping()
+					go func() {
+						if opts.DrainDelay > 0 {
+							Logger(ctx).Info("shutdown requested, draining", "signal", sig.String(), "drain_delay", opts.DrainDelay.String())
+							_ = Sleep(ctx, opts.DrainDelay)
+						}
+						cancel()
+					}()
+				case opts.ImmediateExitSignals > 1 && received >= opts.ImmediateExitSignals:
+					if opts.exitProcess {
+						exitFunc(opts)(opts.ForceExitCode)
+					} else {
+						sup.abandon()
+					}
+				case opts.ForceExitSignals > 1 && received == opts.ForceExitSignals:
+					Logger(ctx).Error("forcing immediate shutdown", "signal", sig.String(), "signals", received)
+					go sup.forceExit(ctx, opts, opts.ForceExitCode)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopAfter()
+		close(done)
+	}
+}
+
+// forceExit skips the remaining shutdown: it flushes OTEL for at most forceExitFlushTimeout and exits the process
+// with code. Unless called by RunAndExit, the process is not exited, but RunC returns ErrForcedShutdown, see
+// abandon.
+func (s *supervisor) forceExit(ctx context.Context, opts Options, code int) {
+	s.mu.Lock()
+	flush := s.flushTelemetry
+	s.mu.Unlock()
+
+	if flush != nil {
+		flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forceExitFlushTimeout)
+		if err := flush(flushCtx); err != nil {
+			Logger(ctx).Warn("failed to flush OTEL before forced exit", "error", err)
+		}
+		cancel()
+	}
+
+	if !opts.exitProcess {
+		s.abandon()
+		return
+	}
+	exitFunc(opts)(code)
+}
+
+// abandon makes RunC return ErrForcedShutdown without waiting for the service. It is safe to call multiple times.
+func (s *supervisor) abandon() {
+	s.forcedOnce.Do(func() { close(s.forced) })
+}
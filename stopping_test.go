@@ -0,0 +1,280 @@
+package as
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"syscall"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestTwoPhaseShutdown(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const drainDelay = 10 * time.Second
+
+		ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+		sup := newSupervisor()
+		ctx = withSupervisor(ctx, sup)
+		sup.setState(StateRunning)
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		signals := make(chan os.Signal, 1)
+		stop := handleShutdownSignals(runCtx, Options{DrainDelay: drainDelay}, signals, cancel)
+		defer stop()
+
+		if IsStopping(runCtx) {
+			t.Fatal("stopping before the signal")
+		}
+
+		signals <- syscall.SIGTERM
+		synctest.Wait()
+
+		// Phase one: stopping, while the context is still alive
+		if !IsStopping(runCtx) {
+			t.Fatal("not stopping after the signal")
+		}
+		if state := CurrentState(runCtx); state != StateStopping {
+			t.Errorf("state = %s, want stopping", state)
+		}
+		if runCtx.Err() != nil {
+			t.Fatal("context cancelled before the drain delay")
+		}
+
+		time.Sleep(drainDelay - time.Millisecond)
+		synctest.Wait()
+		if runCtx.Err() != nil {
+			t.Fatal("context cancelled before the drain delay")
+		}
+
+		// Phase two: the context is cancelled once the drain delay elapsed
+		time.Sleep(time.Millisecond)
+		synctest.Wait()
+		if runCtx.Err() == nil {
+			t.Fatal("context not cancelled after the drain delay")
+		}
+	})
+}
+
+func TestStoppingOnCancellation(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+		ctx = withSupervisor(ctx, newSupervisor())
+		runCtx, cancel := context.WithCancel(ctx)
+
+		stop := handleShutdownSignals(runCtx, Options{DrainDelay: time.Minute}, nil, cancel)
+		defer stop()
+
+		// Cancellation by the parent skips the drain delay, but still closes the stopping channel
+		cancel()
+		synctest.Wait()
+
+		select {
+		case <-Stopping(runCtx):
+		default:
+			t.Error("not stopping after cancellation")
+		}
+	})
+}
+
+func TestStoppingWithoutSupervisor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if IsStopping(ctx) {
+		t.Error("IsStopping() = true before cancellation")
+	}
+
+	cancel()
+	if !IsStopping(ctx) {
+		t.Error("IsStopping() = false after cancellation")
+	}
+}
+
+func TestStoppingInService(t *testing.T) {
+	var cancelledWhenStopping bool
+	svc := &testService{run: func(ctx context.Context) error {
+		go supervisorFrom(ctx).beginStopping()
+
+		<-Stopping(ctx)
+		cancelledWhenStopping = ctx.Err() != nil
+		return nil
+	}}
+
+	if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if cancelledWhenStopping {
+		t.Error("context cancelled when stopping began")
+	}
+}
+
+func TestForceExit(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		logs := &logCapture{}
+		ctx := WithLogger(context.Background(), slog.New(slog.NewJSONHandler(logs, nil)))
+		sup := newSupervisor()
+		ctx = withSupervisor(ctx, sup)
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// The flush hangs, so it is abandoned after a second
+		flushed := false
+		sup.flushTelemetry = func(ctx context.Context) error {
+			flushed = true
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		start := time.Now()
+		exits := make(chan time.Duration, 3)
+		opts := Options{
+			ForceExitSignals:     2,
+			ImmediateExitSignals: 3,
+			ForceExitCode:        42,
+			exitProcess:          true,
+			ExitFunc: func(code int) {
+				if code != 42 {
+					t.Errorf("exit code = %d, want 42", code)
+				}
+				exits <- time.Since(start)
+			},
+		}
+
+		signals := make(chan os.Signal, 1)
+		stop := handleShutdownSignals(runCtx, opts, signals, cancel)
+		defer stop()
+
+		// The first signal starts the graceful shutdown
+		signals <- syscall.SIGTERM
+		synctest.Wait()
+		if !IsStopping(runCtx) || len(exits) != 0 {
+			t.Fatalf("stopping = %t, exits = %d after the first signal", IsStopping(runCtx), len(exits))
+		}
+
+		// The second forces the shutdown, flushing OTEL first
+		signals <- syscall.SIGTERM
+		synctest.Wait()
+		if !flushed || len(exits) != 0 {
+			t.Fatalf("flushed = %t, exits = %d after the second signal", flushed, len(exits))
+		}
+		if logs.find("forcing immediate shutdown") == nil {
+			t.Error("forced shutdown not logged")
+		}
+
+		// The third exits at once, while the flush is still running
+		signals <- syscall.SIGTERM
+		synctest.Wait()
+		if got := <-exits; got != 0 {
+			t.Errorf("immediate exit after %s, want at once", got)
+		}
+
+		time.Sleep(forceExitFlushTimeout)
+		synctest.Wait()
+		if got := <-exits; got != forceExitFlushTimeout {
+			t.Errorf("forced exit after %s, want %s", got, forceExitFlushTimeout)
+		}
+	})
+}
+
+func TestForceExitWithoutExit(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+		sup := newSupervisor()
+		flushed := false
+		sup.flushTelemetry = func(context.Context) error {
+			flushed = true
+			return nil
+		}
+		ctx = withSupervisor(ctx, sup)
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// Outside of RunAndExit, the forced shutdown abandons the service instead of exiting the process
+		exited := false
+		opts := DefaultOptions()
+		opts.ExitFunc = func(int) { exited = true }
+		signals := make(chan os.Signal, 1)
+		stop := handleShutdownSignals(runCtx, opts, signals, cancel)
+		defer stop()
+
+		for range 3 {
+			signals <- syscall.SIGTERM
+			synctest.Wait()
+		}
+		select {
+		case <-sup.forced:
+		default:
+			t.Error("service not abandoned")
+		}
+		if !flushed || exited {
+			t.Errorf("flushed = %t, exited = %t, want flushed without exiting", flushed, exited)
+		}
+	})
+}
+
+func TestForceExitDisabled(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+		ctx = withSupervisor(ctx, newSupervisor())
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		exited := false
+		opts := Options{ForceExitSignals: 1, ImmediateExitSignals: 0, exitProcess: true, ExitFunc: func(int) { exited = true }}
+		signals := make(chan os.Signal, 1)
+		stop := handleShutdownSignals(runCtx, opts, signals, cancel)
+		defer stop()
+
+		for range 5 {
+			signals <- syscall.SIGTERM
+			synctest.Wait()
+		}
+		if exited {
+			t.Error("exited although forcing is disabled")
+		}
+	})
+}
+
+func TestForceExitOnSecondSignal(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprint(enabled), func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+				sup := newSupervisor()
+				sup.flushTelemetry = func(context.Context) error { return nil }
+				ctx = withSupervisor(ctx, sup)
+				runCtx, cancel := context.WithCancel(ctx)
+				defer cancel()
+
+				var exits []int
+				opts := DefaultOptions()
+				WithForceExitOnSecondSignal(enabled)(&opts)
+				opts.exitProcess = true
+				opts.ExitFunc = func(code int) { exits = append(exits, code) }
+
+				signals := make(chan os.Signal, 1)
+				stop := handleShutdownSignals(runCtx, opts, signals, cancel)
+				defer stop()
+
+				signals <- syscall.SIGINT
+				synctest.Wait()
+				if !IsStopping(runCtx) || len(exits) != 0 {
+					t.Fatalf("stopping = %t, exits = %v after the first signal", IsStopping(runCtx), exits)
+				}
+
+				// Enabled, the second signal takes the force path with the default exit code
+				signals <- syscall.SIGINT
+				synctest.Wait()
+				if enabled && (len(exits) != 1 || exits[0] != opts.ForceExitCode) {
+					t.Errorf("exits = %v, want one with code %d", exits, opts.ForceExitCode)
+				}
+				if !enabled && len(exits) != 0 {
+					t.Errorf("exits = %v, want none", exits)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logExitSummary logs a single record summarizing the lifetime of the service: uptime, number of restarts and
+// recovered panics (and restarts counted against a shared RestartBudget), final status, and the reason the
+// supervisor stopped. The record is logged at Info level, or at Error level if the service exits with an error. Keys are stable, so they can be relied on by dashboards.
+func logExitSummary(ctx context.Context, sup *supervisor, err error) {
+	sup.mu.Lock()
+	uptime := time.Since(sup.started)
+	restarts := sup.restarts
+	panics := sup.panics
+	reason := sup.stopReason
+	budget := sup.restartBudget
+	hooks := sup.shutdownHookResults
+	sup.mu.Unlock()
+
+	level := slog.LevelInfo
+	status := "ok"
+	if err != nil {
+		level = slog.LevelError
+		status = "error"
+	}
+
+	attrs := []any{
+		"uptime", uptime.String(),
+		"uptime_seconds", uptime.Seconds(),
+		"restarts", restarts,
+		"panics", panics,
+		"status", status,
+		"reason", reason,
+	}
+	if budget != nil {
+		attrs = append(attrs, "shared_restarts", budget.restartCount())
+	}
+	if len(hooks) > 0 {
+		durations := make([]string, len(hooks))
+		for i, hook := range hooks {
+			durations[i] = hook.String()
+		}
+		attrs = append(attrs, "shutdown_hooks", durations)
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+
+	Logger(ctx).Log(ctx, level, "service exited", attrs...)
+}
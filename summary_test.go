@@ -0,0 +1,63 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExitSummary(t *testing.T) {
+	attempts := 0
+	svc := &testService{run: func(ctx context.Context) error {
+		if attempts++; attempts == 1 {
+			return errors.New("first attempt failed")
+		}
+		return nil
+	}}
+
+	logs := &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	summary := logs.find("service exited")
+	if summary == nil {
+		t.Fatal("no exit summary logged")
+	}
+
+	want := map[string]any{
+		"level":    "INFO",
+		"restarts": float64(1),
+		"panics":   float64(0),
+		"status":   "ok",
+		"reason":   "completed",
+	}
+	for key, value := range want {
+		if summary[key] != value {
+			t.Errorf("%s = %v, want %v", key, summary[key], value)
+		}
+	}
+	for _, key := range []string{"uptime", "uptime_seconds"} {
+		if _, ok := summary[key]; !ok {
+			t.Errorf("summary lacks %s", key)
+		}
+	}
+}
+
+func TestExitSummaryError(t *testing.T) {
+	svc := &testService{run: func(ctx context.Context) error { return errors.New("failed") }}
+
+	logs := &logCapture{}
+	err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs), WithRestartOnError(false))...)
+	if err == nil {
+		t.Fatal("RunC() = nil, want the error")
+	}
+
+	summary := logs.find("service exited")
+	if summary == nil {
+		t.Fatal("no exit summary logged")
+	}
+	if summary["level"] != "ERROR" || summary["status"] != "error" || summary["error"] == nil {
+		t.Errorf("summary = %v, want an error record", summary)
+	}
+}
@@ -0,0 +1,369 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// syslogFacility is the facility of all messages (daemon).
+	syslogFacility = 3
+	// syslogQueueSize is the number of messages buffered while the syslog endpoint is slow or unreachable.
+	syslogQueueSize = 1024
+	// syslogTimeout bounds connecting to and writing to the syslog endpoint, and draining the queue on close.
+	syslogTimeout = 5 * time.Second
+	// syslogMaxBackoff is the maximum delay between reconnection attempts.
+	syslogMaxBackoff = 30 * time.Second
+	// syslogSDID is the structured data ID carrying the attributes of a record.
+	syslogSDID = "attrs@32473"
+)
+
+// syslogWriter sends messages to a syslog endpoint from a background goroutine. Messages are queued in a bounded
+// buffer and dropped if it is full, so logging never blocks on a dead endpoint.
+type syslogWriter struct {
+	network string
+	addr    string
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan []byte
+
+	// ctx is cancelled to abandon the queued messages once draining them on close exceeded its deadline.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stopped is closed once the background goroutine exited and closed the connection.
+	stopped chan struct{}
+}
+
+// newSyslogWriter returns a writer sending to the endpoint and starts its background goroutine, which runs until
+// the writer is closed.
+func newSyslogWriter(network, addr string) *syslogWriter {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &syslogWriter{
+		network: network,
+		addr:    addr,
+		queue:   make(chan []byte, syslogQueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+// enqueue queues the message for sending, dropping it if the queue is full or the writer is closed.
+func (w *syslogWriter) enqueue(msg []byte) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		w.dropped.Add(1)
+		return
+	}
+
+	select {
+	case w.queue <- msg:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// close stops accepting messages and waits at most timeout for the queued ones to be sent. Messages still queued
+// afterward are dropped, and the connection is closed as soon as a pending write returned. It is safe to call
+// multiple times.
+func (w *syslogWriter) close(timeout time.Duration) {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.stopped:
+	case <-timer.C:
+		w.cancel()
+	}
+}
+
+// run sends queued messages, reconnecting with exponential backoff after failures, until the queue is closed and
+// drained or the writer is abandoned. Messages which cannot be sent are counted as dropped and reported once the
+// endpoint is reachable again.
+func (w *syslogWriter) run() {
+	defer close(w.stopped)
+	defer w.cancel()
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	dialer := &net.Dialer{Timeout: syslogTimeout}
+	backoff := 100 * time.Millisecond
+
+	for msg := range w.queue {
+		if w.ctx.Err() != nil {
+			return
+		}
+
+		if conn == nil {
+			c, err := dialer.DialContext(w.ctx, w.network, w.addr)
+			if err != nil {
+				w.dropped.Add(1)
+				if Sleep(w.ctx, backoff) != nil {
+					return
+				}
+				backoff = min(2*backoff, syslogMaxBackoff)
+				continue
+			}
+
+			conn = c
+			backoff = 100 * time.Millisecond
+		}
+
+		if dropped := w.dropped.Swap(0); dropped > 0 {
+			notice := formatSyslogMessage(time.Now(), slog.LevelWarn, "", fmt.Sprintf("dropped %d log records", dropped), nil)
+			msg = append(w.frame(notice), w.frame(msg)...)
+		} else {
+			msg = w.frame(msg)
+		}
+
+		_ = conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		if _, err := conn.Write(msg); err != nil {
+			_ = conn.Close()
+			conn = nil
+			w.dropped.Add(1)
+		}
+	}
+}
+
+// frame prefixes the message with its length for stream transports (RFC 6587 octet counting).
+func (w *syslogWriter) frame(msg []byte) []byte {
+	if w.network != "tcp" {
+		return msg
+	}
+
+	return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+}
+
+// syslogHandler is a slog.Handler formatting records as RFC 5424 messages, carrying the attributes as structured data.
+type syslogHandler struct {
+	writer  *syslogWriter
+	level   slog.Leveler
+	appName string
+	prefix  string
+	params  []logField
+}
+
+// newSyslogHandler returns a handler sending records of at least the given level to the syslog endpoint of the
+// output, which is either "syslog" for the local /dev/log socket, "syslog:///path/to/socket" or
+// "syslog://host[:port][?proto=udp|tcp]".
+func newSyslogHandler(output string, level slog.Leveler, appName string) (*syslogHandler, error) {
+	network, addr, err := parseSyslogOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if network == "unixgram" {
+		if _, err := os.Stat(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	return &syslogHandler{
+		writer:  newSyslogWriter(network, addr),
+		level:   level,
+		appName: appName,
+	}, nil
+}
+
+// parseSyslogOutput parses a syslog LogOutput into the network and address of the endpoint.
+func parseSyslogOutput(output string) (network, addr string, err error) {
+	if output == "syslog" {
+		return "unixgram", "/dev/log", nil
+	}
+
+	u, err := url.Parse(output)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "syslog" {
+		return "", "", errors.New("invalid syslog output " + strconv.Quote(output))
+	}
+
+	if u.Host == "" {
+		if u.Path == "" {
+			return "unixgram", "/dev/log", nil
+		}
+
+		return "unixgram", u.Path, nil
+	}
+
+	network = u.Query().Get("proto")
+	switch network {
+	case "":
+		network = "udp"
+	case "udp", "tcp":
+	default:
+		return "", "", errors.New("invalid syslog protocol " + strconv.Quote(network))
+	}
+
+	addr = u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "514")
+	}
+
+	return network, addr, nil
+}
+
+// Enabled reports whether the handler handles records of the level.
+func (h *syslogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle formats the record and queues it for sending.
+func (h *syslogHandler) Handle(_ context.Context, r slog.Record) error {
+	params := append([]logField(nil), h.params...)
+	r.Attrs(func(a slog.Attr) bool {
+		params = appendSyslogParam(params, h.prefix, a)
+		return true
+	})
+
+	h.writer.enqueue(formatSyslogMessage(r.Time, r.Level, h.appName, r.Message, params))
+
+	return nil
+}
+
+// WithAttrs returns a handler sending the attributes with each record.
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.params = append([]logField(nil), h.params...)
+	for _, a := range attrs {
+		h2.params = appendSyslogParam(h2.params, h.prefix, a)
+	}
+
+	return &h2
+}
+
+// WithGroup returns a handler prefixing the names of subsequent attributes with the group name.
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+
+	return &h2
+}
+
+// appendSyslogParam appends the attribute to params as structured data parameter, flattening groups.
+func appendSyslogParam(params []logField, prefix string, a slog.Attr) []logField {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return params
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = prefix + a.Key + "."
+		}
+
+		for _, ga := range a.Value.Group() {
+			params = appendSyslogParam(params, groupPrefix, ga)
+		}
+
+		return params
+	}
+
+	value := a.Value.String()
+	if a.Value.Kind() == slog.KindTime {
+		value = a.Value.Time().Format(time.RFC3339Nano)
+	}
+
+	return append(params, logField{syslogParamName(prefix + a.Key), value})
+}
+
+// syslogParamName returns a valid structured data parameter name: at most 32 printable ASCII characters,
+// excluding '=', ' ', ']' and '"'.
+func syslogParamName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+
+	if len(b) > 32 {
+		b = b[:32]
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+
+	return string(b)
+}
+
+// formatSyslogMessage formats an RFC 5424 message.
+func formatSyslogMessage(t time.Time, level slog.Level, appName, msg string, params []logField) []byte {
+	hostname, _ := os.Hostname()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - ",
+		syslogFacility*8+journalPriority(level),
+		t.UTC().Format(time.RFC3339Nano),
+		syslogHeaderField(hostname),
+		syslogHeaderField(appName),
+		os.Getpid(),
+	)
+
+	if len(params) == 0 {
+		b.WriteString("-")
+	} else {
+		b.WriteString("[" + syslogSDID)
+		for _, p := range params {
+			b.WriteString(" " + p.key + `="`)
+			b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(p.value))
+			b.WriteString(`"`)
+		}
+		b.WriteString("]")
+	}
+
+	b.WriteString(" " + msg)
+
+	return []byte(b.String())
+}
+
+// syslogHeaderField returns the value as header field, replacing characters which are not printable ASCII and
+// using the nil value "-" for empty values.
+func syslogHeaderField(v string) string {
+	if v == "" {
+		return "-"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r >= 127 {
+			return '_'
+		}
+		return r
+	}, v)
+}
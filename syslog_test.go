@@ -0,0 +1,191 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listenSyslogUDP listens for syslog messages on a local UDP port and returns the output sending to it.
+func listenSyslogUDP(t *testing.T) (*net.UDPConn, string) {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn, "syslog://" + conn.LocalAddr().String() + "?proto=udp"
+}
+
+// readSyslogMessage reads the next datagram from the listener.
+func readSyslogMessage(t *testing.T, conn *net.UDPConn) string {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1<<16)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf[:n])
+}
+
+func TestParseSyslogOutput(t *testing.T) {
+	tests := []struct {
+		output      string
+		wantNetwork string
+		wantAddr    string
+		wantErr     bool
+	}{
+		{output: "syslog", wantNetwork: "unixgram", wantAddr: "/dev/log"},
+		{output: "syslog:///var/run/syslog", wantNetwork: "unixgram", wantAddr: "/var/run/syslog"},
+		{output: "syslog://logs.example.com", wantNetwork: "udp", wantAddr: "logs.example.com:514"},
+		{output: "syslog://logs.example.com:1514?proto=tcp", wantNetwork: "tcp", wantAddr: "logs.example.com:1514"},
+		{output: "syslog://logs.example.com?proto=sctp", wantErr: true},
+		{output: "syslogx://logs.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		network, addr, err := parseSyslogOutput(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSyslogOutput(%q) error = %v, want error %t", tt.output, err, tt.wantErr)
+			continue
+		}
+		if network != tt.wantNetwork || addr != tt.wantAddr {
+			t.Errorf("parseSyslogOutput(%q) = %s, %s, want %s, %s", tt.output, network, addr, tt.wantNetwork, tt.wantAddr)
+		}
+	}
+}
+
+func TestSyslogHandler(t *testing.T) {
+	listener, output := listenSyslogUDP(t)
+
+	h, err := newSyslogHandler(output, slog.LevelInfo, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.writer.close(time.Second)
+
+	logger := slog.New(h).With("service", "test").WithGroup("http")
+	logger.Debug("not sent")
+	logger.Warn("slow request", "method", "GET", "path", `/a"b]`)
+
+	// <facility daemon * 8 + severity warning>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+	want := regexp.MustCompile(`^<28>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?Z \S+ test ` + strconv.Itoa(os.Getpid()) + ` - ` +
+		`\[attrs@32473 service="test" http\.method="GET" http\.path="/a\\"b\\]"\] slow request$`)
+	if got := readSyslogMessage(t, listener); !want.MatchString(got) {
+		t.Errorf("message = %q, want match of %s", got, want)
+	}
+}
+
+func TestFormatSyslogMessageWithoutParams(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := string(formatSyslogMessage(ts, slog.LevelError, "", "failed", nil))
+
+	want := regexp.MustCompile(`^<27>1 2026-01-02T03:04:05Z \S+ - \d+ - - failed$`)
+	if !want.MatchString(got) {
+		t.Errorf("message = %q, want match of %s", got, want)
+	}
+}
+
+func TestSyslogWriterClose(t *testing.T) {
+	listener, output := listenSyslogUDP(t)
+	network, addr, err := parseSyslogOutput(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := newSyslogWriter(network, addr)
+	for i := range 3 {
+		w.enqueue([]byte("message " + strconv.Itoa(i)))
+	}
+	w.close(5 * time.Second)
+
+	select {
+	case <-w.stopped:
+	default:
+		t.Fatal("writer goroutine still running after close")
+	}
+
+	// The queued messages were sent before closing
+	for i := range 3 {
+		if got, want := readSyslogMessage(t, listener), "message "+strconv.Itoa(i); got != want {
+			t.Errorf("message = %q, want %q", got, want)
+		}
+	}
+
+	// Messages logged after close are dropped instead of panicking
+	w.enqueue([]byte("late"))
+	if w.dropped.Load() != 1 {
+		t.Errorf("dropped = %d, want 1", w.dropped.Load())
+	}
+	w.close(time.Second)
+}
+
+func TestSyslogWriterCloseDeadEndpoint(t *testing.T) {
+	// Nothing listens on the port of a closed listener, so every connection attempt fails
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	w := newSyslogWriter("tcp", addr)
+	for range syslogQueueSize + 10 {
+		w.enqueue([]byte("message"))
+	}
+
+	start := time.Now()
+	w.close(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("close took %s, want it bounded by its timeout", elapsed)
+	}
+
+	select {
+	case <-w.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer goroutine did not stop after the drain deadline")
+	}
+}
+
+func TestSyslogOutputClosedOnShutdown(t *testing.T) {
+	listener, output := listenSyslogUDP(t)
+
+	var w *syslogWriter
+	svc := &testService{run: func(ctx context.Context) error {
+		sup := supervisorFrom(ctx)
+		sup.mu.Lock()
+		w = sup.logSyslog
+		sup.mu.Unlock()
+
+		Logger(ctx).Info("running")
+		return nil
+	}}
+	if err := RunC(svc, context.Background(), testOptions(WithLogOutput(output))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	if w == nil {
+		t.Fatal("no syslog writer registered")
+	}
+	select {
+	case <-w.stopped:
+	default:
+		t.Error("syslog writer goroutine still running after RunC returned")
+	}
+
+	// Records logged before the shutdown were flushed
+	found := false
+	for !found {
+		found = regexp.MustCompile(` running$`).MatchString(readSyslogMessage(t, listener))
+	}
+}
@@ -0,0 +1,134 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Tasks is a group of goroutines whose lifetime is bound to the service, similar to errgroup.Group.
+// Tasks are started with Go and waited for with Wait. Use TaskGroup to create a group.
+//
+// The context of the group is cancelled when the first task fails, when shutdown of the service begins (see
+// Stopping), or when the context the group was created with is cancelled. Tasks thus stop as soon as the service
+// drains, not only after DrainDelay. Since the supervisor cancels the context passed to Run once Run returns, and
+// waits for all tasks to return (for at most ShutdownTimeout) before calling Close, tasks are always stopped before
+// the service is closed.
+type Tasks struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	sup    *supervisor
+
+	propagate bool
+	live      metric.Int64UpDownCounter
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// TaskGroupOption is a function which applies a configuration change to a task group.
+type TaskGroupOption func(*Tasks)
+
+// WithPropagateTaskErrors makes the first failing task fail the service, as if Run returned its error:
+// the context passed to Run is cancelled and the task error becomes the service error, subject to the
+// restart policy.
+func WithPropagateTaskErrors(v bool) TaskGroupOption {
+	return func(t *Tasks) { t.propagate = v }
+}
+
+// TaskGroup returns a new, empty task group bound to the service the context belongs to.
+// It is intended to be called from Run, with the context passed to Run.
+func TaskGroup(ctx context.Context, opts ...TaskGroupOption) *Tasks {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	t := &Tasks{
+		ctx:    ctx,
+		cancel: cancel,
+		sup:    supervisorFrom(ctx),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.sup != nil {
+		go func() {
+			select {
+			case <-t.sup.stopping:
+				cancel(context.Canceled)
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	live, err := Meter(ctx).Int64UpDownCounter(
+		"as.tasks.live",
+		metric.WithDescription("Number of running tasks started by as.TaskGroup"),
+	)
+	if err == nil {
+		t.live = live
+	}
+
+	return t
+}
+
+// Context returns the context of the group, which is cancelled when the first task fails or the service begins
+// draining.
+func (t *Tasks) Context() context.Context {
+	return t.ctx
+}
+
+// Go runs fn in a new goroutine as part of the group. The context passed to fn is the context of the group,
+// with the task name added to the logger as "task" attribute.
+//
+// Panics in fn are recovered and handled like errors. The first error cancels the context of the group and is
+// returned by Wait. Errors caused by the cancellation of the group context are ignored.
+func (t *Tasks) Go(name string, fn func(ctx context.Context) error) {
+	t.wg.Add(1)
+	supDone := func() {}
+	if t.sup != nil {
+		supDone = t.sup.trackGoroutine()
+	}
+	if t.live != nil {
+		t.live.Add(t.ctx, 1)
+	}
+
+	go func() {
+		defer func() {
+			if t.live != nil {
+				t.live.Add(t.ctx, -1)
+			}
+			supDone()
+			t.wg.Done()
+		}()
+
+		ctx := WithLogger(t.ctx, Logger(t.ctx).With("task", name))
+
+		err := runGoroutine(ctx, fn)
+		if err == nil || (t.ctx.Err() != nil && errors.Is(err, context.Canceled)) {
+			return
+		}
+
+		err = &goroutineError{name: name, err: err}
+		Logger(ctx).Error("task failed", "error", err)
+
+		t.errOnce.Do(func() {
+			t.err = err
+			t.cancel(err)
+
+			if t.propagate && t.sup != nil {
+				t.sup.failAttempt(err)
+			}
+		})
+	}()
+}
+
+// Wait blocks until all tasks of the group returned, then returns the first task error, if any.
+func (t *Tasks) Wait() error {
+	t.wg.Wait()
+	t.cancel(context.Canceled)
+
+	return t.err
+}
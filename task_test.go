@@ -0,0 +1,102 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTasksErrorPropagation(t *testing.T) {
+	errFailed := errors.New("failed")
+	tasks := TaskGroup(context.Background())
+
+	tasks.Go("failing", func(ctx context.Context) error { return errFailed })
+	tasks.Go("waiting", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	tasks.Go("panicking", func(ctx context.Context) error {
+		<-ctx.Done()
+		panic("boom")
+	})
+
+	err := tasks.Wait()
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("Wait() = %v, want the first task error", err)
+	}
+	if tasks.Context().Err() == nil {
+		t.Error("group context not cancelled")
+	}
+}
+
+func TestTasksPropagateToService(t *testing.T) {
+	errFailed := errors.New("failed")
+	svc := &testService{run: func(ctx context.Context) error {
+		tasks := TaskGroup(ctx, WithPropagateTaskErrors(true))
+		tasks.Go("failing", func(ctx context.Context) error { return errFailed })
+
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	err := RunC(svc, context.Background(), testOptions(WithRestartOnError(false))...)
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("RunC() = %v, want the task error", err)
+	}
+}
+
+func TestTasksCancelledWhenDraining(t *testing.T) {
+	sup := newSupervisor()
+	ctx, cancel := context.WithCancel(withSupervisor(context.Background(), sup))
+	defer cancel()
+
+	tasks := TaskGroup(ctx)
+	tasks.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// The group is cancelled when draining begins, before the context passed to Run
+	sup.beginStopping()
+	if err := tasks.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want the cancellation ignored", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("context of the service cancelled")
+	}
+}
+
+func TestTasksStoppedBeforeClose(t *testing.T) {
+	var running atomic.Int64
+	var runningAtClose int64 = -1
+	svc := &testService{
+		run: func(ctx context.Context) error {
+			tasks := TaskGroup(ctx)
+			for range 3 {
+				running.Add(1)
+				tasks.Go("worker", func(ctx context.Context) error {
+					defer running.Add(-1)
+					<-ctx.Done()
+					time.Sleep(20 * time.Millisecond)
+					return nil
+				})
+			}
+
+			// Run returns without waiting for its tasks
+			return nil
+		},
+		close: func(ctx context.Context) error {
+			runningAtClose = running.Load()
+			return nil
+		},
+	}
+
+	if err := RunC(svc, context.Background(), testOptions()...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if runningAtClose != 0 {
+		t.Errorf("%d tasks were running when Close was called, want 0", runningAtClose)
+	}
+}
@@ -0,0 +1,127 @@
+package as
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/trace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.aledante.io/ae"
+)
+
+// traceFilePrefix is the file name prefix of execution traces.
+const traceFilePrefix = "trace-"
+
+// traceCapturing guards against concurrent captures, since the execution tracer is global to the process.
+var traceCapturing atomic.Bool
+
+// traceCapture writes execution traces to the crash directory of a service.
+type traceCapture struct {
+	dir    string
+	retain int
+}
+
+// CaptureTrace captures a runtime execution trace (see runtime/trace) for d, or until ctx is done, and writes it
+// to the crash directory of the service the context belongs to. It blocks until the capture is complete and
+// returns the path of the trace file. Only the CrashRetain most recent traces are kept.
+//
+// An error is returned if no crash directory is configured or another capture is running. Traces are viewed with
+// go tool trace.
+func CaptureTrace(ctx context.Context, d time.Duration) (string, error) {
+	sup := supervisorFrom(ctx)
+	if sup == nil || sup.traces == nil {
+		return "", ae.New().Msg("cannot capture trace, no crash directory configured")
+	}
+
+	return sup.traces.capture(ctx, d)
+}
+
+// capture captures an execution trace for d or until ctx is done.
+func (t *traceCapture) capture(ctx context.Context, d time.Duration) (string, error) {
+	if !traceCapturing.CompareAndSwap(false, true) {
+		return "", ae.New().Msg("trace capture already running")
+	}
+	defer traceCapturing.Store(false)
+
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return "", ae.Wrap("failed to create crash directory", err)
+	}
+
+	path := filepath.Join(t.dir, traceFilePrefix+time.Now().UTC().Format("20060102T150405.000000000Z")+".out")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", ae.Wrap("failed to create trace file", err)
+	}
+
+	if err := trace.Start(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return "", ae.Wrap("failed to start trace", err)
+	}
+
+	Logger(ctx).Info("capturing execution trace", "path", path, "duration", d.String())
+	aborted := Sleep(ctx, d) != nil
+	trace.Stop()
+
+	if err := f.Close(); err != nil {
+		return "", ae.Wrap("failed to write trace file", err)
+	}
+
+	Logger(ctx).Info("wrote execution trace", "path", path, "aborted", aborted)
+	pruneFiles(ctx, t.dir, traceFilePrefix, t.retain)
+
+	return path, nil
+}
+
+// initTraceCapture enables CaptureTrace if a crash directory is configured and, if opts.SignalTrace is positive,
+// captures a trace of that duration whenever the process receives SIGUSR2. It returns a function which stops
+// handling the signal and aborts a running capture, waiting for its file to be closed.
+func initTraceCapture(ctx context.Context, opts Options) func() {
+	sup := supervisorFrom(ctx)
+	if sup == nil || opts.CrashDir == "" {
+		if opts.SignalTrace > 0 {
+			Logger(ctx).Warn("cannot capture traces on SIGUSR2, no crash directory configured")
+		}
+		return func() {}
+	}
+
+	sup.traces = &traceCapture{
+		dir:    opts.CrashDir,
+		retain: opts.CrashRetain,
+	}
+
+	if opts.SignalTrace <= 0 || len(traceSignals) == 0 {
+		return func() {}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, traceSignals...)
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigs:
+				if _, err := sup.traces.capture(ctx, opts.SignalTrace); err != nil {
+					Logger(ctx).Error("failed to capture trace", "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigs)
+		cancel()
+		wg.Wait()
+	}
+}
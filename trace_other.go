@@ -0,0 +1,8 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package as
+
+import "os"
+
+// traceSignals are the signals triggering an execution trace capture; SIGUSR2 is not available on this platform.
+var traceSignals []os.Signal
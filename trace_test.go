@@ -0,0 +1,120 @@
+package as
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// traceFiles returns the execution traces in dir.
+func traceFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, traceFilePrefix+"*.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return files
+}
+
+// traceContext returns the context of a supervised service capturing traces into dir.
+func traceContext(t *testing.T, dir string, retain int) context.Context {
+	t.Helper()
+
+	ctx := WithLogger(context.Background(), slog.New(slog.DiscardHandler))
+	ctx = withSupervisor(ctx, newSupervisor())
+	t.Cleanup(initTraceCapture(ctx, Options{CrashDir: dir, CrashRetain: retain}))
+
+	return ctx
+}
+
+func TestCaptureTrace(t *testing.T) {
+	dir := t.TempDir()
+	ctx := traceContext(t, dir, 10)
+
+	path, err := CaptureTrace(ctx, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("CaptureTrace() = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() == 0 {
+		t.Error("trace file is empty")
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("trace written to %s, want it in %s", path, dir)
+	}
+}
+
+func TestCaptureTraceConcurrent(t *testing.T) {
+	ctx := traceContext(t, t.TempDir(), 10)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := CaptureTrace(ctx, 200*time.Millisecond)
+		done <- err
+	}()
+	waitFor(t, "capture", traceCapturing.Load)
+
+	if _, err := CaptureTrace(ctx, time.Millisecond); err == nil {
+		t.Error("concurrent CaptureTrace() = nil error, want it rejected")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("CaptureTrace() = %v", err)
+	}
+}
+
+func TestCaptureTraceAbort(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(traceContext(t, dir, 10))
+
+	// Shutdown aborts a running capture, still writing a complete file
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	path, err := CaptureTrace(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("CaptureTrace() = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("aborted capture took %s", elapsed)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		t.Errorf("trace file of the aborted capture = %v, %v", info, err)
+	}
+}
+
+func TestCaptureTraceRetain(t *testing.T) {
+	dir := t.TempDir()
+	ctx := traceContext(t, dir, 2)
+
+	var last string
+	for range 3 {
+		path, err := CaptureTrace(ctx, time.Millisecond)
+		if err != nil {
+			t.Fatalf("CaptureTrace() = %v", err)
+		}
+		last = path
+	}
+
+	files := traceFiles(t, dir)
+	if len(files) != 2 {
+		t.Fatalf("kept %d traces, want 2", len(files))
+	}
+	if files[1] != last {
+		t.Errorf("kept %v, want the latest %s", files, last)
+	}
+}
+
+func TestCaptureTraceWithoutCrashDir(t *testing.T) {
+	ctx := withSupervisor(context.Background(), newSupervisor())
+	if _, err := CaptureTrace(ctx, time.Millisecond); err == nil {
+		t.Error("CaptureTrace() = nil error without a crash directory")
+	}
+}
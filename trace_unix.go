@@ -0,0 +1,11 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package as
+
+import (
+	"os"
+	"syscall"
+)
+
+// traceSignals are the signals triggering an execution trace capture.
+var traceSignals = []os.Signal{syscall.SIGUSR2}
@@ -0,0 +1,34 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package as
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalTrace(t *testing.T) {
+	dir := t.TempDir()
+	svc := &testService{run: func(ctx context.Context) error {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+			return err
+		}
+		waitFor(t, "trace file", func() bool { return len(traceFiles(t, dir)) == 1 && !traceCapturing.Load() })
+		return nil
+	}}
+
+	if err := RunC(svc, context.Background(), testOptions(WithCrashDir(dir), WithSignalTrace(10*time.Millisecond))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	info, err := os.Stat(traceFiles(t, dir)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() == 0 {
+		t.Error("trace file is empty")
+	}
+}
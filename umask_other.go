@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package as
+
+import "os"
+
+// setUmask is a no-op on this platform, e.g. on Windows, which has no umask.
+func setUmask(mask os.FileMode) os.FileMode {
+	return 0
+}
@@ -0,0 +1,13 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package as
+
+import (
+	"os"
+	"syscall"
+)
+
+// setUmask sets the umask of the process, returning the previous one.
+func setUmask(mask os.FileMode) os.FileMode {
+	return os.FileMode(syscall.Umask(int(mask.Perm())))
+}
@@ -0,0 +1,119 @@
+package as
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.aledante.io/ae"
+)
+
+// Validate checks the options for invalid values and contradicting fields, returning an error naming each
+// offending field, its value, and why it is invalid. RunC validates the options after applying the environment
+// and fails with ErrInvalidConfig; embedders may call Validate on DefaultOptions with their options applied to
+// check them in advance.
+//
+// Merely suspicious combinations, e.g. a restart delay exceeding the grace period, are not errors; RunC logs
+// them as warnings.
+func (o Options) Validate() error {
+	var errs []error
+	invalid := func(field string, value any, reason string) {
+		errs = append(errs, ae.New().Msg(fmt.Sprintf("%s %v: %s", field, value, reason)))
+	}
+
+	// DiagnosticsInterval is not listed, since a negative value disables the diagnostics
+	for _, d := range []struct {
+		field string
+		value time.Duration
+	}{
+		{"RestartOnErrorDelay", o.RestartOnErrorDelay},
+		{"RestartOnPanicDelay", o.RestartOnPanicDelay},
+		{"GracePeriod", o.GracePeriod},
+		{"FlapWindow", o.FlapWindow},
+		{"ShutdownTimeout", o.ShutdownTimeout},
+		{"DrainDelay", o.DrainDelay},
+		{"MetricExportInterval", o.MetricExportInterval},
+		{"HTTPClientTimeout", o.HTTPClientTimeout},
+		{"HTTPClientSlowThreshold", o.HTTPClientSlowThreshold},
+		{"BreakerWindow", o.BreakerWindow},
+		{"BreakerCooldown", o.BreakerCooldown},
+		{"SignalTrace", o.SignalTrace},
+		{"MinimumRunDuration", o.MinimumRunDuration},
+		{"MemoryCheckInterval", o.MemoryCheckInterval},
+		{"StartupProbeBudget", o.StartupProbeBudget},
+		{"MaxLifetime", o.MaxLifetime},
+		{"HeartbeatInterval", o.HeartbeatInterval},
+		{"InitWarnAfter", o.InitWarnAfter},
+		{"CloseWarnAfter", o.CloseWarnAfter},
+		{"RequestedRestartDelay", o.RequestedRestartDelay},
+	} {
+		if d.value < 0 {
+			invalid(d.field, d.value, "must not be negative")
+		}
+	}
+
+	// ForceExitSignals and ImmediateExitSignals are disabled by any value below 2, and ForceExitCode is passed to
+	// ExitFunc as is
+	for _, n := range []struct {
+		field string
+		value int
+	}{
+		{"GraceCount", o.GraceCount},
+		{"MaxLifetimeRestarts", o.MaxLifetimeRestarts},
+		{"LeakCheckAttempts", o.LeakCheckAttempts},
+		{"LeakCheckThreshold", o.LeakCheckThreshold},
+		{"HealthHistorySize", o.HealthHistorySize},
+		{"FlapThreshold", o.FlapThreshold},
+		{"BreakerFailures", o.BreakerFailures},
+		{"CrashRetain", o.CrashRetain},
+		{"CrashLogLines", o.CrashLogLines},
+		{"MaxDegradations", o.MaxDegradations},
+	} {
+		if n.value < 0 {
+			invalid(n.field, n.value, "must not be negative")
+		}
+	}
+
+	if o.AutoMemLimit && (o.MemLimitRatio <= 0 || o.MemLimitRatio > 1) {
+		invalid("MemLimitRatio", o.MemLimitRatio, "must be in (0, 1]")
+	}
+	if o.MemorySoftLimitRatio < 0 || o.MemorySoftLimitRatio > 1 {
+		invalid("MemorySoftLimitRatio", o.MemorySoftLimitRatio, "must be in [0, 1]")
+	}
+	if o.Umask != nil && *o.Umask&^os.ModePerm != 0 {
+		invalid("Umask", fmt.Sprintf("%04o", uint32(*o.Umask)), "must only contain permission bits")
+	}
+	if o.StopOnFirstExit != nil && o.RestartOnSuccess {
+		invalid("RestartOnSuccess", o.RestartOnSuccess, "cannot be combined with StopOnFirstExit")
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return ae.WrapMany(fmt.Sprintf("%d invalid options", len(errs)), errs...)
+}
+
+// validationWarnings returns the suspicious combinations of the options which are not rejected by Validate.
+func (o Options) validationWarnings() []string {
+	var warnings []string
+
+	if _, ok := parseLogLevel(o.LogLevel); !ok {
+		warnings = append(warnings, fmt.Sprintf("unknown LogLevel %q, using the default level info", o.LogLevel))
+	}
+	if o.RestartOnPanic && !o.RecoverPanic {
+		warnings = append(warnings, "RestartOnPanic has no effect without RecoverPanic, panics crash the process")
+	}
+	if o.RestartOnError && o.GracePeriod > 0 && o.RestartOnErrorDelay >= o.GracePeriod {
+		warnings = append(warnings, fmt.Sprintf(
+			"RestartOnErrorDelay %s is not below GracePeriod %s, so no restart happens within the grace period",
+			o.RestartOnErrorDelay, o.GracePeriod))
+	}
+	if o.RestartOnPanic && o.GracePeriod > 0 && o.RestartOnPanicDelay >= o.GracePeriod {
+		warnings = append(warnings, fmt.Sprintf(
+			"RestartOnPanicDelay %s is not below GracePeriod %s, so no restart happens within the grace period",
+			o.RestartOnPanicDelay, o.GracePeriod))
+	}
+
+	return warnings
+}
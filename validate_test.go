@@ -0,0 +1,157 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr []string
+	}{
+		{name: "defaults"},
+		{name: "zero values", opts: []Option{WithGraceCount(0), WithShutdownTimeout(0), WithMaxLifetimeRestarts(0)}},
+		{name: "negative grace count", opts: []Option{WithGraceCount(-1)}, wantErr: []string{"GraceCount -1: must not be negative"}},
+		{name: "negative shutdown timeout", opts: []Option{WithShutdownTimeout(-time.Second)}, wantErr: []string{"ShutdownTimeout -1s: must not be negative"}},
+		{
+			name:    "several fields",
+			opts:    []Option{WithRestartOnErrorDelay(-time.Millisecond), WithMaxLifetimeRestarts(-2)},
+			wantErr: []string{"RestartOnErrorDelay -1ms: must not be negative", "MaxLifetimeRestarts -2: must not be negative"},
+		},
+		{name: "memory limit ratio", opts: []Option{WithAutoMemLimit(true), WithMemLimitRatio(1.5)}, wantErr: []string{"MemLimitRatio 1.5: must be in (0, 1]"}},
+		{name: "memory limit ratio unused", opts: []Option{WithAutoMemLimit(false), WithMemLimitRatio(1.5)}},
+		{name: "soft memory limit ratio", opts: []Option{func(o *Options) { o.MemorySoftLimitRatio = -0.1 }}, wantErr: []string{"MemorySoftLimitRatio -0.1: must be in [0, 1]"}},
+		{
+			name:    "stop on first exit with restart on success",
+			opts:    []Option{WithStopOnFirstExit(NewExitGroup()), WithRestartOnSuccess(true)},
+			wantErr: []string{"RestartOnSuccess true: cannot be combined with StopOnFirstExit"},
+		},
+		// Suspicious combinations are only warnings
+		{name: "delay above grace period", opts: []Option{WithRestartOnErrorDelay(time.Hour), WithGracePeriod(time.Minute)}},
+		{name: "restart on panic without recover", opts: []Option{WithRestartOnPanic(true), WithRecoverPanic(false)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := DefaultOptions()
+			for _, opt := range tt.opts {
+				opt(&o)
+			}
+
+			err := o.Validate()
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("Validate() = %v, want errors %q", err, tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() = %q, lacks %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateNegative(t *testing.T) {
+	// Fields for which negative values are meaningful
+	allowed := []string{"DiagnosticsInterval", "ForceExitSignals", "ImmediateExitSignals", "ForceExitCode"}
+
+	typ := reflect.TypeFor[Options]()
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() || (field.Type.Kind() != reflect.Int && field.Type != reflect.TypeFor[time.Duration]()) {
+			continue
+		}
+
+		t.Run(field.Name, func(t *testing.T) {
+			o := DefaultOptions()
+			reflect.ValueOf(&o).Elem().Field(i).SetInt(-1)
+
+			err := o.Validate()
+			if slices.Contains(allowed, field.Name) {
+				if err != nil {
+					t.Errorf("Validate() = %v, want negative values allowed", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), field.Name+" -1") {
+				t.Errorf("Validate() = %v, want the negative value rejected", err)
+			}
+		})
+	}
+}
+
+func TestValidationWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{name: "defaults"},
+		{
+			name: "restart on panic without recover",
+			opts: []Option{WithRestartOnPanic(true), WithRecoverPanic(false)},
+			want: []string{"RestartOnPanic has no effect without RecoverPanic, panics crash the process"},
+		},
+		{
+			name: "error delay above grace period",
+			opts: []Option{WithRestartOnErrorDelay(time.Hour), WithGracePeriod(time.Minute)},
+			want: []string{"RestartOnErrorDelay 1h0m0s is not below GracePeriod 1m0s, so no restart happens within the grace period"},
+		},
+		{name: "error delay without restarts", opts: []Option{WithRestartOnError(false), WithRestartOnErrorDelay(time.Hour), WithGracePeriod(time.Minute)}},
+		{name: "error delay without grace period", opts: []Option{WithRestartOnErrorDelay(time.Hour), WithGracePeriod(0)}},
+		{
+			name: "panic delay above grace period",
+			opts: []Option{WithRestartOnPanic(true), WithRecoverPanic(true), WithRestartOnPanicDelay(time.Minute), WithGracePeriod(time.Minute)},
+			want: []string{"RestartOnPanicDelay 1m0s is not below GracePeriod 1m0s, so no restart happens within the grace period"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := DefaultOptions()
+			for _, opt := range tt.opts {
+				opt(&o)
+			}
+
+			if got := o.validationWarnings(); !slices.Equal(got, tt.want) {
+				t.Errorf("validationWarnings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRunC(t *testing.T) {
+	// Invalid env vars are validated like options set in code
+	t.Setenv("ASTEST_TEST_GRACE_COUNT", "-3")
+
+	runs := 0
+	svc := &testService{run: func(ctx context.Context) error {
+		runs++
+		return nil
+	}}
+	err := RunC(svc, context.Background(), testOptions()...)
+	if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "GraceCount -3") {
+		t.Errorf("RunC() = %v, want ErrInvalidConfig naming GraceCount", err)
+	}
+	if runs != 0 {
+		t.Error("service run with invalid options")
+	}
+
+	// Warnings do not stop the service
+	t.Setenv("ASTEST_TEST_GRACE_COUNT", "3")
+	logs := &logCapture{}
+	opts := testOptions(captureLogs(svc, logs), WithRestartOnErrorDelay(time.Hour), WithGracePeriod(time.Minute))
+	if err := RunC(svc, context.Background(), opts...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+	if warning := logs.find("RestartOnErrorDelay 1h0m0s is not below GracePeriod 1m0s, so no restart happens within the grace period"); warning == nil || warning["level"] != "WARN" {
+		t.Errorf("warning logged as %v", warning)
+	}
+}
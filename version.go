@@ -1,6 +1,12 @@
 package as
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
 
 // versionKey is an unexported type used as the key for storing the version value in a context.
 type versionKey struct{}
@@ -25,3 +31,72 @@ func Version(ctx context.Context) string {
 
 	return v
 }
+
+// versionOverride is the version set with SetVersion.
+var versionOverride atomic.Pointer[string]
+
+// SetVersion sets the version used for services whose Version method returns "" or "dev", unless overridden by the
+// SERVICE_VERSION option. It is intended to be called from main with a variable set by the linker, e.g.
+//
+//	var version = "dev" // go build -ldflags "-X main.version=1.2.3"
+//
+//	func main() {
+//		as.SetVersion(version)
+//		as.RunAndExit(svc)
+//	}
+func SetVersion(v string) {
+	versionOverride.Store(&v)
+}
+
+// resolveVersion returns the version of a service whose Version method returned version. Versions other than ""
+// and "dev" are used as is. Otherwise, the first non-empty of opts.ServiceVersion, the version set with SetVersion,
+// and the version derived from the build info is used.
+func resolveVersion(version string, opts Options) string {
+	if version != "" && version != "dev" {
+		return version
+	}
+
+	if opts.ServiceVersion != "" {
+		return opts.ServiceVersion
+	}
+	if v := versionOverride.Load(); v != nil && *v != "" && *v != "dev" {
+		return *v
+	}
+	if v := buildVersion(); v != "" {
+		return v
+	}
+
+	return version
+}
+
+// buildVersion derives a version from the build info: the module version if the binary was built with
+// go install, or a pseudo-version (v0.0.0-<commit time>-<revision>) of the VCS revision otherwise.
+func buildVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		return bi.Main.Version
+	}
+
+	var revision, commitTime string
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.time":
+			commitTime = setting.Value
+		}
+	}
+	if revision == "" {
+		return ""
+	}
+
+	t, err := time.Parse(time.RFC3339, commitTime)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("v0.0.0-%s-%s", t.UTC().Format("20060102150405"), revision[:min(len(revision), 12)])
+}
@@ -0,0 +1,82 @@
+package as
+
+import (
+	"context"
+	"testing"
+)
+
+// versionedService is a testService with the given version.
+type versionedService struct {
+	testService
+	version string
+}
+
+func (s *versionedService) Version() string { return s.version }
+
+// setTestVersion sets the version of SetVersion for the test.
+func setTestVersion(t *testing.T, v string) {
+	t.Helper()
+
+	SetVersion(v)
+	t.Cleanup(func() { versionOverride.Store(nil) })
+}
+
+func TestResolveVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    string
+		env        string
+		setVersion string
+		want       string
+	}{
+		{name: "method", version: "v1.0.0", env: "v2.0.0", setVersion: "v3.0.0", want: "v1.0.0"},
+		{name: "env", version: "", env: "v2.0.0", setVersion: "v3.0.0", want: "v2.0.0"},
+		{name: "env over dev", version: "dev", env: "v2.0.0", setVersion: "v3.0.0", want: "v2.0.0"},
+		{name: "SetVersion", version: "dev", setVersion: "v3.0.0", want: "v3.0.0"},
+		{name: "SetVersion dev", version: "", setVersion: "dev", want: buildVersion()},
+		{name: "build info", version: "", want: buildVersion()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setVersion != "" {
+				setTestVersion(t, tt.setVersion)
+			}
+
+			want := tt.want
+			if want == "" {
+				// Without build info, the version of the service is kept
+				want = tt.version
+			}
+			if got := resolveVersion(tt.version, Options{ServiceVersion: tt.env}); got != want {
+				t.Errorf("resolveVersion() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestVersionOverride(t *testing.T) {
+	t.Setenv("ASTEST_TEST_SERVICE_VERSION", "v2.0.0")
+	setTestVersion(t, "v3.0.0")
+
+	var version string
+	svc := &versionedService{version: "dev"}
+	svc.run = func(ctx context.Context) error {
+		version = Version(ctx)
+		Logger(ctx).Info("running")
+		return nil
+	}
+
+	logs := &logCapture{}
+	if err := RunC(svc, context.Background(), testOptions(captureLogs(svc, logs))...); err != nil {
+		t.Fatalf("RunC() = %v", err)
+	}
+
+	// The substituted version is used by the context and the logger alike
+	if version != "v2.0.0" {
+		t.Errorf("Version() = %q, want %q", version, "v2.0.0")
+	}
+	if record := logs.find("running"); record == nil || record["version"] != "v2.0.0" {
+		t.Errorf("record = %v, want version v2.0.0", record)
+	}
+}
@@ -0,0 +1,197 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.aledante.io/ae"
+)
+
+// configPollInterval is the interval in which WatchConfigFile checks the modification time of the config file.
+const configPollInterval = 5 * time.Second
+
+// Validator is implemented by configuration types which can validate themselves.
+// Configurations reloaded by WatchEnv and WatchConfigFile are only applied if validation succeeds.
+type Validator interface {
+	Validate() error
+}
+
+// Config holds a configuration reloaded by WatchEnv or WatchConfigFile. It is safe for concurrent use: Load
+// always returns a complete configuration, either the current or the reloaded one.
+type Config[T any] struct {
+	value atomic.Pointer[T]
+}
+
+// Load returns the current configuration.
+func (c *Config[T]) Load() T {
+	return *c.value.Load()
+}
+
+// store publishes cfg as the current configuration.
+func (c *Config[T]) store(cfg T) {
+	c.value.Store(&cfg)
+}
+
+// WatchEnv loads the configuration of type T from the prefixed environment (see LoadEnv), and reloads it
+// whenever the process receives SIGHUP. The returned Config always holds the current configuration.
+//
+// On each reload, the new configuration is validated if it implements Validator and, if it differs from the
+// current one, onChange is called with the current and the new configuration. Only if both succeed, the new
+// configuration is published; otherwise the error is logged and the current configuration is kept. Since
+// onChange runs before the new configuration is published, Load still returns the old one while it runs.
+// Reloads are serialized, so onChange is never called concurrently.
+//
+// The watcher runs in a goroutine started by Go and stops when ctx is cancelled.
+func WatchEnv[T any](ctx context.Context, onChange func(old, new T) error) (*Config[T], error) {
+	w := &configWatcher[T]{
+		config:   &Config[T]{},
+		onChange: onChange,
+		load: func() (T, error) {
+			return LoadEnv[T](ctx)
+		},
+	}
+
+	return w.config, w.start(ctx, "env", nil)
+}
+
+// WatchConfigFile loads the configuration of type T from the JSON file at path, and reloads it whenever the
+// modification time of the file changes or the process receives SIGHUP. The file is checked every 5 seconds.
+// Reloads behave as described for WatchEnv.
+func WatchConfigFile[T any](ctx context.Context, path string, onChange func(old, new T) error) (*Config[T], error) {
+	var modTime time.Time
+	if fi, err := os.Stat(path); err == nil {
+		modTime = fi.ModTime()
+	}
+
+	w := &configWatcher[T]{
+		config:   &Config[T]{},
+		onChange: onChange,
+		load: func() (T, error) {
+			var cfg T
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return cfg, err
+			}
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				return cfg, ae.Wrap("failed to parse config file", err)
+			}
+
+			return cfg, nil
+		},
+	}
+
+	changed := func() bool {
+		fi, err := os.Stat(path)
+		if err != nil || fi.ModTime().Equal(modTime) {
+			return false
+		}
+
+		modTime = fi.ModTime()
+		return true
+	}
+
+	return w.config, w.start(ctx, path, changed)
+}
+
+// configWatcher reloads a configuration of type T.
+type configWatcher[T any] struct {
+	mu       sync.Mutex
+	config   *Config[T]
+	load     func() (T, error)
+	onChange func(old, new T) error
+}
+
+// start loads the initial configuration into the Config and starts a goroutine reloading it on SIGHUP or,
+// if changed is non-nil, whenever changed reports a change when polled.
+func (w *configWatcher[T]) start(ctx context.Context, source string, changed func() bool) error {
+	cfg, err := w.load()
+	if err != nil {
+		return ae.Wrap("failed to load configuration", err)
+	}
+	if err := validateConfig(cfg); err != nil {
+		return ae.Wrap("invalid configuration", err)
+	}
+	w.config.store(cfg)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var poll <-chan time.Time
+	if changed != nil {
+		ticker := time.NewTicker(configPollInterval)
+		context.AfterFunc(ctx, ticker.Stop)
+		poll = ticker.C
+	}
+
+	Go(ctx, "config-watcher", func(ctx context.Context) error {
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-hup:
+				w.reload(ctx, source, "signal")
+			case <-poll:
+				if changed() {
+					w.reload(ctx, source, "file change")
+				}
+			}
+		}
+	})
+
+	return nil
+}
+
+// reload loads, validates, and applies the configuration, publishing it once onChange succeeded.
+func (w *configWatcher[T]) reload(ctx context.Context, source, trigger string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	logger := Logger(ctx).With("config_source", source, "trigger", trigger)
+
+	cfg, err := w.load()
+	if err == nil {
+		err = validateConfig(cfg)
+	}
+	if err != nil {
+		logger.Error("failed to reload configuration, keeping the current one", "error", err)
+		return
+	}
+
+	old := w.config.Load()
+	if reflect.DeepEqual(old, cfg) {
+		logger.Debug("configuration unchanged")
+		return
+	}
+
+	if w.onChange != nil {
+		if err := w.onChange(old, cfg); err != nil {
+			logger.Error("failed to apply configuration, keeping the current one", "error", err)
+			return
+		}
+	}
+
+	w.config.store(cfg)
+	logger.Info("configuration reloaded")
+}
+
+// validateConfig validates cfg if it (or a pointer to it) implements Validator.
+func validateConfig[T any](cfg T) error {
+	if v, ok := any(cfg).(Validator); ok {
+		return v.Validate()
+	}
+	if v, ok := any(&cfg).(Validator); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
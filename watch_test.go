@@ -0,0 +1,109 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type watchedConfig struct {
+	Port int `json:"port"`
+}
+
+func (c watchedConfig) Validate() error {
+	if c.Port <= 0 {
+		return errors.New("port must be positive")
+	}
+	return nil
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reload := func() {
+		t.Helper()
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type change struct{ old, new, loaded watchedConfig }
+	changes := make(chan change, 1)
+	var config *Config[watchedConfig]
+
+	write(`{"port": 8080}`)
+	config, err := WatchConfigFile(ctx, path, func(old, new watchedConfig) error {
+		// The new configuration is published only once onChange succeeded
+		changes <- change{old: old, new: new, loaded: config.Load()}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Load(); got.Port != 8080 {
+		t.Fatalf("initial config = %+v, want port 8080", got)
+	}
+
+	// Readers may load the configuration concurrently with reloads
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_ = config.Load()
+			}
+		}
+	}()
+
+	write(`{"port": 9090}`)
+	reload()
+	select {
+	case c := <-changes:
+		if c.old.Port != 8080 || c.new.Port != 9090 || c.loaded.Port != 8080 {
+			t.Errorf("onChange observed %+v, want 8080 -> 9090 with 8080 loaded", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("onChange not called")
+	}
+	waitFor(t, "new configuration", func() bool { return config.Load().Port == 9090 })
+
+	// Invalid configurations are not applied
+	write(`{"port": -1}`)
+	reload()
+	select {
+	case c := <-changes:
+		t.Errorf("onChange called for an invalid configuration: %+v", c)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if got := config.Load(); got.Port != 9090 {
+		t.Errorf("config = %+v after invalid reload, want port 9090", got)
+	}
+}
+
+func TestWatchConfigFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"port": 0}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := WatchConfigFile[watchedConfig](context.Background(), path, nil); err == nil {
+		t.Error("WatchConfigFile() = nil, want the validation error")
+	}
+}